@@ -0,0 +1,83 @@
+// Command rulecompare reports how far the configured decision list fitter is from the
+// optimal rule found by exact search, for the clusters of one query.
+//
+// Usage:
+//
+//	go run ./cmd/rulecompare -query "headphones" [-budget 2s]
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"ize/internal/algolia"
+	"ize/internal/config"
+	"ize/internal/ize"
+	"ize/internal/logger"
+)
+
+func main() {
+	query := flag.String("query", "", "search query to cluster")
+	budget := flag.Duration("budget", 2*time.Second, "exact search time budget per cluster")
+	flag.Parse()
+
+	log := logger.Default()
+
+	cfg, err := config.Load()
+	if err != nil {
+		log.ErrorWithErr("failed to load configuration", err)
+		os.Exit(1)
+	}
+
+	algoliaClient, err := algolia.NewClientWithConfig(
+		cfg.AlgoliaAppID,
+		cfg.AlgoliaAPIKey,
+		cfg.AlgoliaIndexName,
+		cfg.FieldMapping,
+		cfg.GetFacetFields(),
+		log,
+	)
+	if err != nil {
+		log.ErrorWithErr("failed to create algolia client", err)
+		os.Exit(1)
+	}
+
+	results, err := algoliaClient.SearchRipper(context.Background(), *query, nil)
+	if err != nil {
+		log.ErrorWithErr("algolia search failed", err, "query", *query)
+		os.Exit(1)
+	}
+
+	opts := ize.ClusterOptionsFromConfig(cfg)
+	opts.RuleFit.ExactBudget = *budget
+
+	comparisons, err := ize.CompareRuleFits(*query, results, opts, log)
+	if err != nil {
+		log.ErrorWithErr("rule comparison failed", err, "query", *query)
+		os.Exit(1)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "CLUSTER\tSIZE\tFITTED\tSCORE\tEXACT\tSCORE\tGAP\tEXACT TIME")
+	for _, c := range comparisons {
+		exactTime := c.Exact.Duration.Round(time.Millisecond).String()
+		if c.ExactTimedOut {
+			exactTime += " (timed out)"
+		}
+		fmt.Fprintf(w, "%d\t%d\t%s\t%.3f\t%s\t%.3f\t%.3f\t%s\n",
+			c.Cluster, c.Size,
+			c.Fitted.Rule, c.Fitted.Score,
+			c.Exact.Rule, c.Exact.Score,
+			c.Gap(), exactTime,
+		)
+	}
+	w.Flush()
+
+	if len(comparisons) > 0 {
+		fmt.Printf("\nScores use the %q objective.\n", comparisons[0].Objective)
+	}
+}
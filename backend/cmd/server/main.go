@@ -1,21 +1,29 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"ize/internal/config"
 	"ize/internal/httpapi"
 	"ize/internal/logger"
 )
 
+// shutdownTimeout bounds how long shutdown waits for requests in progress
+const shutdownTimeout = 30 * time.Second
+
 // corsMiddleware adds CORS headers to allow requests from the frontend
 func corsMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		// Set CORS headers
 		w.Header().Set("Access-Control-Allow-Origin", "http://localhost:5173")
-		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
 		
 		// Handle preflight requests
 		if r.Method == "OPTIONS" {
@@ -98,6 +106,93 @@ func main() {
 		searchHandler.HandleCluster(w, r)
 	})
 
+	// Cluster items endpoint ("load more" through a cluster's rule)
+	mux.HandleFunc("/api/cluster/items", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodOptions {
+			// Handle preflight
+			w.Header().Set("Access-Control-Allow-Origin", "http://localhost:5173")
+			w.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS")
+			w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+		searchHandler.HandleClusterItems(w, r)
+	})
+
+	// Cluster endpoint streaming labels as Server-Sent Events
+	mux.HandleFunc("/api/cluster/stream", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodOptions {
+			// Handle preflight
+			w.Header().Set("Access-Control-Allow-Origin", "http://localhost:5173")
+			w.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS")
+			w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+		searchHandler.HandleClusterStream(w, r)
+	})
+
+	// Query suggestion endpoint
+	mux.HandleFunc("/api/suggest", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodOptions {
+			// Handle preflight
+			w.Header().Set("Access-Control-Allow-Origin", "http://localhost:5173")
+			w.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS")
+			w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+		searchHandler.HandleSuggest(w, r)
+	})
+
+	// Scatter/Gather browsing endpoint
+	mux.HandleFunc("/api/scatter-gather", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodOptions {
+			// Handle preflight
+			w.Header().Set("Access-Control-Allow-Origin", "http://localhost:5173")
+			w.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS")
+			w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+		searchHandler.HandleScatterGather(w, r)
+	})
+
+	// Curation endpoint (merge, split and rename clusters)
+	mux.HandleFunc("/api/curate", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodOptions {
+			// Handle preflight
+			w.Header().Set("Access-Control-Allow-Origin", "http://localhost:5173")
+			w.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS")
+			w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+		searchHandler.HandleCurate(w, r)
+	})
+
+	// Admin API for curated groupings
+	mux.HandleFunc("/api/admin/curations", searchHandler.HandleAdminCurations)
+
+	// Admin API for the cluster label cache
+	mux.HandleFunc("/api/admin/label-cache", searchHandler.HandleAdminLabelCache)
+
+	// Admin API for labeling token usage and cost
+	mux.HandleFunc("/api/admin/labeling-usage", searchHandler.HandleAdminLabelingUsage)
+
+	// Topics endpoint
+	mux.HandleFunc("/api/topics", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodOptions {
+			// Handle preflight
+			w.Header().Set("Access-Control-Allow-Origin", "http://localhost:5173")
+			w.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS")
+			w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+		searchHandler.HandleTopics(w, r)
+	})
+
 	port := cfg.Port
 	if port == "" {
 		port = "8080"
@@ -106,11 +201,32 @@ func main() {
 	addr := fmt.Sprintf(":%s", port)
 	log.Info("server starting", "address", addr)
 	
-	// Chain middleware: request ID logging -> CORS -> mux
-	handler := logger.RequestIDMiddleware(log, corsMiddleware(mux))
+	// Chain middleware: request ID logging -> CORS -> labeling usage logging -> mux
+	handler := logger.RequestIDMiddleware(log, corsMiddleware(searchHandler.LogLabelingUsage(mux)))
 	
-	if err := http.ListenAndServe(addr, handler); err != nil {
+	server := &http.Server{Addr: addr, Handler: handler}
+	errs := make(chan error, 1)
+	go func() {
+		errs <- server.ListenAndServe()
+	}()
+
+	// Stop on SIGINT or SIGTERM, letting requests in progress finish
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	select {
+	case err := <-errs:
 		log.ErrorWithErr("server failed to start", err, "address", addr)
 		panic(err)
+	case <-ctx.Done():
+	}
+
+	log.Info("server shutting down")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		log.ErrorWithErr("server shutdown failed", err)
 	}
+	// Write the labels still waiting for the label cache's flush delay
+	searchHandler.Close()
+	log.Info("server stopped")
 }
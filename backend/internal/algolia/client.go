@@ -75,6 +75,7 @@ type Hit struct {
 	Description string                 `json:"description"`
 	Image       string                 `json:"image"`
 	Facets      map[string]interface{} `json:"facets,omitempty"`
+	Vector      []float64              `json:"vector,omitempty"` // Precomputed embedding (when field_mapping.vector is set)
 }
 
 // extractHitFields extracts name, description, and image from raw hit data using field mapping
@@ -93,6 +94,9 @@ func (c *Client) extractHitFields(rawHit map[string]interface{}) Hit {
 		hit.Name = config.ExtractField(rawHit, c.fieldMapping.Name)
 		hit.Description = config.ExtractField(rawHit, c.fieldMapping.Description)
 		hit.Image = config.ExtractField(rawHit, c.fieldMapping.Image)
+		if c.fieldMapping.Vector != "" {
+			hit.Vector = extractVector(config.ExtractFieldValue(rawHit, c.fieldMapping.Vector))
+		}
 	} else {
 		// Legacy behavior: direct field access
 		if name, ok := rawHit["name"].(string); ok {
@@ -135,11 +139,31 @@ func (c *Client) extractHitFields(rawHit map[string]interface{}) Hit {
 	return hit
 }
 
+// extractVector converts a raw JSON array of numbers into a vector
+// Returns nil if the value is not a non-empty array of numbers
+func extractVector(value interface{}) []float64 {
+	arr, ok := value.([]interface{})
+	if !ok || len(arr) == 0 {
+		return nil
+	}
+	vec := make([]float64, len(arr))
+	for i, v := range arr {
+		f, ok := v.(float64)
+		if !ok {
+			return nil
+		}
+		vec[i] = f
+	}
+	return vec
+}
+
 // SearchResult represents the full search response from Algolia
 type SearchResult struct {
 	Hits      []Hit                       `json:"hits"`
 	Facets    map[string]map[string]int32 `json:"facets,omitempty"`
-	TotalHits int                         `json:"nbHits"` // Total number of matching records
+	TotalHits int                         `json:"nbHits"`            // Total number of matching records
+	Page      int                         `json:"page,omitempty"`    // Zero-based page (SearchPage only)
+	NbPages   int                         `json:"nbPages,omitempty"` // Number of reachable pages (SearchPage only)
 }
 
 // Search performs a search query against Algolia
@@ -153,30 +177,7 @@ func (c *Client) Search(ctx context.Context, query string, facetFilters [][]stri
 		"facet_fields", c.facetFields,
 	)
 
-	var facetFiltersParam *search.FacetFilters
-	if len(facetFilters) > 0 {
-		// Represent `[[a,b], c]` style where outer array is AND and inner arrays are OR.
-		// Algolia v4 SDK uses a union type for this.
-		outer := make([]search.FacetFilters, 0, len(facetFilters))
-		for _, group := range facetFilters {
-			if len(group) == 0 {
-				continue
-			}
-			if len(group) == 1 {
-				outer = append(outer, *search.StringAsFacetFilters(group[0]))
-				continue
-			}
-
-			inner := make([]search.FacetFilters, 0, len(group))
-			for _, f := range group {
-				inner = append(inner, *search.StringAsFacetFilters(f))
-			}
-			outer = append(outer, *search.ArrayOfFacetFiltersAsFacetFilters(inner))
-		}
-		if len(outer) > 0 {
-			facetFiltersParam = search.ArrayOfFacetFiltersAsFacetFilters(outer)
-		}
-	}
+	facetFiltersParam := buildFacetFilters(facetFilters)
 
 	// Request all attributes to be retrieved so field mapping can access any field
 	attributesToRetrieve := []string{"*"}
@@ -209,30 +210,12 @@ func (c *Client) Search(ctx context.Context, query string, facetFilters [][]stri
 	}
 
 	// Extract hits from the response using JSON marshaling/unmarshaling
-	var hits []Hit
-	if res.Hits != nil {
-		// Marshal the hits to JSON and then unmarshal into raw maps
-		hitsJSON, err := json.Marshal(res.Hits)
-		if err != nil {
-			log.ErrorWithErr("failed to marshal algolia hits", err,
-				"query", query,
-			)
-			return nil, fmt.Errorf("failed to marshal hits: %w", err)
-		}
-
-		var rawHits []map[string]interface{}
-		if err := json.Unmarshal(hitsJSON, &rawHits); err != nil {
-			log.ErrorWithErr("failed to unmarshal algolia hits", err,
-				"query", query,
-			)
-			return nil, fmt.Errorf("failed to unmarshal hits: %w", err)
-		}
-
-		// Convert to Hit structs using field mapping
-		hits = make([]Hit, 0, len(rawHits))
-		for _, rawHit := range rawHits {
-			hits = append(hits, c.extractHitFields(rawHit))
-		}
+	hits, err := c.convertHits(res.Hits)
+	if err != nil {
+		log.ErrorWithErr("failed to decode algolia hits", err,
+			"query", query,
+		)
+		return nil, err
 	}
 
 	log.Debug("algolia search completed successfully",
@@ -266,28 +249,7 @@ func (c *Client) SearchRipper(ctx context.Context, query string, facetFilters []
 
 	hitsPerPage := int32(100)
 
-	var facetFiltersParam *search.FacetFilters
-	if len(facetFilters) > 0 {
-		outer := make([]search.FacetFilters, 0, len(facetFilters))
-		for _, group := range facetFilters {
-			if len(group) == 0 {
-				continue
-			}
-			if len(group) == 1 {
-				outer = append(outer, *search.StringAsFacetFilters(group[0]))
-				continue
-			}
-
-			inner := make([]search.FacetFilters, 0, len(group))
-			for _, f := range group {
-				inner = append(inner, *search.StringAsFacetFilters(f))
-			}
-			outer = append(outer, *search.ArrayOfFacetFiltersAsFacetFilters(inner))
-		}
-		if len(outer) > 0 {
-			facetFiltersParam = search.ArrayOfFacetFiltersAsFacetFilters(outer)
-		}
-	}
+	facetFiltersParam := buildFacetFilters(facetFilters)
 	// Request all attributes to be retrieved so facet values are included in hits
 	attributesToRetrieve := []string{"*"}
 	// Disable highlighting to avoid SDK unmarshalling issues with complex highlight results
@@ -316,30 +278,12 @@ func (c *Client) SearchRipper(ctx context.Context, query string, facetFilters []
 		return nil, fmt.Errorf("algolia search failed: %w", err)
 	}
 
-	var hits []Hit
-	if res.Hits != nil {
-		hitsJSON, err := json.Marshal(res.Hits)
-		if err != nil {
-			log.ErrorWithErr("failed to marshal algolia hits", err,
-				"query", query,
-			)
-			return nil, fmt.Errorf("failed to marshal hits: %w", err)
-		}
-
-		// Unmarshal into a slice of maps first to capture all fields
-		var rawHits []map[string]interface{}
-		if err := json.Unmarshal(hitsJSON, &rawHits); err != nil {
-			log.ErrorWithErr("failed to unmarshal algolia hits", err,
-				"query", query,
-			)
-			return nil, fmt.Errorf("failed to unmarshal hits: %w", err)
-		}
-
-		// Convert to Hit structs using field mapping
-		hits = make([]Hit, 0, len(rawHits))
-		for _, rawHit := range rawHits {
-			hits = append(hits, c.extractHitFields(rawHit))
-		}
+	hits, err := c.convertHits(res.Hits)
+	if err != nil {
+		log.ErrorWithErr("failed to decode algolia hits", err,
+			"query", query,
+		)
+		return nil, err
 	}
 
 	log.Debug("algolia search completed successfully for RIPPER",
@@ -358,3 +302,113 @@ func (c *Client) SearchRipper(ctx context.Context, query string, facetFilters []
 		TotalHits: int(res.NbHits),
 	}, nil
 }
+
+// SearchPage retrieves one page of results, for paging through the full result set.
+// page is zero-based; Algolia caps how deep pagination can go (paginationLimitedTo,
+// 1000 hits by default), so NbPages may be less than TotalHits / hitsPerPage.
+func (c *Client) SearchPage(ctx context.Context, query string, facetFilters [][]string, page, hitsPerPage int) (*SearchResult, error) {
+	log := c.logger.WithContext(ctx)
+
+	log.Debug("executing algolia paged search",
+		"query", query,
+		"facet_filters", facetFilters,
+		"index_name", c.indexName,
+		"page", page,
+		"hits_per_page", hitsPerPage,
+	)
+
+	searchParamsObject := search.SearchParamsObject{
+		Query:                 &query,
+		FacetFilters:          buildFacetFilters(facetFilters),
+		Page:                  ptr(int32(page)),
+		HitsPerPage:           ptr(int32(hitsPerPage)),
+		AttributesToRetrieve:  []string{"*"},
+		AttributesToHighlight: []string{},
+		Analytics:             ptr(false), // Disable analytics to avoid corrupting production metrics
+	}
+	searchParams := search.SearchParamsObjectAsSearchParams(&searchParamsObject)
+
+	request := c.client.NewApiSearchSingleIndexRequest(c.indexName)
+	request = request.WithSearchParams(searchParams)
+
+	res, err := c.client.SearchSingleIndex(request)
+	if err != nil {
+		log.ErrorWithErr("algolia paged search API call failed", err,
+			"query", query,
+			"index_name", c.indexName,
+			"page", page,
+		)
+		return nil, fmt.Errorf("algolia search failed: %w", err)
+	}
+
+	hits, err := c.convertHits(res.Hits)
+	if err != nil {
+		log.ErrorWithErr("failed to decode algolia hits", err,
+			"query", query,
+		)
+		return nil, err
+	}
+
+	log.Debug("algolia paged search completed successfully",
+		"query", query,
+		"page", page,
+		"hits_count", len(hits),
+		"nb_hits", res.NbHits,
+	)
+
+	return &SearchResult{
+		Hits:      hits,
+		TotalHits: int(res.NbHits),
+		Page:      int(res.Page),
+		NbPages:   int(res.NbPages),
+	}, nil
+}
+
+// buildFacetFilters converts `[[a,b], c]` style filters, where the outer array is AND and
+// inner arrays are OR, to the Algolia v4 SDK union type. Returns nil if there are none.
+func buildFacetFilters(facetFilters [][]string) *search.FacetFilters {
+	outer := make([]search.FacetFilters, 0, len(facetFilters))
+	for _, group := range facetFilters {
+		if len(group) == 0 {
+			continue
+		}
+		if len(group) == 1 {
+			outer = append(outer, *search.StringAsFacetFilters(group[0]))
+			continue
+		}
+
+		inner := make([]search.FacetFilters, 0, len(group))
+		for _, f := range group {
+			inner = append(inner, *search.StringAsFacetFilters(f))
+		}
+		outer = append(outer, *search.ArrayOfFacetFiltersAsFacetFilters(inner))
+	}
+	if len(outer) == 0 {
+		return nil
+	}
+	return search.ArrayOfFacetFiltersAsFacetFilters(outer)
+}
+
+// convertHits decodes SDK hits into Hit structs using the field mapping
+func (c *Client) convertHits(resHits []search.Hit) ([]Hit, error) {
+	if resHits == nil {
+		return nil, nil
+	}
+
+	// Marshal the hits to JSON and then unmarshal into raw maps to capture all fields
+	hitsJSON, err := json.Marshal(resHits)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal hits: %w", err)
+	}
+
+	var rawHits []map[string]interface{}
+	if err := json.Unmarshal(hitsJSON, &rawHits); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal hits: %w", err)
+	}
+
+	hits := make([]Hit, 0, len(rawHits))
+	for _, rawHit := range rawHits {
+		hits = append(hits, c.extractHitFields(rawHit))
+	}
+	return hits, nil
+}
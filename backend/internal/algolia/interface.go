@@ -11,4 +11,6 @@ type ClientInterface interface {
 	Search(ctx context.Context, query string, facetFilters [][]string) (*SearchResult, error)
 	// SearchRipper performs a search with 100 hits per page for RIPPER algorithm
 	SearchRipper(ctx context.Context, query string, facetFilters [][]string) (*SearchResult, error)
+	// SearchPage retrieves one zero-based page of hitsPerPage results, with the total hit count
+	SearchPage(ctx context.Context, query string, facetFilters [][]string, page, hitsPerPage int) (*SearchResult, error)
 }
@@ -15,26 +15,106 @@ import (
 // Supports dot notation (e.g., "attributes.Product_Description") and
 // array index notation (e.g., "images[0]") for accessing nested fields.
 type FieldMapping struct {
-	Name        string `json:"name"`        // Path to name field, e.g., "name.en-US" or "name_ecomm"
-	Description string `json:"description"` // Path to description field, e.g., "attributes.Product_Description"
-	Image       string `json:"image"`       // Path to image field, e.g., "images[0]"
+	Name        string `json:"name"`             // Path to name field, e.g., "name.en-US" or "name_ecomm"
+	Description string `json:"description"`      // Path to description field, e.g., "attributes.Product_Description"
+	Image       string `json:"image"`            // Path to image field, e.g., "images[0]"
+	Vector      string `json:"vector,omitempty"` // Optional path to a precomputed embedding, e.g., "embedding"
 }
 
 // FacetConfig configures which facets to retrieve and how to display them.
 type FacetConfig struct {
-	Field        string `json:"field"`                  // Algolia facet name, e.g., "attributes.Brand"
-	DisplayName  string `json:"displayName"`            // User-friendly name for UI, e.g., "Brand"
-	RemovePrefix string `json:"removePrefix,omitempty"` // Optional prefix to strip from facet values, e.g., "Materials > "
+	Field        string  `json:"field"`                  // Algolia facet name, e.g., "attributes.Brand"
+	DisplayName  string  `json:"displayName"`            // User-friendly name for UI, e.g., "Brand"
+	RemovePrefix string  `json:"removePrefix,omitempty"` // Optional prefix to strip from facet values, e.g., "Materials > "
+	Weight       float64 `json:"weight,omitempty"`       // Relative informativeness for weighted clustering distance (0 = default of 1)
+}
+
+// ClusteringConfig tunes the similarity clustering algorithm.
+type ClusteringConfig struct {
+	Distance    string  `json:"distance,omitempty"`      // Distance metric: "jaccard" (default) or "weighted_jaccard"
+	TextWeight  float64 `json:"text_weight,omitempty"`   // Blend of name/description TF-IDF similarity into facet distance (0-1)
+	LSHMinItems int     `json:"lsh_min_items,omitempty"` // Hit count at which MinHash LSH replaces exact pairwise distances (0 = never)
+	LSHBands    int     `json:"lsh_bands,omitempty"`     // More bands find more neighbor pairs (more accurate, slower)
+	LSHRows     int     `json:"lsh_rows,omitempty"`      // More rows per band require higher similarity to pair (faster, less accurate)
+
+	RuleObjective          string  `json:"rule_objective,omitempty"`           // Rule fitting objective: "recall" (default), "fbeta", "laplace" or "exclusive"
+	RuleBeta               float64 `json:"rule_beta,omitempty"`                // F-beta weight of recall vs precision (default 1; < 1 favors precision)
+	RuleBeamWidth          int     `json:"rule_beam_width,omitempty"`          // Partial rules kept per search step (default 1 = greedy)
+	RuleExclusivityPenalty float64 `json:"rule_exclusivity_penalty,omitempty"` // Weight of the sibling-overlap penalty for "exclusive" (default 1)
+	RuleExact              bool    `json:"rule_exact,omitempty"`               // Find optimal rules by branch-and-bound (falls back when over budget)
+	RuleExactBudgetMs      int     `json:"rule_exact_budget_ms,omitempty"`     // Exact search time budget per cluster in milliseconds (default 250)
+	Assignment             string  `json:"assignment,omitempty"`               // Default item assignment: "overlapping" (default), "first_match" or "best_match"
+
+	CountConcurrency  int     `json:"count_concurrency,omitempty"`  // Parallel Algolia count queries for exact cluster sizes (default 4)
+	IdentityThreshold float64 `json:"identity_threshold,omitempty"` // Similarity at which a cluster keeps its identity across refinements (default 0.3)
+}
+
+// EmbeddingConfig configures semantic embeddings for clustering.
+type EmbeddingConfig struct {
+	Provider   string  `json:"provider"`             // "hashing", "precomputed" (field_mapping.vector) or "openai"
+	Weight     float64 `json:"weight,omitempty"`     // Blend of embedding cosine distance into clustering distance (0-1)
+	Dimensions int     `json:"dimensions,omitempty"` // Vector size for the hashing provider
+	BaseURL    string  `json:"base_url,omitempty"`   // OpenAI-compatible API root, e.g., "http://localhost:11434/v1"
+	APIKey     string  `json:"api_key,omitempty"`    // API key for the openai provider (or EMBEDDING_API_KEY)
+	Model      string  `json:"model,omitempty"`      // Embedding model for the openai provider
+}
+
+// CurationConfig configures the store of curated groupings.
+type CurationConfig struct {
+	Path       string `json:"path,omitempty"`        // JSON file holding curated groupings (empty = in memory only)
+	History    int    `json:"history,omitempty"`     // Previous versions kept per grouping (default 10, negative = none)
+	AdminToken string `json:"admin_token,omitempty"` // Bearer token required by the admin API (or CURATION_ADMIN_TOKEN)
+}
+
+// LabelingConfig configures the LLM that names clusters.
+type LabelingConfig struct {
+	Provider    string            `json:"provider,omitempty"`    // "anthropic", "openai" (or compatible), "ollama" or "local"
+	Model       string            `json:"model,omitempty"`       // Model name (default depends on the provider)
+	MaxTokens   int               `json:"max_tokens,omitempty"`  // Maximum tokens in a generated label (default 20)
+	Temperature *float64          `json:"temperature,omitempty"` // Sampling temperature (unset = provider default)
+	BaseURL     string            `json:"base_url,omitempty"`    // API root, e.g., "http://localhost:11434" for Ollama
+	APIKey      string            `json:"api_key,omitempty"`     // API key (or LABELING_API_KEY; anthropic falls back to anthropic_api_key)
+	Batch       bool              `json:"batch,omitempty"`       // Label all clusters in one JSON request (falls back to per-cluster requests)
+	Cache       *LabelCacheConfig `json:"cache,omitempty"`       // Cache of generated labels (default: in memory)
+
+	MaxConcurrency    int `json:"max_concurrency,omitempty"`     // API requests in flight at once (default 4, negative = no limit)
+	RequestsPerMinute int `json:"requests_per_minute,omitempty"` // API requests started per minute (0 = no limit)
+
+	PromptTemplate        string `json:"prompt_template,omitempty"`         // text/template file for per-cluster prompts (default built in)
+	BatchPromptTemplate   string `json:"batch_prompt_template,omitempty"`   // text/template file for batch prompts (default built in)
+	SuggestPromptTemplate string `json:"suggest_prompt_template,omitempty"` // text/template file for query suggestion prompts (default built in)
+	PromptVersion         string `json:"prompt_version,omitempty"`          // Cache key version of the prompts (default: hash of the templates)
+
+	Pricing        map[string]ModelPricing `json:"pricing,omitempty"`          // Prices by model name, overriding the built-in ones
+	DailyBudgetUSD float64                 `json:"daily_budget_usd,omitempty"` // Estimated spend per UTC day after which labels come from the local labeler (0 = no limit)
+}
+
+// ModelPricing is a model's price in US dollars per million tokens.
+type ModelPricing struct {
+	InputPerMTok  float64 `json:"input_per_mtok"`  // Prompt tokens
+	OutputPerMTok float64 `json:"output_per_mtok"` // Generated tokens
+}
+
+// LabelCacheConfig configures the cache of generated cluster labels.
+type LabelCacheConfig struct {
+	Backend    string `json:"backend,omitempty"`     // "memory" (default), "disk" or "shared" (one file for several instances)
+	Path       string `json:"path,omitempty"`        // Cache file for the disk and shared backends
+	Capacity   int    `json:"capacity,omitempty"`    // Maximum entries; the least recently used are evicted first (default 10000)
+	TTLMinutes int    `json:"ttl_minutes,omitempty"` // How long a label is reused (default 60)
 }
 
 type Config struct {
-	AlgoliaAppID     string        `json:"algolia_app_id"`
-	AlgoliaAPIKey    string        `json:"algolia_api_key"`
-	AlgoliaIndexName string        `json:"algolia_index_name"`
-	AnthropicAPIKey  string        `json:"anthropic_api_key"`
-	Port             string        `json:"port"`
-	FieldMapping     *FieldMapping `json:"field_mapping,omitempty"`
-	Facets           []FacetConfig `json:"facets,omitempty"`
+	AlgoliaAppID     string            `json:"algolia_app_id"`
+	AlgoliaAPIKey    string            `json:"algolia_api_key"`
+	AlgoliaIndexName string            `json:"algolia_index_name"`
+	AnthropicAPIKey  string            `json:"anthropic_api_key"`
+	Port             string            `json:"port"`
+	FieldMapping     *FieldMapping     `json:"field_mapping,omitempty"`
+	Facets           []FacetConfig     `json:"facets,omitempty"`
+	Clustering       *ClusteringConfig `json:"clustering,omitempty"`
+	Embedding        *EmbeddingConfig  `json:"embedding,omitempty"`
+	Curation         *CurationConfig   `json:"curation,omitempty"`
+	Labeling         *LabelingConfig   `json:"labeling,omitempty"`
 }
 
 // GetFacetFields returns the list of facet field names to request from Algolia.
@@ -50,6 +130,26 @@ func (c *Config) GetFacetFields() []string {
 	return fields
 }
 
+// GetLabeling returns the effective labeling configuration. Without a configured
+// provider, clusters are named by Anthropic when anthropic_api_key is set and locally
+// otherwise; the Anthropic provider uses anthropic_api_key when it has no key of its own.
+func (c *Config) GetLabeling() LabelingConfig {
+	var labeling LabelingConfig
+	if c.Labeling != nil {
+		labeling = *c.Labeling
+	}
+	if labeling.Provider == "" {
+		labeling.Provider = "local"
+		if c.AnthropicAPIKey != "" {
+			labeling.Provider = "anthropic"
+		}
+	}
+	if labeling.Provider == "anthropic" && labeling.APIKey == "" {
+		labeling.APIKey = c.AnthropicAPIKey
+	}
+	return labeling
+}
+
 // GetFacetDisplayName returns the display name for a facet field.
 // Returns the field name itself if no display name is configured.
 func (c *Config) GetFacetDisplayName(field string) string {
@@ -290,6 +390,27 @@ func Load() (*Config, error) {
 		envVarsSet = append(envVarsSet, "ANTHROPIC_API_KEY")
 	}
 
+	if embeddingKey := os.Getenv("EMBEDDING_API_KEY"); embeddingKey != "" && cfg.Embedding != nil {
+		cfg.Embedding.APIKey = embeddingKey
+		envVarsSet = append(envVarsSet, "EMBEDDING_API_KEY")
+	}
+
+	if labelingKey := os.Getenv("LABELING_API_KEY"); labelingKey != "" {
+		if cfg.Labeling == nil {
+			cfg.Labeling = &LabelingConfig{}
+		}
+		cfg.Labeling.APIKey = labelingKey
+		envVarsSet = append(envVarsSet, "LABELING_API_KEY")
+	}
+
+	if adminToken := os.Getenv("CURATION_ADMIN_TOKEN"); adminToken != "" {
+		if cfg.Curation == nil {
+			cfg.Curation = &CurationConfig{}
+		}
+		cfg.Curation.AdminToken = adminToken
+		envVarsSet = append(envVarsSet, "CURATION_ADMIN_TOKEN")
+	}
+
 	if len(envVarsSet) > 0 {
 		log.Debug("configuration overridden by environment variables", "vars", envVarsSet)
 	}
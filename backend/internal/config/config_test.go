@@ -185,6 +185,30 @@ func TestConfig_GetFacetDisplayName(t *testing.T) {
 	}
 }
 
+func TestConfig_GetLabeling(t *testing.T) {
+	tests := []struct {
+		name         string
+		config       Config
+		wantProvider string
+		wantKey      string
+	}{
+		{"no key uses local labels", Config{}, "local", ""},
+		{"anthropic key", Config{AnthropicAPIKey: "sk-ant"}, "anthropic", "sk-ant"},
+		{"explicit provider keeps its key", Config{AnthropicAPIKey: "sk-ant", Labeling: &LabelingConfig{Provider: "openai", APIKey: "sk-oai"}}, "openai", "sk-oai"},
+		{"anthropic without its own key", Config{AnthropicAPIKey: "sk-ant", Labeling: &LabelingConfig{Provider: "anthropic", Model: "claude-x"}}, "anthropic", "sk-ant"},
+		{"ollama needs no key", Config{AnthropicAPIKey: "sk-ant", Labeling: &LabelingConfig{Provider: "ollama"}}, "ollama", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.config.GetLabeling()
+			if got.Provider != tt.wantProvider || got.APIKey != tt.wantKey {
+				t.Errorf("GetLabeling() = provider %q, key %q, want %q, %q", got.Provider, got.APIKey, tt.wantProvider, tt.wantKey)
+			}
+		})
+	}
+}
+
 func TestLoad_FromEnv(t *testing.T) {
 	// Set environment variables
 	os.Setenv("ALGOLIA_APP_ID", "test-app-id")
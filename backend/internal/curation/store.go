@@ -0,0 +1,388 @@
+// Package curation stores curated groupings: cluster or RIPPER groupings that
+// merchandisers have edited for a query and that replace the algorithm's output.
+package curation
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"ize/internal/logger"
+)
+
+// Views a grouping can override
+const (
+	ViewCluster = "cluster" // /api/cluster groups
+	ViewRipper  = "ripper"  // /api/ripper groups; rules must be single facet values
+)
+
+// DefaultHistory is the number of previous versions kept per grouping
+const DefaultHistory = 10
+
+// fileFormatVersion is written to the store file so the format can evolve
+const fileFormatVersion = 1
+
+var (
+	// ErrNotFound is returned for an unknown grouping ID or version
+	ErrNotFound = errors.New("curated grouping not found")
+	// ErrVersionConflict is returned when a grouping changed since the version an update was based on
+	ErrVersionConflict = errors.New("curated grouping version conflict")
+	// ErrInvalid is returned for a grouping that cannot be stored
+	ErrInvalid = errors.New("invalid curated grouping")
+)
+
+// Cluster is one curated group, defined by its rule
+type Cluster struct {
+	ID      string     `json:"id"`
+	Name    string     `json:"name"`
+	Pinned  bool       `json:"pinned,omitempty"` // Name set by a curator, never regenerated
+	Color   int        `json:"color"`
+	Rule    [][]string `json:"rule"`              // Algolia filter format
+	Quality *Quality   `json:"quality,omitempty"` // Rule quality when the rule was fitted
+}
+
+// Quality is the rule quality recorded with a cluster
+type Quality struct {
+	Precision float64 `json:"precision"`
+	Recall    float64 `json:"recall"`
+	F1        float64 `json:"f1"`
+}
+
+// Grouping is one version of the curated groups for a query, filters and view
+type Grouping struct {
+	ID           string     `json:"id"` // Derived from view, query and filters (see KeyID)
+	View         string     `json:"view"`
+	Query        string     `json:"query"`        // Normalized
+	FacetFilters [][]string `json:"facetFilters"` // Normalized
+	Clusters     []Cluster  `json:"clusters"`     // In display order
+	NextID       int        `json:"nextId"`       // Counter for new cluster IDs
+	Version      int        `json:"version"`
+	UpdatedAt    time.Time  `json:"updatedAt"`
+}
+
+// record is a grouping with its previous versions (oldest first)
+type record struct {
+	Current Grouping   `json:"current"`
+	History []Grouping `json:"history,omitempty"`
+}
+
+// storeFile is the on-disk layout
+type storeFile struct {
+	Format    int       `json:"format"`
+	Groupings []*record `json:"groupings"`
+}
+
+// Store holds curated groupings in memory and, when it has a path, persists every
+// change to a JSON file (written to a temporary file and renamed into place).
+// It is safe for concurrent use.
+type Store struct {
+	mu      sync.RWMutex
+	path    string
+	history int
+	records map[string]*record
+	logger  *logger.Logger
+	now     func() time.Time
+}
+
+// Open loads the store at path, creating it on the first write if it does not exist.
+// An empty path keeps groupings in memory only. history is the number of previous
+// versions kept per grouping (0 = DefaultHistory, negative = none).
+func Open(path string, history int, log *logger.Logger) (*Store, error) {
+	if log == nil {
+		log = logger.Default()
+	}
+	if history == 0 {
+		history = DefaultHistory
+	}
+	if history < 0 {
+		history = 0
+	}
+
+	s := &Store{
+		path:    path,
+		history: history,
+		records: make(map[string]*record),
+		logger:  log,
+		now:     time.Now,
+	}
+	if path == "" {
+		return s, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		log.Info("curation store file not found, starting empty", "path", path)
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read curation store: %w", err)
+	}
+
+	var file storeFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse curation store: %w", err)
+	}
+	if file.Format > fileFormatVersion {
+		return nil, fmt.Errorf("curation store format %d is newer than supported (%d)", file.Format, fileFormatVersion)
+	}
+	for _, r := range file.Groupings {
+		if r != nil && r.Current.ID != "" {
+			s.records[r.Current.ID] = r
+		}
+	}
+
+	log.Info("curation store loaded", "path", path, "groupings", len(s.records))
+	return s, nil
+}
+
+// NormalizeQuery lowercases a query and collapses its whitespace, so trivially
+// different spellings of a query share a grouping
+func NormalizeQuery(query string) string {
+	return strings.Join(strings.Fields(strings.ToLower(query)), " ")
+}
+
+// NormalizeFilters sorts and deduplicates facet filters (values within each OR group,
+// then the groups) and drops empty ones; the order of filters does not change results
+func NormalizeFilters(facetFilters [][]string) [][]string {
+	normalized := [][]string{}
+	seenGroups := make(map[string]bool)
+	for _, group := range facetFilters {
+		seen := make(map[string]bool)
+		values := []string{}
+		for _, value := range group {
+			value = strings.TrimSpace(value)
+			if value != "" && !seen[value] {
+				seen[value] = true
+				values = append(values, value)
+			}
+		}
+		if len(values) == 0 {
+			continue
+		}
+		sort.Strings(values)
+		key := strings.Join(values, "\x00")
+		if !seenGroups[key] {
+			seenGroups[key] = true
+			normalized = append(normalized, values)
+		}
+	}
+	sort.Slice(normalized, func(a, b int) bool {
+		return strings.Join(normalized[a], "\x00") < strings.Join(normalized[b], "\x00")
+	})
+	return normalized
+}
+
+// KeyID derives the grouping ID for a view, query and filters (normalizing both)
+func KeyID(view, query string, facetFilters [][]string) string {
+	filters, _ := json.Marshal(NormalizeFilters(facetFilters))
+	sum := sha256.Sum256([]byte(view + "\x00" + NormalizeQuery(query) + "\x00" + string(filters)))
+	return hex.EncodeToString(sum[:8])
+}
+
+// Lookup returns the current grouping for a view, query and filters
+func (s *Store) Lookup(view, query string, facetFilters [][]string) (*Grouping, bool) {
+	return s.Get(KeyID(view, query, facetFilters), 0)
+}
+
+// Get returns a grouping by ID, at the given version (0 = current). The grouping
+// shares its slices with the store and must not be modified.
+func (s *Store) Get(id string, version int) (*Grouping, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	r, ok := s.records[id]
+	if !ok {
+		return nil, false
+	}
+	if version == 0 || version == r.Current.Version {
+		g := r.Current
+		return &g, true
+	}
+	for _, old := range r.History {
+		if old.Version == version {
+			g := old
+			return &g, true
+		}
+	}
+	return nil, false
+}
+
+// Versions returns the stored version numbers of a grouping, oldest first
+func (s *Store) Versions(id string) []int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	r, ok := s.records[id]
+	if !ok {
+		return nil
+	}
+	versions := make([]int, 0, len(r.History)+1)
+	for _, old := range r.History {
+		versions = append(versions, old.Version)
+	}
+	return append(versions, r.Current.Version)
+}
+
+// List returns the current version of every grouping, ordered by view, query and ID
+func (s *Store) List() []Grouping {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	groupings := make([]Grouping, 0, len(s.records))
+	for _, r := range s.records {
+		groupings = append(groupings, r.Current)
+	}
+	sort.Slice(groupings, func(a, b int) bool {
+		ga, gb := groupings[a], groupings[b]
+		if ga.View != gb.View {
+			return ga.View < gb.View
+		}
+		if ga.Query != gb.Query {
+			return ga.Query < gb.Query
+		}
+		return ga.ID < gb.ID
+	})
+	return groupings
+}
+
+// Put stores g as the new current version of its grouping, normalizing the query and
+// filters and assigning the ID, version and timestamp. expectedVersion guards against
+// lost updates: when positive it must be the current version, when -1 the grouping
+// must not exist yet, and 0 skips the check. A mismatch returns ErrVersionConflict.
+func (s *Store) Put(g Grouping, expectedVersion int) (*Grouping, error) {
+	if g.View != ViewCluster && g.View != ViewRipper {
+		return nil, fmt.Errorf("%w: unknown view %q", ErrInvalid, g.View)
+	}
+	ids := make(map[string]bool, len(g.Clusters))
+	for i, c := range g.Clusters {
+		if c.ID == "" {
+			return nil, fmt.Errorf("%w: cluster %d has no id", ErrInvalid, i)
+		}
+		if ids[c.ID] {
+			return nil, fmt.Errorf("%w: duplicate cluster id %q", ErrInvalid, c.ID)
+		}
+		ids[c.ID] = true
+		if len(c.Rule) == 0 {
+			return nil, fmt.Errorf("%w: cluster %q has no rule", ErrInvalid, c.ID)
+		}
+		// Keep new IDs from colliding with hand-written ones
+		var n int
+		if _, err := fmt.Sscanf(c.ID, "c%d", &n); err == nil && n >= g.NextID {
+			g.NextID = n + 1
+		}
+	}
+
+	g.Query = NormalizeQuery(g.Query)
+	g.FacetFilters = NormalizeFilters(g.FacetFilters)
+	g.ID = KeyID(g.View, g.Query, g.FacetFilters)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	r, exists := s.records[g.ID]
+	switch {
+	case expectedVersion < 0 && exists:
+		return nil, fmt.Errorf("%w: grouping already exists at version %d", ErrVersionConflict, r.Current.Version)
+	case expectedVersion > 0 && (!exists || r.Current.Version != expectedVersion):
+		current := 0
+		if exists {
+			current = r.Current.Version
+		}
+		return nil, fmt.Errorf("%w: expected version %d, current is %d", ErrVersionConflict, expectedVersion, current)
+	}
+
+	g.Version = 1
+	g.UpdatedAt = s.now().UTC()
+	var history []Grouping
+	if exists {
+		g.Version = r.Current.Version + 1
+		history = append(append(history, r.History...), r.Current)
+		if len(history) > s.history {
+			history = history[len(history)-s.history:]
+		}
+	}
+	updated := &record{Current: g, History: history}
+
+	s.records[g.ID] = updated
+	if err := s.saveLocked(); err != nil {
+		if exists {
+			s.records[g.ID] = r
+		} else {
+			delete(s.records, g.ID)
+		}
+		return nil, err
+	}
+
+	s.logger.Info("curated grouping stored",
+		"id", g.ID,
+		"view", g.View,
+		"query", g.Query,
+		"version", g.Version,
+		"clusters", len(g.Clusters),
+	)
+	return &g, nil
+}
+
+// Delete removes a grouping and its history
+func (s *Store) Delete(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	r, ok := s.records[id]
+	if !ok {
+		return ErrNotFound
+	}
+	delete(s.records, id)
+	if err := s.saveLocked(); err != nil {
+		s.records[id] = r
+		return err
+	}
+
+	s.logger.Info("curated grouping deleted", "id", id, "query", r.Current.Query)
+	return nil
+}
+
+// saveLocked writes the store file; the caller holds the write lock
+func (s *Store) saveLocked() error {
+	if s.path == "" {
+		return nil
+	}
+
+	file := storeFile{Format: fileFormatVersion, Groupings: make([]*record, 0, len(s.records))}
+	for _, r := range s.records {
+		file.Groupings = append(file.Groupings, r)
+	}
+	sort.Slice(file.Groupings, func(a, b int) bool {
+		return file.Groupings[a].Current.ID < file.Groupings[b].Current.ID
+	})
+
+	data, err := json.MarshalIndent(file, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode curation store: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), filepath.Base(s.path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to write curation store: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write curation store: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write curation store: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), s.path); err != nil {
+		return fmt.Errorf("failed to write curation store: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,150 @@
+package curation
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"ize/internal/logger"
+)
+
+func testGrouping(query string, names ...string) Grouping {
+	clusters := make([]Cluster, len(names))
+	for i, name := range names {
+		clusters[i] = Cluster{ID: "c" + string(rune('1'+i)), Name: name, Rule: [][]string{{"brand:" + name}}}
+	}
+	return Grouping{View: ViewCluster, Query: query, FacetFilters: [][]string{{"category:Phones"}}, Clusters: clusters}
+}
+
+func TestNormalize(t *testing.T) {
+	if got := NormalizeQuery("  Running   SHOES "); got != "running shoes" {
+		t.Errorf("NormalizeQuery() = %q, want %q", got, "running shoes")
+	}
+
+	got := NormalizeFilters([][]string{{"color:Red", "color:Blue", "color:Red"}, {}, {" brand:Nike "}, {"color:Blue", "color:Red"}})
+	want := [][]string{{"brand:Nike"}, {"color:Blue", "color:Red"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("NormalizeFilters() = %v, want %v", got, want)
+	}
+
+	a := KeyID(ViewCluster, "Running Shoes", [][]string{{"brand:Nike"}, {"color:Red", "color:Blue"}})
+	b := KeyID(ViewCluster, "running  shoes", [][]string{{"color:Blue", "color:Red"}, {"brand:Nike"}})
+	if a != b {
+		t.Errorf("KeyID() differs for equivalent requests: %s vs %s", a, b)
+	}
+	if a == KeyID(ViewRipper, "running shoes", [][]string{{"brand:Nike"}, {"color:Blue", "color:Red"}}) {
+		t.Error("KeyID() is the same for different views")
+	}
+}
+
+func TestStore_Versions(t *testing.T) {
+	store, err := Open("", 2, logger.Default())
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+
+	first, err := store.Put(testGrouping("Phones", "Samsung"), -1)
+	if err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+	if first.Version != 1 || first.Query != "phones" || first.NextID != 2 {
+		t.Errorf("Put() = version %d, query %q, nextId %d, want 1, %q, 2", first.Version, first.Query, first.NextID, "phones")
+	}
+	if _, err := store.Put(testGrouping("phones", "Apple"), -1); !errors.Is(err, ErrVersionConflict) {
+		t.Errorf("Put(must not exist) error = %v, want ErrVersionConflict", err)
+	}
+
+	for i, names := range [][]string{{"Apple"}, {"Apple", "LG"}, {"LG"}} {
+		g, err := store.Put(testGrouping("phones", names...), i+1)
+		if err != nil {
+			t.Fatalf("Put(version %d) error = %v", i+1, err)
+		}
+		if g.Version != i+2 {
+			t.Errorf("Put() version = %d, want %d", g.Version, i+2)
+		}
+	}
+	if _, err := store.Put(testGrouping("phones", "Stale"), 2); !errors.Is(err, ErrVersionConflict) {
+		t.Errorf("Put(stale version) error = %v, want ErrVersionConflict", err)
+	}
+
+	current, ok := store.Lookup(ViewCluster, " PHONES", [][]string{{"category:Phones"}})
+	if !ok || current.Version != 4 || current.Clusters[0].Name != "LG" {
+		t.Fatalf("Lookup() = %+v, %v, want version 4", current, ok)
+	}
+	if versions := store.Versions(current.ID); !reflect.DeepEqual(versions, []int{2, 3, 4}) {
+		t.Errorf("Versions() = %v, want [2 3 4] (history of 2)", versions)
+	}
+	if old, ok := store.Get(current.ID, 2); !ok || old.Clusters[0].Name != "Apple" {
+		t.Errorf("Get(version 2) = %+v, %v, want the Apple grouping", old, ok)
+	}
+	if _, ok := store.Get(current.ID, 1); ok {
+		t.Error("Get(version 1) found a version beyond the history limit")
+	}
+
+	if err := store.Delete(current.ID); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if _, ok := store.Get(current.ID, 0); ok {
+		t.Error("Get() found a deleted grouping")
+	}
+	if err := store.Delete(current.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Delete(missing) error = %v, want ErrNotFound", err)
+	}
+}
+
+func TestStore_Invalid(t *testing.T) {
+	store, _ := Open("", 0, logger.Default())
+
+	invalid := []Grouping{
+		{View: "search", Query: "q"},
+		{View: ViewCluster, Query: "q", Clusters: []Cluster{{Name: "no id", Rule: [][]string{{"a:b"}}}}},
+		{View: ViewCluster, Query: "q", Clusters: []Cluster{{ID: "c1", Rule: [][]string{{"a:b"}}}, {ID: "c1", Rule: [][]string{{"a:c"}}}}},
+		{View: ViewCluster, Query: "q", Clusters: []Cluster{{ID: "c1"}}},
+	}
+	for i, g := range invalid {
+		if _, err := store.Put(g, 0); !errors.Is(err, ErrInvalid) {
+			t.Errorf("Put(invalid %d) error = %v, want ErrInvalid", i, err)
+		}
+	}
+}
+
+func TestStore_Persistence(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "curations.json")
+
+	store, err := Open(path, 0, logger.Default())
+	if err != nil {
+		t.Fatalf("Open(new file) error = %v", err)
+	}
+	first, _ := store.Put(testGrouping("phones", "Samsung"), 0)
+	second, err := store.Put(testGrouping("phones", "Apple"), first.Version)
+	if err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+
+	reopened, err := Open(path, 0, logger.Default())
+	if err != nil {
+		t.Fatalf("Open(existing file) error = %v", err)
+	}
+	loaded, ok := reopened.Get(second.ID, 0)
+	if !ok || !reflect.DeepEqual(*loaded, *second) {
+		t.Errorf("reopened Get() = %+v, want %+v", loaded, second)
+	}
+	if versions := reopened.Versions(second.ID); !reflect.DeepEqual(versions, []int{1, 2}) {
+		t.Errorf("reopened Versions() = %v, want [1 2]", versions)
+	}
+
+	// No temporary files are left behind
+	entries, _ := os.ReadDir(filepath.Dir(path))
+	if len(entries) != 1 {
+		t.Errorf("store directory has %d entries, want only the store file", len(entries))
+	}
+
+	if err := os.WriteFile(path, []byte("{not json"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Open(path, 0, logger.Default()); err == nil {
+		t.Error("Open(corrupt file) error = nil, want error")
+	}
+}
@@ -0,0 +1,261 @@
+package embedding
+
+import (
+	"container/list"
+	"context"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"ize/internal/config"
+	"ize/internal/logger"
+)
+
+// Provider names accepted in config.EmbeddingConfig.Provider
+const (
+	ProviderHashing     = "hashing"
+	ProviderPrecomputed = "precomputed"
+	ProviderOpenAI      = "openai"
+)
+
+const (
+	defaultHashingDimensions = 256
+	defaultCacheTTL          = 1 * time.Hour
+	defaultCacheCapacity     = 10000 // Vectors kept by the cache before evicting the least recently used
+)
+
+// Item is the input to an Embedder
+type Item struct {
+	ID     string    // Algolia objectID, used as the cache key
+	Text   string    // Text to embed (typically name and description)
+	Vector []float64 // Precomputed vector from the hit, if any
+}
+
+// NewEmbedder creates the configured embedder wrapped in a per-objectID cache
+func NewEmbedder(cfg *config.EmbeddingConfig, log *logger.Logger) (Embedder, error) {
+	if cfg == nil {
+		return nil, fmt.Errorf("embedding configuration is required")
+	}
+
+	var inner Embedder
+	switch cfg.Provider {
+	case ProviderHashing:
+		inner = NewHashingEmbedder(cfg.Dimensions)
+	case ProviderPrecomputed:
+		inner = PrecomputedEmbedder{}
+	case ProviderOpenAI:
+		httpEmbedder, err := NewHTTPEmbedder(cfg.BaseURL, cfg.APIKey, cfg.Model, log)
+		if err != nil {
+			return nil, err
+		}
+		inner = httpEmbedder
+	default:
+		return nil, fmt.Errorf("unknown embedding provider %q", cfg.Provider)
+	}
+
+	log.Info("embedder initialized",
+		"provider", cfg.Provider,
+		"cache_ttl", defaultCacheTTL.String(),
+		"cache_capacity", defaultCacheCapacity,
+	)
+
+	return NewCachingEmbedder(inner, defaultCacheTTL, defaultCacheCapacity), nil
+}
+
+// HashingEmbedder embeds text locally with the hashing trick: each word is hashed to
+// one of Dimensions buckets with a hash-derived sign, then the vector is L2-normalized.
+// It needs no model or network access.
+type HashingEmbedder struct {
+	Dimensions int
+}
+
+// NewHashingEmbedder creates a hashing embedder; dimensions <= 0 uses the default
+func NewHashingEmbedder(dimensions int) *HashingEmbedder {
+	if dimensions <= 0 {
+		dimensions = defaultHashingDimensions
+	}
+	return &HashingEmbedder{Dimensions: dimensions}
+}
+
+// Embed implements Embedder
+func (e *HashingEmbedder) Embed(ctx context.Context, items []Item) ([][]float64, error) {
+	vectors := make([][]float64, len(items))
+	for i, item := range items {
+		vectors[i] = e.embedText(item.Text)
+	}
+	return vectors, nil
+}
+
+// embedText hashes the words of text into a normalized vector (nil if there are no words)
+func (e *HashingEmbedder) embedText(text string) []float64 {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	if len(words) == 0 {
+		return nil
+	}
+
+	vec := make([]float64, e.Dimensions)
+	for _, w := range words {
+		h := fnv.New64a()
+		h.Write([]byte(w))
+		sum := h.Sum64()
+		bucket := int(sum % uint64(e.Dimensions))
+		if sum&(1<<63) != 0 {
+			vec[bucket]--
+		} else {
+			vec[bucket]++
+		}
+	}
+	return Normalize(vec)
+}
+
+// PrecomputedEmbedder returns vectors already stored on the hits
+// (see config.FieldMapping.Vector). Items without a vector get nil.
+type PrecomputedEmbedder struct{}
+
+// Embed implements Embedder
+func (PrecomputedEmbedder) Embed(ctx context.Context, items []Item) ([][]float64, error) {
+	vectors := make([][]float64, len(items))
+	for i, item := range items {
+		if len(item.Vector) > 0 {
+			vectors[i] = Normalize(item.Vector)
+		}
+	}
+	return vectors, nil
+}
+
+// Normalize returns a unit-length copy of v, or nil if v has zero length
+func Normalize(v []float64) []float64 {
+	norm := 0.0
+	for _, x := range v {
+		norm += x * x
+	}
+	if norm == 0 {
+		return nil
+	}
+	norm = math.Sqrt(norm)
+	out := make([]float64, len(v))
+	for i, x := range v {
+		out[i] = x / norm
+	}
+	return out
+}
+
+// cacheEntry holds a cached vector with expiration
+type cacheEntry struct {
+	id        string
+	vector    []float64
+	expiresAt time.Time
+}
+
+// CachingEmbedder caches vectors per objectID so repeated queries that return the
+// same items only embed new ones. It keeps at most capacity vectors, evicting the
+// least recently used, and drops expired entries when they are looked up.
+type CachingEmbedder struct {
+	inner    Embedder
+	ttl      time.Duration
+	capacity int
+	cacheMu  sync.Mutex
+	order    *list.List // *cacheEntry values, most recently used at the front
+	cache    map[string]*list.Element
+}
+
+// NewCachingEmbedder wraps an embedder with a per-objectID cache; capacity <= 0 uses
+// the default
+func NewCachingEmbedder(inner Embedder, ttl time.Duration, capacity int) *CachingEmbedder {
+	if capacity <= 0 {
+		capacity = defaultCacheCapacity
+	}
+	return &CachingEmbedder{
+		inner:    inner,
+		ttl:      ttl,
+		capacity: capacity,
+		order:    list.New(),
+		cache:    make(map[string]*list.Element),
+	}
+}
+
+// Embed implements Embedder, only passing cache misses to the wrapped embedder
+func (c *CachingEmbedder) Embed(ctx context.Context, items []Item) ([][]float64, error) {
+	vectors := make([][]float64, len(items))
+	var missItems []Item
+	var missIndices []int
+
+	c.cacheMu.Lock()
+	for i, item := range items {
+		if vector, ok := c.getLocked(item.ID); ok {
+			vectors[i] = vector
+			continue
+		}
+		missItems = append(missItems, item)
+		missIndices = append(missIndices, i)
+	}
+	c.cacheMu.Unlock()
+
+	if len(missItems) == 0 {
+		return vectors, nil
+	}
+
+	computed, err := c.inner.Embed(ctx, missItems)
+	if err != nil {
+		return nil, err
+	}
+	if len(computed) != len(missItems) {
+		return nil, fmt.Errorf("embedder returned %d vectors for %d items", len(computed), len(missItems))
+	}
+
+	c.cacheMu.Lock()
+	defer c.cacheMu.Unlock()
+	expiresAt := time.Now().Add(c.ttl)
+	for j, idx := range missIndices {
+		vectors[idx] = computed[j]
+		if missItems[j].ID != "" && computed[j] != nil {
+			c.setLocked(&cacheEntry{id: missItems[j].ID, vector: computed[j], expiresAt: expiresAt})
+		}
+	}
+
+	return vectors, nil
+}
+
+// Len returns the number of cached vectors, including expired ones not yet dropped
+func (c *CachingEmbedder) Len() int {
+	c.cacheMu.Lock()
+	defer c.cacheMu.Unlock()
+	return len(c.cache)
+}
+
+// getLocked returns the unexpired vector for id, marking it recently used
+func (c *CachingEmbedder) getLocked(id string) ([]float64, bool) {
+	elem, ok := c.cache[id]
+	if !ok || id == "" {
+		return nil, false
+	}
+	entry := elem.Value.(*cacheEntry)
+	if !time.Now().Before(entry.expiresAt) {
+		c.order.Remove(elem)
+		delete(c.cache, id)
+		return nil, false
+	}
+	c.order.MoveToFront(elem)
+	return entry.vector, true
+}
+
+// setLocked stores an entry, evicting the least recently used beyond capacity
+func (c *CachingEmbedder) setLocked(entry *cacheEntry) {
+	if elem, ok := c.cache[entry.id]; ok {
+		elem.Value = entry
+		c.order.MoveToFront(elem)
+		return
+	}
+	c.cache[entry.id] = c.order.PushFront(entry)
+	for c.order.Len() > c.capacity {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.cache, oldest.Value.(*cacheEntry).id)
+	}
+}
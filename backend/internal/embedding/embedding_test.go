@@ -0,0 +1,253 @@
+package embedding
+
+import (
+	"context"
+	"encoding/json"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"ize/internal/config"
+	"ize/internal/logger"
+)
+
+// dot returns the dot product of two equal-length vectors
+func dot(a, b []float64) float64 {
+	sum := 0.0
+	for i := range a {
+		sum += a[i] * b[i]
+	}
+	return sum
+}
+
+func TestEmbedderInterface(t *testing.T) {
+	var _ Embedder = (*HashingEmbedder)(nil)
+	var _ Embedder = PrecomputedEmbedder{}
+	var _ Embedder = (*HTTPEmbedder)(nil)
+	var _ Embedder = (*CachingEmbedder)(nil)
+}
+
+func TestHashingEmbedder(t *testing.T) {
+	e := NewHashingEmbedder(64)
+	items := []Item{
+		{ID: "1", Text: "wireless noise cancelling headphones"},
+		{ID: "2", Text: "noise cancelling wireless headphones black"},
+		{ID: "3", Text: "leather hiking boots"},
+		{ID: "4", Text: "   "},
+	}
+
+	vectors, err := e.Embed(context.Background(), items)
+	if err != nil {
+		t.Fatalf("Embed() error = %v", err)
+	}
+	if len(vectors) != 4 {
+		t.Fatalf("Embed() returned %d vectors, want 4", len(vectors))
+	}
+
+	for i := 0; i < 3; i++ {
+		if len(vectors[i]) != 64 {
+			t.Errorf("vector %d has %d dimensions, want 64", i, len(vectors[i]))
+		}
+		if math.Abs(dot(vectors[i], vectors[i])-1) > 1e-9 {
+			t.Errorf("vector %d is not unit length", i)
+		}
+	}
+	if vectors[3] != nil {
+		t.Errorf("vector for empty text = %v, want nil", vectors[3])
+	}
+
+	if dot(vectors[0], vectors[1]) <= dot(vectors[0], vectors[2]) {
+		t.Errorf("similar texts should have higher cosine similarity than unrelated texts")
+	}
+
+	// Deterministic across calls
+	again, _ := e.Embed(context.Background(), items[:1])
+	if dot(again[0], vectors[0]) < 1-1e-9 {
+		t.Errorf("HashingEmbedder is not deterministic")
+	}
+}
+
+func TestPrecomputedEmbedder(t *testing.T) {
+	vectors, err := PrecomputedEmbedder{}.Embed(context.Background(), []Item{
+		{ID: "1", Vector: []float64{3, 4}},
+		{ID: "2"},
+	})
+	if err != nil {
+		t.Fatalf("Embed() error = %v", err)
+	}
+	if math.Abs(vectors[0][0]-0.6) > 1e-9 || math.Abs(vectors[0][1]-0.8) > 1e-9 {
+		t.Errorf("Embed() = %v, want normalized [0.6 0.8]", vectors[0])
+	}
+	if vectors[1] != nil {
+		t.Errorf("Embed() for item without vector = %v, want nil", vectors[1])
+	}
+}
+
+// countingEmbedder records how many items it was asked to embed
+type countingEmbedder struct {
+	calls int
+	items int
+}
+
+func (c *countingEmbedder) Embed(ctx context.Context, items []Item) ([][]float64, error) {
+	c.calls++
+	c.items += len(items)
+	vectors := make([][]float64, len(items))
+	for i := range items {
+		vectors[i] = []float64{1, 0}
+	}
+	return vectors, nil
+}
+
+func TestCachingEmbedder(t *testing.T) {
+	inner := &countingEmbedder{}
+	cache := NewCachingEmbedder(inner, time.Hour, 0)
+
+	first := []Item{{ID: "a"}, {ID: "b"}}
+	if _, err := cache.Embed(context.Background(), first); err != nil {
+		t.Fatalf("Embed() error = %v", err)
+	}
+
+	// Second query overlaps the first: only "c" should be computed
+	second := []Item{{ID: "b"}, {ID: "a"}, {ID: "c"}}
+	vectors, err := cache.Embed(context.Background(), second)
+	if err != nil {
+		t.Fatalf("Embed() error = %v", err)
+	}
+	if len(vectors) != 3 || vectors[2] == nil {
+		t.Fatalf("Embed() = %v, want 3 vectors", vectors)
+	}
+	if inner.items != 3 {
+		t.Errorf("inner embedder computed %d items, want 3 (one cache miss on second call)", inner.items)
+	}
+
+	// Fully cached query should not call the inner embedder
+	if _, err := cache.Embed(context.Background(), first); err != nil {
+		t.Fatalf("Embed() error = %v", err)
+	}
+	if inner.calls != 2 {
+		t.Errorf("inner embedder calls = %d, want 2", inner.calls)
+	}
+}
+
+func TestCachingEmbedder_Expiry(t *testing.T) {
+	inner := &countingEmbedder{}
+	cache := NewCachingEmbedder(inner, -time.Second, 0) // Entries expire immediately
+
+	cache.Embed(context.Background(), []Item{{ID: "a"}})
+	cache.Embed(context.Background(), []Item{{ID: "a"}})
+
+	if inner.calls != 2 {
+		t.Errorf("inner embedder calls = %d, want 2 for expired entries", inner.calls)
+	}
+	if cache.Len() != 1 {
+		t.Errorf("cache holds %d vectors, want the expired one replaced", cache.Len())
+	}
+}
+
+func TestCachingEmbedder_Capacity(t *testing.T) {
+	inner := &countingEmbedder{}
+	cache := NewCachingEmbedder(inner, time.Hour, 2)
+
+	cache.Embed(context.Background(), []Item{{ID: "a"}, {ID: "b"}})
+	cache.Embed(context.Background(), []Item{{ID: "a"}}) // "b" is now least recently used
+	cache.Embed(context.Background(), []Item{{ID: "c"}})
+	if cache.Len() != 2 {
+		t.Errorf("cache holds %d vectors, want capacity 2", cache.Len())
+	}
+
+	inner.items = 0
+	cache.Embed(context.Background(), []Item{{ID: "a"}, {ID: "c"}})
+	if inner.items != 0 {
+		t.Errorf("inner embedder computed %d items, want recently used a and c cached", inner.items)
+	}
+	cache.Embed(context.Background(), []Item{{ID: "b"}})
+	if inner.items != 1 {
+		t.Errorf("inner embedder computed %d items, want evicted b recomputed", inner.items)
+	}
+}
+
+func TestHTTPEmbedder_StubServer(t *testing.T) {
+	var gotRequest embeddingsRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/embeddings" {
+			t.Errorf("request path = %s, want /v1/embeddings", r.URL.Path)
+		}
+		if r.Header.Get("Authorization") != "Bearer test-key" {
+			t.Errorf("Authorization header = %q", r.Header.Get("Authorization"))
+		}
+		if err := json.NewDecoder(r.Body).Decode(&gotRequest); err != nil {
+			t.Fatalf("failed to decode request: %v", err)
+		}
+
+		// Return embeddings out of order to check index handling
+		resp := embeddingsResponse{}
+		for i := len(gotRequest.Input) - 1; i >= 0; i-- {
+			resp.Data = append(resp.Data, embeddingData{
+				Index:     i,
+				Embedding: []float64{float64(i + 1), 0},
+			})
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(resp)
+	}))
+	defer server.Close()
+
+	e, err := NewHTTPEmbedder(server.URL+"/v1", "test-key", "test-model", logger.Default())
+	if err != nil {
+		t.Fatalf("NewHTTPEmbedder() error = %v", err)
+	}
+
+	vectors, err := e.Embed(context.Background(), []Item{
+		{ID: "1", Text: "first"},
+		{ID: "2", Text: ""}, // Not sent
+		{ID: "3", Text: "third"},
+	})
+	if err != nil {
+		t.Fatalf("Embed() error = %v", err)
+	}
+
+	if gotRequest.Model != "test-model" {
+		t.Errorf("request model = %q, want test-model", gotRequest.Model)
+	}
+	if len(gotRequest.Input) != 2 || gotRequest.Input[0] != "first" || gotRequest.Input[1] != "third" {
+		t.Errorf("request input = %v, want [first third]", gotRequest.Input)
+	}
+	if vectors[0] == nil || vectors[1] != nil || vectors[2] == nil {
+		t.Fatalf("Embed() = %v, want vectors for items 1 and 3 only", vectors)
+	}
+	if vectors[0][0] != 1 || vectors[2][0] != 1 {
+		t.Errorf("Embed() vectors should be normalized: %v", vectors)
+	}
+}
+
+func TestHTTPEmbedder_ErrorStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte(`{"error":{"type":"auth","message":"bad key"}}`))
+	}))
+	defer server.Close()
+
+	e, _ := NewHTTPEmbedder(server.URL, "", "", logger.Default())
+	if _, err := e.Embed(context.Background(), []Item{{ID: "1", Text: "x"}}); err == nil {
+		t.Error("Embed() should return error for non-200 status")
+	}
+}
+
+func TestNewEmbedder(t *testing.T) {
+	if _, err := NewEmbedder(nil, logger.Default()); err == nil {
+		t.Error("NewEmbedder(nil) should return error")
+	}
+	if _, err := NewEmbedder(&config.EmbeddingConfig{Provider: "unknown"}, logger.Default()); err == nil {
+		t.Error("NewEmbedder() with unknown provider should return error")
+	}
+	e, err := NewEmbedder(&config.EmbeddingConfig{Provider: ProviderHashing}, logger.Default())
+	if err != nil {
+		t.Fatalf("NewEmbedder() error = %v", err)
+	}
+	if _, ok := e.(*CachingEmbedder); !ok {
+		t.Errorf("NewEmbedder() = %T, want *CachingEmbedder", e)
+	}
+}
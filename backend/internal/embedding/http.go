@@ -0,0 +1,177 @@
+package embedding
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"ize/internal/logger"
+)
+
+const (
+	defaultBaseURL = "https://api.openai.com/v1"
+	defaultModel   = "text-embedding-3-small"
+
+	// maxBatchSize is the number of inputs sent per /embeddings request
+	maxBatchSize = 100
+)
+
+// HTTPEmbedder calls an OpenAI-compatible /embeddings endpoint
+type HTTPEmbedder struct {
+	baseURL    string
+	apiKey     string
+	model      string
+	httpClient *http.Client
+	logger     *logger.Logger
+}
+
+// NewHTTPEmbedder creates an embedder for an OpenAI-compatible API.
+// baseURL is the API root (e.g. "https://api.openai.com/v1" or a local server);
+// apiKey may be empty for local servers that don't require authentication.
+func NewHTTPEmbedder(baseURL, apiKey, model string, log *logger.Logger) (*HTTPEmbedder, error) {
+	if baseURL == "" {
+		baseURL = defaultBaseURL
+	}
+	if model == "" {
+		model = defaultModel
+	}
+	if !strings.HasPrefix(baseURL, "http://") && !strings.HasPrefix(baseURL, "https://") {
+		return nil, fmt.Errorf("embedding base URL must be http(s): %q", baseURL)
+	}
+
+	log.Info("embedding HTTP client initialized",
+		"base_url", baseURL,
+		"model", model,
+	)
+
+	return &HTTPEmbedder{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		apiKey:  apiKey,
+		model:   model,
+		httpClient: &http.Client{
+			Timeout: 30 * time.Second,
+		},
+		logger: log,
+	}, nil
+}
+
+// embeddingsRequest represents the /embeddings request format
+type embeddingsRequest struct {
+	Model string   `json:"model"`
+	Input []string `json:"input"`
+}
+
+// embeddingsResponse represents the /embeddings response format
+type embeddingsResponse struct {
+	Data  []embeddingData `json:"data"`
+	Error *apiError       `json:"error,omitempty"`
+}
+
+// embeddingData is one embedding in the response
+type embeddingData struct {
+	Index     int       `json:"index"`
+	Embedding []float64 `json:"embedding"`
+}
+
+// apiError represents an API error
+type apiError struct {
+	Type    string `json:"type"`
+	Message string `json:"message"`
+}
+
+// Embed implements Embedder. Items with empty text are not sent and get a nil vector.
+func (e *HTTPEmbedder) Embed(ctx context.Context, items []Item) ([][]float64, error) {
+	log := e.logger.WithContext(ctx)
+	vectors := make([][]float64, len(items))
+
+	var texts []string
+	var indices []int
+	for i, item := range items {
+		if strings.TrimSpace(item.Text) == "" {
+			continue
+		}
+		texts = append(texts, item.Text)
+		indices = append(indices, i)
+	}
+
+	start := time.Now()
+	for batchStart := 0; batchStart < len(texts); batchStart += maxBatchSize {
+		batchEnd := batchStart + maxBatchSize
+		if batchEnd > len(texts) {
+			batchEnd = len(texts)
+		}
+
+		batch, err := e.doEmbedRequest(ctx, texts[batchStart:batchEnd])
+		if err != nil {
+			return nil, err
+		}
+		for j, vec := range batch {
+			vectors[indices[batchStart+j]] = Normalize(vec)
+		}
+	}
+
+	log.Debug("computed embeddings",
+		"model", e.model,
+		"items", len(texts),
+		"duration_ms", time.Since(start).Milliseconds(),
+	)
+
+	return vectors, nil
+}
+
+// doEmbedRequest makes a single /embeddings request and returns vectors in input order
+func (e *HTTPEmbedder) doEmbedRequest(ctx context.Context, texts []string) ([][]float64, error) {
+	jsonBody, err := json.Marshal(embeddingsRequest{Model: e.model, Input: texts})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", e.baseURL+"/embeddings", bytes.NewReader(jsonBody))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if e.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+e.apiKey)
+	}
+
+	resp, err := e.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("API call failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("API returned status %d: %s", resp.StatusCode, string(body))
+	}
+
+	var embResp embeddingsResponse
+	if err := json.Unmarshal(body, &embResp); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+	if embResp.Error != nil {
+		return nil, fmt.Errorf("API error: %s - %s", embResp.Error.Type, embResp.Error.Message)
+	}
+	if len(embResp.Data) != len(texts) {
+		return nil, fmt.Errorf("API returned %d embeddings for %d inputs", len(embResp.Data), len(texts))
+	}
+
+	vectors := make([][]float64, len(texts))
+	for _, d := range embResp.Data {
+		if d.Index < 0 || d.Index >= len(texts) {
+			return nil, fmt.Errorf("API returned out-of-range embedding index %d", d.Index)
+		}
+		vectors[d.Index] = d.Embedding
+	}
+	return vectors, nil
+}
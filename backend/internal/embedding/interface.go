@@ -0,0 +1,11 @@
+package embedding
+
+import "context"
+
+// Embedder defines the interface for turning items into vectors for semantic clustering
+// This allows different providers to be plugged in and mocked in tests
+type Embedder interface {
+	// Embed returns one vector per item, in the same order as items.
+	// A nil vector means the item could not be embedded.
+	Embed(ctx context.Context, items []Item) ([][]float64, error)
+}
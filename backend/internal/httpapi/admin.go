@@ -0,0 +1,302 @@
+package httpapi
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"ize/internal/curation"
+	"ize/internal/labeler"
+)
+
+// HandleAdminCurations manages curated groupings:
+//
+//	GET    /api/admin/curations                  list current groupings
+//	GET    /api/admin/curations?id=X[&version=N] one grouping (current or older version)
+//	PUT    /api/admin/curations                  create or replace a grouping (CurationRequest)
+//	DELETE /api/admin/curations?id=X             delete a grouping and its history
+//
+// Requests must carry the configured admin token as a bearer token. Without a token
+// configured, the admin API is disabled.
+func (h *SearchHandler) HandleAdminCurations(w http.ResponseWriter, r *http.Request) {
+	log := h.logger.WithContext(r.Context())
+
+	if !h.authorizeAdmin(w, r) {
+		return
+	}
+	if h.curations == nil {
+		log.Error("curation store not configured")
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		h.getCurations(w, r)
+	case http.MethodPut:
+		h.putCuration(w, r)
+	case http.MethodDelete:
+		h.deleteCuration(w, r)
+	default:
+		log.Warn("method not allowed", "method", r.Method)
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// authorizeAdmin checks the request's bearer token against the admin token, writing an
+// error response and returning false if it does not match. Without a configured token
+// the admin API answers 404, so it is never open to anyone who can reach the server.
+func (h *SearchHandler) authorizeAdmin(w http.ResponseWriter, r *http.Request) bool {
+	log := h.logger.WithContext(r.Context())
+
+	if h.adminToken == "" {
+		log.Warn("admin request rejected, no admin token configured", "method", r.Method, "path", r.URL.Path)
+		http.Error(w, "Admin API not enabled", http.StatusNotFound)
+		return false
+	}
+	expected := "Bearer " + h.adminToken
+	if subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), []byte(expected)) != 1 {
+		log.Warn("unauthorized admin request", "method", r.Method, "path", r.URL.Path)
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return false
+	}
+	return true
+}
+
+func (h *SearchHandler) getCurations(w http.ResponseWriter, r *http.Request) {
+	log := h.logger.WithContext(r.Context())
+
+	id := r.URL.Query().Get("id")
+	if id == "" {
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(CurationListResponse{Groupings: h.curations.List()}); err != nil {
+			log.ErrorWithErr("failed to encode curation response", err)
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		return
+	}
+
+	version := 0
+	if v := r.URL.Query().Get("version"); v != "" {
+		var err error
+		if version, err = strconv.Atoi(v); err != nil || version < 1 {
+			http.Error(w, "Invalid version", http.StatusBadRequest)
+			return
+		}
+	}
+
+	grouping, ok := h.curations.Get(id, version)
+	if !ok {
+		http.Error(w, "Curated grouping not found", http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(CurationResponse{Grouping: *grouping, Versions: h.curations.Versions(id)}); err != nil {
+		log.ErrorWithErr("failed to encode curation response", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+}
+
+func (h *SearchHandler) putCuration(w http.ResponseWriter, r *http.Request) {
+	log := h.logger.WithContext(r.Context())
+
+	var req CurationRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		log.ErrorWithErr("failed to decode request body", err)
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	grouping := curation.Grouping{
+		View:         req.View,
+		Query:        req.Query,
+		FacetFilters: req.FacetFilters,
+		Clusters:     req.Clusters,
+	}
+	if previous, ok := h.curations.Lookup(req.View, req.Query, req.FacetFilters); ok {
+		grouping.NextID = previous.NextID
+	}
+
+	// Rules must parse, and RIPPER groups must be single facet values
+	parsed, err := toCuratedGrouping(&grouping)
+	if err == nil && req.View == curation.ViewRipper {
+		err = parsed.ValidateRipper()
+	}
+	if err != nil {
+		log.Warn("invalid curated grouping", "query", req.Query, "error", err)
+		http.Error(w, "Invalid curation: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	stored, err := h.curations.Put(grouping, req.ExpectedVersion)
+	if err != nil {
+		switch {
+		case errors.Is(err, curation.ErrInvalid):
+			log.Warn("invalid curated grouping", "query", req.Query, "error", err)
+			http.Error(w, "Invalid curation: "+err.Error(), http.StatusBadRequest)
+		case errors.Is(err, curation.ErrVersionConflict):
+			log.Warn("curated grouping version conflict", "query", req.Query, "error", err)
+			http.Error(w, "Version conflict", http.StatusConflict)
+		default:
+			log.ErrorWithErr("failed to store curated grouping", err, "query", req.Query)
+			http.Error(w, "Failed to store curation", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(CurationResponse{Grouping: *stored, Versions: h.curations.Versions(stored.ID)}); err != nil {
+		log.ErrorWithErr("failed to encode curation response", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	log.Info("curated grouping saved via admin API",
+		"id", stored.ID,
+		"view", stored.View,
+		"query", stored.Query,
+		"version", stored.Version,
+	)
+}
+
+func (h *SearchHandler) deleteCuration(w http.ResponseWriter, r *http.Request) {
+	log := h.logger.WithContext(r.Context())
+
+	id := r.URL.Query().Get("id")
+	if err := h.curations.Delete(id); err != nil {
+		if errors.Is(err, curation.ErrNotFound) {
+			http.Error(w, "Curated grouping not found", http.StatusNotFound)
+			return
+		}
+		log.ErrorWithErr("failed to delete curated grouping", err, "id", id)
+		http.Error(w, "Failed to delete curation", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+	log.Info("curated grouping deleted via admin API", "id", id)
+}
+
+// defaultLabelCacheLimit is the number of entries listed when no limit is given
+const defaultLabelCacheLimit = 100
+
+// HandleAdminLabelCache inspects and purges the cluster label cache:
+//
+//	GET    /api/admin/label-cache[?limit=N] counters and up to N entries (default 100, 0 = all)
+//	GET    /api/admin/label-cache?key=K     counters and the entry for cache key K
+//	DELETE /api/admin/label-cache[?key=K]   remove every entry, or only K
+//
+// It shares the admin token of the curation API.
+func (h *SearchHandler) HandleAdminLabelCache(w http.ResponseWriter, r *http.Request) {
+	log := h.logger.WithContext(r.Context())
+
+	if !h.authorizeAdmin(w, r) {
+		return
+	}
+	if h.labelCache == nil {
+		http.Error(w, "Label cache not enabled", http.StatusNotFound)
+		return
+	}
+
+	key := r.URL.Query().Get("key")
+	switch r.Method {
+	case http.MethodGet:
+		response := LabelCacheResponse{Stats: h.labelCache.Stats()}
+		if key != "" {
+			entry, ok := h.labelCache.Lookup(key)
+			if !ok {
+				http.Error(w, "Cache entry not found", http.StatusNotFound)
+				return
+			}
+			response.Entries = []labeler.CacheEntry{entry}
+		} else {
+			limit := defaultLabelCacheLimit
+			if v := r.URL.Query().Get("limit"); v != "" {
+				var err error
+				if limit, err = strconv.Atoi(v); err != nil || limit < 0 {
+					http.Error(w, "Invalid limit", http.StatusBadRequest)
+					return
+				}
+			}
+			response.Entries = h.labelCache.Entries(limit)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(response); err != nil {
+			log.ErrorWithErr("failed to encode label cache response", err)
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+
+	case http.MethodDelete:
+		purged := 0
+		if key != "" {
+			if !h.labelCache.Delete(key) {
+				http.Error(w, "Cache entry not found", http.StatusNotFound)
+				return
+			}
+			purged = 1
+		} else {
+			purged = h.labelCache.Purge()
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(LabelCachePurgeResponse{Purged: purged}); err != nil {
+			log.ErrorWithErr("failed to encode label cache response", err)
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		log.Info("label cache purged via admin API", "key", key, "purged", purged)
+
+	default:
+		log.Warn("method not allowed", "method", r.Method)
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// defaultUsageQueryLimit is the number of queries reported when no limit is given
+const defaultUsageQueryLimit = 100
+
+// HandleAdminLabelingUsage reports the tokens and estimated cost of labeling API calls
+// since startup, in total, per model and per query, and today's spend against the
+// daily budget:
+//
+//	GET /api/admin/labeling-usage[?limit=N] totals and the N most expensive queries (default 100, 0 = all)
+//
+// It shares the admin token of the curation API.
+func (h *SearchHandler) HandleAdminLabelingUsage(w http.ResponseWriter, r *http.Request) {
+	log := h.logger.WithContext(r.Context())
+
+	if !h.authorizeAdmin(w, r) {
+		return
+	}
+	if r.Method != http.MethodGet {
+		log.Warn("method not allowed", "method", r.Method)
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if h.usage == nil {
+		http.Error(w, "Labeling usage not tracked", http.StatusNotFound)
+		return
+	}
+
+	limit := defaultUsageQueryLimit
+	if v := r.URL.Query().Get("limit"); v != "" {
+		var err error
+		if limit, err = strconv.Atoi(v); err != nil || limit < 0 {
+			http.Error(w, "Invalid limit", http.StatusBadRequest)
+			return
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(h.usage.Report(limit)); err != nil {
+		log.ErrorWithErr("failed to encode labeling usage response", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+}
@@ -0,0 +1,257 @@
+package httpapi
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"ize/internal/algolia"
+	"ize/internal/curation"
+	"ize/internal/labeler"
+	"ize/internal/logger"
+)
+
+func TestSearchHandler_HandleAdminCurations(t *testing.T) {
+	hits := scatterGatherHits()
+	handler := &SearchHandler{
+		algoliaClient: &mockAlgoliaClient{
+			searchRipperFunc: func(ctx context.Context, query string, facetFilters [][]string) (*algolia.SearchResult, error) {
+				return &algolia.SearchResult{Hits: hits, TotalHits: len(hits)}, nil
+			},
+		},
+		logger:     logger.Default(),
+		curations:  memoryCurations(t),
+		adminToken: "secret",
+	}
+
+	admin := func(method, target string, body interface{}, wantStatus int) *httptest.ResponseRecorder {
+		t.Helper()
+		var buf bytes.Buffer
+		if body != nil {
+			json.NewEncoder(&buf).Encode(body)
+		}
+		req := httptest.NewRequest(method, target, &buf)
+		req.Header.Set("Authorization", "Bearer secret")
+		w := httptest.NewRecorder()
+
+		handler.HandleAdminCurations(w, req)
+
+		if w.Code != wantStatus {
+			t.Fatalf("HandleAdminCurations(%s %s) status = %d, want %d: %s", method, target, w.Code, wantStatus, w.Body.String())
+		}
+		return w
+	}
+	ripper := func(query string) RipperResponse {
+		t.Helper()
+		body, _ := json.Marshal(SearchRequest{Query: query})
+		w := httptest.NewRecorder()
+		handler.HandleRipper(w, httptest.NewRequest(http.MethodPost, "/api/ripper", bytes.NewBuffer(body)))
+		if w.Code != http.StatusOK {
+			t.Fatalf("HandleRipper() status = %d, want %d", w.Code, http.StatusOK)
+		}
+		var response RipperResponse
+		if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+			t.Fatalf("Failed to decode response: %v", err)
+		}
+		return response
+	}
+
+	// Missing token
+	w := httptest.NewRecorder()
+	handler.HandleAdminCurations(w, httptest.NewRequest(http.MethodGet, "/api/admin/curations", nil))
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("HandleAdminCurations(no token) status = %d, want %d", w.Code, http.StatusUnauthorized)
+	}
+
+	request := CurationRequest{
+		View:  curation.ViewRipper,
+		Query: "Test",
+		Clusters: []curation.Cluster{
+			{ID: "c1", Name: "Apple phones", Rule: [][]string{{"brand:Apple"}}},
+			{ID: "c2", Name: "Shirts", Rule: [][]string{{"category:Shirts"}}},
+		},
+		ExpectedVersion: -1,
+	}
+	var created CurationResponse
+	json.NewDecoder(admin(http.MethodPut, "/api/admin/curations", request, http.StatusOK).Body).Decode(&created)
+	if created.Version != 1 || created.ID == "" || created.Query != "test" {
+		t.Fatalf("created = %+v, want version 1 of query %q", created, "test")
+	}
+
+	// RIPPER now returns the curated groups for the normalized query
+	curated := ripper("  TEST ")
+	if !curated.Curated || len(curated.Groups) != 2 {
+		t.Fatalf("HandleRipper() = %d groups (curated %v), want 2 curated", len(curated.Groups), curated.Curated)
+	}
+	if g := curated.Groups[0]; g.Name != "Apple phones" || g.FacetValue != "Apple" || len(g.Items) != 2 {
+		t.Errorf("first group = %+v, want the 2 Apple phones", g)
+	}
+	if len(curated.OtherGroup) != 2 {
+		t.Errorf("OtherGroup = %d items, want the 2 Samsung phones", len(curated.OtherGroup))
+	}
+
+	// Updates need the current version
+	request.Clusters = request.Clusters[1:]
+	request.ExpectedVersion = 1
+	admin(http.MethodPut, "/api/admin/curations", request, http.StatusOK)
+	admin(http.MethodPut, "/api/admin/curations", request, http.StatusConflict)
+
+	var fetched CurationResponse
+	json.NewDecoder(admin(http.MethodGet, "/api/admin/curations?id="+created.ID, nil, http.StatusOK).Body).Decode(&fetched)
+	if fetched.Version != 2 || len(fetched.Clusters) != 1 || len(fetched.Versions) != 2 {
+		t.Errorf("fetched = version %d with %d clusters and versions %v, want version 2, 1 cluster, 2 versions", fetched.Version, len(fetched.Clusters), fetched.Versions)
+	}
+	json.NewDecoder(admin(http.MethodGet, "/api/admin/curations?id="+created.ID+"&version=1", nil, http.StatusOK).Body).Decode(&fetched)
+	if fetched.Version != 1 || len(fetched.Clusters) != 2 {
+		t.Errorf("fetched version 1 = version %d with %d clusters, want 2 clusters", fetched.Version, len(fetched.Clusters))
+	}
+
+	var list CurationListResponse
+	json.NewDecoder(admin(http.MethodGet, "/api/admin/curations", nil, http.StatusOK).Body).Decode(&list)
+	if len(list.Groupings) != 1 {
+		t.Errorf("list = %d groupings, want 1", len(list.Groupings))
+	}
+
+	// RIPPER rules must be single facet values
+	request.Clusters = []curation.Cluster{{ID: "c1", Rule: [][]string{{"brand:Apple", "brand:Samsung"}}}}
+	request.ExpectedVersion = 0
+	admin(http.MethodPut, "/api/admin/curations", request, http.StatusBadRequest)
+
+	admin(http.MethodDelete, "/api/admin/curations?id="+created.ID, nil, http.StatusNoContent)
+	admin(http.MethodDelete, "/api/admin/curations?id="+created.ID, nil, http.StatusNotFound)
+	admin(http.MethodPost, "/api/admin/curations", nil, http.StatusMethodNotAllowed)
+	if ripper("test").Curated {
+		t.Error("HandleRipper() still curated after delete")
+	}
+}
+
+func TestSearchHandler_HandleAdminLabelCache(t *testing.T) {
+	cache := labeler.NewLRUCache(10, time.Hour)
+	cache.Set("k1", labeler.Label{Name: "Apple Phones"})
+	cache.Set("k2", labeler.Label{Name: "Samsung Phones"})
+	handler := &SearchHandler{
+		logger:     logger.Default(),
+		labelCache: cache,
+		adminToken: "secret",
+	}
+
+	admin := func(method, target string, wantStatus int) *httptest.ResponseRecorder {
+		t.Helper()
+		req := httptest.NewRequest(method, target, nil)
+		req.Header.Set("Authorization", "Bearer secret")
+		w := httptest.NewRecorder()
+
+		handler.HandleAdminLabelCache(w, req)
+
+		if w.Code != wantStatus {
+			t.Fatalf("HandleAdminLabelCache(%s %s) status = %d, want %d: %s", method, target, w.Code, wantStatus, w.Body.String())
+		}
+		return w
+	}
+
+	w := httptest.NewRecorder()
+	handler.HandleAdminLabelCache(w, httptest.NewRequest(http.MethodGet, "/api/admin/label-cache", nil))
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("HandleAdminLabelCache(no token) status = %d, want %d", w.Code, http.StatusUnauthorized)
+	}
+
+	var response LabelCacheResponse
+	json.NewDecoder(admin(http.MethodGet, "/api/admin/label-cache?limit=1", http.StatusOK).Body).Decode(&response)
+	if response.Stats.Entries != 2 || len(response.Entries) != 1 || response.Entries[0].Name != "Samsung Phones" {
+		t.Errorf("GET limit=1 = %+v, want 2 entries counted and the most recent listed", response)
+	}
+	json.NewDecoder(admin(http.MethodGet, "/api/admin/label-cache?key=k1", http.StatusOK).Body).Decode(&response)
+	if len(response.Entries) != 1 || response.Entries[0].Name != "Apple Phones" {
+		t.Errorf("GET key=k1 = %+v, want the Apple Phones entry", response.Entries)
+	}
+	admin(http.MethodGet, "/api/admin/label-cache?key=missing", http.StatusNotFound)
+	admin(http.MethodGet, "/api/admin/label-cache?limit=-1", http.StatusBadRequest)
+
+	var purge LabelCachePurgeResponse
+	json.NewDecoder(admin(http.MethodDelete, "/api/admin/label-cache?key=k1", http.StatusOK).Body).Decode(&purge)
+	if purge.Purged != 1 {
+		t.Errorf("DELETE key=k1 purged = %d, want 1", purge.Purged)
+	}
+	admin(http.MethodDelete, "/api/admin/label-cache?key=k1", http.StatusNotFound)
+	json.NewDecoder(admin(http.MethodDelete, "/api/admin/label-cache", http.StatusOK).Body).Decode(&purge)
+	if purge.Purged != 1 || cache.Stats().Entries != 0 {
+		t.Errorf("DELETE purged = %d leaving %d entries, want 1 and none", purge.Purged, cache.Stats().Entries)
+	}
+	admin(http.MethodPut, "/api/admin/label-cache", http.StatusMethodNotAllowed)
+
+	handler.labelCache = nil
+	admin(http.MethodGet, "/api/admin/label-cache", http.StatusNotFound)
+}
+
+func TestSearchHandler_HandleAdminLabelingUsage(t *testing.T) {
+	handler := &SearchHandler{
+		logger:     logger.Default(),
+		usage:      labeler.NewUsageTracker(nil, 5),
+		adminToken: "secret",
+	}
+
+	admin := func(method, target string, wantStatus int) *httptest.ResponseRecorder {
+		t.Helper()
+		req := httptest.NewRequest(method, target, nil)
+		req.Header.Set("Authorization", "Bearer secret")
+		w := httptest.NewRecorder()
+
+		handler.HandleAdminLabelingUsage(w, req)
+
+		if w.Code != wantStatus {
+			t.Fatalf("HandleAdminLabelingUsage(%s %s) status = %d, want %d: %s", method, target, w.Code, wantStatus, w.Body.String())
+		}
+		return w
+	}
+
+	w := httptest.NewRecorder()
+	handler.HandleAdminLabelingUsage(w, httptest.NewRequest(http.MethodGet, "/api/admin/labeling-usage", nil))
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("HandleAdminLabelingUsage(no token) status = %d, want %d", w.Code, http.StatusUnauthorized)
+	}
+
+	var report labeler.UsageReport
+	json.NewDecoder(admin(http.MethodGet, "/api/admin/labeling-usage?limit=10", http.StatusOK).Body).Decode(&report)
+	if report.Totals.Requests != 0 || report.Today.BudgetUSD != 5 || report.Today.Exceeded {
+		t.Errorf("GET = %+v, want no usage against a $5 budget", report)
+	}
+	admin(http.MethodGet, "/api/admin/labeling-usage?limit=x", http.StatusBadRequest)
+	admin(http.MethodDelete, "/api/admin/labeling-usage", http.StatusMethodNotAllowed)
+
+	handler.usage = nil
+	admin(http.MethodGet, "/api/admin/labeling-usage", http.StatusNotFound)
+}
+
+func TestSearchHandler_AdminDisabledWithoutToken(t *testing.T) {
+	handler := &SearchHandler{
+		logger:    logger.Default(),
+		curations: memoryCurations(t),
+		usage:     labeler.NewUsageTracker(nil, 5),
+	}
+
+	tests := []struct {
+		name   string
+		target string
+		handle http.HandlerFunc
+	}{
+		{"curations", "/api/admin/curations", handler.HandleAdminCurations},
+		{"label cache", "/api/admin/label-cache", handler.HandleAdminLabelCache},
+		{"labeling usage", "/api/admin/labeling-usage", handler.HandleAdminLabelingUsage},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Even a request with some bearer token is refused
+			req := httptest.NewRequest(http.MethodGet, tt.target, nil)
+			req.Header.Set("Authorization", "Bearer ")
+			w := httptest.NewRecorder()
+			tt.handle(w, req)
+			if w.Code != http.StatusNotFound {
+				t.Errorf("%s without admin token status = %d, want %d", tt.target, w.Code, http.StatusNotFound)
+			}
+		})
+	}
+}
@@ -0,0 +1,234 @@
+package httpapi
+
+import (
+	"fmt"
+
+	"ize/internal/algolia"
+	"ize/internal/curation"
+	"ize/internal/ize"
+)
+
+// toSearchResults converts ize.Result items to httpapi.SearchResult
+func toSearchResults(results []ize.Result) []SearchResult {
+	converted := make([]SearchResult, len(results))
+	for i, item := range results {
+		converted[i] = SearchResult{
+			ID:          item.ID,
+			Name:        item.Name,
+			Description: item.Description,
+			Image:       item.Image,
+		}
+	}
+	return converted
+}
+
+// toClusterResponse converts an ize.ClusterResult to the API response
+// Percentages are approximate, computed from the sample of hits that was clustered
+func toClusterResponse(clusterResult *ize.ClusterResult, algoliaResults *algolia.SearchResult) ClusterResponse {
+	totalHits := algoliaResults.TotalHits
+	sampleSize := len(algoliaResults.Hits)
+
+	groups := make([]ClusterGroup, len(clusterResult.Groups))
+	for i, group := range clusterResult.Groups {
+		topFacets := make([]FacetCount, len(group.TopFacets))
+		for j, f := range group.TopFacets {
+			topFacets[j] = FacetCount{
+				FacetName:  f.FacetName,
+				FacetValue: f.FacetValue,
+				Count:      f.Count,
+				Percentage: f.Percentage,
+			}
+		}
+
+		// Convert rule and quality if present
+		var rule [][]string
+		var ruleDescription string
+		var ruleQuality *RuleQuality
+		if group.Rule != nil {
+			rule = group.Rule.ToAlgoliaFilter()
+			ruleDescription = group.Rule.String()
+		}
+		if group.RuleQuality != nil {
+			ruleQuality = &RuleQuality{
+				Precision: group.RuleQuality.Precision,
+				Recall:    group.RuleQuality.Recall,
+				F1:        group.RuleQuality.F1,
+			}
+		}
+
+		var ruleDiagnostics *RuleDiagnostics
+		if group.RuleDiagnostics != nil {
+			ruleDiagnostics = toRuleDiagnostics(group.RuleDiagnostics)
+		}
+
+		// Calculate approximate percentage from sample
+		var percentage float64
+		if sampleSize > 0 {
+			percentage = float64(len(group.Items)) / float64(sampleSize) * 100
+		}
+
+		groups[i] = ClusterGroup{
+			ID:              group.ID,
+			Color:           group.Color,
+			Stable:          group.Stable,
+			Pinned:          group.Pinned,
+			Name:            group.Name,
+			Description:     group.Description,
+			Items:           toSearchResults(group.Items),
+			Percentage:      percentage,
+			TopFacets:       topFacets,
+			TopTerms:        group.TopTerms,
+			Rule:            rule,
+			RuleDescription: ruleDescription,
+			RuleQuality:     ruleQuality,
+			RuleDiagnostics: ruleDiagnostics,
+		}
+	}
+
+	var repeatedItems map[string]int
+	if len(clusterResult.RepeatedItems) > 0 {
+		repeatedItems = clusterResult.RepeatedItems
+	}
+
+	var explanations map[string]ItemExplanation
+	if clusterResult.Explanations != nil {
+		explanations = make(map[string]ItemExplanation, len(clusterResult.Explanations))
+		for id, e := range clusterResult.Explanations {
+			explanations[id] = toItemExplanation(e, groups)
+		}
+	}
+
+	return ClusterResponse{
+		Groups:        groups,
+		OtherGroup:    toSearchResults(clusterResult.OtherGroup),
+		ClusterCount:  clusterResult.ClusterCount,
+		TotalHits:     totalHits,
+		RepeatedItems: repeatedItems,
+		Explanations:  explanations,
+	}
+}
+
+// toItemExplanation converts an ize.ItemExplanation, naming memberships after the
+// converted groups so LLM labels are reflected
+func toItemExplanation(e ize.ItemExplanation, groups []ClusterGroup) ItemExplanation {
+	memberships := make([]ClusterMembership, len(e.Memberships))
+	for i, m := range e.Memberships {
+		memberships[i] = toClusterMembership(m, groups)
+	}
+
+	var alternative *ClusterMembership
+	if e.NearestAlternative != nil {
+		m := toClusterMembership(*e.NearestAlternative, groups)
+		alternative = &m
+	}
+
+	return ItemExplanation{
+		Memberships:        memberships,
+		Silhouette:         e.Silhouette,
+		NearestAlternative: alternative,
+	}
+}
+
+// toClusterMembership converts an ize.ClusterMembership
+func toClusterMembership(m ize.ClusterMembership, groups []ClusterGroup) ClusterMembership {
+	var name string
+	if m.Group >= 0 && m.Group < len(groups) {
+		name = groups[m.Group].Name
+	}
+	return ClusterMembership{
+		Group:            m.Group,
+		Name:             name,
+		SatisfiedClauses: m.SatisfiedClauses,
+		FailedClauses:    m.FailedClauses,
+		Distance:         m.Distance,
+	}
+}
+
+// toRuleDiagnostics converts ize.RuleDiagnostics to the API representation
+func toRuleDiagnostics(d *ize.RuleDiagnostics) *RuleDiagnostics {
+	rejected := make([]RejectedCandidate, len(d.Rejected))
+	for i, r := range d.Rejected {
+		rejected[i] = RejectedCandidate{
+			Rule:      r.Rule,
+			Score:     r.Score,
+			Precision: r.Precision,
+			Recall:    r.Recall,
+			Reason:    r.Reason,
+		}
+	}
+	return &RuleDiagnostics{
+		Objective:     string(d.Objective),
+		Score:         d.Score,
+		Rejected:      rejected,
+		Exact:         d.Exact,
+		ExactTimedOut: d.ExactTimedOut,
+		NodesExplored: d.NodesExplored,
+	}
+}
+
+// toCuratedGrouping converts a stored grouping to ize form, parsing its rules
+func toCuratedGrouping(g *curation.Grouping) (*ize.CuratedGrouping, error) {
+	clusters := make([]ize.CuratedCluster, len(g.Clusters))
+	for i, c := range g.Clusters {
+		rule, err := ize.ParseAlgoliaFilter(c.Rule)
+		if err != nil {
+			return nil, fmt.Errorf("cluster %s: %w", c.ID, err)
+		}
+		var quality *ize.RuleQuality
+		if c.Quality != nil {
+			quality = &ize.RuleQuality{
+				Precision: c.Quality.Precision,
+				Recall:    c.Quality.Recall,
+				F1:        c.Quality.F1,
+			}
+		}
+		clusters[i] = ize.CuratedCluster{
+			ID:          c.ID,
+			Name:        c.Name,
+			Pinned:      c.Pinned,
+			Color:       c.Color,
+			Rule:        rule,
+			RuleQuality: quality,
+		}
+	}
+	return &ize.CuratedGrouping{
+		Query:        g.Query,
+		FacetFilters: g.FacetFilters,
+		Clusters:     clusters,
+		NextID:       g.NextID,
+	}, nil
+}
+
+// fromCuratedGrouping converts an ize grouping to the stored form for a view
+func fromCuratedGrouping(view string, g *ize.CuratedGrouping) curation.Grouping {
+	clusters := make([]curation.Cluster, len(g.Clusters))
+	for i, c := range g.Clusters {
+		var rule [][]string
+		if c.Rule != nil {
+			rule = c.Rule.ToAlgoliaFilter()
+		}
+		var quality *curation.Quality
+		if c.RuleQuality != nil {
+			quality = &curation.Quality{
+				Precision: c.RuleQuality.Precision,
+				Recall:    c.RuleQuality.Recall,
+				F1:        c.RuleQuality.F1,
+			}
+		}
+		clusters[i] = curation.Cluster{
+			ID:      c.ID,
+			Name:    c.Name,
+			Pinned:  c.Pinned,
+			Color:   c.Color,
+			Rule:    rule,
+			Quality: quality,
+		}
+	}
+	return curation.Grouping{
+		View:         view,
+		Query:        g.Query,
+		FacetFilters: g.FacetFilters,
+		Clusters:     clusters,
+		NextID:       g.NextID,
+	}
+}
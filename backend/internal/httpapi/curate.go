@@ -0,0 +1,202 @@
+package httpapi
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"ize/internal/algolia"
+	"ize/internal/curation"
+	"ize/internal/ize"
+	"ize/internal/logger"
+)
+
+// Curation actions
+const (
+	actionMerge  = "merge"
+	actionSplit  = "split"
+	actionRename = "rename"
+)
+
+// curatedGrouping returns the stored curated grouping for a view, query and filters,
+// with its version. Groupings whose rules cannot be parsed are logged and ignored.
+func (h *SearchHandler) curatedGrouping(log *logger.Logger, view, query string, facetFilters [][]string) (*ize.CuratedGrouping, int) {
+	if h.curations == nil {
+		return nil, 0
+	}
+	stored, ok := h.curations.Lookup(view, query, facetFilters)
+	if !ok {
+		return nil, 0
+	}
+	grouping, err := toCuratedGrouping(stored)
+	if err != nil {
+		log.Warn("ignoring unparseable curated grouping", "id", stored.ID, "version", stored.Version, "error", err)
+		return nil, 0
+	}
+	return grouping, stored.Version
+}
+
+// HandleCurate merges, splits or renames clusters of a query. The first edit starts from
+// the automatic clustering; each edit is stored as a new version of the query's curated
+// grouping, which later /api/cluster requests return.
+func (h *SearchHandler) HandleCurate(w http.ResponseWriter, r *http.Request) {
+	log := h.logger.WithContext(r.Context())
+
+	if r.Method != http.MethodPost {
+		log.Warn("method not allowed", "method", r.Method)
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req CurateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		log.ErrorWithErr("failed to decode request body", err)
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if req.Action != actionMerge && req.Action != actionSplit && req.Action != actionRename {
+		log.Warn("invalid curation action", "action", req.Action)
+		http.Error(w, "Invalid action", http.StatusBadRequest)
+		return
+	}
+	if req.Action == actionMerge && len(req.ClusterIDs) != 2 {
+		log.Warn("merge needs exactly two clusters", "cluster_ids", req.ClusterIDs)
+		http.Error(w, "Merge needs exactly two clusters", http.StatusBadRequest)
+		return
+	}
+	if h.curations == nil {
+		log.Error("curation store not configured")
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	log.Debug("processing Curate request",
+		"query", req.Query,
+		"facet_filters", req.FacetFilters,
+		"action", req.Action,
+		"cluster_ids", req.ClusterIDs,
+		"cluster_id", req.ClusterID,
+	)
+
+	algoliaResults, err := h.algoliaClient.SearchRipper(r.Context(), req.Query, req.FacetFilters)
+	if err != nil {
+		log.ErrorWithErr("algolia search failed for Curate", err, "query", req.Query)
+		http.Error(w, "Search failed", http.StatusInternalServerError)
+		return
+	}
+
+	grouping, version := h.curatedGrouping(log, curation.ViewCluster, req.Query, req.FacetFilters)
+	if grouping == nil {
+		clusterResult, nextID, err := h.clusterHits(r.Context(), req.Query, algoliaResults, h.clusterOptions, nil)
+		if err != nil {
+			log.ErrorWithErr("Cluster processing failed for Curate", err, "query", req.Query)
+			http.Error(w, "Cluster processing failed", http.StatusInternalServerError)
+			return
+		}
+		grouping = ize.NewCuratedGrouping(req.Query, req.FacetFilters, clusterResult, nextID)
+	}
+
+	var newIDs []string
+	switch req.Action {
+	case actionMerge:
+		var id string
+		grouping, id, err = ize.MergeClusters(grouping, algoliaResults, req.ClusterIDs[0], req.ClusterIDs[1], h.clusterOptions, log)
+		newIDs = []string{id}
+	case actionSplit:
+		grouping, newIDs, err = ize.SplitCluster(grouping, algoliaResults, req.ClusterID, req.Parts, h.clusterOptions, log)
+	case actionRename:
+		grouping, err = ize.RenameCluster(grouping, req.ClusterID, req.Name)
+	}
+	if err != nil {
+		log.Warn("curation rejected", "action", req.Action, "error", err)
+		switch {
+		case errors.Is(err, ize.ErrClusterNotFound):
+			http.Error(w, "Cluster not found", http.StatusNotFound)
+		case errors.Is(err, ize.ErrCannotSplit):
+			http.Error(w, "Cluster has too few items to split", http.StatusBadRequest)
+		default:
+			http.Error(w, "Invalid curation", http.StatusBadRequest)
+		}
+		return
+	}
+
+	response, err := h.curatedResponse(r.Context(), grouping, algoliaResults, newIDs)
+	if err != nil {
+		log.ErrorWithErr("failed to apply curated grouping", err, "query", req.Query)
+		http.Error(w, "Cluster processing failed", http.StatusInternalServerError)
+		return
+	}
+	expectedVersion := version
+	if expectedVersion == 0 {
+		expectedVersion = -1 // Must still not exist
+	}
+	if _, err := h.curations.Put(fromCuratedGrouping(curation.ViewCluster, grouping), expectedVersion); err != nil {
+		switch {
+		case errors.Is(err, curation.ErrInvalid):
+			log.Warn("invalid curated grouping", "query", req.Query, "error", err)
+			http.Error(w, "Invalid curation: "+err.Error(), http.StatusBadRequest)
+		case errors.Is(err, curation.ErrVersionConflict):
+			log.Warn("curated grouping changed concurrently", "query", req.Query, "error", err)
+			http.Error(w, "Curated grouping changed, retry", http.StatusConflict)
+		default:
+			log.ErrorWithErr("failed to store curated grouping", err, "query", req.Query)
+			http.Error(w, "Failed to store curation", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		log.ErrorWithErr("failed to encode Curate response", err, "query", req.Query)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	log.Info("Curate request completed successfully",
+		"query", req.Query,
+		"action", req.Action,
+		"new_clusters", newIDs,
+		"cluster_count", len(response.Groups),
+	)
+}
+
+// curatedResponse groups hits by a curated grouping, names the clusters in newIDs (saving
+// the names into the grouping) and converts the result for the API, with a result token
+// so the curated clusters can be refined like automatic ones
+func (h *SearchHandler) curatedResponse(ctx context.Context, grouping *ize.CuratedGrouping, algoliaResults *algolia.SearchResult, newIDs []string) (ClusterResponse, error) {
+	clusterResult, err := ize.ApplyCuratedGrouping(grouping.Query, algoliaResults, grouping, h.clusterOptions, h.logger.WithContext(ctx))
+	if err != nil {
+		return ClusterResponse{}, err
+	}
+
+	if len(newIDs) > 0 {
+		isNew := make(map[string]bool, len(newIDs))
+		for _, id := range newIDs {
+			isNew[id] = true
+		}
+		var unnamed []int
+		for i, group := range clusterResult.Groups {
+			if isNew[group.ID] {
+				unnamed = append(unnamed, i)
+			}
+		}
+		h.nameClusters(ctx, grouping.Query, clusterResult.Groups, unnamed)
+		for _, i := range unnamed {
+			grouping.Clusters[i].Name = clusterResult.Groups[i].Name
+		}
+	}
+
+	response := toClusterResponse(clusterResult, algoliaResults)
+	response.Curated = true
+	if h.lineages != nil {
+		token, err := h.lineages.put(ize.NewClusterLineage(clusterResult.Groups, grouping.NextID))
+		if err != nil {
+			h.logger.WithContext(ctx).Warn("failed to store cluster identities, response has no result token", "error", err)
+		} else {
+			response.ResultToken = token
+		}
+	}
+	return response, nil
+}
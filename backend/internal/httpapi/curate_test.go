@@ -0,0 +1,184 @@
+package httpapi
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"ize/internal/algolia"
+	"ize/internal/curation"
+	"ize/internal/ize"
+	"ize/internal/logger"
+)
+
+// memoryCurations opens an in-memory curation store
+func memoryCurations(t *testing.T) *curation.Store {
+	t.Helper()
+	store, err := curation.Open("", 0, logger.Default())
+	if err != nil {
+		t.Fatalf("curation.Open() error = %v", err)
+	}
+	return store
+}
+
+func TestSearchHandler_HandleCurate(t *testing.T) {
+	hits := scatterGatherHits()
+	handler := &SearchHandler{
+		algoliaClient: &mockAlgoliaClient{
+			searchRipperFunc: func(ctx context.Context, query string, facetFilters [][]string) (*algolia.SearchResult, error) {
+				return &algolia.SearchResult{Hits: hits, TotalHits: len(hits)}, nil
+			},
+		},
+		logger:            logger.Default(),
+		identityThreshold: ize.DefaultIdentityThreshold,
+		lineages:          newTokenStore[*ize.ClusterLineage](10),
+		curations:         memoryCurations(t),
+	}
+
+	curate := func(request CurateRequest, wantStatus int) ClusterResponse {
+		t.Helper()
+		body, _ := json.Marshal(request)
+		req := httptest.NewRequest(http.MethodPost, "/api/curate", bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+
+		handler.HandleCurate(w, req)
+
+		if w.Code != wantStatus {
+			t.Fatalf("HandleCurate(%s) status = %d, want %d", request.Action, w.Code, wantStatus)
+		}
+		var response ClusterResponse
+		if wantStatus == http.StatusOK {
+			if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+				t.Fatalf("Failed to decode response: %v", err)
+			}
+		}
+		return response
+	}
+	cluster := func(query string) ClusterResponse {
+		t.Helper()
+		body, _ := json.Marshal(ClusterRequest{SearchRequest: SearchRequest{Query: query}})
+		req := httptest.NewRequest(http.MethodPost, "/api/cluster", bytes.NewBuffer(body))
+		w := httptest.NewRecorder()
+
+		handler.HandleCluster(w, req)
+
+		if w.Code != http.StatusOK {
+			t.Fatalf("HandleCluster() status = %d, want %d", w.Code, http.StatusOK)
+		}
+		var response ClusterResponse
+		if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+			t.Fatalf("Failed to decode response: %v", err)
+		}
+		return response
+	}
+
+	automatic := cluster("test")
+	if automatic.Curated || len(automatic.Groups) < 2 {
+		t.Fatalf("automatic clustering = %d groups (curated %v), want at least 2 uncurated", len(automatic.Groups), automatic.Curated)
+	}
+	search := SearchRequest{Query: "test"}
+
+	// The first edit starts from the automatic clustering
+	first, second := automatic.Groups[0], automatic.Groups[1]
+	merged := curate(CurateRequest{SearchRequest: search, Action: "merge", ClusterIDs: []string{first.ID, second.ID}}, http.StatusOK)
+	if !merged.Curated || merged.ResultToken == "" {
+		t.Errorf("merge response curated = %v, token = %q, want curated with token", merged.Curated, merged.ResultToken)
+	}
+	if len(merged.Groups) != len(automatic.Groups)-1 {
+		t.Fatalf("merged groups = %d, want %d", len(merged.Groups), len(automatic.Groups)-1)
+	}
+	mergedID := merged.Groups[0].ID
+	if mergedID == first.ID || mergedID == second.ID || merged.Groups[0].Color != first.Color {
+		t.Errorf("merged group = %s/%d, want a new ID with color %d", mergedID, merged.Groups[0].Color, first.Color)
+	}
+	if n := len(merged.Groups[0].Items); n != len(first.Items)+len(second.Items) {
+		t.Errorf("merged group items = %d, want %d", n, len(first.Items)+len(second.Items))
+	}
+
+	renamed := curate(CurateRequest{SearchRequest: search, Action: "rename", ClusterID: mergedID, Name: "Everything"}, http.StatusOK)
+	if g := renamed.Groups[0]; g.Name != "Everything" || !g.Pinned {
+		t.Errorf("renamed group = %q (pinned %v), want pinned %q", g.Name, g.Pinned, "Everything")
+	}
+
+	// The next identical cluster request returns the curated version
+	again := cluster("test")
+	if !again.Curated || len(again.Groups) != len(renamed.Groups) || again.Groups[0].Name != "Everything" {
+		t.Errorf("cluster after curation = %d groups (curated %v, first %q), want the curated grouping", len(again.Groups), again.Curated, again.Groups[0].Name)
+	}
+	if other := cluster("other query"); other.Curated {
+		t.Error("curation applied to a different query")
+	}
+
+	curate(CurateRequest{SearchRequest: search, Action: "delete"}, http.StatusBadRequest)
+	curate(CurateRequest{SearchRequest: search, Action: "merge", ClusterIDs: []string{mergedID}}, http.StatusBadRequest)
+	curate(CurateRequest{SearchRequest: search, Action: "rename", ClusterID: "missing", Name: "x"}, http.StatusNotFound)
+	curate(CurateRequest{SearchRequest: search, Action: "split", ClusterID: mergedID, Parts: 9}, http.StatusBadRequest)
+}
+
+func TestSearchHandler_HandleCurate_NoRule(t *testing.T) {
+	// Hits without facets are clustered by text, so no cluster has a rule
+	hits := make([]algolia.Hit, 0, 20)
+	for i := 0; i < 20; i++ {
+		name := fmt.Sprintf("Wireless noise cancelling headphones %d", i)
+		if i%2 == 1 {
+			name = fmt.Sprintf("Waterproof leather hiking boots %d", i)
+		}
+		hits = append(hits, algolia.Hit{ObjectID: fmt.Sprintf("%d", i), Name: name})
+	}
+	handler := &SearchHandler{
+		algoliaClient: &mockAlgoliaClient{
+			searchRipperFunc: func(ctx context.Context, query string, facetFilters [][]string) (*algolia.SearchResult, error) {
+				return &algolia.SearchResult{Hits: hits, TotalHits: len(hits)}, nil
+			},
+		},
+		logger:            logger.Default(),
+		clusterOptions:    ize.ClusterOptions{TextWeight: 1},
+		identityThreshold: ize.DefaultIdentityThreshold,
+		lineages:          newTokenStore[*ize.ClusterLineage](10),
+		curations:         memoryCurations(t),
+	}
+
+	clusterIDs := func() []string {
+		body, _ := json.Marshal(ClusterRequest{SearchRequest: SearchRequest{Query: "test"}})
+		w := httptest.NewRecorder()
+		handler.HandleCluster(w, httptest.NewRequest(http.MethodPost, "/api/cluster", bytes.NewBuffer(body)))
+		var response ClusterResponse
+		if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+			t.Fatalf("Failed to decode response: %v", err)
+		}
+		ids := make([]string, len(response.Groups))
+		for i, group := range response.Groups {
+			if group.Rule != nil {
+				t.Fatalf("group %s has rule %v, want none", group.ID, group.Rule)
+			}
+			ids[i] = group.ID
+		}
+		return ids
+	}
+	ids := clusterIDs()
+	if len(ids) < 2 {
+		t.Fatalf("text clustering = %d groups, want at least 2", len(ids))
+	}
+
+	search := SearchRequest{Query: "test"}
+	for _, request := range []CurateRequest{
+		{SearchRequest: search, Action: "merge", ClusterIDs: ids[:2]},
+		{SearchRequest: search, Action: "split", ClusterID: ids[0]},
+		{SearchRequest: search, Action: "rename", ClusterID: ids[0], Name: "Headphones"},
+	} {
+		body, _ := json.Marshal(request)
+		w := httptest.NewRecorder()
+		handler.HandleCurate(w, httptest.NewRequest(http.MethodPost, "/api/curate", bytes.NewBuffer(body)))
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("HandleCurate(%s) of a cluster without a rule status = %d, want %d", request.Action, w.Code, http.StatusBadRequest)
+		}
+	}
+	if _, ok := handler.curations.Lookup(curation.ViewCluster, "test", nil); ok {
+		t.Error("curation of clusters without rules was stored")
+	}
+}
@@ -1,11 +1,37 @@
 package httpapi
 
+import (
+	"ize/internal/curation"
+	"ize/internal/labeler"
+)
+
 // SearchRequest represents the incoming search request
 type SearchRequest struct {
 	Query        string     `json:"query"`
 	FacetFilters [][]string `json:"facetFilters,omitempty"`
 }
 
+// ClusterRequest represents the incoming cluster request
+type ClusterRequest struct {
+	SearchRequest
+	Assignment string `json:"assignment,omitempty"` // "overlapping" (default), "first_match" or "best_match"
+	Explain    bool   `json:"explain,omitempty"`    // Include per-item membership explanations
+	// ExactCounts runs a count query per cluster rule so totalCount and percentage are
+	// exact rather than estimated from the 100-hit sample
+	ExactCounts bool `json:"exactCounts,omitempty"`
+	// PreviousToken is the resultToken of the response being refined; clusters that match
+	// its clusters keep their id, color and name
+	PreviousToken string `json:"previousToken,omitempty"`
+}
+
+// ClusterItemsRequest pages through all items matching a cluster's rule
+type ClusterItemsRequest struct {
+	SearchRequest
+	Rule        [][]string `json:"rule"`                  // Cluster rule in Algolia filter format, ANDed with facetFilters
+	Page        int        `json:"page,omitempty"`        // Zero-based page
+	HitsPerPage int        `json:"hitsPerPage,omitempty"` // Defaults to 20, at most 1000
+}
+
 // FacetMeta provides display metadata for a facet field
 type FacetMeta struct {
 	Field        string `json:"field"`                  // Algolia facet field name
@@ -32,6 +58,7 @@ type SearchResult struct {
 type RipperGroup struct {
 	FacetName  string         `json:"facetName"`
 	FacetValue string         `json:"facetValue"`
+	Name       string         `json:"name,omitempty"` // Curated label
 	Items      []SearchResult `json:"items"`
 	Count      int            `json:"count"` // Accurate count from Algolia facets
 }
@@ -41,6 +68,7 @@ type RipperResponse struct {
 	Groups     []RipperGroup  `json:"groups"`
 	OtherGroup []SearchResult `json:"otherGroup"`
 	FacetMeta  []FacetMeta    `json:"facetMeta,omitempty"`
+	Curated    bool           `json:"curated,omitempty"` // Groups come from a curated grouping
 }
 
 // FacetCount represents a facet:value pair with its count and percentage
@@ -58,21 +86,171 @@ type RuleQuality struct {
 	F1        float64 `json:"f1"`        // Harmonic mean of precision and recall
 }
 
+// RuleDiagnostics explains how a cluster's rule was chosen by the rule search
+type RuleDiagnostics struct {
+	Objective string              `json:"objective"` // Objective the rule was fitted for
+	Score     float64             `json:"score"`     // Objective score of the selected rule
+	Rejected  []RejectedCandidate `json:"rejected"`  // Best-scoring candidates that were not selected
+
+	Exact         bool `json:"exact,omitempty"`         // Rule is proven optimal by exact search
+	ExactTimedOut bool `json:"exactTimedOut,omitempty"` // Exact search ran out of time; rule is from the fallback fitter
+	NodesExplored int  `json:"nodesExplored,omitempty"` // Partial rules evaluated by exact search
+}
+
+// RejectedCandidate is a candidate rule that the search discarded
+type RejectedCandidate struct {
+	Rule      string  `json:"rule"`      // Human-readable rule
+	Score     float64 `json:"score"`     // Objective score
+	Precision float64 `json:"precision"` // Precision of the candidate
+	Recall    float64 `json:"recall"`    // Recall of the candidate
+	Reason    string  `json:"reason"`    // Why the candidate was rejected
+}
+
 // ClusterGroup represents a cluster of items with similar facet profiles
 type ClusterGroup struct {
-	Name            string         `json:"name"` // LLM-generated label
-	Items           []SearchResult `json:"items"`
-	Percentage      float64        `json:"percentage"`                // Approximate percentage of total results (~X%)
-	TopFacets       []FacetCount   `json:"topFacets"`                 // For transparency
-	Rule            [][]string     `json:"rule,omitempty"`            // Algolia filter format for "load more"
-	RuleDescription string         `json:"ruleDescription,omitempty"` // Human-readable rule
-	RuleQuality     *RuleQuality   `json:"ruleQuality,omitempty"`     // Rule quality metrics
+	ID              string           `json:"id,omitempty"`          // Identity kept across refinements
+	Color           int              `json:"color"`                 // Palette index kept with the identity
+	Stable          bool             `json:"stable,omitempty"`      // Identity inherited from the previous result
+	Pinned          bool             `json:"pinned,omitempty"`      // Name pinned by a curator
+	Name            string           `json:"name"`                  // LLM-generated label
+	Description     string           `json:"description,omitempty"` // One-sentence description (batch labeling only)
+	Items           []SearchResult   `json:"items"`
+	Percentage      float64          `json:"percentage"`                // Approximate percentage of total results (~X%), exact with totalCount
+	TotalCount      *int             `json:"totalCount,omitempty"`      // Exact number of records matching the rule (exactCounts only, nil if not counted)
+	TopFacets       []FacetCount     `json:"topFacets"`                 // For transparency
+	TopTerms        []string         `json:"topTerms,omitempty"`        // Topic terms (topic groupings only)
+	Rule            [][]string       `json:"rule,omitempty"`            // Algolia filter format for "load more"
+	RuleDescription string           `json:"ruleDescription,omitempty"` // Human-readable rule
+	RuleQuality     *RuleQuality     `json:"ruleQuality,omitempty"`     // Rule quality metrics
+	RuleDiagnostics *RuleDiagnostics `json:"ruleDiagnostics,omitempty"` // Rejected rule candidates
 }
 
 // ClusterResponse represents the clustering algorithm response
 type ClusterResponse struct {
-	Groups       []ClusterGroup `json:"groups"`
-	OtherGroup   []SearchResult `json:"otherGroup"`
-	ClusterCount int            `json:"clusterCount"` // Selected k value
-	TotalHits    int            `json:"totalHits"`    // Total matching records from Algolia
+	Groups        []ClusterGroup             `json:"groups"`
+	OtherGroup    []SearchResult             `json:"otherGroup"`
+	ClusterCount  int                        `json:"clusterCount"`            // Selected k value
+	RepeatedItems map[string]int             `json:"repeatedItems,omitempty"` // Item ID -> number of groups, for items in more than one group
+	Explanations  map[string]ItemExplanation `json:"explanations,omitempty"`  // Item ID -> membership explanation (when requested)
+	TotalHits     int                        `json:"totalHits"`               // Total matching records from Algolia
+	ResultToken   string                     `json:"resultToken,omitempty"`   // Pass as previousToken when refining this result
+	Curated       bool                       `json:"curated,omitempty"`       // Groups come from a curated grouping
+}
+
+// ClusterLabelEvent updates one group's name in a cluster stream
+type ClusterLabelEvent struct {
+	Index       int    `json:"index"` // Index into the groups of the clusters event
+	ID          string `json:"id,omitempty"`
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+}
+
+// ClusterStreamSummary is the last event of a cluster stream
+type ClusterStreamSummary struct {
+	ClusterCount int    `json:"clusterCount"`
+	Labeled      int    `json:"labeled"` // Groups renamed by a label event
+	Failed       int    `json:"failed"`  // Groups that kept their fallback name
+	DurationMs   int64  `json:"durationMs"`
+	ResultToken  string `json:"resultToken,omitempty"` // Pass as previousToken when refining this result
+}
+
+// SuggestResponse lists refinements of a query that have results
+type SuggestResponse struct {
+	Query       string            `json:"query"`
+	Suggestions []QuerySuggestion `json:"suggestions"` // In the model's order
+}
+
+// QuerySuggestion is a suggested query and its number of hits under the request's facet filters
+type QuerySuggestion struct {
+	Query  string `json:"query"`
+	NbHits int    `json:"nbHits"`
+}
+
+// ItemExplanation explains an item's cluster membership
+type ItemExplanation struct {
+	Memberships        []ClusterMembership `json:"memberships"`                  // Groups containing the item (empty for "Other" items)
+	Silhouette         float64             `json:"silhouette"`                   // -1..1: how much closer the item is to its group than to the nearest alternative
+	NearestAlternative *ClusterMembership  `json:"nearestAlternative,omitempty"` // Closest group not containing the item
+}
+
+// ClusterMembership relates an item to one cluster group
+type ClusterMembership struct {
+	Group            int      `json:"group"`            // Index into groups
+	Name             string   `json:"name"`             // Group name
+	SatisfiedClauses []string `json:"satisfiedClauses"` // Rule clauses the item satisfies
+	FailedClauses    []string `json:"failedClauses"`    // Rule clauses the item does not satisfy
+	Distance         float64  `json:"distance"`         // Average distance to the group's other items
+}
+
+// ClusterItemsResponse is one page of the items matching a cluster's rule
+type ClusterItemsResponse struct {
+	Items       []SearchResult `json:"items"`
+	NbHits      int            `json:"nbHits"`      // Total matching records, the true cluster size
+	Page        int            `json:"page"`        // Zero-based page
+	NbPages     int            `json:"nbPages"`     // Number of reachable pages
+	HitsPerPage int            `json:"hitsPerPage"` // Page size used
+}
+
+// ScatterGatherRequest drives a Scatter/Gather browsing session
+type ScatterGatherRequest struct {
+	Action       string     `json:"action"`                 // "scatter" (start a session), "gather", "back" or "forward"
+	SessionID    string     `json:"sessionId,omitempty"`    // Required except for "scatter"
+	Query        string     `json:"query,omitempty"`        // "scatter" only
+	FacetFilters [][]string `json:"facetFilters,omitempty"` // "scatter" only
+	ClusterIDs   []string   `json:"clusterIds,omitempty"`   // "gather": clusters of the current step ("other" for the Other group)
+}
+
+// ScatterGatherResponse is the clustering at the current step of a Scatter/Gather session
+type ScatterGatherResponse struct {
+	ClusterResponse
+	SessionID  string   `json:"sessionId"`
+	Step       int      `json:"step"`               // Zero-based position in the session history
+	Steps      int      `json:"steps"`              // Length of the session history
+	Gathered   []string `json:"gathered,omitempty"` // Cluster IDs of the previous step gathered into this one
+	CanBack    bool     `json:"canBack"`
+	CanForward bool     `json:"canForward"`
+}
+
+// CurateRequest edits the curated grouping of a query (started from the automatic
+// clustering if there is none yet)
+type CurateRequest struct {
+	SearchRequest
+	Action     string   `json:"action"`               // "merge", "split" or "rename"
+	ClusterIDs []string `json:"clusterIds,omitempty"` // "merge": the two clusters to merge
+	ClusterID  string   `json:"clusterId,omitempty"`  // "split" and "rename": the cluster to edit
+	Parts      int      `json:"parts,omitempty"`      // "split": number of parts (default 2, at most 5)
+	Name       string   `json:"name,omitempty"`       // "rename": the name to pin
+}
+
+// CurationRequest creates or replaces a curated grouping through the admin API
+type CurationRequest struct {
+	View         string             `json:"view"` // "cluster" or "ripper"
+	Query        string             `json:"query"`
+	FacetFilters [][]string         `json:"facetFilters,omitempty"`
+	Clusters     []curation.Cluster `json:"clusters"` // In display order; "ripper" rules must be single facet values
+	// ExpectedVersion is the version being replaced: an update fails with 409 if the
+	// grouping has changed since. -1 requires that it does not exist yet; 0 skips the check.
+	ExpectedVersion int `json:"expectedVersion,omitempty"`
+}
+
+// CurationResponse is one version of a curated grouping
+type CurationResponse struct {
+	curation.Grouping
+	Versions []int `json:"versions"` // Stored versions, oldest first
+}
+
+// CurationListResponse lists the current version of every curated grouping
+type CurationListResponse struct {
+	Groupings []curation.Grouping `json:"groupings"`
+}
+
+// LabelCacheResponse shows the label cache's counters and entries
+type LabelCacheResponse struct {
+	Stats   labeler.CacheStats   `json:"stats"`
+	Entries []labeler.CacheEntry `json:"entries"` // Most recently used first
+}
+
+// LabelCachePurgeResponse reports how many label cache entries were removed
+type LabelCachePurgeResponse struct {
+	Purged int `json:"purged"`
 }
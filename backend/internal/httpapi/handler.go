@@ -1,21 +1,38 @@
 package httpapi
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"net/http"
+	"strings"
+	"sync"
 
 	"ize/internal/algolia"
-	"ize/internal/anthropic"
 	"ize/internal/config"
+	"ize/internal/curation"
+	"ize/internal/embedding"
 	"ize/internal/ize"
+	"ize/internal/labeler"
 	"ize/internal/logger"
 )
 
 type SearchHandler struct {
-	algoliaClient   algolia.ClientInterface
-	anthropicClient anthropic.ClientInterface
-	logger          *logger.Logger
-	facetMeta       []FacetMeta // Pre-computed facet metadata for responses
+	algoliaClient  algolia.ClientInterface
+	labeler        labeler.Labeler
+	labelCache     labeler.Cache         // Cache of the LLM labeler (nil for the local labeler)
+	usage          *labeler.UsageTracker // API usage of the LLM labeler (nil for the local labeler)
+	embedder       embedding.Embedder
+	logger         *logger.Logger
+	facetMeta      []FacetMeta // Pre-computed facet metadata for responses
+	clusterOptions ize.ClusterOptions
+
+	countConcurrency  int                                // Parallel count queries for exact cluster sizes
+	identityThreshold float64                            // Similarity at which clusters keep their identity
+	lineages          *tokenStore[*ize.ClusterLineage]   // Cluster identities of recent responses (nil disables tokens)
+	sessions          *tokenStore[*scatterGatherSession] // Scatter/Gather browsing sessions
+	curations         *curation.Store                    // Curated groupings (nil disables curation)
+	adminToken        string                             // Bearer token for the admin API (empty = admin API disabled)
 }
 
 func NewSearchHandler(cfg *config.Config, log *logger.Logger) (*SearchHandler, error) {
@@ -32,15 +49,29 @@ func NewSearchHandler(cfg *config.Config, log *logger.Logger) (*SearchHandler, e
 		return nil, err
 	}
 
-	// Anthropic client is optional - cluster naming will use fallback if not configured
-	var anthropicClient anthropic.ClientInterface
-	if cfg.AnthropicAPIKey != "" {
-		anthropicClient, err = anthropic.NewClient(cfg.AnthropicAPIKey, log)
+	// Cluster names come from the configured LLM provider, or the local labeler without one
+	labeling := cfg.GetLabeling()
+	clusterLabeler, err := labeler.New(&labeling, log)
+	if err != nil {
+		log.Warn("failed to create labeler, cluster naming will use local labels", "provider", labeling.Provider, "error", err)
+		clusterLabeler = labeler.NewLocal(log)
+	}
+	var labelCache labeler.Cache
+	if cached, ok := clusterLabeler.(labeler.Cached); ok {
+		labelCache = cached.Cache()
+	}
+	var usage *labeler.UsageTracker
+	if metered, ok := clusterLabeler.(labeler.Metered); ok {
+		usage = metered.Usage()
+	}
+
+	// Embedder is optional - clustering uses facets (and text) only if not configured
+	var embedder embedding.Embedder
+	if cfg.Embedding != nil {
+		embedder, err = embedding.NewEmbedder(cfg.Embedding, log)
 		if err != nil {
-			log.Warn("failed to create anthropic client, cluster naming will use fallback", "error", err)
+			log.Warn("failed to create embedder, clustering will not use embeddings", "error", err)
 		}
-	} else {
-		log.Info("anthropic API key not configured, cluster naming will use fallback labels")
 	}
 
 	// Build facet metadata from config
@@ -53,14 +84,77 @@ func NewSearchHandler(cfg *config.Config, log *logger.Logger) (*SearchHandler, e
 		})
 	}
 
+	var countConcurrency int
+	identityThreshold := ize.DefaultIdentityThreshold
+	if cfg.Clustering != nil {
+		countConcurrency = cfg.Clustering.CountConcurrency
+		if cfg.Clustering.IdentityThreshold > 0 {
+			identityThreshold = cfg.Clustering.IdentityThreshold
+		}
+	}
+
+	var curationPath, adminToken string
+	var curationHistory int
+	if cfg.Curation != nil {
+		curationPath = cfg.Curation.Path
+		curationHistory = cfg.Curation.History
+		adminToken = cfg.Curation.AdminToken
+	}
+	curations, err := curation.Open(curationPath, curationHistory, log)
+	if err != nil {
+		return nil, err
+	}
+
 	return &SearchHandler{
-		algoliaClient:   algoliaClient,
-		anthropicClient: anthropicClient,
-		logger:          log,
-		facetMeta:       facetMeta,
+		algoliaClient:     algoliaClient,
+		labeler:           clusterLabeler,
+		labelCache:        labelCache,
+		usage:             usage,
+		embedder:          embedder,
+		logger:            log,
+		facetMeta:         facetMeta,
+		clusterOptions:    ize.ClusterOptionsFromConfig(cfg),
+		countConcurrency:  countConcurrency,
+		identityThreshold: identityThreshold,
+		lineages:          newTokenStore[*ize.ClusterLineage](defaultLineageCapacity),
+		sessions:          newTokenStore[*scatterGatherSession](defaultSessionCapacity),
+		curations:         curations,
+		adminToken:        adminToken,
 	}, nil
 }
 
+// Close writes the label cache's pending changes. Call it once the server has stopped
+// serving requests.
+func (h *SearchHandler) Close() {
+	if flusher, ok := h.labelCache.(labeler.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// LogLabelingUsage wraps next so each request that called the labeling API logs the
+// calls, tokens and estimated cost it used
+func (h *SearchHandler) LogLabelingUsage(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if h.usage == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		ctx, usage := labeler.WithRequestUsage(r.Context())
+		next.ServeHTTP(w, r.WithContext(ctx))
+
+		if totals := usage.Totals(); totals.Requests > 0 {
+			h.logger.WithContext(ctx).Info("labeling usage",
+				"path", r.URL.Path,
+				"api_requests", totals.Requests,
+				"input_tokens", totals.InputTokens,
+				"output_tokens", totals.OutputTokens,
+				"cost_usd", totals.CostUSD,
+			)
+		}
+	})
+}
+
 func (h *SearchHandler) HandleSearch(w http.ResponseWriter, r *http.Request) {
 	log := h.logger.WithContext(r.Context())
 
@@ -167,8 +261,16 @@ func (h *SearchHandler) HandleRipper(w http.ResponseWriter, r *http.Request) {
 		"hits_count", len(algoliaResults.Hits),
 	)
 
-	// Process through RIPPER algorithm
-	ripperResult, err := ize.ProcessRipper(req.Query, algoliaResults, log)
+	// A curated grouping for this query and filters replaces the algorithm
+	grouping, _ := h.curatedGrouping(log, curation.ViewRipper, req.Query, req.FacetFilters)
+
+	var ripperResult *ize.RipperResult
+	if grouping != nil {
+		log.Debug("using curated grouping", "query", req.Query, "groups", len(grouping.Clusters))
+		ripperResult, err = ize.ApplyCuratedRipper(req.Query, algoliaResults, grouping, log)
+	} else {
+		ripperResult, err = ize.ProcessRipper(req.Query, algoliaResults, log)
+	}
 	if err != nil {
 		log.ErrorWithErr("RIPPER processing failed", err, "query", req.Query)
 		http.Error(w, "RIPPER processing failed", http.StatusInternalServerError)
@@ -196,6 +298,7 @@ func (h *SearchHandler) HandleRipper(w http.ResponseWriter, r *http.Request) {
 		groups[i] = RipperGroup{
 			FacetName:  group.FacetName,
 			FacetValue: group.FacetValue,
+			Name:       group.Name,
 			Items:      items,
 			Count:      group.TotalCount, // Accurate count from Algolia facets
 		}
@@ -216,6 +319,7 @@ func (h *SearchHandler) HandleRipper(w http.ResponseWriter, r *http.Request) {
 		Groups:     groups,
 		OtherGroup: otherGroup,
 		FacetMeta:  h.facetMeta,
+		Curated:    grouping != nil,
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -235,22 +339,18 @@ func (h *SearchHandler) HandleRipper(w http.ResponseWriter, r *http.Request) {
 func (h *SearchHandler) HandleCluster(w http.ResponseWriter, r *http.Request) {
 	log := h.logger.WithContext(r.Context())
 
-	if r.Method != http.MethodPost {
-		log.Warn("method not allowed", "method", r.Method)
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-		return
-	}
-
-	var req SearchRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		log.ErrorWithErr("failed to decode request body", err)
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+	req, opts, ok := h.decodeClusterRequest(w, r)
+	if !ok {
 		return
 	}
 
 	log.Debug("processing Cluster request",
 		"query", req.Query,
 		"facet_filters", req.FacetFilters,
+		"assignment", req.Assignment,
+		"explain", req.Explain,
+		"exact_counts", req.ExactCounts,
+		"previous_token", req.PreviousToken,
 	)
 
 	// Search Algolia with 100 hits per page (same as RIPPER)
@@ -266,138 +366,441 @@ func (h *SearchHandler) HandleCluster(w http.ResponseWriter, r *http.Request) {
 		"hits_count", len(algoliaResults.Hits),
 	)
 
-	// Process through clustering algorithm
-	clusterResult, err := ize.ProcessCluster(req.Query, algoliaResults, log)
+	// Keep IDs, colors and names of clusters that match the previous result
+	var previous *ize.ClusterLineage
+	if req.PreviousToken != "" && h.lineages != nil {
+		var ok bool
+		if previous, ok = h.lineages.get(req.PreviousToken); !ok {
+			log.Debug("previous result token not found, assigning new cluster identities", "previous_token", req.PreviousToken)
+		}
+	}
+
+	// A curated grouping for this query and filters replaces the automatic clustering
+	grouping, _ := h.curatedGrouping(log, curation.ViewCluster, req.Query, req.FacetFilters)
+
+	var clusterResult *ize.ClusterResult
+	var nextID int
+	if grouping != nil {
+		log.Debug("using curated grouping", "query", req.Query, "clusters", len(grouping.Clusters))
+		clusterResult, err = ize.ApplyCuratedGrouping(req.Query, algoliaResults, grouping, opts, log)
+		nextID = grouping.NextID
+	} else {
+		clusterResult, nextID, err = h.clusterHits(r.Context(), req.Query, algoliaResults, opts, previous)
+	}
 	if err != nil {
 		log.ErrorWithErr("Cluster processing failed", err, "query", req.Query)
 		http.Error(w, "Cluster processing failed", http.StatusInternalServerError)
 		return
 	}
 
-	log.Debug("Cluster processing completed",
+	response := toClusterResponse(clusterResult, algoliaResults)
+	response.Curated = grouping != nil
+	if h.lineages != nil {
+		token, err := h.lineages.put(ize.NewClusterLineage(clusterResult.Groups, nextID))
+		if err != nil {
+			log.Warn("failed to store cluster identities, response has no result token", "error", err)
+		} else {
+			response.ResultToken = token
+		}
+	}
+	if req.ExactCounts {
+		h.countClusterGroups(r.Context(), req.Query, req.FacetFilters, response.Groups, response.TotalHits)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		log.ErrorWithErr("failed to encode Cluster response", err, "query", req.Query)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	log.Info("Cluster request completed successfully",
 		"query", req.Query,
-		"cluster_count", clusterResult.ClusterCount,
-		"other_group_count", len(clusterResult.OtherGroup),
+		"cluster_count", len(response.Groups),
+		"stable_clusters", countStable(clusterResult.Groups),
+		"other_group_count", len(response.OtherGroup),
 	)
+}
 
-	// Generate LLM-based cluster names if Anthropic client is available
-	if h.anthropicClient != nil && len(clusterResult.Groups) > 0 {
-		statsSlice := make([]anthropic.ClusterStats, len(clusterResult.Groups))
-		for i, group := range clusterResult.Groups {
-			facetInfos := make([]anthropic.FacetInfo, len(group.TopFacets))
-			for j, f := range group.TopFacets {
-				facetInfos[j] = anthropic.FacetInfo{
-					Name:       f.FacetName,
-					Value:      f.FacetValue,
-					Percentage: f.Percentage,
-				}
-			}
-			statsSlice[i] = anthropic.ClusterStats{
-				Size:      group.Stats.Size,
-				TopFacets: facetInfos,
-			}
-		}
+// decodeClusterRequest decodes a POSTed ClusterRequest and the clustering options it
+// selects, writing an error response and returning false if it is invalid
+func (h *SearchHandler) decodeClusterRequest(w http.ResponseWriter, r *http.Request) (ClusterRequest, ize.ClusterOptions, bool) {
+	log := h.logger.WithContext(r.Context())
+
+	var req ClusterRequest
+	opts := h.clusterOptions
+	if r.Method != http.MethodPost {
+		log.Warn("method not allowed", "method", r.Method)
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return req, opts, false
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		log.ErrorWithErr("failed to decode request body", err)
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return req, opts, false
+	}
 
-		names, err := h.anthropicClient.GenerateClusterNames(r.Context(), statsSlice)
+	if req.Assignment != "" {
+		assignment, err := ize.ParseAssignmentMode(req.Assignment)
 		if err != nil {
-			log.Warn("failed to generate cluster names, using fallbacks", "error", err)
-		} else {
-			for i, name := range names {
-				if i < len(clusterResult.Groups) {
-					clusterResult.Groups[i].Name = name
-				}
-			}
+			log.Warn("invalid assignment mode", "assignment", req.Assignment)
+			http.Error(w, "Invalid assignment mode", http.StatusBadRequest)
+			return req, opts, false
 		}
+		opts.Assignment = assignment
+	}
+	opts.Explain = req.Explain
+	return req, opts, true
+}
+
+// clusterHits clusters hits, assigns cluster identities (keeping those matching previous,
+// which may be nil) and names the new clusters. Returns the ID counter for the next lineage.
+func (h *SearchHandler) clusterHits(ctx context.Context, query string, algoliaResults *algolia.SearchResult, opts ize.ClusterOptions, previous *ize.ClusterLineage) (*ize.ClusterResult, int, error) {
+	clusterResult, nextID, unnamed, err := h.stabilizedClusters(ctx, query, algoliaResults, opts, previous)
+	if err != nil {
+		return nil, 0, err
+	}
+	h.nameClusters(ctx, query, clusterResult.Groups, unnamed)
+	return clusterResult, nextID, nil
+}
+
+// stabilizedClusters clusters hits and assigns cluster identities, keeping those matching
+// previous (which may be nil). Returns the ID counter for the next lineage and the
+// indices of the groups that still need names.
+func (h *SearchHandler) stabilizedClusters(ctx context.Context, query string, algoliaResults *algolia.SearchResult, opts ize.ClusterOptions, previous *ize.ClusterLineage) (*ize.ClusterResult, int, []int, error) {
+	log := h.logger.WithContext(ctx)
+
+	if h.embedder != nil && opts.EmbeddingWeight > 0 {
+		opts.Embeddings = h.embedHits(ctx, algoliaResults.Hits)
 	}
 
-	// Convert ize.ClusterGroup to httpapi.ClusterGroup
-	// Calculate approximate percentages based on sample
-	totalHits := algoliaResults.TotalHits
-	sampleSize := len(algoliaResults.Hits)
+	clusterResult, err := ize.ProcessClusterWithOptions(query, algoliaResults, opts, log)
+	if err != nil {
+		return nil, 0, nil, err
+	}
 
-	groups := make([]ClusterGroup, len(clusterResult.Groups))
+	log.Debug("Cluster processing completed",
+		"query", query,
+		"cluster_count", clusterResult.ClusterCount,
+		"other_group_count", len(clusterResult.OtherGroup),
+	)
+
+	nextID := ize.StabilizeClusters(clusterResult, previous, h.identityThreshold)
+
+	var unnamed []int
 	for i, group := range clusterResult.Groups {
-		items := make([]SearchResult, len(group.Items))
-		for j, item := range group.Items {
-			items[j] = SearchResult{
-				ID:          item.ID,
-				Name:        item.Name,
-				Description: item.Description,
-				Image:       item.Image,
+		if !group.Stable {
+			unnamed = append(unnamed, i)
+		}
+	}
+	return clusterResult, nextID, unnamed, nil
+}
+
+// nameClusters generates names for the groups at the unnamed indices with the configured
+// labeler, if any. Over the daily labeling budget the local labeler names the groups
+// without a cached label instead. Groups keep their fallback names on failure.
+func (h *SearchHandler) nameClusters(ctx context.Context, query string, groups []ize.ClusterGroup, unnamed []int) {
+	log := h.logger.WithContext(ctx)
+
+	if h.labeler == nil || len(unnamed) == 0 {
+		return
+	}
+
+	statsSlice := h.labelerStats(query, groups, unnamed)
+	labels, err := h.labeler.GenerateClusterLabels(ctx, statsSlice)
+	if errors.Is(err, labeler.ErrBudgetExceeded) {
+		log.Warn("daily labeling budget exceeded, using local labels")
+		// The local labeler contrasts each group with all the others
+		var local []labeler.Label
+		local, err = labeler.NewLocal(h.logger).GenerateClusterLabels(ctx, statsSlice)
+		for i := range local {
+			if i >= len(labels) {
+				labels = append(labels, local[i])
+			} else if labels[i].Name == "" {
+				labels[i] = local[i]
 			}
 		}
+	}
+	if err != nil {
+		log.Warn("failed to generate cluster names, using fallbacks", "error", err)
+		return
+	}
+	for i, label := range labels {
+		if i < len(unnamed) && label.Name != "" {
+			groups[unnamed[i]].Name = label.Name
+			groups[unnamed[i]].Description = label.Description
+		}
+	}
+}
+
+// labelerStats describes the groups at the unnamed indices for the labeler
+func (h *SearchHandler) labelerStats(query string, groups []ize.ClusterGroup, unnamed []int) []labeler.ClusterStats {
+	displayNames := make(map[string]string, len(h.facetMeta))
+	for _, meta := range h.facetMeta {
+		displayNames[meta.Field] = meta.DisplayName
+	}
 
-		topFacets := make([]FacetCount, len(group.TopFacets))
+	statsSlice := make([]labeler.ClusterStats, len(unnamed))
+	for i, groupIndex := range unnamed {
+		group := groups[groupIndex]
+		facetInfos := make([]labeler.FacetInfo, len(group.TopFacets))
 		for j, f := range group.TopFacets {
-			topFacets[j] = FacetCount{
-				FacetName:  f.FacetName,
-				FacetValue: f.FacetValue,
-				Count:      f.Count,
-				Percentage: f.Percentage,
+			facetInfos[j] = labeler.FacetInfo{
+				Name:        f.FacetName,
+				DisplayName: displayNames[f.FacetName],
+				Value:       f.FacetValue,
+				Percentage:  f.Percentage,
 			}
 		}
-
-		// Convert rule and quality if present
-		var rule [][]string
-		var ruleDescription string
-		var ruleQuality *RuleQuality
-		if group.Rule != nil {
-			rule = group.Rule.ToAlgoliaFilter()
-			ruleDescription = group.Rule.String()
+		items := make([]labeler.ItemInfo, len(group.Items))
+		for j, item := range group.Items {
+			items[j] = labeler.ItemInfo{Name: item.Name, Description: item.Description}
 		}
-		if group.RuleQuality != nil {
-			ruleQuality = &RuleQuality{
-				Precision: group.RuleQuality.Precision,
-				Recall:    group.RuleQuality.Recall,
-				F1:        group.RuleQuality.F1,
-			}
+		statsSlice[i] = labeler.ClusterStats{
+			Size:      group.Stats.Size,
+			TopFacets: facetInfos,
+			Items:     items,
+			Query:     query,
 		}
+	}
+	return statsSlice
+}
 
-		// Calculate approximate percentage from sample
-		var percentage float64
-		if sampleSize > 0 {
-			percentage = float64(len(group.Items)) / float64(sampleSize) * 100
+// countStable returns how many groups kept their identity from the previous result
+func countStable(groups []ize.ClusterGroup) int {
+	count := 0
+	for _, group := range groups {
+		if group.Stable {
+			count++
 		}
+	}
+	return count
+}
 
-		groups[i] = ClusterGroup{
-			Name:            group.Name,
-			Items:           items,
-			Percentage:      percentage,
-			TopFacets:       topFacets,
-			Rule:            rule,
-			RuleDescription: ruleDescription,
-			RuleQuality:     ruleQuality,
-		}
+// Page sizes for HandleClusterItems; Algolia rejects hitsPerPage above 1000
+const (
+	defaultClusterItemsPerPage = 20
+	maxClusterItemsPerPage     = 1000
+)
+
+// HandleClusterItems pages through the full result set of a cluster by running the query
+// with the cluster's rule ANDed with the current facet filters
+func (h *SearchHandler) HandleClusterItems(w http.ResponseWriter, r *http.Request) {
+	log := h.logger.WithContext(r.Context())
+
+	if r.Method != http.MethodPost {
+		log.Warn("method not allowed", "method", r.Method)
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
 	}
 
-	// Convert ize.Result to httpapi.SearchResult for Other group
-	otherGroup := make([]SearchResult, len(clusterResult.OtherGroup))
-	for i, item := range clusterResult.OtherGroup {
-		otherGroup[i] = SearchResult{
-			ID:          item.ID,
-			Name:        item.Name,
-			Description: item.Description,
-			Image:       item.Image,
-		}
+	var req ClusterItemsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		log.ErrorWithErr("failed to decode request body", err)
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if len(req.Rule) == 0 {
+		log.Warn("cluster items request without rule", "query", req.Query)
+		http.Error(w, "Rule is required", http.StatusBadRequest)
+		return
+	}
+	if req.HitsPerPage == 0 {
+		req.HitsPerPage = defaultClusterItemsPerPage
+	}
+	if req.Page < 0 || req.HitsPerPage < 0 || req.HitsPerPage > maxClusterItemsPerPage {
+		log.Warn("invalid pagination", "page", req.Page, "hits_per_page", req.HitsPerPage)
+		http.Error(w, "Invalid pagination", http.StatusBadRequest)
+		return
 	}
 
-	response := ClusterResponse{
-		Groups:       groups,
-		OtherGroup:   otherGroup,
-		ClusterCount: clusterResult.ClusterCount,
-		TotalHits:    totalHits,
+	facetFilters := withRule(req.FacetFilters, req.Rule)
+
+	log.Debug("processing ClusterItems request",
+		"query", req.Query,
+		"facet_filters", facetFilters,
+		"page", req.Page,
+		"hits_per_page", req.HitsPerPage,
+	)
+
+	algoliaResults, err := h.algoliaClient.SearchPage(r.Context(), req.Query, facetFilters, req.Page, req.HitsPerPage)
+	if err != nil {
+		log.ErrorWithErr("algolia search failed for ClusterItems", err, "query", req.Query)
+		http.Error(w, "Search failed", http.StatusInternalServerError)
+		return
+	}
+
+	response := ClusterItemsResponse{
+		Items:       toSearchResults(ize.Process(req.Query, algoliaResults)),
+		NbHits:      algoliaResults.TotalHits,
+		Page:        algoliaResults.Page,
+		NbPages:     algoliaResults.NbPages,
+		HitsPerPage: req.HitsPerPage,
 	}
 
 	w.Header().Set("Content-Type", "application/json")
 	if err := json.NewEncoder(w).Encode(response); err != nil {
-		log.ErrorWithErr("failed to encode Cluster response", err, "query", req.Query)
+		log.ErrorWithErr("failed to encode ClusterItems response", err, "query", req.Query)
 		http.Error(w, "Internal server error", http.StatusInternalServerError)
 		return
 	}
 
-	log.Info("Cluster request completed successfully",
+	log.Info("ClusterItems request completed successfully",
 		"query", req.Query,
-		"cluster_count", len(groups),
-		"other_group_count", len(otherGroup),
+		"page", response.Page,
+		"items_count", len(response.Items),
+		"nb_hits", response.NbHits,
+	)
+}
+
+func (h *SearchHandler) HandleTopics(w http.ResponseWriter, r *http.Request) {
+	log := h.logger.WithContext(r.Context())
+
+	if r.Method != http.MethodPost {
+		log.Warn("method not allowed", "method", r.Method)
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req SearchRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		log.ErrorWithErr("failed to decode request body", err)
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	log.Debug("processing Topics request",
+		"query", req.Query,
+		"facet_filters", req.FacetFilters,
+	)
+
+	// Search Algolia with 100 hits per page (same as RIPPER)
+	algoliaResults, err := h.algoliaClient.SearchRipper(r.Context(), req.Query, req.FacetFilters)
+	if err != nil {
+		log.ErrorWithErr("algolia search failed for Topics", err, "query", req.Query)
+		http.Error(w, "Search failed", http.StatusInternalServerError)
+		return
+	}
+
+	// Process through topic modeling
+	topicResult, err := ize.ProcessTopics(req.Query, algoliaResults, log)
+	if err != nil {
+		log.ErrorWithErr("Topics processing failed", err, "query", req.Query)
+		http.Error(w, "Topics processing failed", http.StatusInternalServerError)
+		return
+	}
+
+	response := toClusterResponse(topicResult, algoliaResults)
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		log.ErrorWithErr("failed to encode Topics response", err, "query", req.Query)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	log.Info("Topics request completed successfully",
+		"query", req.Query,
+		"topic_count", len(response.Groups),
+		"other_group_count", len(response.OtherGroup),
+	)
+}
+
+// embedHits computes embeddings for hits keyed by objectID
+// Returns nil on failure so clustering falls back to facet (and text) similarity
+func (h *SearchHandler) embedHits(ctx context.Context, hits []algolia.Hit) map[string][]float64 {
+	log := h.logger.WithContext(ctx)
+
+	items := make([]embedding.Item, len(hits))
+	for i, hit := range hits {
+		items[i] = embedding.Item{
+			ID:     hit.ObjectID,
+			Text:   strings.TrimSpace(hit.Name + " " + hit.Description),
+			Vector: hit.Vector,
+		}
+	}
+
+	vectors, err := h.embedder.Embed(ctx, items)
+	if err != nil {
+		log.Warn("failed to compute embeddings, clustering without them", "error", err)
+		return nil
+	}
+
+	embeddings := make(map[string][]float64, len(vectors))
+	for i, vec := range vectors {
+		if vec != nil {
+			embeddings[items[i].ID] = vec
+		}
+	}
+
+	log.Debug("computed hit embeddings",
+		"hits", len(hits),
+		"embedded", len(embeddings),
+	)
+
+	return embeddings
+}
+
+// withRule ANDs a cluster rule with facet filters. Outer slices are ANDed, so appending
+// the rule's groups intersects it with the filters.
+func withRule(facetFilters, rule [][]string) [][]string {
+	combined := make([][]string, 0, len(facetFilters)+len(rule))
+	combined = append(combined, facetFilters...)
+	return append(combined, rule...)
+}
+
+// defaultCountConcurrency bounds parallel count queries when not configured
+const defaultCountConcurrency = 4
+
+// countClusterGroups sets TotalCount on each group with a rule by running a hitsPerPage=0
+// query for the rule ANDed with facetFilters, and makes Percentage exact.
+// Groups whose count query fails keep their sample-based percentage.
+func (h *SearchHandler) countClusterGroups(ctx context.Context, query string, facetFilters [][]string, groups []ClusterGroup, totalHits int) {
+	log := h.logger.WithContext(ctx)
+
+	concurrency := h.countConcurrency
+	if concurrency <= 0 {
+		concurrency = defaultCountConcurrency
+	}
+
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for i := range groups {
+		if len(groups[i].Rule) == 0 {
+			continue
+		}
+
+		wg.Add(1)
+		go func(group *ClusterGroup) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			result, err := h.algoliaClient.SearchPage(ctx, query, withRule(facetFilters, group.Rule), 0, 0)
+			if err != nil {
+				log.Warn("cluster count query failed, keeping sample percentage",
+					"query", query,
+					"cluster", group.Name,
+					"error", err,
+				)
+				return
+			}
+
+			// Each goroutine writes only its own group
+			count := result.TotalHits
+			group.TotalCount = &count
+			if totalHits > 0 {
+				group.Percentage = float64(result.TotalHits) / float64(totalHits) * 100
+			}
+		}(&groups[i])
+	}
+	wg.Wait()
+
+	log.Debug("cluster count queries completed",
+		"query", query,
+		"groups", len(groups),
+		"concurrency", concurrency,
 	)
 }
@@ -4,11 +4,17 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"net/http/httptest"
+	"reflect"
+	"sync"
 	"testing"
+	"time"
 
 	"ize/internal/algolia"
+	"ize/internal/ize"
+	"ize/internal/labeler"
 	"ize/internal/logger"
 )
 
@@ -16,6 +22,7 @@ import (
 type mockAlgoliaClient struct {
 	searchFunc      func(ctx context.Context, query string, facetFilters [][]string) (*algolia.SearchResult, error)
 	searchRipperFunc func(ctx context.Context, query string, facetFilters [][]string) (*algolia.SearchResult, error)
+	searchPageFunc   func(ctx context.Context, query string, facetFilters [][]string, page, hitsPerPage int) (*algolia.SearchResult, error)
 }
 
 func (m *mockAlgoliaClient) Search(ctx context.Context, query string, facetFilters [][]string) (*algolia.SearchResult, error) {
@@ -32,6 +39,13 @@ func (m *mockAlgoliaClient) SearchRipper(ctx context.Context, query string, face
 	return &algolia.SearchResult{Hits: []algolia.Hit{}}, nil
 }
 
+func (m *mockAlgoliaClient) SearchPage(ctx context.Context, query string, facetFilters [][]string, page, hitsPerPage int) (*algolia.SearchResult, error) {
+	if m.searchPageFunc != nil {
+		return m.searchPageFunc(ctx, query, facetFilters, page, hitsPerPage)
+	}
+	return &algolia.SearchResult{Hits: []algolia.Hit{}}, nil
+}
+
 func TestSearchHandler_HandleSearch(t *testing.T) {
 	tests := []struct {
 		name           string
@@ -139,3 +153,475 @@ func TestSearchHandler_HandleSearch_InvalidJSON(t *testing.T) {
 		t.Errorf("HandleSearch() status = %d, want %d", w.Code, http.StatusBadRequest)
 	}
 }
+
+func TestSearchHandler_HandleCluster_Assignment(t *testing.T) {
+	hits := []algolia.Hit{
+		{ObjectID: "1", Name: "Phone 1", Facets: map[string]interface{}{"category": "Phones", "brand": "Samsung"}},
+		{ObjectID: "2", Name: "Phone 2", Facets: map[string]interface{}{"category": "Phones", "brand": "Samsung"}},
+		{ObjectID: "3", Name: "Phone 3", Facets: map[string]interface{}{"category": "Phones", "brand": "Apple"}},
+		{ObjectID: "4", Name: "Shirt 1", Facets: map[string]interface{}{"category": "Shirts", "brand": "Nike"}},
+		{ObjectID: "5", Name: "Shirt 2", Facets: map[string]interface{}{"category": "Shirts", "brand": "Nike"}},
+		{ObjectID: "6", Name: "Shirt 3", Facets: map[string]interface{}{"category": "Shirts", "brand": "Apple"}},
+	}
+
+	tests := []struct {
+		name       string
+		assignment string
+		wantStatus int
+	}{
+		{name: "default", assignment: "", wantStatus: http.StatusOK},
+		{name: "best match", assignment: "best_match", wantStatus: http.StatusOK},
+		{name: "first match", assignment: "first_match", wantStatus: http.StatusOK},
+		{name: "invalid", assignment: "random", wantStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := &SearchHandler{
+				algoliaClient: &mockAlgoliaClient{
+					searchRipperFunc: func(ctx context.Context, query string, facetFilters [][]string) (*algolia.SearchResult, error) {
+						return &algolia.SearchResult{Hits: hits, TotalHits: len(hits)}, nil
+					},
+				},
+				logger: logger.Default(),
+			}
+
+			body, _ := json.Marshal(ClusterRequest{SearchRequest: SearchRequest{Query: "test"}, Assignment: tt.assignment})
+			req := httptest.NewRequest(http.MethodPost, "/api/cluster", bytes.NewBuffer(body))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+
+			handler.HandleCluster(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("HandleCluster() status = %d, want %d", w.Code, tt.wantStatus)
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+
+			var response ClusterResponse
+			if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+				t.Fatalf("Failed to decode response: %v", err)
+			}
+
+			// Exclusive modes never repeat items across groups
+			if tt.assignment != "" && len(response.RepeatedItems) != 0 {
+				t.Errorf("HandleCluster() repeatedItems = %v, want none for %s", response.RepeatedItems, tt.assignment)
+			}
+			seen := make(map[string]int)
+			for _, group := range response.Groups {
+				for _, item := range group.Items {
+					seen[item.ID]++
+				}
+			}
+			for id, count := range seen {
+				if count > 1 && response.RepeatedItems[id] != count {
+					t.Errorf("HandleCluster() item %s in %d groups, repeatedItems = %d", id, count, response.RepeatedItems[id])
+				}
+			}
+		})
+	}
+}
+
+func TestSearchHandler_HandleCluster_Explain(t *testing.T) {
+	hits := []algolia.Hit{
+		{ObjectID: "1", Name: "Phone 1", Facets: map[string]interface{}{"category": "Phones", "brand": "Samsung"}},
+		{ObjectID: "2", Name: "Phone 2", Facets: map[string]interface{}{"category": "Phones", "brand": "Samsung"}},
+		{ObjectID: "3", Name: "Phone 3", Facets: map[string]interface{}{"category": "Phones", "brand": "Apple"}},
+		{ObjectID: "4", Name: "Shirt 1", Facets: map[string]interface{}{"category": "Shirts", "brand": "Nike"}},
+		{ObjectID: "5", Name: "Shirt 2", Facets: map[string]interface{}{"category": "Shirts", "brand": "Nike"}},
+		{ObjectID: "6", Name: "Shirt 3", Facets: map[string]interface{}{"category": "Shirts", "brand": "Apple"}},
+	}
+
+	handler := &SearchHandler{
+		algoliaClient: &mockAlgoliaClient{
+			searchRipperFunc: func(ctx context.Context, query string, facetFilters [][]string) (*algolia.SearchResult, error) {
+				return &algolia.SearchResult{Hits: hits, TotalHits: len(hits)}, nil
+			},
+		},
+		logger: logger.Default(),
+	}
+
+	body, _ := json.Marshal(ClusterRequest{SearchRequest: SearchRequest{Query: "test"}, Explain: true})
+	req := httptest.NewRequest(http.MethodPost, "/api/cluster", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+
+	handler.HandleCluster(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("HandleCluster() status = %d, want %d", w.Code, http.StatusOK)
+	}
+
+	var response ClusterResponse
+	if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+
+	if len(response.Explanations) != len(hits) {
+		t.Fatalf("HandleCluster() explanations = %d, want %d", len(response.Explanations), len(hits))
+	}
+	for id, explanation := range response.Explanations {
+		for _, m := range explanation.Memberships {
+			if m.Group >= len(response.Groups) || m.Name != response.Groups[m.Group].Name {
+				t.Errorf("item %s membership %+v does not match a response group", id, m)
+			}
+		}
+	}
+}
+
+func TestSearchHandler_HandleClusterItems(t *testing.T) {
+	tests := []struct {
+		name        string
+		requestBody ClusterItemsRequest
+		wantStatus  int
+		wantFilters [][]string
+		wantPerPage int
+	}{
+		{
+			name: "rule ANDed with facet filters",
+			requestBody: ClusterItemsRequest{
+				SearchRequest: SearchRequest{Query: "phone", FacetFilters: [][]string{{"category:Phones"}}},
+				Rule:          [][]string{{"brand:Apple", "brand:Samsung"}, {"color:-Red"}},
+				Page:          2,
+			},
+			wantStatus:  http.StatusOK,
+			wantFilters: [][]string{{"category:Phones"}, {"brand:Apple", "brand:Samsung"}, {"color:-Red"}},
+			wantPerPage: defaultClusterItemsPerPage,
+		},
+		{
+			name: "custom page size",
+			requestBody: ClusterItemsRequest{
+				SearchRequest: SearchRequest{Query: "phone"},
+				Rule:          [][]string{{"brand:Apple"}},
+				HitsPerPage:   50,
+			},
+			wantStatus:  http.StatusOK,
+			wantFilters: [][]string{{"brand:Apple"}},
+			wantPerPage: 50,
+		},
+		{
+			name:        "missing rule",
+			requestBody: ClusterItemsRequest{SearchRequest: SearchRequest{Query: "phone"}},
+			wantStatus:  http.StatusBadRequest,
+		},
+		{
+			name: "page size too large",
+			requestBody: ClusterItemsRequest{
+				SearchRequest: SearchRequest{Query: "phone"},
+				Rule:          [][]string{{"brand:Apple"}},
+				HitsPerPage:   maxClusterItemsPerPage + 1,
+			},
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name: "negative page",
+			requestBody: ClusterItemsRequest{
+				SearchRequest: SearchRequest{Query: "phone"},
+				Rule:          [][]string{{"brand:Apple"}},
+				Page:          -1,
+			},
+			wantStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotFilters [][]string
+			var gotPage, gotPerPage int
+			handler := &SearchHandler{
+				algoliaClient: &mockAlgoliaClient{
+					searchPageFunc: func(ctx context.Context, query string, facetFilters [][]string, page, hitsPerPage int) (*algolia.SearchResult, error) {
+						gotFilters, gotPage, gotPerPage = facetFilters, page, hitsPerPage
+						return &algolia.SearchResult{
+							Hits:      []algolia.Hit{{ObjectID: "1", Name: "iPhone"}},
+							TotalHits: 437,
+							Page:      page,
+							NbPages:   22,
+						}, nil
+					},
+				},
+				logger: logger.Default(),
+			}
+
+			body, _ := json.Marshal(tt.requestBody)
+			req := httptest.NewRequest(http.MethodPost, "/api/cluster/items", bytes.NewBuffer(body))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+
+			handler.HandleClusterItems(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("HandleClusterItems() status = %d, want %d", w.Code, tt.wantStatus)
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+
+			if !reflect.DeepEqual(gotFilters, tt.wantFilters) {
+				t.Errorf("SearchPage() facetFilters = %v, want %v", gotFilters, tt.wantFilters)
+			}
+			if gotPage != tt.requestBody.Page || gotPerPage != tt.wantPerPage {
+				t.Errorf("SearchPage() page/hitsPerPage = %d/%d, want %d/%d", gotPage, gotPerPage, tt.requestBody.Page, tt.wantPerPage)
+			}
+
+			var response ClusterItemsResponse
+			if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+				t.Fatalf("Failed to decode response: %v", err)
+			}
+			if response.NbHits != 437 || response.NbPages != 22 || response.Page != tt.requestBody.Page {
+				t.Errorf("HandleClusterItems() nbHits/nbPages/page = %d/%d/%d, want 437/22/%d", response.NbHits, response.NbPages, response.Page, tt.requestBody.Page)
+			}
+			if len(response.Items) != 1 || response.Items[0].ID != "1" {
+				t.Errorf("HandleClusterItems() items = %+v, want item 1", response.Items)
+			}
+		})
+	}
+}
+
+func TestSearchHandler_CountClusterGroups(t *testing.T) {
+	groups := []ClusterGroup{
+		{Name: "Apple", Rule: [][]string{{"brand:Apple"}}, Percentage: 30},
+		{Name: "Samsung", Rule: [][]string{{"brand:Samsung"}}, Percentage: 20},
+		{Name: "Broken", Rule: [][]string{{"brand:Broken"}}, Percentage: 10},
+		{Name: "No rule", Percentage: 5},
+		{Name: "Sony", Rule: [][]string{{"brand:Sony"}}, Percentage: 5},
+		{Name: "LG", Rule: [][]string{{"brand:LG"}}, Percentage: 5},
+	}
+	counts := map[string]int{"brand:Apple": 400, "brand:Samsung": 250, "brand:Sony": 50, "brand:LG": 0}
+
+	var mu sync.Mutex
+	var inFlight, maxInFlight, calls int
+	handler := &SearchHandler{
+		algoliaClient: &mockAlgoliaClient{
+			searchPageFunc: func(ctx context.Context, query string, facetFilters [][]string, page, hitsPerPage int) (*algolia.SearchResult, error) {
+				mu.Lock()
+				calls++
+				inFlight++
+				if inFlight > maxInFlight {
+					maxInFlight = inFlight
+				}
+				mu.Unlock()
+				defer func() {
+					mu.Lock()
+					inFlight--
+					mu.Unlock()
+				}()
+				time.Sleep(5 * time.Millisecond)
+
+				if hitsPerPage != 0 {
+					t.Errorf("count query hitsPerPage = %d, want 0", hitsPerPage)
+				}
+				if len(facetFilters) != 2 || facetFilters[0][0] != "category:Phones" {
+					t.Errorf("count query facetFilters = %v, want category filter ANDed with rule", facetFilters)
+				}
+				rule := facetFilters[len(facetFilters)-1][0]
+				if rule == "brand:Broken" {
+					return nil, errors.New("algolia unavailable")
+				}
+				return &algolia.SearchResult{TotalHits: counts[rule]}, nil
+			},
+		},
+		logger:           logger.Default(),
+		countConcurrency: 2,
+	}
+
+	handler.countClusterGroups(context.Background(), "phone", [][]string{{"category:Phones"}}, groups, 1000)
+
+	if calls != 5 {
+		t.Errorf("count queries = %d, want 5 (groups with rules)", calls)
+	}
+	if maxInFlight > 2 {
+		t.Errorf("max concurrent count queries = %d, want <= 2", maxInFlight)
+	}
+
+	expected := []struct {
+		totalCount int // -1 = not counted
+		percentage float64
+	}{
+		{400, 40},
+		{250, 25},
+		{-1, 10}, // Failed query keeps the sample percentage
+		{-1, 5},  // No rule to count
+		{50, 5},
+		{0, 0}, // An exact count of 0 is kept
+	}
+	for i, e := range expected {
+		totalCount := -1
+		if groups[i].TotalCount != nil {
+			totalCount = *groups[i].TotalCount
+		}
+		if totalCount != e.totalCount || groups[i].Percentage != e.percentage {
+			t.Errorf("group %q totalCount/percentage = %d/%v, want %d/%v",
+				groups[i].Name, totalCount, groups[i].Percentage, e.totalCount, e.percentage)
+		}
+	}
+
+	// The exact 0 is sent, uncounted groups omit totalCount
+	body, _ := json.Marshal(groups[5])
+	if !bytes.Contains(body, []byte(`"totalCount":0`)) {
+		t.Errorf("counted group JSON = %s, want totalCount 0", body)
+	}
+	body, _ = json.Marshal(groups[2])
+	if bytes.Contains(body, []byte(`"totalCount"`)) {
+		t.Errorf("uncounted group JSON = %s, want no totalCount", body)
+	}
+}
+
+func TestSearchHandler_HandleCluster_StableIdentities(t *testing.T) {
+	hits := []algolia.Hit{
+		{ObjectID: "1", Name: "Phone 1", Facets: map[string]interface{}{"category": "Phones", "brand": "Samsung"}},
+		{ObjectID: "2", Name: "Phone 2", Facets: map[string]interface{}{"category": "Phones", "brand": "Samsung"}},
+		{ObjectID: "3", Name: "Phone 3", Facets: map[string]interface{}{"category": "Phones", "brand": "Samsung"}},
+		{ObjectID: "4", Name: "Shirt 1", Facets: map[string]interface{}{"category": "Shirts", "brand": "Nike"}},
+		{ObjectID: "5", Name: "Shirt 2", Facets: map[string]interface{}{"category": "Shirts", "brand": "Nike"}},
+		{ObjectID: "6", Name: "Shirt 3", Facets: map[string]interface{}{"category": "Shirts", "brand": "Nike"}},
+	}
+
+	handler := &SearchHandler{
+		algoliaClient: &mockAlgoliaClient{
+			searchRipperFunc: func(ctx context.Context, query string, facetFilters [][]string) (*algolia.SearchResult, error) {
+				// The refinement returns the same items in reverse order
+				if len(facetFilters) > 0 {
+					reversed := make([]algolia.Hit, len(hits))
+					for i, hit := range hits {
+						reversed[len(hits)-1-i] = hit
+					}
+					return &algolia.SearchResult{Hits: reversed, TotalHits: len(hits)}, nil
+				}
+				return &algolia.SearchResult{Hits: hits, TotalHits: len(hits)}, nil
+			},
+		},
+		logger:            logger.Default(),
+		identityThreshold: ize.DefaultIdentityThreshold,
+		lineages:          newTokenStore[*ize.ClusterLineage](10),
+	}
+
+	cluster := func(request ClusterRequest) ClusterResponse {
+		body, _ := json.Marshal(request)
+		req := httptest.NewRequest(http.MethodPost, "/api/cluster", bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+
+		handler.HandleCluster(w, req)
+
+		if w.Code != http.StatusOK {
+			t.Fatalf("HandleCluster() status = %d, want %d", w.Code, http.StatusOK)
+		}
+		var response ClusterResponse
+		if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+			t.Fatalf("Failed to decode response: %v", err)
+		}
+		return response
+	}
+
+	first := cluster(ClusterRequest{SearchRequest: SearchRequest{Query: "test"}})
+	if first.ResultToken == "" {
+		t.Fatal("HandleCluster() resultToken is empty")
+	}
+	if len(first.Groups) == 0 {
+		t.Fatal("HandleCluster() returned no groups")
+	}
+
+	second := cluster(ClusterRequest{
+		SearchRequest: SearchRequest{Query: "test", FacetFilters: [][]string{{"category:Phones", "category:Shirts"}}},
+		PreviousToken: first.ResultToken,
+	})
+	if len(second.Groups) != len(first.Groups) {
+		t.Fatalf("refined groups = %d, want %d", len(second.Groups), len(first.Groups))
+	}
+	for i := range first.Groups {
+		a, b := first.Groups[i], second.Groups[i]
+		if a.ID != b.ID || a.Color != b.Color || a.Name != b.Name || !b.Stable {
+			t.Errorf("group %d changed identity: %s/%d/%q -> %s/%d/%q (stable %v)", i, a.ID, a.Color, a.Name, b.ID, b.Color, b.Name, b.Stable)
+		}
+	}
+
+	// Unknown tokens are not an error
+	third := cluster(ClusterRequest{SearchRequest: SearchRequest{Query: "test"}, PreviousToken: "expired"})
+	for _, group := range third.Groups {
+		if group.Stable {
+			t.Errorf("group %s stable with unknown previous token", group.ID)
+		}
+	}
+}
+
+// overBudgetLabeler is a labeler whose daily budget is spent, with the cached labels
+// it can still serve
+type overBudgetLabeler struct {
+	cached []labeler.Label
+}
+
+func (overBudgetLabeler) GenerateClusterName(ctx context.Context, stats labeler.ClusterStats) (string, error) {
+	return "", labeler.ErrBudgetExceeded
+}
+
+func (overBudgetLabeler) GenerateClusterNames(ctx context.Context, statsSlice []labeler.ClusterStats) ([]string, error) {
+	return nil, labeler.ErrBudgetExceeded
+}
+
+func (l overBudgetLabeler) GenerateClusterLabels(ctx context.Context, statsSlice []labeler.ClusterStats) ([]labeler.Label, error) {
+	return l.cached, labeler.ErrBudgetExceeded
+}
+
+func TestSearchHandler_NameClusters_OverBudget(t *testing.T) {
+	handler := &SearchHandler{labeler: overBudgetLabeler{}, logger: logger.Default()}
+	groups := []ize.ClusterGroup{
+		{
+			Name:      "brand:Apple",
+			Items:     []ize.Result{{Name: "iPhone 15"}, {Name: "iPhone 14"}},
+			TopFacets: []ize.FacetCount{{FacetName: "brand", FacetValue: "Apple", Count: 2, Percentage: 100}},
+			Stats:     ize.ClusterStats{Size: 2},
+		},
+		{
+			Name:      "brand:Samsung",
+			Items:     []ize.Result{{Name: "Galaxy S24"}, {Name: "Galaxy S23"}},
+			TopFacets: []ize.FacetCount{{FacetName: "brand", FacetValue: "Samsung", Count: 2, Percentage: 100}},
+			Stats:     ize.ClusterStats{Size: 2},
+		},
+	}
+
+	handler.nameClusters(context.Background(), "phones", groups, []int{0, 1})
+
+	for i, fallback := range []string{"brand:Apple", "brand:Samsung"} {
+		if name := groups[i].Name; name == "" || name == fallback {
+			t.Errorf("group %d name = %q, want a local label", i, name)
+		}
+	}
+
+	// Cached labels are kept; only the rest are labeled locally
+	groups[0].Name, groups[1].Name = "brand:Apple", "brand:Samsung"
+	handler.labeler = overBudgetLabeler{cached: []labeler.Label{{Name: "Apple Phones", Description: "iPhones."}, {}}}
+	handler.nameClusters(context.Background(), "phones", groups, []int{0, 1})
+	if groups[0].Name != "Apple Phones" || groups[0].Description != "iPhones." {
+		t.Errorf("group 0 = %q, %q, want the cached label", groups[0].Name, groups[0].Description)
+	}
+	if name := groups[1].Name; name == "" || name == "brand:Samsung" {
+		t.Errorf("group 1 name = %q, want a local label", name)
+	}
+}
+
+func TestSearchHandler_Close(t *testing.T) {
+	path := t.TempDir() + "/labels.json"
+	cache, err := labeler.OpenFileCache(path, false, 0, time.Hour, logger.Default())
+	if err != nil {
+		t.Fatalf("OpenFileCache() error = %v", err)
+	}
+	handler := &SearchHandler{labelCache: cache, logger: logger.Default()}
+
+	// The label is waiting for the flush delay until the handler is closed
+	cache.Set("k", labeler.Label{Name: "Phones"})
+	handler.Close()
+
+	reopened, err := labeler.OpenFileCache(path, false, 0, time.Hour, logger.Default())
+	if err != nil {
+		t.Fatalf("OpenFileCache() error = %v", err)
+	}
+	if label, ok := reopened.Get("k"); !ok || label.Name != "Phones" {
+		t.Errorf("label after Close() = %+v, %v, want it written to the file", label, ok)
+	}
+
+	// Without a file cache there is nothing to write
+	(&SearchHandler{logger: logger.Default()}).Close()
+}
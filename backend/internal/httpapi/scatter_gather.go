@@ -0,0 +1,349 @@
+package httpapi
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sync"
+
+	"ize/internal/algolia"
+	"ize/internal/ize"
+)
+
+// Scatter/Gather actions
+const (
+	actionScatter = "scatter"
+	actionGather  = "gather"
+	actionBack    = "back"
+	actionForward = "forward"
+)
+
+// otherClusterID selects the "Other" group in a gather request
+const otherClusterID = "other"
+
+// Scatter/Gather limits
+const (
+	maxGatherQueries      = 8   // Algolia queries issued to fetch more items per gather
+	maxGatherItems        = 500 // Items re-clustered per gather
+	maxScatterGatherSteps = 20  // History kept per session; the oldest steps are dropped
+)
+
+var (
+	errNoSelection    = errors.New("no clusters selected")
+	errUnknownCluster = errors.New("unknown cluster")
+)
+
+// scatterGatherSession is the back/forward history of one browsing session
+type scatterGatherSession struct {
+	mu       sync.Mutex
+	steps    []*scatterGatherStep
+	position int
+}
+
+// scatterGatherStep is one clustering in the session history
+type scatterGatherStep struct {
+	query string
+	// scope is a list of filter sets whose union is this step's collection; every set
+	// is the session's facetFilters ANDed with the rules gathered along the way. A
+	// selection without a rule keeps the previous step's scope, a superset of the
+	// collection.
+	scope    [][][]string
+	gathered []string
+	response ClusterResponse
+	hits     map[string]algolia.Hit // Clustered hits by objectID, to gather without refetching
+	nextID   int                    // Cluster ID counter, so IDs are unique within the session
+}
+
+// HandleScatterGather implements the Scatter/Gather browsing loop: scatter a query into
+// clusters, gather selected clusters (fetching more of their items through their rules)
+// and re-scatter the union, with back/forward navigation through the session history
+func (h *SearchHandler) HandleScatterGather(w http.ResponseWriter, r *http.Request) {
+	log := h.logger.WithContext(r.Context())
+
+	if r.Method != http.MethodPost {
+		log.Warn("method not allowed", "method", r.Method)
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req ScatterGatherRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		log.ErrorWithErr("failed to decode request body", err)
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if h.sessions == nil {
+		log.Error("scatter/gather sessions not configured")
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	log.Debug("processing ScatterGather request",
+		"action", req.Action,
+		"session_id", req.SessionID,
+		"query", req.Query,
+		"cluster_ids", req.ClusterIDs,
+	)
+
+	if req.Action == actionScatter {
+		h.startScatterGather(w, r, req)
+		return
+	}
+
+	session, ok := h.sessions.get(req.SessionID)
+	if !ok {
+		log.Warn("scatter/gather session not found", "session_id", req.SessionID)
+		http.Error(w, "Session not found", http.StatusNotFound)
+		return
+	}
+
+	if req.Action == actionGather {
+		// Gathering calls Algolia and the labeler, so it works from the current step
+		// without holding the session lock; back and forward are not blocked meanwhile
+		session.mu.Lock()
+		base := session.steps[session.position]
+		session.mu.Unlock()
+
+		step, err := h.gather(r.Context(), base, req.ClusterIDs)
+		if errors.Is(err, errNoSelection) || errors.Is(err, errUnknownCluster) {
+			log.Warn("invalid gather selection", "error", err, "cluster_ids", req.ClusterIDs)
+			http.Error(w, "Invalid cluster selection", http.StatusBadRequest)
+			return
+		}
+		if err != nil {
+			log.ErrorWithErr("gather failed", err, "session_id", req.SessionID)
+			http.Error(w, "Gather failed", http.StatusInternalServerError)
+			return
+		}
+
+		session.mu.Lock()
+		defer session.mu.Unlock()
+		session.pushAfter(base, step)
+		h.writeScatterGather(w, r, req.SessionID, session)
+		return
+	}
+
+	session.mu.Lock()
+	defer session.mu.Unlock()
+
+	switch req.Action {
+	case actionBack:
+		if session.position == 0 {
+			http.Error(w, "No previous step", http.StatusBadRequest)
+			return
+		}
+		session.position--
+	case actionForward:
+		if session.position == len(session.steps)-1 {
+			http.Error(w, "No next step", http.StatusBadRequest)
+			return
+		}
+		session.position++
+	default:
+		log.Warn("invalid scatter/gather action", "action", req.Action)
+		http.Error(w, "Invalid action", http.StatusBadRequest)
+		return
+	}
+
+	h.writeScatterGather(w, r, req.SessionID, session)
+}
+
+// startScatterGather clusters the query and starts a new session
+func (h *SearchHandler) startScatterGather(w http.ResponseWriter, r *http.Request, req ScatterGatherRequest) {
+	log := h.logger.WithContext(r.Context())
+
+	algoliaResults, err := h.algoliaClient.SearchRipper(r.Context(), req.Query, req.FacetFilters)
+	if err != nil {
+		log.ErrorWithErr("algolia search failed for ScatterGather", err, "query", req.Query)
+		http.Error(w, "Search failed", http.StatusInternalServerError)
+		return
+	}
+
+	scope := [][][]string{req.FacetFilters}
+	step, err := h.scatter(r.Context(), req.Query, algoliaResults, scope, nil, 1)
+	if err != nil {
+		log.ErrorWithErr("Cluster processing failed for ScatterGather", err, "query", req.Query)
+		http.Error(w, "Cluster processing failed", http.StatusInternalServerError)
+		return
+	}
+
+	session := &scatterGatherSession{steps: []*scatterGatherStep{step}}
+	sessionID, err := h.sessions.put(session)
+	if err != nil {
+		log.ErrorWithErr("failed to create scatter/gather session", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	session.mu.Lock()
+	defer session.mu.Unlock()
+	h.writeScatterGather(w, r, sessionID, session)
+}
+
+// gather collects the items of the selected clusters of step, fetches more through
+// their rules within the step's scope, and scatters the union into a new step. The
+// Other group and clusters without a rule fetch nothing more; when one is selected the
+// new step keeps the step's scope, so later rule queries still reach its items.
+func (h *SearchHandler) gather(ctx context.Context, step *scatterGatherStep, clusterIDs []string) (*scatterGatherStep, error) {
+	log := h.logger.WithContext(ctx)
+
+	if len(clusterIDs) == 0 {
+		return nil, errNoSelection
+	}
+
+	groupsByID := make(map[string]ClusterGroup, len(step.response.Groups))
+	for _, group := range step.response.Groups {
+		groupsByID[group.ID] = group
+	}
+
+	union := make(map[string]algolia.Hit)
+	var order []string
+	add := func(hit algolia.Hit) {
+		if _, ok := union[hit.ObjectID]; ok || len(order) >= maxGatherItems {
+			return
+		}
+		union[hit.ObjectID] = hit
+		order = append(order, hit.ObjectID)
+	}
+
+	// Items already in the selected clusters, then rule queries for more of them
+	var queries [][][]string
+	ruleless := false
+	for _, id := range clusterIDs {
+		var items []SearchResult
+		if id == otherClusterID {
+			items = step.response.OtherGroup
+			ruleless = true
+		} else {
+			group, ok := groupsByID[id]
+			if !ok {
+				return nil, errUnknownCluster
+			}
+			items = group.Items
+			if len(group.Rule) > 0 {
+				for _, filters := range step.scope {
+					queries = append(queries, withRule(filters, group.Rule))
+				}
+			} else {
+				ruleless = true
+			}
+		}
+		for _, item := range items {
+			if hit, ok := step.hits[item.ID]; ok {
+				add(hit)
+			}
+		}
+	}
+
+	if len(queries) > maxGatherQueries {
+		log.Warn("gather scope truncated, some matching items will not be fetched",
+			"filter_sets", len(queries),
+			"max_queries", maxGatherQueries,
+		)
+		queries = queries[:maxGatherQueries]
+	}
+	for _, filters := range queries {
+		results, err := h.algoliaClient.SearchRipper(ctx, step.query, filters)
+		if err != nil {
+			return nil, err
+		}
+		for _, hit := range results.Hits {
+			add(hit)
+		}
+	}
+
+	hits := make([]algolia.Hit, len(order))
+	for i, id := range order {
+		hits[i] = union[id]
+	}
+
+	log.Debug("gathered items",
+		"cluster_ids", clusterIDs,
+		"items", len(hits),
+		"queries", len(queries),
+	)
+
+	gathered := &algolia.SearchResult{Hits: hits, TotalHits: len(hits)}
+	scope := queries
+	if ruleless {
+		// The selection's other items are somewhere in the step's collection
+		scope = step.scope
+	}
+	return h.scatter(ctx, step.query, gathered, scope, clusterIDs, step.nextID)
+}
+
+// scatter clusters a collection into a new step
+func (h *SearchHandler) scatter(ctx context.Context, query string, algoliaResults *algolia.SearchResult, scope [][][]string, gathered []string, nextID int) (*scatterGatherStep, error) {
+	clusterResult, nextID, err := h.clusterHits(ctx, query, algoliaResults, h.clusterOptions, &ize.ClusterLineage{NextID: nextID})
+	if err != nil {
+		return nil, err
+	}
+
+	hits := make(map[string]algolia.Hit, len(algoliaResults.Hits))
+	for _, hit := range algoliaResults.Hits {
+		hits[hit.ObjectID] = hit
+	}
+
+	return &scatterGatherStep{
+		query:    query,
+		scope:    scope,
+		gathered: gathered,
+		response: toClusterResponse(clusterResult, algoliaResults),
+		hits:     hits,
+		nextID:   nextID,
+	}, nil
+}
+
+// push appends a step after the current one, discarding any forward history
+func (s *scatterGatherSession) push(step *scatterGatherStep) {
+	s.steps = append(s.steps[:s.position+1], step)
+	if len(s.steps) > maxScatterGatherSteps {
+		s.steps = s.steps[len(s.steps)-maxScatterGatherSteps:]
+	}
+	s.position = len(s.steps) - 1
+}
+
+// pushAfter appends a step after base, discarding the history that followed it. If
+// base has since been dropped from the history, the step follows the current one.
+func (s *scatterGatherSession) pushAfter(base, step *scatterGatherStep) {
+	for i, existing := range s.steps {
+		if existing == base {
+			s.position = i
+			break
+		}
+	}
+	s.push(step)
+}
+
+// writeScatterGather writes the session's current step; the caller holds session.mu
+func (h *SearchHandler) writeScatterGather(w http.ResponseWriter, r *http.Request, sessionID string, session *scatterGatherSession) {
+	log := h.logger.WithContext(r.Context())
+
+	step := session.steps[session.position]
+	response := ScatterGatherResponse{
+		ClusterResponse: step.response,
+		SessionID:       sessionID,
+		Step:            session.position,
+		Steps:           len(session.steps),
+		Gathered:        step.gathered,
+		CanBack:         session.position > 0,
+		CanForward:      session.position < len(session.steps)-1,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		log.ErrorWithErr("failed to encode ScatterGather response", err, "session_id", sessionID)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	log.Info("ScatterGather request completed successfully",
+		"session_id", sessionID,
+		"step", response.Step,
+		"steps", response.Steps,
+		"cluster_count", len(response.Groups),
+		"items", len(step.hits),
+	)
+}
@@ -0,0 +1,292 @@
+package httpapi
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"ize/internal/algolia"
+	"ize/internal/logger"
+)
+
+func scatterGatherHits() []algolia.Hit {
+	return []algolia.Hit{
+		{ObjectID: "1", Name: "Phone 1", Facets: map[string]interface{}{"category": "Phones", "brand": "Samsung"}},
+		{ObjectID: "2", Name: "Phone 2", Facets: map[string]interface{}{"category": "Phones", "brand": "Samsung"}},
+		{ObjectID: "3", Name: "Phone 3", Facets: map[string]interface{}{"category": "Phones", "brand": "Apple"}},
+		{ObjectID: "4", Name: "Phone 4", Facets: map[string]interface{}{"category": "Phones", "brand": "Apple"}},
+		{ObjectID: "5", Name: "Shirt 1", Facets: map[string]interface{}{"category": "Shirts", "brand": "Nike"}},
+		{ObjectID: "6", Name: "Shirt 2", Facets: map[string]interface{}{"category": "Shirts", "brand": "Nike"}},
+		{ObjectID: "7", Name: "Shirt 3", Facets: map[string]interface{}{"category": "Shirts", "brand": "Adidas"}},
+	}
+}
+
+func TestSearchHandler_HandleScatterGather(t *testing.T) {
+	hits := scatterGatherHits()
+	var ruleQueries [][][]string
+	handler := &SearchHandler{
+		algoliaClient: &mockAlgoliaClient{
+			searchRipperFunc: func(ctx context.Context, query string, facetFilters [][]string) (*algolia.SearchResult, error) {
+				if len(facetFilters) <= 1 {
+					return &algolia.SearchResult{Hits: hits, TotalHits: len(hits)}, nil
+				}
+				// Rule query: return one extra phone beyond the sample
+				ruleQueries = append(ruleQueries, facetFilters)
+				extra := algolia.Hit{ObjectID: "8", Name: "Phone 5", Facets: map[string]interface{}{"category": "Phones", "brand": "Apple"}}
+				return &algolia.SearchResult{Hits: []algolia.Hit{extra}, TotalHits: 1}, nil
+			},
+		},
+		logger:   logger.Default(),
+		sessions: newTokenStore[*scatterGatherSession](10),
+	}
+
+	call := func(request ScatterGatherRequest, wantStatus int) ScatterGatherResponse {
+		t.Helper()
+		body, _ := json.Marshal(request)
+		req := httptest.NewRequest(http.MethodPost, "/api/scatter-gather", bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+
+		handler.HandleScatterGather(w, req)
+
+		if w.Code != wantStatus {
+			t.Fatalf("HandleScatterGather(%s) status = %d, want %d", request.Action, w.Code, wantStatus)
+		}
+		var response ScatterGatherResponse
+		if wantStatus == http.StatusOK {
+			if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+				t.Fatalf("Failed to decode response: %v", err)
+			}
+		}
+		return response
+	}
+
+	start := call(ScatterGatherRequest{Action: "scatter", Query: "test", FacetFilters: [][]string{{"inStock:true"}}}, http.StatusOK)
+	if start.SessionID == "" || start.Step != 0 || start.Steps != 1 || start.CanBack || start.CanForward {
+		t.Fatalf("scatter response = session %q step %d/%d back %v forward %v", start.SessionID, start.Step, start.Steps, start.CanBack, start.CanForward)
+	}
+
+	// Gather the phone cluster
+	var phones ClusterGroup
+	for _, group := range start.Groups {
+		if len(group.Items) > 0 && group.Items[0].Name[:5] == "Phone" {
+			phones = group
+		}
+	}
+	if phones.ID == "" {
+		t.Fatalf("no phone cluster in %+v", start.Groups)
+	}
+
+	gathered := call(ScatterGatherRequest{Action: "gather", SessionID: start.SessionID, ClusterIDs: []string{phones.ID}}, http.StatusOK)
+	if gathered.Step != 1 || !gathered.CanBack || gathered.CanForward {
+		t.Errorf("gather response step %d back %v forward %v, want 1/true/false", gathered.Step, gathered.CanBack, gathered.CanForward)
+	}
+	if len(gathered.Gathered) != 1 || gathered.Gathered[0] != phones.ID {
+		t.Errorf("gather response gathered = %v, want [%s]", gathered.Gathered, phones.ID)
+	}
+
+	// The rule query is scoped to the session's filters
+	if len(phones.Rule) > 0 {
+		if len(ruleQueries) != 1 || ruleQueries[0][0][0] != "inStock:true" {
+			t.Errorf("rule queries = %v, want one query starting with the session filter", ruleQueries)
+		}
+	}
+
+	// Only phones (including the fetched one) are re-clustered
+	seen := make(map[string]bool)
+	for _, group := range gathered.Groups {
+		for _, item := range group.Items {
+			seen[item.ID] = true
+		}
+	}
+	for _, item := range gathered.OtherGroup {
+		seen[item.ID] = true
+	}
+	for id := range seen {
+		if id == "5" || id == "6" || id == "7" {
+			t.Errorf("gathered step contains shirt %s", id)
+		}
+	}
+	if len(phones.Rule) > 0 && !seen["8"] {
+		t.Error("gathered step is missing the item fetched through the rule")
+	}
+
+	// IDs are unique within the session
+	for _, group := range gathered.Groups {
+		for _, old := range start.Groups {
+			if group.ID == old.ID {
+				t.Errorf("cluster ID %s reused after gather", group.ID)
+			}
+		}
+	}
+
+	back := call(ScatterGatherRequest{Action: "back", SessionID: start.SessionID}, http.StatusOK)
+	if back.Step != 0 || !back.CanForward || len(back.Groups) != len(start.Groups) {
+		t.Errorf("back response step %d forward %v groups %d, want 0/true/%d", back.Step, back.CanForward, len(back.Groups), len(start.Groups))
+	}
+	call(ScatterGatherRequest{Action: "back", SessionID: start.SessionID}, http.StatusBadRequest)
+
+	forward := call(ScatterGatherRequest{Action: "forward", SessionID: start.SessionID}, http.StatusOK)
+	if forward.Step != 1 || forward.CanForward {
+		t.Errorf("forward response step %d forward %v, want 1/false", forward.Step, forward.CanForward)
+	}
+
+	call(ScatterGatherRequest{Action: "gather", SessionID: start.SessionID, ClusterIDs: []string{"nope"}}, http.StatusBadRequest)
+	call(ScatterGatherRequest{Action: "gather", SessionID: start.SessionID}, http.StatusBadRequest)
+	call(ScatterGatherRequest{Action: "gather", SessionID: "missing", ClusterIDs: []string{phones.ID}}, http.StatusNotFound)
+	call(ScatterGatherRequest{Action: "shuffle", SessionID: start.SessionID}, http.StatusBadRequest)
+}
+
+func TestScatterGatherSession_Push(t *testing.T) {
+	session := &scatterGatherSession{steps: []*scatterGatherStep{{nextID: 0}}}
+	for i := 1; i < 3; i++ {
+		session.push(&scatterGatherStep{nextID: i})
+	}
+	session.position = 0
+
+	// Pushing from an earlier step discards the forward history
+	session.push(&scatterGatherStep{nextID: 10})
+	if len(session.steps) != 2 || session.position != 1 || session.steps[1].nextID != 10 {
+		t.Errorf("push() steps = %d position = %d, want 2/1", len(session.steps), session.position)
+	}
+
+	for i := 0; i < maxScatterGatherSteps+5; i++ {
+		session.push(&scatterGatherStep{})
+	}
+	if len(session.steps) != maxScatterGatherSteps || session.position != maxScatterGatherSteps-1 {
+		t.Errorf("push() steps = %d position = %d, want capped at %d", len(session.steps), session.position, maxScatterGatherSteps)
+	}
+}
+
+func TestSearchHandler_HandleScatterGather_NavigateDuringGather(t *testing.T) {
+	hits := scatterGatherHits()
+	gathering := make(chan struct{})
+	release := make(chan struct{})
+	handler := &SearchHandler{
+		algoliaClient: &mockAlgoliaClient{
+			searchRipperFunc: func(ctx context.Context, query string, facetFilters [][]string) (*algolia.SearchResult, error) {
+				if len(facetFilters) > 1 {
+					close(gathering)
+					<-release
+				}
+				return &algolia.SearchResult{Hits: hits, TotalHits: len(hits)}, nil
+			},
+		},
+		logger:   logger.Default(),
+		sessions: newTokenStore[*scatterGatherSession](10),
+	}
+	call := func(request ScatterGatherRequest) *httptest.ResponseRecorder {
+		body, _ := json.Marshal(request)
+		w := httptest.NewRecorder()
+		handler.HandleScatterGather(w, httptest.NewRequest(http.MethodPost, "/api/scatter-gather", bytes.NewBuffer(body)))
+		return w
+	}
+
+	var start ScatterGatherResponse
+	json.NewDecoder(call(ScatterGatherRequest{Action: "scatter", Query: "test", FacetFilters: [][]string{{"inStock:true"}}}).Body).Decode(&start)
+	var ruled ClusterGroup
+	for _, group := range start.Groups {
+		if len(group.Rule) > 0 {
+			ruled = group
+		}
+	}
+	if ruled.ID == "" {
+		t.Fatalf("no cluster with a rule in %+v", start.Groups)
+	}
+
+	done := make(chan *httptest.ResponseRecorder)
+	go func() {
+		done <- call(ScatterGatherRequest{Action: "gather", SessionID: start.SessionID, ClusterIDs: []string{ruled.ID}})
+	}()
+	<-gathering
+
+	// The session is not locked while the gather waits on Algolia
+	if w := call(ScatterGatherRequest{Action: "back", SessionID: start.SessionID}); w.Code != http.StatusBadRequest {
+		t.Errorf("back during gather status = %d, want %d (no previous step)", w.Code, http.StatusBadRequest)
+	}
+
+	close(release)
+	var gathered ScatterGatherResponse
+	json.NewDecoder((<-done).Body).Decode(&gathered)
+	if gathered.Step != 1 || gathered.Steps != 2 {
+		t.Errorf("gather response step %d/%d, want 1/2", gathered.Step, gathered.Steps)
+	}
+}
+
+func TestScatterGatherSession_PushAfter(t *testing.T) {
+	first, second := &scatterGatherStep{nextID: 0}, &scatterGatherStep{nextID: 1}
+	session := &scatterGatherSession{steps: []*scatterGatherStep{first, second}, position: 1}
+
+	// A gather that started from the first step replaces what followed it
+	session.pushAfter(first, &scatterGatherStep{nextID: 10})
+	if len(session.steps) != 2 || session.position != 1 || session.steps[1].nextID != 10 {
+		t.Errorf("pushAfter() steps = %d position = %d, want the new step after the first", len(session.steps), session.position)
+	}
+
+	// A base no longer in the history appends after the current step
+	session.pushAfter(second, &scatterGatherStep{nextID: 11})
+	if len(session.steps) != 3 || session.position != 2 || session.steps[2].nextID != 11 {
+		t.Errorf("pushAfter() with a dropped base steps = %d position = %d, want 3/2", len(session.steps), session.position)
+	}
+}
+
+func TestSearchHandler_GatherWithoutRule(t *testing.T) {
+	hits := scatterGatherHits()
+	var queries [][][]string
+	handler := &SearchHandler{
+		algoliaClient: &mockAlgoliaClient{
+			searchRipperFunc: func(ctx context.Context, query string, facetFilters [][]string) (*algolia.SearchResult, error) {
+				queries = append(queries, facetFilters)
+				return &algolia.SearchResult{}, nil
+			},
+		},
+		logger: logger.Default(),
+	}
+
+	byID := make(map[string]algolia.Hit, len(hits))
+	for _, hit := range hits {
+		byID[hit.ObjectID] = hit
+	}
+	parentScope := [][][]string{{{"inStock:true"}}}
+	step := &scatterGatherStep{
+		query: "test",
+		scope: parentScope,
+		response: ClusterResponse{
+			Groups: []ClusterGroup{
+				{ID: "c1", Rule: [][]string{{"category:Phones"}}, Items: []SearchResult{{ID: "1"}, {ID: "2"}, {ID: "3"}, {ID: "4"}}},
+				{ID: "c2", Items: []SearchResult{{ID: "5"}, {ID: "6"}}}, // Clustered by text, no rule
+			},
+			OtherGroup: []SearchResult{{ID: "7"}},
+		},
+		hits:   byID,
+		nextID: 3,
+	}
+
+	tests := []struct {
+		clusterIDs  []string
+		wantQueries int
+		wantScope   [][][]string
+	}{
+		{[]string{"c1"}, 1, [][][]string{{{"inStock:true"}, {"category:Phones"}}}},
+		{[]string{otherClusterID}, 0, parentScope},
+		{[]string{"c2"}, 0, parentScope},
+		{[]string{"c1", "c2"}, 1, parentScope},
+	}
+	for _, tt := range tests {
+		queries = nil
+		next, err := handler.gather(context.Background(), step, tt.clusterIDs)
+		if err != nil {
+			t.Fatalf("gather(%v) error = %v", tt.clusterIDs, err)
+		}
+		if len(queries) != tt.wantQueries {
+			t.Errorf("gather(%v) queries = %v, want %d", tt.clusterIDs, queries, tt.wantQueries)
+		}
+		if !reflect.DeepEqual(next.scope, tt.wantScope) {
+			t.Errorf("gather(%v) scope = %v, want %v", tt.clusterIDs, next.scope, tt.wantScope)
+		}
+	}
+}
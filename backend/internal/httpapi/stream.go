@@ -0,0 +1,202 @@
+package httpapi
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"ize/internal/curation"
+	"ize/internal/ize"
+	"ize/internal/labeler"
+)
+
+// Cluster stream events
+const (
+	eventClusters = "clusters" // ClusterResponse with fallback names for the new groups
+	eventLabel    = "label"    // ClusterLabelEvent, once per group as its label arrives
+	eventDone     = "done"     // ClusterStreamSummary, last
+)
+
+// HandleClusterStream clusters like HandleCluster but answers with Server-Sent Events,
+// so results show before the labeler has named them: a clusters event as soon as the
+// clustering finishes, a label event as each group's name arrives, and a done event.
+// The result token is sent in the done event, since it records the final names.
+func (h *SearchHandler) HandleClusterStream(w http.ResponseWriter, r *http.Request) {
+	log := h.logger.WithContext(r.Context())
+
+	req, opts, ok := h.decodeClusterRequest(w, r)
+	if !ok {
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		log.Error("response writer does not support streaming")
+		http.Error(w, "Streaming not supported", http.StatusInternalServerError)
+		return
+	}
+
+	log.Debug("processing Cluster stream request",
+		"query", req.Query,
+		"facet_filters", req.FacetFilters,
+		"previous_token", req.PreviousToken,
+	)
+	start := time.Now()
+
+	algoliaResults, err := h.algoliaClient.SearchRipper(r.Context(), req.Query, req.FacetFilters)
+	if err != nil {
+		log.ErrorWithErr("algolia search failed for Cluster stream", err, "query", req.Query)
+		http.Error(w, "Search failed", http.StatusInternalServerError)
+		return
+	}
+
+	var previous *ize.ClusterLineage
+	if req.PreviousToken != "" && h.lineages != nil {
+		if previous, ok = h.lineages.get(req.PreviousToken); !ok {
+			log.Debug("previous result token not found, assigning new cluster identities", "previous_token", req.PreviousToken)
+		}
+	}
+
+	// Curated groupings are named by the curator, so their stream has no label events
+	grouping, _ := h.curatedGrouping(log, curation.ViewCluster, req.Query, req.FacetFilters)
+
+	var clusterResult *ize.ClusterResult
+	var nextID int
+	var unnamed []int
+	if grouping != nil {
+		clusterResult, err = ize.ApplyCuratedGrouping(req.Query, algoliaResults, grouping, opts, log)
+		nextID = grouping.NextID
+	} else {
+		clusterResult, nextID, unnamed, err = h.stabilizedClusters(r.Context(), req.Query, algoliaResults, opts, previous)
+	}
+	if err != nil {
+		log.ErrorWithErr("Cluster processing failed", err, "query", req.Query)
+		http.Error(w, "Cluster processing failed", http.StatusInternalServerError)
+		return
+	}
+
+	response := toClusterResponse(clusterResult, algoliaResults)
+	response.Curated = grouping != nil
+	if req.ExactCounts {
+		h.countClusterGroups(r.Context(), req.Query, req.FacetFilters, response.Groups, response.TotalHits)
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no") // Keep proxies from buffering the stream
+	if err := writeEvent(w, flusher, eventClusters, response); err != nil {
+		log.Warn("failed to write Cluster stream event", "event", eventClusters, "error", err)
+		return
+	}
+
+	labeled, failed := h.streamLabels(r.Context(), req.Query, clusterResult.Groups, unnamed, func(index int, label labeler.Label) error {
+		return writeEvent(w, flusher, eventLabel, ClusterLabelEvent{
+			Index:       index,
+			ID:          clusterResult.Groups[index].ID,
+			Name:        label.Name,
+			Description: label.Description,
+		})
+	})
+	if r.Context().Err() != nil {
+		log.Info("Cluster stream closed by client", "query", req.Query, "labeled", labeled)
+		return
+	}
+
+	summary := ClusterStreamSummary{
+		ClusterCount: len(response.Groups),
+		Labeled:      labeled,
+		Failed:       failed,
+		DurationMs:   time.Since(start).Milliseconds(),
+	}
+	if h.lineages != nil {
+		token, err := h.lineages.put(ize.NewClusterLineage(clusterResult.Groups, nextID))
+		if err != nil {
+			log.Warn("failed to store cluster identities, response has no result token", "error", err)
+		} else {
+			summary.ResultToken = token
+		}
+	}
+	if err := writeEvent(w, flusher, eventDone, summary); err != nil {
+		log.Warn("failed to write Cluster stream event", "event", eventDone, "error", err)
+		return
+	}
+
+	log.Info("Cluster stream request completed successfully",
+		"query", req.Query,
+		"cluster_count", summary.ClusterCount,
+		"labeled", labeled,
+		"failed", failed,
+		"duration_ms", summary.DurationMs,
+	)
+}
+
+// streamLabels names the groups at the unnamed indices as /api/cluster would, calling
+// emit with the group index and label as each label arrives. Labelers that cannot
+// stream hand over all labels at once. Over the daily labeling budget the local labeler
+// names the groups without a cached label. Returns how many groups were named and how
+// many kept their fallback names; emitting stops at the first emit error.
+func (h *SearchHandler) streamLabels(ctx context.Context, query string, groups []ize.ClusterGroup, unnamed []int, emit func(index int, label labeler.Label) error) (labeled, failed int) {
+	log := h.logger.WithContext(ctx)
+
+	if h.labeler == nil || len(unnamed) == 0 {
+		return 0, 0
+	}
+
+	statsSlice := h.labelerStats(query, groups, unnamed)
+	named := make([]bool, len(statsSlice))
+	var emitErr error
+	name := func(pos int, label labeler.Label) {
+		if pos < 0 || pos >= len(named) || named[pos] || label.Name == "" {
+			return
+		}
+		index := unnamed[pos]
+		groups[index].Name = label.Name
+		groups[index].Description = label.Description
+		named[pos] = true
+		labeled++
+		if emitErr == nil {
+			emitErr = emit(index, label)
+		}
+	}
+
+	var err error
+	if streamer, ok := h.labeler.(labeler.Streamer); ok {
+		err = streamer.StreamClusterLabels(ctx, statsSlice, name)
+	} else {
+		var labels []labeler.Label
+		labels, err = h.labeler.GenerateClusterLabels(ctx, statsSlice)
+		for pos, label := range labels {
+			name(pos, label)
+		}
+	}
+
+	switch {
+	case errors.Is(err, labeler.ErrBudgetExceeded):
+		log.Warn("daily labeling budget exceeded, using local labels", "cluster_count", len(statsSlice)-labeled)
+		// The local labeler contrasts each group with all the others
+		labels, err := labeler.NewLocal(h.logger).GenerateClusterLabels(ctx, statsSlice)
+		if err == nil {
+			for pos, label := range labels {
+				name(pos, label)
+			}
+		}
+	case err != nil:
+		log.Warn("failed to generate cluster names, keeping fallbacks", "error", err)
+	}
+	return labeled, len(statsSlice) - labeled
+}
+
+// writeEvent sends one Server-Sent Event with data encoded as JSON
+func writeEvent(w http.ResponseWriter, flusher http.Flusher, event string, data interface{}) error {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return fmt.Errorf("failed to encode %s event: %w", event, err)
+	}
+	if _, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, payload); err != nil {
+		return err
+	}
+	flusher.Flush()
+	return nil
+}
@@ -0,0 +1,237 @@
+package httpapi
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"ize/internal/algolia"
+	"ize/internal/ize"
+	"ize/internal/labeler"
+	"ize/internal/logger"
+)
+
+// facetLabeler names a cluster after its top facet value, failing for "Nike"
+type facetLabeler struct{}
+
+func (facetLabeler) GenerateClusterName(ctx context.Context, stats labeler.ClusterStats) (string, error) {
+	for _, f := range stats.TopFacets {
+		if f.Value == "Nike" {
+			return "", fmt.Errorf("labeling failed")
+		}
+	}
+	return stats.TopFacets[0].Value + " Picks", nil
+}
+
+func (l facetLabeler) GenerateClusterNames(ctx context.Context, statsSlice []labeler.ClusterStats) ([]string, error) {
+	names := make([]string, len(statsSlice))
+	for i, stats := range statsSlice {
+		names[i], _ = l.GenerateClusterName(ctx, stats)
+	}
+	return names, nil
+}
+
+func (l facetLabeler) GenerateClusterLabels(ctx context.Context, statsSlice []labeler.ClusterStats) ([]labeler.Label, error) {
+	names, _ := l.GenerateClusterNames(ctx, statsSlice)
+	labels := make([]labeler.Label, len(names))
+	for i, name := range names {
+		labels[i] = labeler.Label{Name: name}
+	}
+	return labels, nil
+}
+
+// sseEvent is a parsed Server-Sent Event
+type sseEvent struct {
+	name string
+	data string
+}
+
+func parseEvents(t *testing.T, body string) []sseEvent {
+	t.Helper()
+	var events []sseEvent
+	for _, block := range strings.Split(strings.TrimSpace(body), "\n\n") {
+		var event sseEvent
+		for _, line := range strings.Split(block, "\n") {
+			switch {
+			case strings.HasPrefix(line, "event: "):
+				event.name = strings.TrimPrefix(line, "event: ")
+			case strings.HasPrefix(line, "data: "):
+				event.data = strings.TrimPrefix(line, "data: ")
+			default:
+				t.Fatalf("unexpected event line %q", line)
+			}
+		}
+		events = append(events, event)
+	}
+	return events
+}
+
+func newStreamTestHandler(l labeler.Labeler) *SearchHandler {
+	hits := []algolia.Hit{
+		{ObjectID: "1", Name: "Phone 1", Facets: map[string]interface{}{"category": "Phones", "brand": "Samsung"}},
+		{ObjectID: "2", Name: "Phone 2", Facets: map[string]interface{}{"category": "Phones", "brand": "Samsung"}},
+		{ObjectID: "3", Name: "Phone 3", Facets: map[string]interface{}{"category": "Phones", "brand": "Samsung"}},
+		{ObjectID: "4", Name: "Shirt 1", Facets: map[string]interface{}{"category": "Shirts", "brand": "Nike"}},
+		{ObjectID: "5", Name: "Shirt 2", Facets: map[string]interface{}{"category": "Shirts", "brand": "Nike"}},
+		{ObjectID: "6", Name: "Shirt 3", Facets: map[string]interface{}{"category": "Shirts", "brand": "Nike"}},
+	}
+	return &SearchHandler{
+		algoliaClient: &mockAlgoliaClient{
+			searchRipperFunc: func(ctx context.Context, query string, facetFilters [][]string) (*algolia.SearchResult, error) {
+				return &algolia.SearchResult{Hits: hits, TotalHits: len(hits)}, nil
+			},
+		},
+		labeler:           l,
+		logger:            logger.Default(),
+		identityThreshold: ize.DefaultIdentityThreshold,
+		lineages:          newTokenStore[*ize.ClusterLineage](10),
+	}
+}
+
+func streamClusters(t *testing.T, handler *SearchHandler, request ClusterRequest) []sseEvent {
+	t.Helper()
+	body, _ := json.Marshal(request)
+	req := httptest.NewRequest(http.MethodPost, "/api/cluster/stream", bytes.NewBuffer(body))
+	w := httptest.NewRecorder()
+
+	handler.HandleClusterStream(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("HandleClusterStream() status = %d, want %d: %s", w.Code, http.StatusOK, w.Body.String())
+	}
+	if ct := w.Header().Get("Content-Type"); ct != "text/event-stream" {
+		t.Errorf("HandleClusterStream() Content-Type = %q, want text/event-stream", ct)
+	}
+	return parseEvents(t, w.Body.String())
+}
+
+func TestSearchHandler_HandleClusterStream(t *testing.T) {
+	handler := newStreamTestHandler(facetLabeler{})
+	events := streamClusters(t, handler, ClusterRequest{SearchRequest: SearchRequest{Query: "test"}})
+
+	if len(events) < 2 || events[0].name != eventClusters || events[len(events)-1].name != eventDone {
+		t.Fatalf("events = %+v, want clusters first and done last", events)
+	}
+
+	var clusters ClusterResponse
+	if err := json.Unmarshal([]byte(events[0].data), &clusters); err != nil {
+		t.Fatalf("Failed to decode clusters event: %v", err)
+	}
+	if len(clusters.Groups) != 2 || clusters.ResultToken != "" {
+		t.Fatalf("clusters event = %d groups, token %q, want 2 groups and no token yet", len(clusters.Groups), clusters.ResultToken)
+	}
+	for _, group := range clusters.Groups {
+		if strings.HasSuffix(group.Name, " Picks") {
+			t.Errorf("clusters event group %q already has its label", group.Name)
+		}
+	}
+
+	// The Samsung group is labeled; the Nike group's labeling fails and keeps its name
+	labels := events[1 : len(events)-1]
+	if len(labels) != 1 || labels[0].name != eventLabel {
+		t.Fatalf("label events = %+v, want one", labels)
+	}
+	var label ClusterLabelEvent
+	json.Unmarshal([]byte(labels[0].data), &label)
+	if label.Name != "Samsung Picks" || clusters.Groups[label.Index].ID != label.ID {
+		t.Errorf("label event = %+v, want Samsung Picks for the group it names", label)
+	}
+
+	var summary ClusterStreamSummary
+	json.Unmarshal([]byte(events[len(events)-1].data), &summary)
+	if summary.ClusterCount != 2 || summary.Labeled != 1 || summary.Failed != 1 || summary.ResultToken == "" {
+		t.Errorf("done event = %+v, want 1 labeled, 1 failed and a result token", summary)
+	}
+
+	// The result token keeps the streamed labels
+	lineage, ok := handler.lineages.get(summary.ResultToken)
+	if !ok {
+		t.Fatal("result token not stored")
+	}
+	refined := ize.ClusterResult{Groups: []ize.ClusterGroup{{Items: []ize.Result{{ID: "1"}, {ID: "2"}, {ID: "3"}}}}}
+	ize.StabilizeClusters(&refined, lineage, ize.DefaultIdentityThreshold)
+	if refined.Groups[0].Name != "Samsung Picks" {
+		t.Errorf("refined group name = %q, want the streamed label", refined.Groups[0].Name)
+	}
+}
+
+func TestSearchHandler_HandleClusterStream_OverBudget(t *testing.T) {
+	events := streamClusters(t, newStreamTestHandler(overBudgetLabeler{}), ClusterRequest{SearchRequest: SearchRequest{Query: "test"}})
+
+	var summary ClusterStreamSummary
+	json.Unmarshal([]byte(events[len(events)-1].data), &summary)
+	if len(events) != 4 || summary.Labeled != 2 || summary.Failed != 0 {
+		t.Errorf("events = %+v, want both groups labeled locally", events)
+	}
+}
+
+// streamingLabeler streams a label for the first cluster it is given and refuses the
+// others over budget, recording how many clusters each call covered
+type streamingLabeler struct {
+	facetLabeler
+	calls []int
+}
+
+func (l *streamingLabeler) StreamClusterLabels(ctx context.Context, statsSlice []labeler.ClusterStats, emit func(i int, label labeler.Label)) error {
+	l.calls = append(l.calls, len(statsSlice))
+	emit(0, labeler.Label{Name: "First", Description: fmt.Sprintf("One of %d clusters.", len(statsSlice))})
+	return labeler.ErrBudgetExceeded
+}
+
+func TestSearchHandler_HandleClusterStream_Streamer(t *testing.T) {
+	l := &streamingLabeler{}
+	events := streamClusters(t, newStreamTestHandler(l), ClusterRequest{SearchRequest: SearchRequest{Query: "test"}})
+
+	// The labeler sees every group at once, as it would for /api/cluster
+	if len(l.calls) != 1 || l.calls[0] != 2 {
+		t.Fatalf("StreamClusterLabels() calls = %v, want one call with both groups", l.calls)
+	}
+	if len(events) != 4 {
+		t.Fatalf("events = %+v, want clusters, two labels and done", events)
+	}
+	var first ClusterLabelEvent
+	json.Unmarshal([]byte(events[1].data), &first)
+	if first.Name != "First" || first.Description != "One of 2 clusters." {
+		t.Errorf("first label event = %+v, want the streamed label", first)
+	}
+
+	// The group refused over budget is labeled locally
+	var summary ClusterStreamSummary
+	json.Unmarshal([]byte(events[3].data), &summary)
+	if summary.Labeled != 2 || summary.Failed != 0 {
+		t.Errorf("done event = %+v, want both groups labeled", summary)
+	}
+}
+
+func TestSearchHandler_HandleClusterStream_Errors(t *testing.T) {
+	handler := newStreamTestHandler(facetLabeler{})
+
+	w := httptest.NewRecorder()
+	handler.HandleClusterStream(w, httptest.NewRequest(http.MethodGet, "/api/cluster/stream", nil))
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("HandleClusterStream(GET) status = %d, want %d", w.Code, http.StatusMethodNotAllowed)
+	}
+
+	w = httptest.NewRecorder()
+	handler.HandleClusterStream(w, httptest.NewRequest(http.MethodPost, "/api/cluster/stream", strings.NewReader("{")))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("HandleClusterStream(invalid body) status = %d, want %d", w.Code, http.StatusBadRequest)
+	}
+
+	handler.algoliaClient = &mockAlgoliaClient{
+		searchRipperFunc: func(ctx context.Context, query string, facetFilters [][]string) (*algolia.SearchResult, error) {
+			return nil, fmt.Errorf("algolia down")
+		},
+	}
+	body, _ := json.Marshal(ClusterRequest{SearchRequest: SearchRequest{Query: "test"}})
+	w = httptest.NewRecorder()
+	handler.HandleClusterStream(w, httptest.NewRequest(http.MethodPost, "/api/cluster/stream", bytes.NewBuffer(body)))
+	if w.Code != http.StatusInternalServerError {
+		t.Errorf("HandleClusterStream(search error) status = %d, want %d", w.Code, http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,190 @@
+package httpapi
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+
+	"ize/internal/algolia"
+	"ize/internal/labeler"
+)
+
+// Facet values described to the model when suggesting queries
+const (
+	suggestValuesPerFacet = 5  // Most common values of each facet
+	suggestMaxFacets      = 20 // Most common values overall
+)
+
+// HandleSuggest asks the labeler for refinements of the query, based on the facet
+// distribution of its results, and returns those that have hits under the request's
+// facet filters. Answers 404 if the labeler cannot suggest queries.
+func (h *SearchHandler) HandleSuggest(w http.ResponseWriter, r *http.Request) {
+	log := h.logger.WithContext(r.Context())
+
+	if r.Method != http.MethodPost {
+		log.Warn("method not allowed", "method", r.Method)
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	suggester, ok := h.labeler.(labeler.Suggester)
+	if !ok {
+		http.Error(w, "Query suggestions not enabled", http.StatusNotFound)
+		return
+	}
+
+	var req SearchRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		log.ErrorWithErr("failed to decode request body", err)
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	req.Query = strings.TrimSpace(req.Query)
+	if req.Query == "" {
+		http.Error(w, "Query is required", http.StatusBadRequest)
+		return
+	}
+
+	log.Debug("processing Suggest request",
+		"query", req.Query,
+		"facet_filters", req.FacetFilters,
+	)
+
+	algoliaResults, err := h.algoliaClient.Search(r.Context(), req.Query, req.FacetFilters)
+	if err != nil {
+		log.ErrorWithErr("algolia search failed for Suggest", err, "query", req.Query)
+		http.Error(w, "Search failed", http.StatusInternalServerError)
+		return
+	}
+
+	candidates, err := suggester.SuggestQueries(r.Context(), req.Query, h.suggestFacets(algoliaResults))
+	if errors.Is(err, labeler.ErrBudgetExceeded) {
+		log.Warn("daily labeling budget exceeded, no query suggestions", "query", req.Query)
+		http.Error(w, "Daily labeling budget exceeded", http.StatusServiceUnavailable)
+		return
+	}
+	if err != nil {
+		log.ErrorWithErr("query suggestion failed", err, "query", req.Query)
+		http.Error(w, "Suggestion generation failed", http.StatusInternalServerError)
+		return
+	}
+
+	response := SuggestResponse{
+		Query:       req.Query,
+		Suggestions: h.validateSuggestions(r.Context(), candidates, req.FacetFilters),
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		log.ErrorWithErr("failed to encode Suggest response", err, "query", req.Query)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	log.Info("Suggest request completed successfully",
+		"query", req.Query,
+		"candidate_count", len(candidates),
+		"suggestion_count", len(response.Suggestions),
+	)
+}
+
+// suggestFacets describes the most common facet values of the results, with their
+// share of the total hits, for the labeler
+func (h *SearchHandler) suggestFacets(results *algolia.SearchResult) []labeler.FacetInfo {
+	displayNames := make(map[string]string, len(h.facetMeta))
+	for _, meta := range h.facetMeta {
+		displayNames[meta.Field] = meta.DisplayName
+	}
+
+	type facetCount struct {
+		info  labeler.FacetInfo
+		count int32
+	}
+	// byCount sorts most common first, breaking ties by value so prompts are stable
+	byCount := func(counts []facetCount) {
+		sort.Slice(counts, func(i, j int) bool {
+			if counts[i].count != counts[j].count {
+				return counts[i].count > counts[j].count
+			}
+			if counts[i].info.Name != counts[j].info.Name {
+				return counts[i].info.Name < counts[j].info.Name
+			}
+			return counts[i].info.Value < counts[j].info.Value
+		})
+	}
+
+	var top []facetCount
+	for name, values := range results.Facets {
+		counts := make([]facetCount, 0, len(values))
+		for value, count := range values {
+			var percentage float64
+			if results.TotalHits > 0 {
+				percentage = float64(count) / float64(results.TotalHits) * 100
+			}
+			counts = append(counts, facetCount{
+				info: labeler.FacetInfo{
+					Name:        name,
+					DisplayName: displayNames[name],
+					Value:       value,
+					Percentage:  percentage,
+				},
+				count: count,
+			})
+		}
+		byCount(counts)
+		if len(counts) > suggestValuesPerFacet {
+			counts = counts[:suggestValuesPerFacet]
+		}
+		top = append(top, counts...)
+	}
+	byCount(top)
+	if len(top) > suggestMaxFacets {
+		top = top[:suggestMaxFacets]
+	}
+
+	facets := make([]labeler.FacetInfo, len(top))
+	for i, f := range top {
+		facets[i] = f.info
+	}
+	return facets
+}
+
+// validateSuggestions runs each suggested query with hitsPerPage=0 under facetFilters
+// and keeps, in order, those with hits. Suggestions whose query fails are dropped.
+func (h *SearchHandler) validateSuggestions(ctx context.Context, candidates []string, facetFilters [][]string) []QuerySuggestion {
+	log := h.logger.WithContext(ctx)
+
+	hits := make([]int, len(candidates))
+	var wg sync.WaitGroup
+	for i, candidate := range candidates {
+		wg.Add(1)
+		go func(i int, candidate string) {
+			defer wg.Done()
+			result, err := h.algoliaClient.SearchPage(ctx, candidate, facetFilters, 0, 0)
+			if err != nil {
+				log.Warn("suggestion validation query failed, dropping suggestion",
+					"suggestion", candidate,
+					"error", err,
+				)
+				return
+			}
+			// Each goroutine writes only its own count
+			hits[i] = result.TotalHits
+		}(i, candidate)
+	}
+	wg.Wait()
+
+	suggestions := make([]QuerySuggestion, 0, len(candidates))
+	for i, candidate := range candidates {
+		if hits[i] == 0 {
+			log.Debug("dropping suggestion without hits", "suggestion", candidate)
+			continue
+		}
+		suggestions = append(suggestions, QuerySuggestion{Query: candidate, NbHits: hits[i]})
+	}
+	return suggestions
+}
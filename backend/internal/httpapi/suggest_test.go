@@ -0,0 +1,136 @@
+package httpapi
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"ize/internal/algolia"
+	"ize/internal/labeler"
+	"ize/internal/logger"
+)
+
+// suggestLabeler suggests fixed queries and records the facets it was given
+type suggestLabeler struct {
+	facetLabeler
+	suggestions []string
+	err         error
+	facets      []labeler.FacetInfo
+}
+
+func (l *suggestLabeler) SuggestQueries(ctx context.Context, query string, facets []labeler.FacetInfo) ([]string, error) {
+	l.facets = facets
+	return l.suggestions, l.err
+}
+
+func newSuggestTestHandler(l labeler.Labeler) *SearchHandler {
+	return &SearchHandler{
+		algoliaClient: &mockAlgoliaClient{
+			searchFunc: func(ctx context.Context, query string, facetFilters [][]string) (*algolia.SearchResult, error) {
+				return &algolia.SearchResult{
+					TotalHits: 200,
+					Facets: map[string]map[string]int32{
+						"brand":    {"Sony": 80, "Bose": 60, "JBL": 10},
+						"category": {"Headphones": 150},
+					},
+				}, nil
+			},
+			searchPageFunc: func(ctx context.Context, query string, facetFilters [][]string, page, hitsPerPage int) (*algolia.SearchResult, error) {
+				switch query {
+				case "sony headphones":
+					return &algolia.SearchResult{TotalHits: 80}, nil
+				case "broken":
+					return nil, fmt.Errorf("algolia down")
+				}
+				return &algolia.SearchResult{TotalHits: 0}, nil
+			},
+		},
+		labeler:   l,
+		logger:    logger.Default(),
+		facetMeta: []FacetMeta{{Field: "brand", DisplayName: "Brand"}},
+	}
+}
+
+func postSuggest(handler *SearchHandler, body string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	handler.HandleSuggest(w, httptest.NewRequest(http.MethodPost, "/api/suggest", strings.NewReader(body)))
+	return w
+}
+
+func TestSearchHandler_HandleSuggest(t *testing.T) {
+	l := &suggestLabeler{suggestions: []string{"wireless earbuds", "sony headphones", "broken"}}
+	body, _ := json.Marshal(SearchRequest{Query: "headphones"})
+	w := postSuggest(newSuggestTestHandler(l), string(body))
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("HandleSuggest() status = %d, want %d: %s", w.Code, http.StatusOK, w.Body.String())
+	}
+	var response SuggestResponse
+	if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+
+	// Suggestions without hits or whose query fails are dropped
+	want := []QuerySuggestion{{Query: "sony headphones", NbHits: 80}}
+	if response.Query != "headphones" || !reflect.DeepEqual(response.Suggestions, want) {
+		t.Errorf("HandleSuggest() = %+v, want %+v", response, want)
+	}
+
+	// The labeler sees the most common facet values first, as shares of all hits
+	if len(l.facets) != 4 {
+		t.Fatalf("labeler got %d facet values, want 4", len(l.facets))
+	}
+	first := l.facets[0]
+	if first.Name != "category" || first.Value != "Headphones" || first.Percentage != 75 {
+		t.Errorf("first facet = %+v, want category:Headphones at 75%%", first)
+	}
+	if second := l.facets[1]; second.Value != "Sony" || second.DisplayName != "Brand" {
+		t.Errorf("second facet = %+v, want brand:Sony with its display name", second)
+	}
+}
+
+func TestSearchHandler_HandleSuggest_Errors(t *testing.T) {
+	query, _ := json.Marshal(SearchRequest{Query: "headphones"})
+
+	tests := []struct {
+		name       string
+		labeler    labeler.Labeler
+		method     string
+		body       string
+		wantStatus int
+	}{
+		{"not a suggester", facetLabeler{}, http.MethodPost, string(query), http.StatusNotFound},
+		{"no labeler", nil, http.MethodPost, string(query), http.StatusNotFound},
+		{"wrong method", &suggestLabeler{}, http.MethodGet, "", http.StatusMethodNotAllowed},
+		{"invalid body", &suggestLabeler{}, http.MethodPost, "{", http.StatusBadRequest},
+		{"empty query", &suggestLabeler{}, http.MethodPost, `{"query": "  "}`, http.StatusBadRequest},
+		{"over budget", &suggestLabeler{err: labeler.ErrBudgetExceeded}, http.MethodPost, string(query), http.StatusServiceUnavailable},
+		{"labeler error", &suggestLabeler{err: fmt.Errorf("boom")}, http.MethodPost, string(query), http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := newSuggestTestHandler(tt.labeler)
+			w := httptest.NewRecorder()
+			handler.HandleSuggest(w, httptest.NewRequest(tt.method, "/api/suggest", bytes.NewBufferString(tt.body)))
+			if w.Code != tt.wantStatus {
+				t.Errorf("HandleSuggest() status = %d, want %d", w.Code, tt.wantStatus)
+			}
+		})
+	}
+
+	handler := newSuggestTestHandler(&suggestLabeler{})
+	handler.algoliaClient = &mockAlgoliaClient{
+		searchFunc: func(ctx context.Context, query string, facetFilters [][]string) (*algolia.SearchResult, error) {
+			return nil, fmt.Errorf("algolia down")
+		},
+	}
+	if w := postSuggest(handler, string(query)); w.Code != http.StatusInternalServerError {
+		t.Errorf("HandleSuggest(search error) status = %d, want %d", w.Code, http.StatusInternalServerError)
+	}
+}
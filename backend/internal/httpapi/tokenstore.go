@@ -0,0 +1,57 @@
+package httpapi
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"sync"
+)
+
+// Capacities of the in-memory stores behind result tokens and Scatter/Gather sessions
+const (
+	defaultLineageCapacity = 1000
+	defaultSessionCapacity = 100
+)
+
+// tokenStore keeps recent server-side state for clients, keyed by a random token
+// handed out with the response (e.g. cluster identities behind a resultToken).
+// The oldest entries are evicted first once capacity is reached.
+type tokenStore[T any] struct {
+	mu       sync.Mutex
+	capacity int
+	entries  map[string]T
+	order    []string // Tokens in insertion order
+}
+
+func newTokenStore[T any](capacity int) *tokenStore[T] {
+	return &tokenStore[T]{
+		capacity: capacity,
+		entries:  make(map[string]T),
+	}
+}
+
+// get returns the value for token; ok is false if it is unknown or was evicted
+func (s *tokenStore[T]) get(token string) (T, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	value, ok := s.entries[token]
+	return value, ok
+}
+
+// put stores a value under a new random token
+func (s *tokenStore[T]) put(value T) (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	token := hex.EncodeToString(b)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.entries[token] = value
+	s.order = append(s.order, token)
+	for len(s.order) > s.capacity {
+		delete(s.entries, s.order[0])
+		s.order = s.order[1:]
+	}
+	return token, nil
+}
@@ -0,0 +1,35 @@
+package httpapi
+
+import (
+	"testing"
+
+	"ize/internal/ize"
+)
+
+func TestTokenStore(t *testing.T) {
+	store := newTokenStore[*ize.ClusterLineage](2)
+
+	tokens := make([]string, 3)
+	for i := range tokens {
+		token, err := store.put(&ize.ClusterLineage{NextID: i})
+		if err != nil {
+			t.Fatalf("put() error = %v", err)
+		}
+		tokens[i] = token
+	}
+
+	if tokens[0] == tokens[1] {
+		t.Error("put() returned the same token twice")
+	}
+	if _, ok := store.get(tokens[0]); ok {
+		t.Error("get() returned the oldest entry after it should have been evicted")
+	}
+	for i := 1; i < 3; i++ {
+		if lineage, ok := store.get(tokens[i]); !ok || lineage.NextID != i {
+			t.Errorf("get(tokens[%d]) = %+v, %v, want NextID %d", i, lineage, ok, i)
+		}
+	}
+	if _, ok := store.get("unknown"); ok {
+		t.Error("get() found an unknown token")
+	}
+}
@@ -3,19 +3,28 @@ package ize
 import (
 	"fmt"
 	"sort"
+	"time"
 
 	"ize/internal/algolia"
+	"ize/internal/config"
 	"ize/internal/logger"
 )
 
 // ClusterGroup represents a cluster of items with similar facet profiles
 type ClusterGroup struct {
-	Name        string        // LLM-generated label (or fallback)
-	Items       []Result      // Items in this cluster
-	TopFacets   []FacetCount  // Most common facet:value pairs in this cluster
-	Stats       ClusterStats  // Statistics for LLM labeling
-	Rule        *DecisionList // Filter rule that defines this cluster (nil if not fitted)
-	RuleQuality *RuleQuality  // Quality metrics for the fitted rule (nil if not fitted)
+	ID              string           // Identity kept across refinements (set by StabilizeClusters)
+	Color           int              // Palette index kept with the identity (set by StabilizeClusters)
+	Stable          bool             // Identity was inherited from the previous result
+	Pinned          bool             // Name was pinned by a curator
+	Name            string           // LLM-generated label (or fallback)
+	Description     string           // One-sentence LLM description (batch labeling only)
+	Items           []Result         // Items in this cluster
+	TopFacets       []FacetCount     // Most common facet:value pairs in this cluster
+	Stats           ClusterStats     // Statistics for LLM labeling
+	Rule            *DecisionList    // Filter rule that defines this cluster (nil if not fitted)
+	RuleQuality     *RuleQuality     // Quality metrics for the fitted rule (nil if not fitted)
+	TopTerms        []string         // Most characteristic text terms (topic groupings only)
+	RuleDiagnostics *RuleDiagnostics // Rejected rule candidates (nil unless fitted with a search objective)
 }
 
 // FacetCount represents a facet:value pair with its count and percentage
@@ -37,6 +46,13 @@ type ClusterResult struct {
 	Groups       []ClusterGroup
 	OtherGroup   []Result
 	ClusterCount int // The selected k value
+
+	// RepeatedItems maps the ID of each item that appears in more than one group
+	// to the number of groups containing it (only possible with AssignOverlapping)
+	RepeatedItems map[string]int
+
+	// Explanations maps each item ID to why it is in its groups (only with ClusterOptions.Explain)
+	Explanations map[string]ItemExplanation
 }
 
 // FacetSet represents an item's facets as a set of "facetName:facetValue" strings
@@ -45,9 +61,88 @@ type FacetSet map[string]bool
 // Minimum cluster size - clusters smaller than this go to "Other"
 const minClusterSize = 2
 
+// ClusterOptions tunes ProcessClusterWithOptions. The zero value gives the default
+// exact clustering used by ProcessCluster.
+type ClusterOptions struct {
+	// ApproximateThreshold is the number of hits at or above which pairwise distances
+	// are approximated with a MinHash LSH neighbor graph instead of a dense matrix.
+	// Zero disables approximation.
+	ApproximateThreshold int
+	// LSH controls the accuracy/speed tradeoff of the approximation
+	LSH LSHConfig
+	// Distance selects the pairwise distance metric (default DistanceJaccard)
+	Distance DistanceMetric
+	// FacetWeights scales the informativeness of each facet for DistanceWeightedJaccard,
+	// keyed by facet name. Facets not listed have weight 1.
+	FacetWeights map[string]float64
+	// TextWeight blends TF-IDF text similarity of names and descriptions into the facet
+	// distance: 0 (default) is facets only, 1 is text only
+	TextWeight float64
+	// Embeddings holds unit-length semantic vectors keyed by objectID, computed by the
+	// caller (see the embedding package). Items without a vector are treated as unrelated.
+	Embeddings map[string][]float64
+	// EmbeddingWeight blends embedding cosine distance into the facet/text distance
+	EmbeddingWeight float64
+	// RuleFit configures how decision list rules are fitted to clusters
+	RuleFit RuleFitOptions
+	// Assignment controls whether items can belong to several clusters (default AssignOverlapping)
+	Assignment AssignmentMode
+	// Explain attaches a per-item membership explanation to the result
+	Explain bool
+}
+
+// useApproximation reports whether n items should be clustered on a neighbor graph
+func (o ClusterOptions) useApproximation(n int) bool {
+	return o.ApproximateThreshold > 0 && n >= o.ApproximateThreshold
+}
+
+// ClusterOptionsFromConfig converts the clustering, embedding and facet config into options
+func ClusterOptionsFromConfig(cfg *config.Config) ClusterOptions {
+	var opts ClusterOptions
+
+	for _, fc := range cfg.Facets {
+		if fc.Weight > 0 {
+			if opts.FacetWeights == nil {
+				opts.FacetWeights = make(map[string]float64)
+			}
+			opts.FacetWeights[fc.Field] = fc.Weight
+		}
+	}
+
+	if cc := cfg.Clustering; cc != nil {
+		opts.ApproximateThreshold = cc.LSHMinItems
+		opts.LSH = LSHConfig{
+			Bands: cc.LSHBands,
+			Rows:  cc.LSHRows,
+		}
+		opts.Distance = DistanceMetric(cc.Distance)
+		opts.TextWeight = cc.TextWeight
+		opts.RuleFit = RuleFitOptions{
+			Objective:          RuleObjective(cc.RuleObjective),
+			Beta:               cc.RuleBeta,
+			BeamWidth:          cc.RuleBeamWidth,
+			ExclusivityPenalty: cc.RuleExclusivityPenalty,
+			Exact:              cc.RuleExact,
+			ExactBudget:        time.Duration(cc.RuleExactBudgetMs) * time.Millisecond,
+		}
+		opts.Assignment = AssignmentMode(cc.Assignment)
+	}
+
+	if cfg.Embedding != nil {
+		opts.EmbeddingWeight = cfg.Embedding.Weight
+	}
+
+	return opts
+}
+
 // ProcessCluster implements facet-space clustering using Jaccard similarity
 // and agglomerative hierarchical clustering with silhouette-based k selection
 func ProcessCluster(query string, algoliaResults *algolia.SearchResult, log *logger.Logger) (*ClusterResult, error) {
+	return ProcessClusterWithOptions(query, algoliaResults, ClusterOptions{}, log)
+}
+
+// ProcessClusterWithOptions is ProcessCluster with tunable options
+func ProcessClusterWithOptions(query string, algoliaResults *algolia.SearchResult, opts ClusterOptions, log *logger.Logger) (*ClusterResult, error) {
 	if log == nil {
 		log = logger.Default()
 	}
@@ -73,15 +168,12 @@ func ProcessCluster(query string, algoliaResults *algolia.SearchResult, log *log
 	log.Debug("ProcessCluster: extracted facet sets", "total_items", totalItems)
 
 	// Handle edge cases
-	if result := handleEdgeCases(allItems, facetSets, log); result != nil {
+	if result := handleEdgeCases(allItems, facetSets, opts, log); result != nil {
 		return result, nil
 	}
 
-	// Build distance matrix and find optimal clustering
-	distMatrix := buildDistanceMatrix(facetSets)
-	log.Debug("ProcessCluster: built distance matrix", "matrix_size", len(distMatrix))
-
-	optimalK, assignments, silhouetteScores := selectOptimalK(distMatrix, facetSets, log)
+	// Build distances and find optimal clustering
+	optimalK, assignments, silhouetteScores := clusterBySimilarity(allItems, facetSets, opts, log)
 	logSilhouetteScores(log, silhouetteScores, optimalK)
 
 	// Build cluster groups from similarity clustering
@@ -92,22 +184,69 @@ func ProcessCluster(query string, algoliaResults *algolia.SearchResult, log *log
 	)
 
 	// Fit decision list rules and reassign items based on rules
-	groups = fitAndReassign(groups, allItems, facetSets, log)
+	groups = fitAndReassign(groups, allItems, facetSets, opts, log)
+	repeatedItems := findRepeatedItems(groups)
+
+	var explanations map[string]ItemExplanation
+	if opts.Explain {
+		explanations = explainMemberships(groups, allItems, facetSets, newPairDistance(allItems, facetSets, opts))
+	}
 
 	actualClusterCount := len(groups)
 	log.Info("ProcessCluster: completed",
 		"selected_k", optimalK,
 		"actual_clusters", actualClusterCount,
 		"other_count", len(otherItems),
+		"assignment", string(opts.Assignment.orDefault()),
+		"repeated_items", len(repeatedItems),
 	)
 
 	return &ClusterResult{
-		Groups:       groups,
-		OtherGroup:   otherItems,
-		ClusterCount: actualClusterCount,
+		Groups:        groups,
+		OtherGroup:    otherItems,
+		ClusterCount:  actualClusterCount,
+		RepeatedItems: repeatedItems,
+		Explanations:  explanations,
 	}, nil
 }
 
+// clusterBySimilarity builds pairwise distances (dense or LSH-approximated) and
+// selects the best clustering. With the approximation, candidate pairs come from facet
+// values and, when text or embedding similarity is blended in, from the items' text
+// terms or embeddings as well.
+func clusterBySimilarity(allItems []Result, facetSets []FacetSet, opts ClusterOptions, log *logger.Logger) (int, []int, map[int]float64) {
+	dist := newPairDistance(allItems, facetSets, opts)
+
+	if opts.useApproximation(len(facetSets)) {
+		indexes := []bandIndex{minHashIndex(facetSets, opts.LSH)}
+		if opts.TextWeight > 0 {
+			indexes = append(indexes, minHashIndex(textTermSets(buildTextVectors(allItems)), opts.LSH))
+		}
+		if embeddings := itemEmbeddings(allItems, opts); embeddings != nil {
+			indexes = append(indexes, hyperplaneIndex(embeddings, opts.LSH))
+		}
+		graph := buildCandidateGraph(len(facetSets), dist, indexes...)
+		log.Debug("ProcessCluster: built LSH neighbor graph",
+			"items", graph.n,
+			"edges", graph.edgeCount(),
+			"lsh", describeLSH(opts.LSH),
+			"distance", opts.Distance,
+			"text_weight", opts.TextWeight,
+			"embedding_weight", opts.EmbeddingWeight,
+		)
+		return selectOptimalKGraph(graph, log)
+	}
+
+	distMatrix := buildDistanceMatrixWith(len(facetSets), dist)
+	log.Debug("ProcessCluster: built distance matrix",
+		"matrix_size", len(distMatrix),
+		"distance", opts.Distance,
+		"text_weight", opts.TextWeight,
+		"embedding_weight", opts.EmbeddingWeight,
+	)
+	return selectOptimalK(distMatrix, facetSets, log)
+}
+
 // hitsCount safely returns the number of hits
 func hitsCount(results *algolia.SearchResult) int {
 	if results == nil {
@@ -134,8 +273,9 @@ func extractItemsAndFacets(algoliaResults *algolia.SearchResult) ([]Result, []Fa
 	return allItems, facetSets
 }
 
-// handleEdgeCases checks for conditions that prevent clustering
-func handleEdgeCases(allItems []Result, facetSets []FacetSet, log *logger.Logger) *ClusterResult {
+// handleEdgeCases checks for conditions that prevent clustering. Items without facets
+// can still be clustered when text or embedding similarity is blended in.
+func handleEdgeCases(allItems []Result, facetSets []FacetSet, opts ClusterOptions, log *logger.Logger) *ClusterResult {
 	if len(allItems) < 2 {
 		log.Debug("ProcessCluster: too few items for clustering")
 		return &ClusterResult{
@@ -145,7 +285,7 @@ func handleEdgeCases(allItems []Result, facetSets []FacetSet, log *logger.Logger
 		}
 	}
 
-	if !hasAnyFacets(facetSets) {
+	if !hasAnyFacets(facetSets) && opts.TextWeight <= 0 && itemEmbeddings(allItems, opts) == nil {
 		log.Debug("ProcessCluster: no items have facets, returning all as Other")
 		return &ClusterResult{
 			Groups:       []ClusterGroup{},
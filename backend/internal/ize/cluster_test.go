@@ -2,9 +2,11 @@ package ize
 
 import (
 	"ize/internal/algolia"
+	"ize/internal/config"
 	"ize/internal/logger"
 	"math"
 	"testing"
+	"time"
 )
 
 func TestProcessCluster_EmptyResults(t *testing.T) {
@@ -503,6 +505,16 @@ func TestDecisionList_ToAlgoliaFilter(t *testing.T) {
 			},
 			expected: [][]string{{"brand:Samsung", "brand:LG"}, {"color:Black"}},
 		},
+		{
+			name: "negated clause",
+			rule: DecisionList{
+				Clauses: []Clause{
+					{FacetName: "category", Values: []string{"Laptops"}},
+					{FacetName: "brand", Values: []string{"Apple", "Dell"}, Negated: true},
+				},
+			},
+			expected: [][]string{{"category:Laptops"}, {"brand:-Apple"}, {"brand:-Dell"}},
+		},
 	}
 
 	for _, tt := range tests {
@@ -527,6 +539,40 @@ func TestDecisionList_ToAlgoliaFilter(t *testing.T) {
 	}
 }
 
+func TestParseAlgoliaFilter(t *testing.T) {
+	rule := DecisionList{Clauses: []Clause{
+		{FacetName: "category", Values: []string{"Laptops"}},
+		{FacetName: "brand", Values: []string{"Samsung", "LG"}},
+		{FacetName: "color", Values: []string{"Red"}, Negated: true},
+	}}
+
+	parsed, err := ParseAlgoliaFilter(rule.ToAlgoliaFilter())
+	if err != nil {
+		t.Fatalf("ParseAlgoliaFilter() error = %v", err)
+	}
+	if parsed.String() != rule.String() {
+		t.Errorf("ParseAlgoliaFilter() = %q, want %q", parsed.String(), rule.String())
+	}
+
+	// Values may contain colons; only the first separates the facet
+	parsed, err = ParseAlgoliaFilter([][]string{{"time:12:00"}})
+	if err != nil || parsed.Clauses[0].FacetName != "time" || parsed.Clauses[0].Values[0] != "12:00" {
+		t.Errorf("ParseAlgoliaFilter(time:12:00) = %+v, %v", parsed, err)
+	}
+
+	invalid := [][][]string{
+		{{"brand"}},
+		{{"brand:"}},
+		{{"brand:Samsung", "color:Red"}},
+		{{"brand:-Apple", "brand:-Dell"}},
+	}
+	for _, filters := range invalid {
+		if _, err := ParseAlgoliaFilter(filters); err == nil {
+			t.Errorf("ParseAlgoliaFilter(%v) error = nil, want error", filters)
+		}
+	}
+}
+
 func TestDecisionList_Matches(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -614,6 +660,38 @@ func TestDecisionList_Matches(t *testing.T) {
 			facetSet: FacetSet{"brand:LG": true, "color:Black": true, "size:Large": true},
 			expected: true,
 		},
+		{
+			name: "negated clause - value absent",
+			rule: DecisionList{
+				Clauses: []Clause{
+					{FacetName: "category", Values: []string{"Laptops"}},
+					{FacetName: "brand", Values: []string{"Apple"}, Negated: true},
+				},
+			},
+			facetSet: FacetSet{"category:Laptops": true, "brand:Dell": true},
+			expected: true,
+		},
+		{
+			name: "negated clause - value present",
+			rule: DecisionList{
+				Clauses: []Clause{
+					{FacetName: "category", Values: []string{"Laptops"}},
+					{FacetName: "brand", Values: []string{"Apple"}, Negated: true},
+				},
+			},
+			facetSet: FacetSet{"category:Laptops": true, "brand:Apple": true},
+			expected: false,
+		},
+		{
+			name: "negated clause - any excluded value present",
+			rule: DecisionList{
+				Clauses: []Clause{
+					{FacetName: "brand", Values: []string{"Apple", "Dell"}, Negated: true},
+				},
+			},
+			facetSet: FacetSet{"brand:Dell": true},
+			expected: false,
+		},
 	}
 
 	for _, tt := range tests {
@@ -665,6 +743,17 @@ func TestDecisionList_String(t *testing.T) {
 			},
 			expected: "brand:Samsung AND color:Black",
 		},
+		{
+			name: "negated clauses",
+			rule: DecisionList{
+				Clauses: []Clause{
+					{FacetName: "category", Values: []string{"Laptops"}},
+					{FacetName: "brand", Values: []string{"Apple"}, Negated: true},
+					{FacetName: "color", Values: []string{"Red", "Pink"}, Negated: true},
+				},
+			},
+			expected: "category:Laptops AND NOT brand:Apple AND NOT (color:Red OR color:Pink)",
+		},
 	}
 
 	for _, tt := range tests {
@@ -711,6 +800,67 @@ func TestFitDecisionList_BasicCase(t *testing.T) {
 	}
 }
 
+func TestFitDecisionList_NegatedClause(t *testing.T) {
+	// The cluster is "laptops except Apple": the positive brands are common on phones
+	// too (no lift), so only excluding brand:Apple separates it from the Apple laptops
+	facetSets := []FacetSet{
+		{"category:Laptops": true, "brand:Dell": true},
+		{"category:Laptops": true, "brand:Lenovo": true},
+		{"category:Laptops": true, "brand:HP": true},
+		{"category:Laptops": true, "brand:Asus": true},
+		{"category:Laptops": true, "brand:Apple": true},
+		{"category:Laptops": true, "brand:Apple": true},
+	}
+	for _, brand := range []string{"Dell", "Lenovo", "HP", "Asus"} {
+		for i := 0; i < 2; i++ {
+			facetSets = append(facetSets, FacetSet{"category:Phones": true, "brand:" + brand: true})
+		}
+	}
+	positiveIndices := []int{0, 1, 2, 3}
+
+	rule, quality := fitDecisionList(positiveIndices, facetSets, logger.Default())
+
+	hasNegation := false
+	for _, clause := range rule.Clauses {
+		if clause.Negated {
+			hasNegation = true
+		}
+	}
+	if !hasNegation {
+		t.Errorf("fitDecisionList() rule = %q, want a negated clause", rule.String())
+	}
+	if quality.Recall != 1.0 {
+		t.Errorf("fitDecisionList() recall = %.3f, want 1.0", quality.Recall)
+	}
+	if quality.Precision != 1.0 {
+		t.Errorf("fitDecisionList() precision = %.3f, want 1.0 (rule %q)", quality.Precision, rule.String())
+	}
+}
+
+func TestFitDecisionList_NoNegationThatHurtsRecall(t *testing.T) {
+	// Every brand among the matched negatives also appears on a positive,
+	// so no value can be excluded without losing recall
+	facetSets := []FacetSet{
+		{"category:Laptops": true, "brand:Dell": true},
+		{"category:Laptops": true, "brand:Apple": true},
+		{"category:Laptops": true, "brand:Dell": true},
+		{"category:Laptops": true, "brand:Apple": true},
+		{"category:Phones": true, "brand:Samsung": true},
+	}
+	positiveIndices := []int{0, 1}
+
+	rule, quality := fitDecisionList(positiveIndices, facetSets, logger.Default())
+
+	for _, clause := range rule.Clauses {
+		if clause.Negated {
+			t.Errorf("fitDecisionList() rule = %q, want no negated clause", rule.String())
+		}
+	}
+	if quality.Recall != 1.0 {
+		t.Errorf("fitDecisionList() recall = %.3f, want 1.0", quality.Recall)
+	}
+}
+
 func TestFitDecisionList_EmptyPositives(t *testing.T) {
 	facetSets := []FacetSet{
 		{"brand:A": true},
@@ -797,3 +947,311 @@ func TestProcessCluster_HasRules(t *testing.T) {
 		}
 	}
 }
+
+// Tests for weighted Jaccard distance
+
+func TestComputeTokenWeights(t *testing.T) {
+	facetSets := []FacetSet{
+		{"brand:Sony": true, "stock:in": true},
+		{"brand:LG": true, "stock:in": true},
+		{"brand:LG": true, "stock:in": true},
+		{"brand:Sony": true, "stock:in": true},
+	}
+
+	weights := computeTokenWeights(facetSets, nil)
+
+	// Universal value keeps the smallest weight, ln(1 + 1)
+	if math.Abs(weights["stock:in"]-math.Log(2)) > 0.0001 {
+		t.Errorf("computeTokenWeights() stock:in = %f, want %f", weights["stock:in"], math.Log(2))
+	}
+	// Value on half the items has idf ln(1 + 2)
+	if math.Abs(weights["brand:Sony"]-math.Log(3)) > 0.0001 {
+		t.Errorf("computeTokenWeights() brand:Sony = %f, want %f", weights["brand:Sony"], math.Log(3))
+	}
+
+	// Per-facet weights scale the idf
+	scaled := computeTokenWeights(facetSets, map[string]float64{"brand": 3})
+	if math.Abs(scaled["brand:Sony"]-3*math.Log(3)) > 0.0001 {
+		t.Errorf("computeTokenWeights() with facet weight brand:Sony = %f, want %f", scaled["brand:Sony"], 3*math.Log(3))
+	}
+
+	// Items sharing only values found on every item are still similar
+	if d := weightedJaccardDistance(FacetSet{"stock:in": true}, FacetSet{"stock:in": true}, weights); d != 0 {
+		t.Errorf("weightedJaccardDistance() of identical universal values = %f, want 0", d)
+	}
+}
+
+func TestWeightedJaccardDistance(t *testing.T) {
+	weights := map[string]float64{
+		"brand:Sony": 2.0,
+		"brand:LG":   2.0,
+		"stock:in":   0.0,
+		"color:Red":  1.0,
+	}
+
+	tests := []struct {
+		name     string
+		a        FacetSet
+		b        FacetSet
+		expected float64
+	}{
+		{
+			name:     "only universal value shared",
+			a:        FacetSet{"brand:Sony": true, "stock:in": true},
+			b:        FacetSet{"brand:LG": true, "stock:in": true},
+			expected: 1.0, // Shared value has no weight
+		},
+		{
+			name:     "identical",
+			a:        FacetSet{"brand:Sony": true, "color:Red": true},
+			b:        FacetSet{"brand:Sony": true, "color:Red": true},
+			expected: 0.0,
+		},
+		{
+			name:     "distinctive value shared",
+			a:        FacetSet{"brand:Sony": true, "color:Red": true},
+			b:        FacetSet{"brand:Sony": true, "stock:in": true},
+			expected: 1.0 - 2.0/3.0, // intersection=2, union=3
+		},
+		{
+			name:     "identical without weight",
+			a:        FacetSet{"stock:in": true},
+			b:        FacetSet{"stock:in": true},
+			expected: 0.0,
+		},
+		{
+			name:     "different without weight",
+			a:        FacetSet{"stock:in": true},
+			b:        FacetSet{"stock:out": true},
+			expected: 1.0,
+		},
+		{
+			name:     "both empty",
+			a:        FacetSet{},
+			b:        FacetSet{},
+			expected: 1.0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := weightedJaccardDistance(tt.a, tt.b, weights)
+			if math.Abs(result-tt.expected) > 0.0001 {
+				t.Errorf("weightedJaccardDistance() = %f, want %f", result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestProcessClusterWithOptions_WeightedJaccard(t *testing.T) {
+	// Every item is in stock; brand is the only distinguishing trait
+	algoliaResults := &algolia.SearchResult{
+		Hits: []algolia.Hit{
+			{ObjectID: "1", Facets: map[string]interface{}{"brand": "Sony", "stock": "in", "color": "Black"}},
+			{ObjectID: "2", Facets: map[string]interface{}{"brand": "Sony", "stock": "in", "color": "White"}},
+			{ObjectID: "3", Facets: map[string]interface{}{"brand": "Sony", "stock": "in", "color": "Silver"}},
+			{ObjectID: "4", Facets: map[string]interface{}{"brand": "LG", "stock": "in", "color": "Black"}},
+			{ObjectID: "5", Facets: map[string]interface{}{"brand": "LG", "stock": "in", "color": "White"}},
+			{ObjectID: "6", Facets: map[string]interface{}{"brand": "LG", "stock": "in", "color": "Silver"}},
+		},
+	}
+
+	opts := ClusterOptions{
+		Distance:     DistanceWeightedJaccard,
+		FacetWeights: map[string]float64{"brand": 2},
+	}
+	result, err := ProcessClusterWithOptions("test", algoliaResults, opts, logger.Default())
+	if err != nil {
+		t.Fatalf("ProcessClusterWithOptions() error = %v", err)
+	}
+
+	if len(result.Groups) != 2 {
+		t.Fatalf("ProcessClusterWithOptions() groups = %d, want 2 (one per brand)", len(result.Groups))
+	}
+	for i, group := range result.Groups {
+		if group.Rule == nil || len(group.Rule.Clauses) == 0 || group.Rule.Clauses[0].FacetName != "brand" {
+			t.Errorf("group %d rule = %v, want a brand rule", i, group.Rule)
+		}
+	}
+}
+
+func TestReassignItemsByRules_AssignmentModes(t *testing.T) {
+	allItems := []Result{{ID: "phone"}, {ID: "apple-phone"}, {ID: "apple-watch"}, {ID: "shirt"}}
+	facetSets := []FacetSet{
+		{"category:Phones": true, "brand:Samsung": true},
+		{"category:Phones": true, "brand:Apple": true},
+		{"category:Watches": true, "brand:Apple": true},
+		{"category:Shirts": true, "brand:Nike": true},
+	}
+	clusterRules := []clusterRuleInfo{
+		{
+			name:    "phones",
+			rule:    &DecisionList{Clauses: []Clause{{FacetName: "category", Values: []string{"Phones"}}}},
+			quality: &RuleQuality{Precision: 0.6, F1: 0.7},
+		},
+		{
+			name:    "apple",
+			rule:    &DecisionList{Clauses: []Clause{{FacetName: "brand", Values: []string{"Apple"}}}},
+			quality: &RuleQuality{Precision: 0.9, F1: 0.8},
+		},
+	}
+
+	ids := func(items []Result) []string {
+		var out []string
+		for _, item := range items {
+			out = append(out, item.ID)
+		}
+		return out
+	}
+
+	tests := []struct {
+		mode     AssignmentMode
+		expected [][]string
+	}{
+		{AssignOverlapping, [][]string{{"phone", "apple-phone"}, {"apple-phone", "apple-watch"}}},
+		{AssignFirstMatch, [][]string{{"phone", "apple-phone"}, {"apple-watch"}}},
+		{AssignBestMatch, [][]string{{"phone"}, {"apple-phone", "apple-watch"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.mode), func(t *testing.T) {
+			groups := reassignItemsByRules(clusterRules, allItems, facetSets, tt.mode)
+			for i, group := range groups {
+				if got := ids(group.Items); !equalStrings(got, tt.expected[i]) {
+					t.Errorf("reassignItemsByRules(%s) group %d = %v, want %v", tt.mode, i, got, tt.expected[i])
+				}
+			}
+
+			repeated := findRepeatedItems(groups)
+			if tt.mode == AssignOverlapping {
+				if repeated["apple-phone"] != 2 || len(repeated) != 1 {
+					t.Errorf("findRepeatedItems() = %v, want map[apple-phone:2]", repeated)
+				}
+			} else if len(repeated) != 0 {
+				t.Errorf("findRepeatedItems() = %v, want empty for %s", repeated, tt.mode)
+			}
+		})
+	}
+}
+
+func TestReassignItemsByRules_NoRule(t *testing.T) {
+	allItems := []Result{{ID: "phone"}, {ID: "apple-phone"}, {ID: "boots"}}
+	facetSets := []FacetSet{
+		{"category:Phones": true},
+		{"category:Phones": true, "brand:Apple": true},
+		{},
+	}
+	clusterRules := []clusterRuleInfo{
+		{name: "phones", rule: &DecisionList{Clauses: []Clause{{FacetName: "category", Values: []string{"Phones"}}}}},
+		{name: "text", members: []int{1, 2}}, // Clustered by text; no rule could be fitted
+	}
+
+	// The cluster without a rule keeps its members and matches nothing else; in
+	// exclusive modes its members are not matched against other rules
+	for mode, want := range map[AssignmentMode][][]string{
+		AssignOverlapping: {{"phone", "apple-phone"}, {"apple-phone", "boots"}},
+		AssignFirstMatch:  {{"phone"}, {"apple-phone", "boots"}},
+	} {
+		groups := reassignItemsByRules(clusterRules, allItems, facetSets, mode)
+		for i, group := range groups {
+			var got []string
+			for _, item := range group.Items {
+				got = append(got, item.ID)
+			}
+			if !equalStrings(got, want[i]) {
+				t.Errorf("reassignItemsByRules(%s) group %d = %v, want %v", mode, i, got, want[i])
+			}
+		}
+	}
+}
+
+func TestRefreshReassignedGroups(t *testing.T) {
+	allItems := []Result{{ID: "phone"}, {ID: "apple-phone"}, {ID: "apple-watch"}}
+	facetSets := []FacetSet{
+		{"category:Phones": true, "brand:Samsung": true},
+		{"category:Phones": true, "brand:Apple": true},
+		{"category:Watches": true, "brand:Apple": true},
+	}
+	itemIndex := map[string]int{"phone": 0, "apple-phone": 1, "apple-watch": 2}
+	stale := &RuleQuality{Precision: 0.9, Recall: 0.9, F1: 0.9}
+	clusterRules := []clusterRuleInfo{
+		{name: "phones", rule: &DecisionList{Clauses: []Clause{{FacetName: "category", Values: []string{"Phones"}}}}, quality: stale},
+		{name: "samsung", rule: &DecisionList{Clauses: []Clause{{FacetName: "brand", Values: []string{"Samsung"}}}}, quality: stale},
+		{name: "apple", rule: &DecisionList{Clauses: []Clause{{FacetName: "brand", Values: []string{"Apple"}}}}, quality: stale},
+	}
+
+	groups := reassignItemsByRules(clusterRules, allItems, facetSets, AssignFirstMatch)
+	groups = refreshReassignedGroups(groups, facetSets, itemIndex, AssignFirstMatch, logger.Default())
+
+	// "samsung" lost its only item to "phones"
+	if len(groups) != 2 || groups[0].Name != "phones" || groups[1].Name != "apple" {
+		t.Fatalf("refreshReassignedGroups() = %d groups, want phones and apple", len(groups))
+	}
+	if q := groups[0].RuleQuality; q.Precision != 1 || q.Recall != 1 {
+		t.Errorf("phones rule quality = %+v, want precision and recall 1", q)
+	}
+	// The apple rule matches two items, but apple-phone went to "phones"
+	if q := groups[1].RuleQuality; q.Precision != 0.5 || q.Recall != 1 || groups[1].Stats.Size != 1 {
+		t.Errorf("apple rule quality = %+v, size %d, want precision 0.5, recall 1, size 1", q, groups[1].Stats.Size)
+	}
+
+	// Overlapping membership keeps the quality fitted on the original clusters
+	groups = reassignItemsByRules(clusterRules, allItems, facetSets, AssignOverlapping)
+	groups = refreshReassignedGroups(groups, facetSets, itemIndex, AssignOverlapping, logger.Default())
+	if len(groups) != 3 || groups[2].RuleQuality != stale {
+		t.Errorf("overlapping refreshReassignedGroups() = %d groups, want 3 with fitted quality", len(groups))
+	}
+}
+
+func TestParseAssignmentMode(t *testing.T) {
+	if mode, err := ParseAssignmentMode(""); err != nil || mode != AssignOverlapping {
+		t.Errorf("ParseAssignmentMode(\"\") = %q, %v, want %q", mode, err, AssignOverlapping)
+	}
+	if mode, err := ParseAssignmentMode("best_match"); err != nil || mode != AssignBestMatch {
+		t.Errorf("ParseAssignmentMode(best_match) = %q, %v, want %q", mode, err, AssignBestMatch)
+	}
+	if _, err := ParseAssignmentMode("random"); err == nil {
+		t.Error("ParseAssignmentMode(random) error = nil, want error")
+	}
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestClusterOptionsFromConfig(t *testing.T) {
+	cfg := &config.Config{
+		Facets: []config.FacetConfig{{Field: "brand", Weight: 2}, {Field: "color"}},
+		Clustering: &config.ClusteringConfig{
+			LSHMinItems:       500,
+			Distance:          "weighted_jaccard",
+			TextWeight:        0.3,
+			RuleExactBudgetMs: 250,
+			Assignment:        "first_match",
+		},
+		Embedding: &config.EmbeddingConfig{Weight: 0.4},
+	}
+
+	opts := ClusterOptionsFromConfig(cfg)
+	if len(opts.FacetWeights) != 1 || opts.FacetWeights["brand"] != 2 {
+		t.Errorf("FacetWeights = %v, want only brand:2", opts.FacetWeights)
+	}
+	if opts.ApproximateThreshold != 500 || opts.Distance != DistanceWeightedJaccard || opts.TextWeight != 0.3 {
+		t.Errorf("options = %+v, want the clustering section", opts)
+	}
+	if opts.RuleFit.ExactBudget != 250*time.Millisecond || opts.Assignment != AssignFirstMatch || opts.EmbeddingWeight != 0.4 {
+		t.Errorf("options = %+v, want the rule budget, assignment and embedding weight", opts)
+	}
+
+	if defaults := ClusterOptionsFromConfig(&config.Config{}); defaults.ApproximateThreshold != 0 || defaults.FacetWeights != nil {
+		t.Errorf("ClusterOptionsFromConfig(empty) = %+v, want zero options", defaults)
+	}
+}
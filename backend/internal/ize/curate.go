@@ -0,0 +1,491 @@
+package ize
+
+import (
+	"errors"
+	"fmt"
+
+	"ize/internal/algolia"
+	"ize/internal/logger"
+)
+
+// MaxSplitParts is the largest number of clusters SplitCluster can cut a cluster into
+const MaxSplitParts = 5
+
+var (
+	// ErrClusterNotFound is returned when a curation refers to an unknown cluster ID
+	ErrClusterNotFound = errors.New("cluster not found")
+	// ErrCannotSplit is returned when a cluster has too few items to split
+	ErrCannotSplit = errors.New("cluster has too few items to split")
+	// ErrInvalidCuration is returned for malformed merge or split arguments, and for
+	// clusters without a rule, which curation cannot keep
+	ErrInvalidCuration = errors.New("invalid curation")
+)
+
+// CuratedCluster is a cluster as edited by a curator. Like automatic clusters, it is
+// defined by its rule: its items are the hits the rule matches.
+type CuratedCluster struct {
+	ID          string
+	Name        string
+	Pinned      bool // Name was set by a curator and is never regenerated
+	Color       int
+	Rule        *DecisionList
+	RuleQuality *RuleQuality // Quality of the rule when it was fitted
+}
+
+// CuratedGrouping is the curated set of clusters for one query
+type CuratedGrouping struct {
+	Query        string
+	FacetFilters [][]string
+	Clusters     []CuratedCluster
+	NextID       int // Counter for IDs of clusters created by merges and splits
+}
+
+// NewCuratedGrouping starts a curation from an automatic clustering (after
+// StabilizeClusters has assigned IDs)
+func NewCuratedGrouping(query string, facetFilters [][]string, result *ClusterResult, nextID int) *CuratedGrouping {
+	clusters := make([]CuratedCluster, len(result.Groups))
+	for i, group := range result.Groups {
+		clusters[i] = CuratedCluster{
+			ID:          group.ID,
+			Name:        group.Name,
+			Color:       group.Color,
+			Rule:        group.Rule,
+			RuleQuality: group.RuleQuality,
+		}
+	}
+	return &CuratedGrouping{
+		Query:        query,
+		FacetFilters: facetFilters,
+		Clusters:     clusters,
+		NextID:       nextID,
+	}
+}
+
+// clone copies the grouping so edits do not affect a stored version
+func (g *CuratedGrouping) clone() *CuratedGrouping {
+	c := *g
+	c.Clusters = append([]CuratedCluster(nil), g.Clusters...)
+	return &c
+}
+
+// index returns the position of the cluster with the given ID, or -1
+func (g *CuratedGrouping) index(id string) int {
+	for i, c := range g.Clusters {
+		if c.ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+// newID returns a fresh cluster ID
+func (g *CuratedGrouping) newID() string {
+	if g.NextID < 1 {
+		g.NextID = 1
+	}
+	id := fmt.Sprintf("c%d", g.NextID)
+	g.NextID++
+	return id
+}
+
+// ApplyCuratedGrouping groups hits by the curated rules. Groups keep the curated IDs,
+// colors and names (all marked Stable); items matching no rule go to Other. A cluster
+// without a rule matches nothing.
+func ApplyCuratedGrouping(query string, algoliaResults *algolia.SearchResult, grouping *CuratedGrouping, opts ClusterOptions, log *logger.Logger) (*ClusterResult, error) {
+	if log == nil {
+		log = logger.Default()
+	}
+	if algoliaResults == nil {
+		algoliaResults = &algolia.SearchResult{}
+	}
+
+	allItems, facetSets := extractItemsAndFacets(algoliaResults)
+	itemIndex := make(map[string]int, len(allItems))
+	for i, item := range allItems {
+		itemIndex[item.ID] = i
+	}
+
+	clusterRules := make([]clusterRuleInfo, len(grouping.Clusters))
+	for i, c := range grouping.Clusters {
+		var rule *DecisionList
+		if hasRule(c.Rule) {
+			rule = c.Rule
+		}
+		clusterRules[i] = clusterRuleInfo{rule: rule, quality: c.RuleQuality, name: c.Name}
+	}
+
+	groups := reassignItemsByRules(clusterRules, allItems, facetSets, opts.Assignment.orDefault())
+	matched := make(map[string]bool)
+	for i := range groups {
+		c := grouping.Clusters[i]
+		groups[i].ID = c.ID
+		groups[i].Color = c.Color
+		groups[i].Stable = true
+		groups[i].Pinned = c.Pinned
+		groups[i].TopFacets = calculateTopFacets(groups[i].Items, facetSets, itemIndex)
+		groups[i].Stats = ClusterStats{
+			Size:      len(groups[i].Items),
+			TopFacets: groups[i].TopFacets,
+		}
+		for _, item := range groups[i].Items {
+			matched[item.ID] = true
+		}
+	}
+
+	otherItems := []Result{}
+	for _, item := range allItems {
+		if !matched[item.ID] {
+			otherItems = append(otherItems, item)
+		}
+	}
+
+	var explanations map[string]ItemExplanation
+	if opts.Explain {
+		explanations = explainMemberships(groups, allItems, facetSets, newPairDistance(allItems, facetSets, opts))
+	}
+
+	log.Info("ApplyCuratedGrouping: completed",
+		"query", query,
+		"clusters", len(groups),
+		"other_count", len(otherItems),
+	)
+
+	return &ClusterResult{
+		Groups:        groups,
+		OtherGroup:    otherItems,
+		ClusterCount:  len(groups),
+		RepeatedItems: findRepeatedItems(groups),
+		Explanations:  explanations,
+	}, nil
+}
+
+// ApplyCuratedRipper groups hits by a curated RIPPER grouping, whose rules must each be a
+// single facet value. As in ProcessRipper, items go to the first group they match and
+// group counts come from Algolia's facet counts when available.
+func ApplyCuratedRipper(query string, algoliaResults *algolia.SearchResult, grouping *CuratedGrouping, log *logger.Logger) (*RipperResult, error) {
+	if log == nil {
+		log = logger.Default()
+	}
+	if algoliaResults == nil {
+		algoliaResults = &algolia.SearchResult{}
+	}
+
+	if err := grouping.ValidateRipper(); err != nil {
+		return nil, err
+	}
+
+	allItems, facetSets := extractItemsAndFacets(algoliaResults)
+	assigned := make([]bool, len(allItems))
+	groups := make([]RipperGroup, len(grouping.Clusters))
+	for i, c := range grouping.Clusters {
+		clause := c.Rule.Clauses[0]
+		group := RipperGroup{
+			FacetName:  clause.FacetName,
+			FacetValue: clause.Values[0],
+			Name:       c.Name,
+			Items:      []Result{},
+		}
+		for idx, fs := range facetSets {
+			if !c.Rule.Matches(fs) {
+				continue
+			}
+			group.TotalCount++
+			if !assigned[idx] {
+				assigned[idx] = true
+				group.Items = append(group.Items, allItems[idx])
+			}
+		}
+		if count, ok := algoliaResults.Facets[group.FacetName][group.FacetValue]; ok {
+			group.TotalCount = int(count)
+		}
+		groups[i] = group
+	}
+
+	otherItems := []Result{}
+	for idx, item := range allItems {
+		if !assigned[idx] {
+			otherItems = append(otherItems, item)
+		}
+	}
+
+	log.Info("ApplyCuratedRipper: completed",
+		"query", query,
+		"groups", len(groups),
+		"other_count", len(otherItems),
+	)
+
+	return &RipperResult{Groups: groups, OtherGroup: otherItems}, nil
+}
+
+// ValidateRipper checks that a grouping can be shown as RIPPER groups: every rule must
+// be a single facet value
+func (g *CuratedGrouping) ValidateRipper() error {
+	for _, c := range g.Clusters {
+		if !isFacetValueRule(c.Rule) {
+			return fmt.Errorf("%w: RIPPER group %s must be a single facet value", ErrInvalidCuration, c.ID)
+		}
+	}
+	return nil
+}
+
+// isFacetValueRule reports whether a rule is a single positive facet value, the only
+// kind of rule a RIPPER group can have
+func isFacetValueRule(rule *DecisionList) bool {
+	return rule != nil && len(rule.Clauses) == 1 && !rule.Clauses[0].Negated && len(rule.Clauses[0].Values) == 1
+}
+
+// MergeClusters replaces two clusters with one whose rule is refitted on the union of
+// their items in algoliaResults. The merged cluster takes the position and color of the
+// first. Returns the new grouping and the merged cluster's ID.
+func MergeClusters(grouping *CuratedGrouping, algoliaResults *algolia.SearchResult, a, b string, opts ClusterOptions, log *logger.Logger) (*CuratedGrouping, string, error) {
+	if log == nil {
+		log = logger.Default()
+	}
+	if a == b {
+		return nil, "", fmt.Errorf("%w: cannot merge a cluster with itself", ErrInvalidCuration)
+	}
+	ia, ib := grouping.index(a), grouping.index(b)
+	if ia < 0 || ib < 0 {
+		return nil, "", ErrClusterNotFound
+	}
+	if err := grouping.requireRules(ia, ib); err != nil {
+		return nil, "", err
+	}
+
+	_, facetSets := extractItemsAndFacets(algoliaResults)
+	members := curatedMembers(grouping, facetSets)
+
+	union := mergeIndices(members[ia], members[ib])
+	var siblings []int
+	for i, indices := range members {
+		if i != ia && i != ib {
+			siblings = append(siblings, indices...)
+		}
+	}
+
+	rule, quality, _ := fitDecisionListWithOptions(union, siblings, facetSets, opts.RuleFit, log)
+
+	merged := grouping.clone()
+	id := merged.newID()
+	merged.Clusters[ia] = CuratedCluster{
+		ID:          id,
+		Name:        curatedFallbackName(rule, merged.Clusters[ia].Name+" & "+merged.Clusters[ib].Name),
+		Color:       merged.Clusters[ia].Color,
+		Rule:        rule,
+		RuleQuality: quality,
+	}
+	merged.Clusters = append(merged.Clusters[:ib], merged.Clusters[ib+1:]...)
+
+	log.Info("MergeClusters: merged",
+		"query", grouping.Query,
+		"clusters", []string{a, b},
+		"merged_id", id,
+		"items", len(union),
+		"rule", rule.String(),
+	)
+
+	return merged, id, nil
+}
+
+// SplitCluster re-cuts the dendrogram of one cluster's items into parts (2 by default)
+// and replaces the cluster with them. Each part's rule is the parent rule ANDed with
+// clauses fitted to tell the part apart from its siblings, so parts stay within the
+// parent. Returns the new grouping and the IDs of the parts.
+func SplitCluster(grouping *CuratedGrouping, algoliaResults *algolia.SearchResult, id string, parts int, opts ClusterOptions, log *logger.Logger) (*CuratedGrouping, []string, error) {
+	if log == nil {
+		log = logger.Default()
+	}
+	if parts == 0 {
+		parts = 2
+	}
+	if parts < 2 || parts > MaxSplitParts {
+		return nil, nil, fmt.Errorf("%w: parts must be between 2 and %d", ErrInvalidCuration, MaxSplitParts)
+	}
+	idx := grouping.index(id)
+	if idx < 0 {
+		return nil, nil, ErrClusterNotFound
+	}
+	if err := grouping.requireRules(idx); err != nil {
+		return nil, nil, err
+	}
+
+	allItems, facetSets := extractItemsAndFacets(algoliaResults)
+	members := curatedMembers(grouping, facetSets)[idx]
+	if len(members) < parts*minClusterSize {
+		return nil, nil, ErrCannotSplit
+	}
+
+	// Dendrogram over the cluster's own items
+	subItems := collectItems(allItems, members)
+	subFacetSets := make([]FacetSet, len(members))
+	for i, m := range members {
+		subFacetSets[i] = facetSets[m]
+	}
+	dist := newPairDistance(subItems, subFacetSets, opts)
+	root := agglomerativeCluster(buildDistanceMatrixWith(len(members), dist))
+	cuts := cutDendrogram(root, parts)
+	for _, cut := range cuts {
+		if len(cut) < minClusterSize {
+			return nil, nil, ErrCannotSplit
+		}
+	}
+
+	parent := grouping.Clusters[idx]
+	split := grouping.clone()
+	newClusters := make([]CuratedCluster, len(cuts))
+	ids := make([]string, len(cuts))
+	for p, cut := range cuts {
+		// Fit within the parent's items, so the rule only has to separate the parts
+		var siblings []int
+		for q, other := range cuts {
+			if q != p {
+				siblings = append(siblings, other...)
+			}
+		}
+		partRule, _, _ := fitDecisionListWithOptions(cut, siblings, subFacetSets, opts.RuleFit, log)
+		rule := andRules(parent.Rule, partRule)
+
+		positives := make([]int, len(cut))
+		for i, c := range cut {
+			positives[i] = members[c]
+		}
+
+		ids[p] = split.newID()
+		color := parent.Color
+		if p > 0 {
+			color = unusedCuratedColor(split.Clusters, newClusters[:p])
+		}
+		newClusters[p] = CuratedCluster{
+			ID:          ids[p],
+			Name:        curatedFallbackName(partRule, fmt.Sprintf("%s (%d)", parent.Name, p+1)),
+			Color:       color,
+			Rule:        rule,
+			RuleQuality: computeRuleQuality(*rule, positives, facetSets),
+		}
+	}
+
+	clusters := make([]CuratedCluster, 0, len(split.Clusters)+len(newClusters)-1)
+	clusters = append(clusters, split.Clusters[:idx]...)
+	clusters = append(clusters, newClusters...)
+	clusters = append(clusters, split.Clusters[idx+1:]...)
+	split.Clusters = clusters
+
+	log.Info("SplitCluster: split",
+		"query", grouping.Query,
+		"cluster", id,
+		"parts", ids,
+		"items", len(members),
+	)
+
+	return split, ids, nil
+}
+
+// RenameCluster pins a curator-chosen name on a cluster
+func RenameCluster(grouping *CuratedGrouping, id, name string) (*CuratedGrouping, error) {
+	if name == "" {
+		return nil, fmt.Errorf("%w: name is empty", ErrInvalidCuration)
+	}
+	idx := grouping.index(id)
+	if idx < 0 {
+		return nil, ErrClusterNotFound
+	}
+	if err := grouping.requireRules(idx); err != nil {
+		return nil, err
+	}
+	renamed := grouping.clone()
+	renamed.Clusters[idx].Name = name
+	renamed.Clusters[idx].Pinned = true
+	return renamed, nil
+}
+
+// requireRules returns ErrInvalidCuration if any of the clusters at the given positions
+// has no rule (e.g., clustered by text without facets): its items cannot be found again
+func (g *CuratedGrouping) requireRules(indices ...int) error {
+	for _, i := range indices {
+		if !hasRule(g.Clusters[i].Rule) {
+			return fmt.Errorf("%w: cluster %s has no rule", ErrInvalidCuration, g.Clusters[i].ID)
+		}
+	}
+	return nil
+}
+
+// hasRule reports whether a rule has clauses; nil and empty rules match nothing in a
+// curated grouping
+func hasRule(rule *DecisionList) bool {
+	return rule != nil && len(rule.Clauses) > 0
+}
+
+// curatedMembers returns the indices of the hits each curated rule matches
+func curatedMembers(grouping *CuratedGrouping, facetSets []FacetSet) [][]int {
+	members := make([][]int, len(grouping.Clusters))
+	for i, c := range grouping.Clusters {
+		if !hasRule(c.Rule) {
+			continue
+		}
+		for idx, fs := range facetSets {
+			if c.Rule.Matches(fs) {
+				members[i] = append(members[i], idx)
+			}
+		}
+	}
+	return members
+}
+
+// mergeIndices returns the sorted union of two sorted index lists
+func mergeIndices(a, b []int) []int {
+	union := make([]int, 0, len(a)+len(b))
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case j >= len(b) || (i < len(a) && a[i] < b[j]):
+			union = append(union, a[i])
+			i++
+		case i >= len(a) || b[j] < a[i]:
+			union = append(union, b[j])
+			j++
+		default:
+			union = append(union, a[i])
+			i++
+			j++
+		}
+	}
+	return union
+}
+
+// andRules concatenates the clauses of two rules, skipping exact duplicates
+func andRules(a, b *DecisionList) *DecisionList {
+	combined := &DecisionList{}
+	seen := make(map[string]bool)
+	for _, rule := range []*DecisionList{a, b} {
+		if rule == nil {
+			continue
+		}
+		for _, clause := range rule.Clauses {
+			key := DecisionList{Clauses: []Clause{clause}}.String()
+			if !seen[key] {
+				seen[key] = true
+				combined.Clauses = append(combined.Clauses, clause)
+			}
+		}
+	}
+	return combined
+}
+
+// curatedFallbackName names a new cluster after its rule, like automatic clusters
+func curatedFallbackName(rule *DecisionList, fallback string) string {
+	if !hasRule(rule) {
+		return fallback
+	}
+	return rule.String()
+}
+
+// unusedCuratedColor returns the lowest color not used by any of the clusters
+func unusedCuratedColor(groups ...[]CuratedCluster) int {
+	used := make(map[int]bool)
+	for _, clusters := range groups {
+		for _, c := range clusters {
+			used[c.Color] = true
+		}
+	}
+	return lowestUnusedColor(used)
+}
@@ -0,0 +1,245 @@
+package ize
+
+import (
+	"errors"
+	"testing"
+
+	"ize/internal/algolia"
+	"ize/internal/logger"
+)
+
+// curationHits has two brands of phones and one brand of shirts
+func curationHits() *algolia.SearchResult {
+	hit := func(id, category, brand string) algolia.Hit {
+		return algolia.Hit{ObjectID: id, Name: id, Facets: map[string]interface{}{"category": category, "brand": brand}}
+	}
+	return &algolia.SearchResult{Hits: []algolia.Hit{
+		hit("p1", "Phones", "Samsung"),
+		hit("p2", "Phones", "Samsung"),
+		hit("p3", "Phones", "Apple"),
+		hit("p4", "Phones", "Apple"),
+		hit("s1", "Shirts", "Nike"),
+		hit("s2", "Shirts", "Nike"),
+		hit("x1", "Hats", "Acme"),
+	}}
+}
+
+func facetRule(facet, value string) *DecisionList {
+	return &DecisionList{Clauses: []Clause{{FacetName: facet, Values: []string{value}}}}
+}
+
+func curationGrouping() *CuratedGrouping {
+	return &CuratedGrouping{
+		Query: "test",
+		Clusters: []CuratedCluster{
+			{ID: "c1", Name: "Samsung", Color: 0, Rule: facetRule("brand", "Samsung")},
+			{ID: "c2", Name: "Apple", Color: 1, Rule: facetRule("brand", "Apple")},
+			{ID: "c3", Name: "Shirts", Color: 2, Rule: facetRule("category", "Shirts")},
+		},
+		NextID: 4,
+	}
+}
+
+func groupItemIDs(group ClusterGroup) []string {
+	ids := make([]string, len(group.Items))
+	for i, item := range group.Items {
+		ids[i] = item.ID
+	}
+	return ids
+}
+
+func TestApplyCuratedGrouping(t *testing.T) {
+	grouping := curationGrouping()
+	grouping.Clusters[2].Pinned = true
+
+	result, err := ApplyCuratedGrouping("test", curationHits(), grouping, ClusterOptions{Explain: true}, logger.Default())
+	if err != nil {
+		t.Fatalf("ApplyCuratedGrouping() error = %v", err)
+	}
+	if len(result.Groups) != 3 {
+		t.Fatalf("ApplyCuratedGrouping() groups = %d, want 3", len(result.Groups))
+	}
+	for i, group := range result.Groups {
+		c := grouping.Clusters[i]
+		if group.ID != c.ID || group.Color != c.Color || group.Name != c.Name || !group.Stable || group.Pinned != c.Pinned {
+			t.Errorf("group %d = %s/%d/%q (stable %v, pinned %v), want curated %+v", i, group.ID, group.Color, group.Name, group.Stable, group.Pinned, c)
+		}
+	}
+	if ids := groupItemIDs(result.Groups[1]); len(ids) != 2 || ids[0] != "p3" || ids[1] != "p4" {
+		t.Errorf("Apple items = %v, want [p3 p4]", ids)
+	}
+	if len(result.OtherGroup) != 1 || result.OtherGroup[0].ID != "x1" {
+		t.Errorf("OtherGroup = %+v, want [x1]", result.OtherGroup)
+	}
+	if len(result.Explanations) != 7 {
+		t.Errorf("Explanations = %d, want 7", len(result.Explanations))
+	}
+}
+
+func TestApplyCuratedGrouping_NoRule(t *testing.T) {
+	// Nil and empty rules both match nothing, so their items go to Other
+	for _, rule := range []*DecisionList{nil, {}} {
+		grouping := curationGrouping()
+		grouping.Clusters[2].Rule = rule
+
+		result, err := ApplyCuratedGrouping("test", curationHits(), grouping, ClusterOptions{}, logger.Default())
+		if err != nil {
+			t.Fatalf("ApplyCuratedGrouping() error = %v", err)
+		}
+		if n := len(result.Groups[2].Items); n != 0 {
+			t.Errorf("rule %v: cluster without a rule has %d items, want 0", rule, n)
+		}
+		if len(result.OtherGroup) != 3 {
+			t.Errorf("rule %v: OtherGroup = %d items, want 3 (shirts and hat)", rule, len(result.OtherGroup))
+		}
+		if len(result.RepeatedItems) != 0 {
+			t.Errorf("rule %v: RepeatedItems = %v, want none", rule, result.RepeatedItems)
+		}
+	}
+}
+
+func TestCurateClusterWithoutRule(t *testing.T) {
+	grouping := curationGrouping()
+	grouping.Clusters[1].Rule = nil
+	grouping.Clusters[2].Rule = &DecisionList{}
+
+	if _, _, err := MergeClusters(grouping, curationHits(), "c1", "c2", ClusterOptions{}, logger.Default()); !errors.Is(err, ErrInvalidCuration) {
+		t.Errorf("MergeClusters(no rule) error = %v, want ErrInvalidCuration", err)
+	}
+	if _, _, err := SplitCluster(grouping, curationHits(), "c3", 2, ClusterOptions{}, logger.Default()); !errors.Is(err, ErrInvalidCuration) {
+		t.Errorf("SplitCluster(empty rule) error = %v, want ErrInvalidCuration", err)
+	}
+	if _, err := RenameCluster(grouping, "c2", "Apple"); !errors.Is(err, ErrInvalidCuration) {
+		t.Errorf("RenameCluster(no rule) error = %v, want ErrInvalidCuration", err)
+	}
+}
+
+func TestMergeClusters(t *testing.T) {
+	grouping := curationGrouping()
+	merged, id, err := MergeClusters(grouping, curationHits(), "c1", "c2", ClusterOptions{}, logger.Default())
+	if err != nil {
+		t.Fatalf("MergeClusters() error = %v", err)
+	}
+	if id != "c4" || merged.NextID != 5 {
+		t.Errorf("MergeClusters() id = %q, NextID = %d, want c4, 5", id, merged.NextID)
+	}
+	if len(merged.Clusters) != 2 || merged.Clusters[0].ID != id || merged.Clusters[1].ID != "c3" {
+		t.Fatalf("MergeClusters() clusters = %+v, want [c4 c3]", merged.Clusters)
+	}
+	if merged.Clusters[0].Color != 0 {
+		t.Errorf("merged color = %d, want 0 (first cluster's)", merged.Clusters[0].Color)
+	}
+	if q := merged.Clusters[0].RuleQuality; q == nil || q.Precision != 1 || q.Recall != 1 {
+		t.Errorf("merged rule quality = %+v, want exact rule for the phones", q)
+	}
+	if len(grouping.Clusters) != 3 {
+		t.Errorf("MergeClusters() modified the input grouping")
+	}
+
+	result, _ := ApplyCuratedGrouping("test", curationHits(), merged, ClusterOptions{}, logger.Default())
+	if n := len(result.Groups[0].Items); n != 4 {
+		t.Errorf("merged cluster items = %d, want 4", n)
+	}
+
+	if _, _, err := MergeClusters(grouping, curationHits(), "c1", "c1", ClusterOptions{}, logger.Default()); !errors.Is(err, ErrInvalidCuration) {
+		t.Errorf("MergeClusters(self) error = %v, want ErrInvalidCuration", err)
+	}
+	if _, _, err := MergeClusters(grouping, curationHits(), "c1", "c9", ClusterOptions{}, logger.Default()); !errors.Is(err, ErrClusterNotFound) {
+		t.Errorf("MergeClusters(unknown) error = %v, want ErrClusterNotFound", err)
+	}
+}
+
+func TestSplitCluster(t *testing.T) {
+	grouping := &CuratedGrouping{
+		Query:    "test",
+		Clusters: []CuratedCluster{{ID: "c1", Name: "Phones", Color: 3, Rule: facetRule("category", "Phones")}},
+		NextID:   2,
+	}
+
+	split, ids, err := SplitCluster(grouping, curationHits(), "c1", 0, ClusterOptions{}, logger.Default())
+	if err != nil {
+		t.Fatalf("SplitCluster() error = %v", err)
+	}
+	if len(ids) != 2 || len(split.Clusters) != 2 {
+		t.Fatalf("SplitCluster() ids = %v, clusters = %d, want 2 parts", ids, len(split.Clusters))
+	}
+	if split.Clusters[0].Color != 3 || split.Clusters[1].Color == 3 {
+		t.Errorf("part colors = %d, %d, want 3 then a different color", split.Clusters[0].Color, split.Clusters[1].Color)
+	}
+
+	result, _ := ApplyCuratedGrouping("test", curationHits(), split, ClusterOptions{}, logger.Default())
+	for i, group := range result.Groups {
+		if len(group.Items) != 2 {
+			t.Errorf("part %d items = %v, want 2 phones of one brand", i, groupItemIDs(group))
+		}
+		for _, item := range group.Items {
+			if item.ID[0] != 'p' {
+				t.Errorf("part %d contains %s, outside the parent cluster", i, item.ID)
+			}
+		}
+	}
+
+	if _, _, err := SplitCluster(grouping, curationHits(), "c1", 3, ClusterOptions{}, logger.Default()); !errors.Is(err, ErrCannotSplit) {
+		t.Errorf("SplitCluster(3 parts of 4 items) error = %v, want ErrCannotSplit", err)
+	}
+	if _, _, err := SplitCluster(grouping, curationHits(), "c1", MaxSplitParts+1, ClusterOptions{}, logger.Default()); !errors.Is(err, ErrInvalidCuration) {
+		t.Errorf("SplitCluster(too many parts) error = %v, want ErrInvalidCuration", err)
+	}
+	if _, _, err := SplitCluster(grouping, curationHits(), "c9", 2, ClusterOptions{}, logger.Default()); !errors.Is(err, ErrClusterNotFound) {
+		t.Errorf("SplitCluster(unknown) error = %v, want ErrClusterNotFound", err)
+	}
+}
+
+func TestRenameCluster(t *testing.T) {
+	grouping := curationGrouping()
+	renamed, err := RenameCluster(grouping, "c2", "Apple phones")
+	if err != nil {
+		t.Fatalf("RenameCluster() error = %v", err)
+	}
+	if c := renamed.Clusters[1]; c.Name != "Apple phones" || !c.Pinned {
+		t.Errorf("renamed cluster = %+v, want pinned name", c)
+	}
+	if grouping.Clusters[1].Name != "Apple" {
+		t.Errorf("RenameCluster() modified the input grouping")
+	}
+	if _, err := RenameCluster(grouping, "c2", ""); !errors.Is(err, ErrInvalidCuration) {
+		t.Errorf("RenameCluster(empty) error = %v, want ErrInvalidCuration", err)
+	}
+}
+
+func TestApplyCuratedRipper(t *testing.T) {
+	hits := curationHits()
+	hits.Facets = map[string]map[string]int32{"category": {"Shirts": 40}}
+	grouping := &CuratedGrouping{
+		Query: "test",
+		Clusters: []CuratedCluster{
+			{ID: "c1", Name: "Shirts first", Rule: facetRule("category", "Shirts")},
+			{ID: "c2", Name: "Samsung", Rule: facetRule("brand", "Samsung")},
+			{ID: "c3", Name: "Nike", Rule: facetRule("brand", "Nike")},
+		},
+	}
+
+	result, err := ApplyCuratedRipper("test", hits, grouping, logger.Default())
+	if err != nil {
+		t.Fatalf("ApplyCuratedRipper() error = %v", err)
+	}
+	if len(result.Groups) != 3 {
+		t.Fatalf("ApplyCuratedRipper() groups = %d, want 3", len(result.Groups))
+	}
+	shirts, nike := result.Groups[0], result.Groups[2]
+	if shirts.Name != "Shirts first" || shirts.FacetName != "category" || shirts.FacetValue != "Shirts" || shirts.TotalCount != 40 {
+		t.Errorf("first group = %+v, want curated Shirts group with Algolia count 40", shirts)
+	}
+	// Nike shirts already went to the first group, but still count towards Nike
+	if len(nike.Items) != 0 || nike.TotalCount != 2 {
+		t.Errorf("Nike group items = %d, count = %d, want 0 items and count 2", len(nike.Items), nike.TotalCount)
+	}
+	if len(result.OtherGroup) != 3 {
+		t.Errorf("OtherGroup = %d items, want 3 (Apple phones and the hat)", len(result.OtherGroup))
+	}
+
+	grouping.Clusters[1].Rule = &DecisionList{Clauses: []Clause{{FacetName: "brand", Values: []string{"Samsung", "Apple"}}}}
+	if _, err := ApplyCuratedRipper("test", hits, grouping, logger.Default()); !errors.Is(err, ErrInvalidCuration) {
+		t.Errorf("ApplyCuratedRipper(multi-value rule) error = %v, want ErrInvalidCuration", err)
+	}
+}
@@ -2,15 +2,32 @@ package ize
 
 import (
 	"fmt"
+	"sort"
+	"strings"
 
 	"ize/internal/logger"
 )
 
 // Clause represents a single facet with one or more values (OR of values)
 // e.g., brand:Samsung OR brand:LG
+// A negated clause matches items that have none of the values
+// e.g., NOT (brand:Apple OR brand:Dell)
 type Clause struct {
 	FacetName string   // The facet name (e.g., "brand")
 	Values    []string // The values to match (OR semantics)
+	Negated   bool     // If true, the clause excludes items with any of the values
+}
+
+// matches tests whether an item's facet set satisfies this clause
+func (c Clause) matches(fs FacetSet) bool {
+	hasValue := false
+	for _, value := range c.Values {
+		if fs[fmt.Sprintf("%s:%s", c.FacetName, value)] {
+			hasValue = true
+			break
+		}
+	}
+	return hasValue != c.Negated
 }
 
 // DecisionList represents a cluster's filter rule as a conjunction of clauses
@@ -23,6 +40,8 @@ type DecisionList struct {
 // ToAlgoliaFilter converts the decision list to Algolia's facetFilters format
 // Returns [][]string where outer array is AND, inner arrays are OR
 // e.g., [["brand:Samsung", "brand:LG"], ["color:Black"]]
+// Negated clauses use Algolia's "-" prefix on the value, one AND group per value,
+// since NOT (a OR b) is NOT a AND NOT b: [["brand:-Apple"], ["brand:-Dell"]]
 func (d DecisionList) ToAlgoliaFilter() [][]string {
 	if len(d.Clauses) == 0 {
 		return nil
@@ -33,6 +52,12 @@ func (d DecisionList) ToAlgoliaFilter() [][]string {
 		if len(clause.Values) == 0 {
 			continue
 		}
+		if clause.Negated {
+			for _, value := range clause.Values {
+				filters = append(filters, []string{fmt.Sprintf("%s:-%s", clause.FacetName, value)})
+			}
+			continue
+		}
 		orGroup := make([]string, 0, len(clause.Values))
 		for _, value := range clause.Values {
 			orGroup = append(orGroup, fmt.Sprintf("%s:%s", clause.FacetName, value))
@@ -42,23 +67,50 @@ func (d DecisionList) ToAlgoliaFilter() [][]string {
 	return filters
 }
 
+// ParseAlgoliaFilter converts Algolia facetFilters back into a decision list, the
+// inverse of ToAlgoliaFilter. Each OR group becomes a clause and must use a single
+// facet; a negated value ("facet:-value") must be alone in its group.
+func ParseAlgoliaFilter(filters [][]string) (*DecisionList, error) {
+	rule := &DecisionList{}
+	for _, group := range filters {
+		if len(group) == 0 {
+			continue
+		}
+		var clause Clause
+		for i, filter := range group {
+			facetName, value, ok := strings.Cut(filter, ":")
+			if !ok || facetName == "" || value == "" {
+				return nil, fmt.Errorf("invalid facet filter %q", filter)
+			}
+			negated := strings.HasPrefix(value, "-")
+			if negated {
+				value = value[1:]
+				if len(group) > 1 {
+					return nil, fmt.Errorf("negated facet filter %q must be alone in its group", filter)
+				}
+			}
+			if i == 0 {
+				clause = Clause{FacetName: facetName, Negated: negated}
+			} else if facetName != clause.FacetName {
+				return nil, fmt.Errorf("facet filter group mixes facets %q and %q", clause.FacetName, facetName)
+			}
+			clause.Values = append(clause.Values, value)
+		}
+		rule.Clauses = append(rule.Clauses, clause)
+	}
+	return rule, nil
+}
+
 // Matches tests whether an item's facet set matches this decision list
 // All clauses must match (AND semantics), and within a clause, any value matches (OR semantics)
+// A negated clause matches only if none of its values are present
 func (d DecisionList) Matches(fs FacetSet) bool {
 	if len(d.Clauses) == 0 {
 		return true // Empty rule matches everything
 	}
 
 	for _, clause := range d.Clauses {
-		clauseMatches := false
-		for _, value := range clause.Values {
-			key := fmt.Sprintf("%s:%s", clause.FacetName, value)
-			if fs[key] {
-				clauseMatches = true
-				break
-			}
-		}
-		if !clauseMatches {
+		if !clause.matches(fs) {
 			return false // AND semantics: all clauses must match
 		}
 	}
@@ -73,15 +125,20 @@ func (d DecisionList) String() string {
 
 	var parts []string
 	for _, clause := range d.Clauses {
+		var part string
 		if len(clause.Values) == 1 {
-			parts = append(parts, fmt.Sprintf("%s:%s", clause.FacetName, clause.Values[0]))
+			part = fmt.Sprintf("%s:%s", clause.FacetName, clause.Values[0])
 		} else {
 			var orParts []string
 			for _, v := range clause.Values {
 				orParts = append(orParts, fmt.Sprintf("%s:%s", clause.FacetName, v))
 			}
-			parts = append(parts, fmt.Sprintf("(%s)", joinStrings(orParts, " OR ")))
+			part = fmt.Sprintf("(%s)", joinStrings(orParts, " OR "))
 		}
+		if clause.Negated {
+			part = "NOT " + part
+		}
+		parts = append(parts, part)
 	}
 	return joinStrings(parts, " AND ")
 }
@@ -93,6 +150,41 @@ type RuleQuality struct {
 	F1        float64 // Harmonic mean of precision and recall
 }
 
+// AssignmentMode controls how items are assigned to clusters once rules are fitted
+type AssignmentMode string
+
+const (
+	// AssignOverlapping puts an item in every cluster whose rule it matches (default)
+	AssignOverlapping AssignmentMode = "overlapping"
+
+	// AssignFirstMatch treats the rules as an ordered decision list: an item joins the
+	// first cluster whose rule it matches
+	AssignFirstMatch AssignmentMode = "first_match"
+
+	// AssignBestMatch puts an item in the matching cluster whose rule is most confident
+	AssignBestMatch AssignmentMode = "best_match"
+)
+
+// ParseAssignmentMode validates an assignment mode name; empty selects the default
+func ParseAssignmentMode(s string) (AssignmentMode, error) {
+	switch mode := AssignmentMode(s); mode {
+	case "":
+		return AssignOverlapping, nil
+	case AssignOverlapping, AssignFirstMatch, AssignBestMatch:
+		return mode, nil
+	default:
+		return "", fmt.Errorf("unknown assignment mode %q", s)
+	}
+}
+
+// orDefault returns the mode, or AssignOverlapping if it is empty or unknown
+func (m AssignmentMode) orDefault() AssignmentMode {
+	if mode, err := ParseAssignmentMode(string(m)); err == nil {
+		return mode
+	}
+	return AssignOverlapping
+}
+
 // Rule fitting constants
 const (
 	// MaxClausesInRule is the maximum number of facet clauses in a decision list
@@ -101,6 +193,9 @@ const (
 	// MinLiftThreshold is the minimum lift for a value to be included in a clause
 	// Lift = P(value|positive) / P(value|all) - values with lift > 1 are over-represented in positives
 	MinLiftThreshold = 1.2
+
+	// MaxNegatedValues is the maximum number of values excluded by one negated clause
+	MaxNegatedValues = 3
 )
 
 // valueStats tracks counts for a facet value in positive and total sets
@@ -221,9 +316,108 @@ func selectClausesGreedy(facetStats facetValueStats, positiveIndices []int, allF
 		)
 	}
 
+	// Use any remaining clause slots to exclude values that only match negatives
+	for len(clauses) < MaxClausesInRule {
+		negated, ok := findBestNegatedClause(clauses, usedFacets, positiveIndices, allFacetSets)
+		if !ok {
+			break
+		}
+
+		clauses = append(clauses, negated)
+		usedFacets[negated.FacetName] = true
+
+		log.Debug("fitDecisionList: added negated clause",
+			"facet", negated.FacetName,
+			"values", negated.Values,
+			"new_precision", fmt.Sprintf("%.3f", computePrecision(DecisionList{Clauses: clauses}, positiveIndices, allFacetSets)),
+		)
+	}
+
 	return clauses
 }
 
+// findBestNegatedClause finds the negated clause that most improves precision without
+// hurting recall: it may only exclude values that no positive matched by the current
+// rule has. Facets already used by a clause are skipped.
+// Returns false if no negated clause improves precision.
+func findBestNegatedClause(currentClauses []Clause, usedFacets map[string]bool, positiveIndices []int, allFacetSets []FacetSet) (Clause, bool) {
+	currentRule := DecisionList{Clauses: currentClauses}
+
+	positiveSet := make(map[int]bool, len(positiveIndices))
+	for _, idx := range positiveIndices {
+		positiveSet[idx] = true
+	}
+
+	// Among items the current rule matches, find values present on any positive
+	// (excluding them would lose recall) and count negatives carrying each value
+	positiveValues := make(map[string]bool)
+	negativeCounts := make(map[string]int)
+	for idx, fs := range allFacetSets {
+		if !currentRule.Matches(fs) {
+			continue
+		}
+		for facetKey := range fs {
+			if positiveSet[idx] {
+				positiveValues[facetKey] = true
+			} else {
+				negativeCounts[facetKey]++
+			}
+		}
+	}
+
+	// Group excludable values by facet
+	excludable := make(map[string][]string)
+	for facetKey := range negativeCounts {
+		if positiveValues[facetKey] {
+			continue
+		}
+		facetName, facetValue := parseFacetKey(facetKey)
+		if facetName == "" || usedFacets[facetName] {
+			continue
+		}
+		excludable[facetName] = append(excludable[facetName], facetValue)
+	}
+
+	currentPrecision := computePrecision(currentRule, positiveIndices, allFacetSets)
+	bestClause := Clause{}
+	bestPrecision := currentPrecision
+	found := false
+
+	// Iterate facets in sorted order so ties resolve deterministically
+	facetNames := make([]string, 0, len(excludable))
+	for facetName := range excludable {
+		facetNames = append(facetNames, facetName)
+	}
+	sort.Strings(facetNames)
+
+	for _, facetName := range facetNames {
+		values := excludable[facetName]
+		// Keep the values that remove the most negatives
+		sort.Slice(values, func(a, b int) bool {
+			ca := negativeCounts[facetName+":"+values[a]]
+			cb := negativeCounts[facetName+":"+values[b]]
+			if ca != cb {
+				return ca > cb
+			}
+			return values[a] < values[b]
+		})
+		if len(values) > MaxNegatedValues {
+			values = values[:MaxNegatedValues]
+		}
+
+		candidate := Clause{FacetName: facetName, Values: values, Negated: true}
+		candidateRule := DecisionList{Clauses: append(append([]Clause{}, currentClauses...), candidate)}
+		precision := computePrecision(candidateRule, positiveIndices, allFacetSets)
+		if precision > bestPrecision {
+			bestClause = candidate
+			bestPrecision = precision
+			found = true
+		}
+	}
+
+	return bestClause, found
+}
+
 // findBestClause finds the best facet clause to add given current clauses
 func findBestClause(currentClauses []Clause, facetStats facetValueStats, usedFacets map[string]bool, positiveIndices []int, allFacetSets []FacetSet, totalPositives, totalItems int) (Clause, string, float64, float64) {
 	bestFacet := ""
@@ -337,11 +531,19 @@ func computeRuleQuality(rule DecisionList, positiveIndices []int, allFacetSets [
 
 // fitAndReassign fits decision list rules to each cluster and reassigns items based on rules
 // Items can belong to multiple clusters if they match multiple rules (overlapping clusters)
-func fitAndReassign(groups []ClusterGroup, allItems []Result, facetSets []FacetSet, log *logger.Logger) []ClusterGroup {
+func fitAndReassign(groups []ClusterGroup, allItems []Result, facetSets []FacetSet, opts ClusterOptions, log *logger.Logger) []ClusterGroup {
 	if len(groups) == 0 {
 		return groups
 	}
 
+	ruleFit := opts.RuleFit.withDefaults()
+	if !ruleFit.isKnown() {
+		log.Warn("fitAndReassign: unknown rule objective, using default",
+			"objective", string(ruleFit.Objective),
+		)
+		ruleFit.Objective = RuleObjectiveRecall
+	}
+
 	// Build item index lookup (Result.ID -> index in allItems)
 	itemIndex := make(map[string]int)
 	for i, item := range allItems {
@@ -349,59 +551,111 @@ func fitAndReassign(groups []ClusterGroup, allItems []Result, facetSets []FacetS
 	}
 
 	// Phase 1: Fit rules for each cluster based on original membership
-	clusterRules := fitRulesForClusters(groups, itemIndex, facetSets, log)
+	clusterRules := fitRulesForClusters(groups, itemIndex, facetSets, ruleFit, log)
 
-	// Phase 2: Reassign items based on rules (allows overlapping membership)
-	newGroups := reassignItemsByRules(clusterRules, allItems, facetSets)
+	// Phase 2: Reassign items based on rules
+	newGroups := reassignItemsByRules(clusterRules, allItems, facetSets, opts.Assignment.orDefault())
 
-	// Phase 3: Recalculate TopFacets and Stats for each cluster
-	for i := range newGroups {
-		newGroups[i].TopFacets = calculateTopFacets(newGroups[i].Items, facetSets, itemIndex)
-		newGroups[i].Stats = ClusterStats{
-			Size:      len(newGroups[i].Items),
-			TopFacets: newGroups[i].TopFacets,
+	// Phase 3: Drop emptied clusters and recalculate TopFacets, Stats and rule quality
+	return refreshReassignedGroups(newGroups, facetSets, itemIndex, opts.Assignment.orDefault(), log)
+}
+
+// refreshReassignedGroups drops groups left without items by reassignment and
+// recalculates the TopFacets and Stats of the others. In first_match and best_match
+// modes an item matching several rules joins only one group, so rule quality is
+// recomputed against the reassigned membership: precision then shows how many of the
+// rule's matches went to other groups.
+func refreshReassignedGroups(groups []ClusterGroup, facetSets []FacetSet, itemIndex map[string]int, mode AssignmentMode, log *logger.Logger) []ClusterGroup {
+	kept := make([]ClusterGroup, 0, len(groups))
+	for i, group := range groups {
+		if len(group.Items) == 0 {
+			log.Debug("fitAndReassign: dropped cluster left empty by reassignment",
+				"cluster", i,
+				"rule", group.Rule.String(),
+			)
+			continue
+		}
+
+		group.TopFacets = calculateTopFacets(group.Items, facetSets, itemIndex)
+		group.Stats = ClusterStats{
+			Size:      len(group.Items),
+			TopFacets: group.TopFacets,
+		}
+		if mode != AssignOverlapping && group.Rule != nil {
+			memberIndices := make([]int, 0, len(group.Items))
+			for _, item := range group.Items {
+				memberIndices = append(memberIndices, itemIndex[item.ID])
+			}
+			group.RuleQuality = computeRuleQuality(*group.Rule, memberIndices, facetSets)
 		}
 
 		log.Debug("fitAndReassign: reassigned cluster",
 			"cluster", i,
-			"new_size", len(newGroups[i].Items),
+			"new_size", len(group.Items),
 		)
+		kept = append(kept, group)
 	}
-
-	return newGroups
+	return kept
 }
 
 // clusterRuleInfo holds the fitted rule and metadata for a cluster
 type clusterRuleInfo struct {
-	rule    *DecisionList
-	quality *RuleQuality
-	name    string
+	rule        *DecisionList // nil when no rule could be fitted (e.g., items without facets)
+	quality     *RuleQuality
+	diagnostics *RuleDiagnostics
+	name        string
+	members     []int // Original member indices, kept for clusters without a rule
 }
 
 // fitRulesForClusters fits decision list rules for each cluster
-func fitRulesForClusters(groups []ClusterGroup, itemIndex map[string]int, facetSets []FacetSet, log *logger.Logger) []clusterRuleInfo {
+func fitRulesForClusters(groups []ClusterGroup, itemIndex map[string]int, facetSets []FacetSet, opts RuleFitOptions, log *logger.Logger) []clusterRuleInfo {
 	rules := make([]clusterRuleInfo, len(groups))
 
+	memberIndices := make([][]int, len(groups))
 	for i, group := range groups {
-		positiveIndices := make([]int, 0, len(group.Items))
+		memberIndices[i] = make([]int, 0, len(group.Items))
 		for _, item := range group.Items {
 			if idx, ok := itemIndex[item.ID]; ok {
-				positiveIndices = append(positiveIndices, idx)
+				memberIndices[i] = append(memberIndices[i], idx)
+			}
+		}
+	}
+
+	for i, group := range groups {
+		positiveIndices := memberIndices[i]
+
+		// Items of the other clusters, for objectives that penalize overlap
+		var siblingIndices []int
+		for j, indices := range memberIndices {
+			if j != i {
+				siblingIndices = append(siblingIndices, indices...)
 			}
 		}
 
-		rule, quality := fitDecisionList(positiveIndices, facetSets, log)
+		rule, quality, diagnostics := fitDecisionListWithOptions(positiveIndices, siblingIndices, facetSets, opts, log)
 
 		// Generate name from the rule - this ensures unique names for different rules
 		name := rule.String()
-		if name == "(empty rule)" {
-			name = group.Name // Fallback to original name if rule is empty
+		if len(rule.Clauses) == 0 {
+			// An empty rule would match every item, so the cluster keeps its members
+			// (clustered by text or embeddings) instead of being reassigned by rule
+			rules[i] = clusterRuleInfo{
+				diagnostics: diagnostics,
+				name:        group.Name,
+				members:     positiveIndices,
+			}
+			log.Debug("fitAndReassign: no rule for cluster, keeping its members",
+				"cluster", i,
+				"size", len(group.Items),
+			)
+			continue
 		}
 
 		rules[i] = clusterRuleInfo{
-			rule:    rule,
-			quality: quality,
-			name:    name,
+			rule:        rule,
+			quality:     quality,
+			diagnostics: diagnostics,
+			name:        name,
 		}
 
 		log.Debug("fitAndReassign: fitted rule for cluster",
@@ -417,25 +671,98 @@ func fitRulesForClusters(groups []ClusterGroup, itemIndex map[string]int, facetS
 }
 
 // reassignItemsByRules creates new cluster groups by applying rules to all items
-func reassignItemsByRules(clusterRules []clusterRuleInfo, allItems []Result, facetSets []FacetSet) []ClusterGroup {
+// The mode decides which clusters an item matching several rules joins. Clusters
+// without a rule keep their members; in first_match and best_match modes those items
+// are not matched against the other rules.
+func reassignItemsByRules(clusterRules []clusterRuleInfo, allItems []Result, facetSets []FacetSet, mode AssignmentMode) []ClusterGroup {
 	newGroups := make([]ClusterGroup, len(clusterRules))
 	for i := range newGroups {
 		newGroups[i] = ClusterGroup{
-			Name:        clusterRules[i].name,
-			Items:       []Result{},
-			Rule:        clusterRules[i].rule,
-			RuleQuality: clusterRules[i].quality,
+			Name:            clusterRules[i].name,
+			Items:           []Result{},
+			Rule:            clusterRules[i].rule,
+			RuleQuality:     clusterRules[i].quality,
+			RuleDiagnostics: clusterRules[i].diagnostics,
+		}
+	}
+
+	ruleless := make(map[int][]int) // Item index -> clusters without a rule it belongs to
+	for i, cr := range clusterRules {
+		if cr.rule == nil {
+			for _, idx := range cr.members {
+				ruleless[idx] = append(ruleless[idx], i)
+			}
 		}
 	}
 
-	// Assign each item to all clusters whose rules it matches
 	for idx, fs := range facetSets {
-		for i, cr := range clusterRules {
-			if cr.rule.Matches(fs) {
+		if clusters := ruleless[idx]; len(clusters) > 0 {
+			for _, i := range clusters {
 				newGroups[i].Items = append(newGroups[i].Items, allItems[idx])
 			}
+			if mode != AssignOverlapping {
+				continue
+			}
+		}
+		switch mode {
+		case AssignFirstMatch:
+			// Ordered decision list: the first cluster whose rule matches wins
+			for i, cr := range clusterRules {
+				if cr.rule != nil && cr.rule.Matches(fs) {
+					newGroups[i].Items = append(newGroups[i].Items, allItems[idx])
+					break
+				}
+			}
+		case AssignBestMatch:
+			// The matching rule with the highest confidence wins
+			best := -1
+			for i, cr := range clusterRules {
+				if cr.rule != nil && cr.rule.Matches(fs) && (best < 0 || moreConfident(cr, clusterRules[best])) {
+					best = i
+				}
+			}
+			if best >= 0 {
+				newGroups[best].Items = append(newGroups[best].Items, allItems[idx])
+			}
+		default:
+			// Assign each item to all clusters whose rules it matches
+			for i, cr := range clusterRules {
+				if cr.rule != nil && cr.rule.Matches(fs) {
+					newGroups[i].Items = append(newGroups[i].Items, allItems[idx])
+				}
+			}
 		}
 	}
 
 	return newGroups
 }
+
+// moreConfident reports whether rule a is more confident than rule b: higher precision,
+// then higher F1. Ties keep the earlier cluster.
+func moreConfident(a, b clusterRuleInfo) bool {
+	if a.quality == nil || b.quality == nil {
+		return a.quality != nil
+	}
+	if a.quality.Precision != b.quality.Precision {
+		return a.quality.Precision > b.quality.Precision
+	}
+	return a.quality.F1 > b.quality.F1
+}
+
+// findRepeatedItems returns how many groups contain each item that is in more than one
+func findRepeatedItems(groups []ClusterGroup) map[string]int {
+	counts := make(map[string]int)
+	for _, group := range groups {
+		for _, item := range group.Items {
+			counts[item.ID]++
+		}
+	}
+
+	repeated := make(map[string]int)
+	for id, count := range counts {
+		if count > 1 {
+			repeated[id] = count
+		}
+	}
+	return repeated
+}
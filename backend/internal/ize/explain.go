@@ -0,0 +1,121 @@
+package ize
+
+// ItemExplanation explains why an item is (or is not) in each cluster
+type ItemExplanation struct {
+	// Memberships describes each group containing the item (empty for "Other" items)
+	Memberships []ClusterMembership
+	// Silhouette compares the item's distance to its closest group with its distance to
+	// the nearest alternative, in [-1, 1]: near 1 is a clear fit, near 0 is borderline,
+	// negative means the alternative is closer. Zero when there is nothing to compare.
+	Silhouette float64
+	// NearestAlternative is the closest group not containing the item (nil if none)
+	NearestAlternative *ClusterMembership
+}
+
+// ClusterMembership relates an item to one cluster group
+type ClusterMembership struct {
+	Group            int      // Index into ClusterResult.Groups
+	SatisfiedClauses []string // Clauses of the group's rule the item satisfies
+	FailedClauses    []string // Clauses of the group's rule the item does not satisfy
+	Distance         float64  // Average distance from the item to the group's other items
+}
+
+// explainMemberships builds an explanation for every item, keyed by item ID
+func explainMemberships(groups []ClusterGroup, allItems []Result, facetSets []FacetSet, dist pairDistance) map[string]ItemExplanation {
+	itemIndex := make(map[string]int, len(allItems))
+	for i, item := range allItems {
+		itemIndex[item.ID] = i
+	}
+
+	// Item indices of each group, and which groups each item is in
+	members := make([][]int, len(groups))
+	inGroup := make([]map[int]bool, len(allItems))
+	for g, group := range groups {
+		for _, item := range group.Items {
+			idx, ok := itemIndex[item.ID]
+			if !ok {
+				continue
+			}
+			members[g] = append(members[g], idx)
+			if inGroup[idx] == nil {
+				inGroup[idx] = make(map[int]bool)
+			}
+			inGroup[idx][g] = true
+		}
+	}
+
+	explanations := make(map[string]ItemExplanation, len(allItems))
+	for idx, item := range allItems {
+		explanation := ItemExplanation{Memberships: []ClusterMembership{}}
+		var own, alternative *ClusterMembership
+
+		for g := range groups {
+			membership := explainGroup(idx, g, groups[g].Rule, members[g], facetSets, dist)
+			if inGroup[idx][g] {
+				explanation.Memberships = append(explanation.Memberships, membership)
+				if own == nil || membership.Distance < own.Distance {
+					own = &explanation.Memberships[len(explanation.Memberships)-1]
+				}
+			} else if alternative == nil || membership.Distance < alternative.Distance {
+				m := membership
+				alternative = &m
+			}
+		}
+
+		explanation.NearestAlternative = alternative
+		if own != nil && alternative != nil {
+			explanation.Silhouette = silhouetteValue(own.Distance, alternative.Distance)
+		}
+		explanations[item.ID] = explanation
+	}
+
+	return explanations
+}
+
+// explainGroup relates item idx to group g: which rule clauses it satisfies and how far
+// it is, on average, from the group's other members
+func explainGroup(idx, g int, rule *DecisionList, members []int, facetSets []FacetSet, dist pairDistance) ClusterMembership {
+	membership := ClusterMembership{
+		Group:            g,
+		SatisfiedClauses: []string{},
+		FailedClauses:    []string{},
+	}
+
+	if rule != nil {
+		for _, clause := range rule.Clauses {
+			description := DecisionList{Clauses: []Clause{clause}}.String()
+			if clause.matches(facetSets[idx]) {
+				membership.SatisfiedClauses = append(membership.SatisfiedClauses, description)
+			} else {
+				membership.FailedClauses = append(membership.FailedClauses, description)
+			}
+		}
+	}
+
+	total, count := 0.0, 0
+	for _, other := range members {
+		if other == idx {
+			continue
+		}
+		total += dist(idx, other)
+		count++
+	}
+	if count > 0 {
+		membership.Distance = total / float64(count)
+	}
+
+	return membership
+}
+
+// silhouetteValue computes (b - a) / max(a, b) for own-group distance a and nearest
+// alternative distance b
+func silhouetteValue(a, b float64) float64 {
+	maxAB := a
+	if b > maxAB {
+		maxAB = b
+	}
+	if maxAB == 0 {
+		return 0
+	}
+	return (b - a) / maxAB
+}
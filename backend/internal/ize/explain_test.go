@@ -0,0 +1,123 @@
+package ize
+
+import (
+	"fmt"
+	"ize/internal/algolia"
+	"ize/internal/logger"
+	"math"
+	"testing"
+)
+
+func TestSilhouetteValue(t *testing.T) {
+	tests := []struct {
+		name     string
+		a, b     float64
+		expected float64
+	}{
+		{"clear fit", 0.2, 0.8, 0.75},
+		{"borderline", 0.5, 0.5, 0},
+		{"misplaced", 0.8, 0.4, -0.5},
+		{"identical items", 0, 0, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if result := silhouetteValue(tt.a, tt.b); math.Abs(result-tt.expected) > 1e-9 {
+				t.Errorf("silhouetteValue(%v, %v) = %f, want %f", tt.a, tt.b, result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestExplainMemberships(t *testing.T) {
+	facetSets := shoesFacetSets()
+	allItems := make([]Result, len(facetSets))
+	for i := range allItems {
+		allItems[i] = Result{ID: fmt.Sprintf("%d", i)}
+	}
+	groups := []ClusterGroup{
+		{Name: "Nike Shoes", Items: allItems[:3], Rule: &DecisionList{Clauses: []Clause{
+			{FacetName: "category", Values: []string{"Shoes"}},
+			{FacetName: "brand", Values: []string{"Nike"}},
+		}}},
+		{Name: "Hats", Items: allItems[5:], Rule: &DecisionList{Clauses: []Clause{
+			{FacetName: "category", Values: []string{"Hats"}},
+		}}},
+	}
+
+	explanations := explainMemberships(groups, allItems, facetSets, newFacetDistance(facetSets, ClusterOptions{}))
+	if len(explanations) != len(allItems) {
+		t.Fatalf("explainMemberships() returned %d explanations, want %d", len(explanations), len(allItems))
+	}
+
+	// A Nike shoe satisfies both clauses of its own group and fits it clearly
+	nike := explanations["0"]
+	if len(nike.Memberships) != 1 || nike.Memberships[0].Group != 0 {
+		t.Fatalf("item 0 memberships = %+v, want group 0", nike.Memberships)
+	}
+	if len(nike.Memberships[0].SatisfiedClauses) != 2 || len(nike.Memberships[0].FailedClauses) != 0 {
+		t.Errorf("item 0 clauses = %+v, want both satisfied", nike.Memberships[0])
+	}
+	if nike.NearestAlternative == nil || nike.NearestAlternative.Group != 1 {
+		t.Errorf("item 0 nearest alternative = %+v, want group 1", nike.NearestAlternative)
+	}
+	if nike.Silhouette <= 0.5 {
+		t.Errorf("item 0 silhouette = %f, want > 0.5", nike.Silhouette)
+	}
+
+	// The red shoe is in no group: it reports the failed brand clause and no silhouette
+	red := explanations["3"]
+	if len(red.Memberships) != 0 {
+		t.Errorf("item 3 memberships = %+v, want none", red.Memberships)
+	}
+	if red.NearestAlternative == nil || red.NearestAlternative.Group != 0 {
+		t.Fatalf("item 3 nearest alternative = %+v, want group 0", red.NearestAlternative)
+	}
+	if got := red.NearestAlternative.FailedClauses; len(got) != 1 || got[0] != "brand:Nike" {
+		t.Errorf("item 3 failed clauses = %v, want [brand:Nike]", got)
+	}
+	if red.Silhouette != 0 {
+		t.Errorf("item 3 silhouette = %f, want 0", red.Silhouette)
+	}
+}
+
+func TestProcessClusterWithOptions_Explain(t *testing.T) {
+	algoliaResults := &algolia.SearchResult{
+		Hits: []algolia.Hit{
+			{ObjectID: "1", Name: "iPhone", Facets: map[string]interface{}{"category": "Electronics", "brand": "Apple"}},
+			{ObjectID: "2", Name: "iPad", Facets: map[string]interface{}{"category": "Electronics", "brand": "Apple"}},
+			{ObjectID: "3", Name: "Galaxy", Facets: map[string]interface{}{"category": "Electronics", "brand": "Samsung"}},
+			{ObjectID: "4", Name: "T-Shirt", Facets: map[string]interface{}{"category": "Clothing", "brand": "Nike"}},
+			{ObjectID: "5", Name: "Hoodie", Facets: map[string]interface{}{"category": "Clothing", "brand": "Nike"}},
+			{ObjectID: "6", Name: "Jeans", Facets: map[string]interface{}{"category": "Clothing", "brand": "Levi"}},
+		},
+	}
+
+	result, err := ProcessClusterWithOptions("test", algoliaResults, ClusterOptions{}, logger.Default())
+	if err != nil {
+		t.Fatalf("ProcessClusterWithOptions() error = %v", err)
+	}
+	if result.Explanations != nil {
+		t.Errorf("ProcessClusterWithOptions() explanations = %v, want nil when not requested", result.Explanations)
+	}
+
+	result, err = ProcessClusterWithOptions("test", algoliaResults, ClusterOptions{Explain: true}, logger.Default())
+	if err != nil {
+		t.Fatalf("ProcessClusterWithOptions(Explain) error = %v", err)
+	}
+	for _, hit := range algoliaResults.Hits {
+		explanation, ok := result.Explanations[hit.ObjectID]
+		if !ok {
+			t.Errorf("ProcessClusterWithOptions(Explain) missing explanation for %s", hit.ObjectID)
+			continue
+		}
+		if explanation.Silhouette < -1 || explanation.Silhouette > 1 {
+			t.Errorf("item %s silhouette = %f, want within [-1, 1]", hit.ObjectID, explanation.Silhouette)
+		}
+		for _, m := range explanation.Memberships {
+			if m.Group < 0 || m.Group >= len(result.Groups) {
+				t.Errorf("item %s membership group %d out of range", hit.ObjectID, m.Group)
+			}
+		}
+	}
+}
@@ -0,0 +1,304 @@
+package ize
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+// DefaultIdentityThreshold is the minimum similarity at which a new cluster inherits
+// the identity of a previous one
+const DefaultIdentityThreshold = 0.3
+
+// ClusterIdentity is what is remembered about a cluster to recognize it in a later result
+type ClusterIdentity struct {
+	ID           string
+	Color        int
+	Name         string
+	Description  string
+	ItemIDs      []string
+	RuleLiterals []string // See ruleLiterals
+}
+
+// ClusterLineage is the identity state carried from one cluster result to the next
+type ClusterLineage struct {
+	Clusters []ClusterIdentity
+	NextID   int // Counter for IDs of clusters that match nothing
+}
+
+// NewClusterLineage records the identities of groups (after StabilizeClusters and naming)
+func NewClusterLineage(groups []ClusterGroup, nextID int) *ClusterLineage {
+	clusters := make([]ClusterIdentity, len(groups))
+	for i, group := range groups {
+		itemIDs := make([]string, len(group.Items))
+		for j, item := range group.Items {
+			itemIDs[j] = item.ID
+		}
+		clusters[i] = ClusterIdentity{
+			ID:           group.ID,
+			Color:        group.Color,
+			Name:         group.Name,
+			Description:  group.Description,
+			ItemIDs:      itemIDs,
+			RuleLiterals: ruleLiterals(group.Rule),
+		}
+	}
+	return &ClusterLineage{Clusters: clusters, NextID: nextID}
+}
+
+// StabilizeClusters aligns the groups of result with the clusters of a previous result
+// so that the same cluster keeps its ID, color and name as the query is refined.
+// Groups are matched one-to-one by maximum total similarity (Hungarian algorithm), where
+// the similarity of two clusters is the larger of their item overlap and rule overlap
+// (both Jaccard). Matches below minSimilarity are discarded.
+//
+// Matched groups inherit the previous identity (Stable is set) and are ordered as
+// before; unmatched groups follow with fresh IDs and the lowest unused colors.
+// Explanation group indices are remapped to the new order. previous may be nil.
+// Returns the ID counter to store in the next lineage.
+func StabilizeClusters(result *ClusterResult, previous *ClusterLineage, minSimilarity float64) int {
+	var prevClusters []ClusterIdentity
+	nextID := 1
+	if previous != nil {
+		prevClusters = previous.Clusters
+		if previous.NextID > nextID {
+			nextID = previous.NextID
+		}
+	}
+
+	groups := result.Groups
+	matchedPrev := make([]int, len(groups))
+	for i := range matchedPrev {
+		matchedPrev[i] = -1
+	}
+
+	if len(prevClusters) > 0 && len(groups) > 0 {
+		similarity := make([][]float64, len(groups))
+		for i, group := range groups {
+			similarity[i] = make([]float64, len(prevClusters))
+			current := NewClusterLineage([]ClusterGroup{group}, 0).Clusters[0]
+			for j, prev := range prevClusters {
+				similarity[i][j] = clusterSimilarity(current, prev)
+			}
+		}
+
+		for i, j := range maxWeightMatching(similarity) {
+			if j >= 0 && similarity[i][j] >= minSimilarity {
+				matchedPrev[i] = j
+			}
+		}
+	}
+
+	// Order: matched groups in previous order, then new groups in their original order
+	order := make([]int, len(groups))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		pa, pb := matchedPrev[order[a]], matchedPrev[order[b]]
+		if pa < 0 || pb < 0 {
+			return pa >= 0 && pb < 0
+		}
+		return pa < pb
+	})
+
+	usedColors := make(map[int]bool)
+	for _, j := range matchedPrev {
+		if j >= 0 {
+			usedColors[prevClusters[j].Color] = true
+		}
+	}
+
+	stabilized := make([]ClusterGroup, len(groups))
+	newIndex := make([]int, len(groups))
+	for pos, i := range order {
+		group := groups[i]
+		if j := matchedPrev[i]; j >= 0 {
+			prev := prevClusters[j]
+			group.ID = prev.ID
+			group.Color = prev.Color
+			group.Stable = true
+			if prev.Name != "" {
+				group.Name = prev.Name
+				group.Description = prev.Description
+			}
+		} else {
+			group.ID = fmt.Sprintf("c%d", nextID)
+			nextID++
+			group.Color = lowestUnusedColor(usedColors)
+			usedColors[group.Color] = true
+			group.Stable = false
+		}
+		stabilized[pos] = group
+		newIndex[i] = pos
+	}
+	result.Groups = stabilized
+
+	remapExplanations(result.Explanations, newIndex)
+	return nextID
+}
+
+// remapExplanations rewrites group indices after groups have been reordered
+func remapExplanations(explanations map[string]ItemExplanation, newIndex []int) {
+	for id, e := range explanations {
+		for i := range e.Memberships {
+			e.Memberships[i].Group = newIndex[e.Memberships[i].Group]
+		}
+		if e.NearestAlternative != nil {
+			e.NearestAlternative.Group = newIndex[e.NearestAlternative.Group]
+		}
+		sort.Slice(e.Memberships, func(a, b int) bool {
+			return e.Memberships[a].Group < e.Memberships[b].Group
+		})
+		explanations[id] = e
+	}
+}
+
+// lowestUnusedColor returns the smallest color index not in used
+func lowestUnusedColor(used map[int]bool) int {
+	color := 0
+	for used[color] {
+		color++
+	}
+	return color
+}
+
+// clusterSimilarity is the larger of the item overlap and the rule overlap of two clusters
+func clusterSimilarity(a, b ClusterIdentity) float64 {
+	items := jaccardStrings(a.ItemIDs, b.ItemIDs)
+	rules := jaccardStrings(a.RuleLiterals, b.RuleLiterals)
+	return math.Max(items, rules)
+}
+
+// jaccardStrings computes |A ∩ B| / |A ∪ B| of two string sets (0 if both are empty)
+func jaccardStrings(a, b []string) float64 {
+	if len(a) == 0 && len(b) == 0 {
+		return 0
+	}
+	set := make(map[string]bool, len(a))
+	for _, s := range a {
+		set[s] = true
+	}
+	intersection := 0
+	union := len(set)
+	seen := make(map[string]bool, len(b))
+	for _, s := range b {
+		if seen[s] {
+			continue
+		}
+		seen[s] = true
+		if set[s] {
+			intersection++
+		} else {
+			union++
+		}
+	}
+	return float64(intersection) / float64(union)
+}
+
+// ruleLiterals flattens a rule into "facet:value" literals ("-facet:value" for negated
+// clauses) so rules can be compared as sets regardless of how values are grouped
+func ruleLiterals(rule *DecisionList) []string {
+	if rule == nil {
+		return nil
+	}
+	var literals []string
+	for _, clause := range rule.Clauses {
+		prefix := ""
+		if clause.Negated {
+			prefix = "-"
+		}
+		for _, v := range clause.Values {
+			literals = append(literals, prefix+clause.FacetName+":"+v)
+		}
+	}
+	return literals
+}
+
+// maxWeightMatching finds the one-to-one assignment of rows to columns that maximizes
+// total weight (Hungarian algorithm, O(n³)). Weights must be in [0, 1].
+// Returns the column assigned to each row, or -1 for rows left unassigned when there
+// are more rows than columns.
+func maxWeightMatching(weights [][]float64) []int {
+	rows := len(weights)
+	if rows == 0 {
+		return nil
+	}
+	cols := len(weights[0])
+	n := rows
+	if cols > n {
+		n = cols
+	}
+
+	// Square cost matrix (1-indexed for the potentials formulation); padding costs 1,
+	// the same as a zero-similarity pair
+	cost := func(i, j int) float64 {
+		if i <= rows && j <= cols {
+			return 1 - weights[i-1][j-1]
+		}
+		return 1
+	}
+
+	u := make([]float64, n+1)
+	v := make([]float64, n+1)
+	p := make([]int, n+1) // p[j] = row matched to column j
+	way := make([]int, n+1)
+
+	for i := 1; i <= n; i++ {
+		p[0] = i
+		j0 := 0
+		minv := make([]float64, n+1)
+		used := make([]bool, n+1)
+		for j := range minv {
+			minv[j] = math.Inf(1)
+		}
+		for {
+			used[j0] = true
+			i0 := p[j0]
+			delta := math.Inf(1)
+			j1 := 0
+			for j := 1; j <= n; j++ {
+				if used[j] {
+					continue
+				}
+				cur := cost(i0, j) - u[i0] - v[j]
+				if cur < minv[j] {
+					minv[j] = cur
+					way[j] = j0
+				}
+				if minv[j] < delta {
+					delta = minv[j]
+					j1 = j
+				}
+			}
+			for j := 0; j <= n; j++ {
+				if used[j] {
+					u[p[j]] += delta
+					v[j] -= delta
+				} else {
+					minv[j] -= delta
+				}
+			}
+			j0 = j1
+			if p[j0] == 0 {
+				break
+			}
+		}
+		for j0 != 0 {
+			j1 := way[j0]
+			p[j0] = p[j1]
+			j0 = j1
+		}
+	}
+
+	assignment := make([]int, rows)
+	for i := range assignment {
+		assignment[i] = -1
+	}
+	for j := 1; j <= n; j++ {
+		if p[j] >= 1 && p[j] <= rows && j <= cols {
+			assignment[p[j]-1] = j - 1
+		}
+	}
+	return assignment
+}
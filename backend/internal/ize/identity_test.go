@@ -0,0 +1,180 @@
+package ize
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"testing"
+)
+
+// bruteForceMatching returns the best total weight over all one-to-one assignments
+func bruteForceMatching(weights [][]float64) float64 {
+	cols := len(weights[0])
+	best := 0.0
+	used := make([]bool, cols)
+	var recurse func(row int, total float64)
+	recurse = func(row int, total float64) {
+		if row == len(weights) {
+			best = math.Max(best, total)
+			return
+		}
+		recurse(row+1, total) // Leave row unassigned
+		for j := 0; j < cols; j++ {
+			if !used[j] {
+				used[j] = true
+				recurse(row+1, total+weights[row][j])
+				used[j] = false
+			}
+		}
+	}
+	recurse(0, 0)
+	return best
+}
+
+func TestMaxWeightMatching(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	for trial := 0; trial < 50; trial++ {
+		rows, cols := 1+rng.Intn(5), 1+rng.Intn(5)
+		weights := make([][]float64, rows)
+		for i := range weights {
+			weights[i] = make([]float64, cols)
+			for j := range weights[i] {
+				weights[i][j] = rng.Float64()
+			}
+		}
+
+		assignment := maxWeightMatching(weights)
+		total := 0.0
+		usedCols := make(map[int]bool)
+		assigned := 0
+		for i, j := range assignment {
+			if j < 0 {
+				continue
+			}
+			if usedCols[j] {
+				t.Fatalf("trial %d: column %d assigned twice", trial, j)
+			}
+			usedCols[j] = true
+			total += weights[i][j]
+			assigned++
+		}
+		if want := min(rows, cols); assigned != want {
+			t.Errorf("trial %d: %d rows assigned, want %d", trial, assigned, want)
+		}
+		if expected := bruteForceMatching(weights); math.Abs(total-expected) > 1e-9 {
+			t.Errorf("trial %d: matching weight = %f, want %f", trial, total, expected)
+		}
+	}
+}
+
+// identityGroup builds a group with the given items and single-clause rule
+func identityGroup(name, facet, value string, ids ...int) ClusterGroup {
+	items := make([]Result, len(ids))
+	for i, id := range ids {
+		items[i] = Result{ID: fmt.Sprintf("%d", id)}
+	}
+	return ClusterGroup{
+		Name:  name,
+		Items: items,
+		Rule:  &DecisionList{Clauses: []Clause{{FacetName: facet, Values: []string{value}}}},
+	}
+}
+
+func TestStabilizeClusters(t *testing.T) {
+	first := &ClusterResult{Groups: []ClusterGroup{
+		identityGroup("Apple Phones", "brand", "Apple", 1, 2, 3),
+		identityGroup("Samsung Phones", "brand", "Samsung", 4, 5, 6),
+		identityGroup("Cases", "category", "Cases", 7, 8),
+	}}
+
+	first.Groups[0].Description = "iPhones from Apple."
+	nextID := StabilizeClusters(first, nil, DefaultIdentityThreshold)
+	for i, group := range first.Groups {
+		if want := fmt.Sprintf("c%d", i+1); group.ID != want || group.Color != i || group.Stable {
+			t.Errorf("first result group %d id/color/stable = %s/%d/%v, want %s/%d/false", i, group.ID, group.Color, group.Stable, want, i)
+		}
+	}
+	if nextID != 4 {
+		t.Errorf("StabilizeClusters() nextID = %d, want 4", nextID)
+	}
+	lineage := NewClusterLineage(first.Groups, nextID)
+
+	// Refinement: clusters come back in a different order with fresh names, Cases is
+	// gone, Samsung keeps only its rule, and a new Chargers cluster appears
+	second := &ClusterResult{
+		Groups: []ClusterGroup{
+			identityGroup("Chargers", "category", "Chargers", 9, 10),
+			identityGroup("Samsung", "brand", "Samsung", 11, 12),
+			identityGroup("Apple", "brand", "Apple", 1, 2),
+		},
+		Explanations: map[string]ItemExplanation{
+			"1": {
+				Memberships:        []ClusterMembership{{Group: 2}},
+				NearestAlternative: &ClusterMembership{Group: 0},
+			},
+		},
+	}
+
+	nextID = StabilizeClusters(second, lineage, DefaultIdentityThreshold)
+
+	expected := []struct {
+		id     string
+		color  int
+		name   string
+		stable bool
+	}{
+		{"c1", 0, "Apple Phones", true},
+		{"c2", 1, "Samsung Phones", true},
+		{"c4", 2, "Chargers", false}, // Lowest color not used by a stable cluster
+	}
+	for i, e := range expected {
+		group := second.Groups[i]
+		if group.ID != e.id || group.Color != e.color || group.Name != e.name || group.Stable != e.stable {
+			t.Errorf("second result group %d = %s/%d/%q/%v, want %s/%d/%q/%v",
+				i, group.ID, group.Color, group.Name, group.Stable, e.id, e.color, e.name, e.stable)
+		}
+	}
+	if nextID != 5 {
+		t.Errorf("StabilizeClusters() nextID = %d, want 5", nextID)
+	}
+	if second.Groups[0].Description != "iPhones from Apple." {
+		t.Errorf("stable group description = %q, want the previous description", second.Groups[0].Description)
+	}
+
+	// Explanation indices follow the reordering
+	explanation := second.Explanations["1"]
+	if explanation.Memberships[0].Group != 0 || explanation.NearestAlternative.Group != 2 {
+		t.Errorf("remapped explanation groups = %d/%d, want 0/2", explanation.Memberships[0].Group, explanation.NearestAlternative.Group)
+	}
+}
+
+func TestStabilizeClusters_WeakMatchGetsNewIdentity(t *testing.T) {
+	previous := NewClusterLineage([]ClusterGroup{
+		{ID: "c1", Color: 0, Name: "Apple", Items: []Result{{ID: "1"}, {ID: "2"}, {ID: "3"}, {ID: "4"}}},
+	}, 2)
+
+	// One shared item of seven and no rule overlap: below the threshold
+	result := &ClusterResult{Groups: []ClusterGroup{
+		identityGroup("Sony", "brand", "Sony", 4, 5, 6, 7),
+	}}
+	StabilizeClusters(result, previous, DefaultIdentityThreshold)
+
+	if group := result.Groups[0]; group.ID != "c2" || group.Stable || group.Name != "Sony" {
+		t.Errorf("weakly matched group = %s/%v/%q, want c2/false/Sony", group.ID, group.Stable, group.Name)
+	}
+}
+
+func TestRuleLiterals(t *testing.T) {
+	rule := &DecisionList{Clauses: []Clause{
+		{FacetName: "brand", Values: []string{"Apple", "Samsung"}},
+		{FacetName: "color", Values: []string{"Red"}, Negated: true},
+	}}
+	literals := ruleLiterals(rule)
+	expected := []string{"brand:Apple", "brand:Samsung", "-color:Red"}
+	if !equalStrings(literals, expected) {
+		t.Errorf("ruleLiterals() = %v, want %v", literals, expected)
+	}
+	if ruleLiterals(nil) != nil {
+		t.Error("ruleLiterals(nil) should be nil")
+	}
+}
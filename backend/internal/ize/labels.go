@@ -0,0 +1,84 @@
+package ize
+
+import (
+	"math"
+	"sort"
+)
+
+// DistinctiveTerms ranks the terms of each class of items by class-based TF-IDF
+// (c-TF-IDF): all items of a class are treated as one document, and a term's weight is
+// its frequency in the class times log(1 + A/f), where A is the average number of terms
+// per class and f the term's frequency across all classes. Terms found in every class
+// (when there are several) cannot tell classes apart and are dropped, as are terms that
+// occur in only one item of a class with several items.
+// Returns up to n display words per class, best first.
+func DistinctiveTerms(classes [][]Result, n int) [][]string {
+	classCounts := make([]map[string]int, len(classes))
+	classTotals := make([]int, len(classes))
+	itemFreq := make([]map[string]int, len(classes))
+	termFreq := make(map[string]int)
+	classFreq := make(map[string]int)
+	var texts []string
+
+	for c, items := range classes {
+		classCounts[c] = make(map[string]int)
+		itemFreq[c] = make(map[string]int)
+		for _, item := range items {
+			for term, count := range itemTermCounts(item) {
+				classCounts[c][term] += count
+				classTotals[c] += count
+				termFreq[term] += count
+				itemFreq[c][term]++
+			}
+			texts = append(texts, item.Name, item.Description)
+		}
+		for term := range classCounts[c] {
+			classFreq[term]++
+		}
+	}
+
+	totalTerms := 0
+	for _, total := range classTotals {
+		totalTerms += total
+	}
+	avgTerms := float64(totalTerms) / math.Max(1, float64(len(classes)))
+	forms := surfaceForms(texts)
+
+	type scoredTerm struct {
+		term  string
+		score float64
+	}
+	terms := make([][]string, len(classes))
+	for c, counts := range classCounts {
+		minItems := min(2, len(classes[c]))
+		scored := make([]scoredTerm, 0, len(counts))
+		for term, count := range counts {
+			if len(classes) > 1 && classFreq[term] == len(classes) {
+				continue
+			}
+			if itemFreq[c][term] < minItems {
+				continue
+			}
+			tf := float64(count) / float64(classTotals[c])
+			scored = append(scored, scoredTerm{term, tf * math.Log(1+avgTerms/float64(termFreq[term]))})
+		}
+		sort.Slice(scored, func(a, b int) bool {
+			if scored[a].score != scored[b].score {
+				return scored[a].score > scored[b].score
+			}
+			return scored[a].term < scored[b].term
+		})
+		if len(scored) > n {
+			scored = scored[:n]
+		}
+
+		terms[c] = make([]string, len(scored))
+		for i, s := range scored {
+			terms[c][i] = s.term
+			if form, ok := forms[s.term]; ok {
+				terms[c][i] = form
+			}
+		}
+	}
+	return terms
+}
@@ -0,0 +1,38 @@
+package ize
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDistinctiveTerms(t *testing.T) {
+	classes := [][]Result{
+		{
+			{Name: "Trail running shoes", Description: "Waterproof shoes for muddy trails"},
+			{Name: "Trail shoes", Description: "Grippy sole for trails"},
+		},
+		{
+			{Name: "Leather dress shoes", Description: "Polished leather oxford"},
+			{Name: "Dress shoes", Description: "Classic leather derby"},
+		},
+	}
+
+	terms := DistinctiveTerms(classes, 2)
+	want := [][]string{{"trail"}, {"dress", "leather"}} // Terms of a single item are not distinctive
+	if !reflect.DeepEqual(terms, want) {
+		t.Errorf("DistinctiveTerms() = %v, want %v", terms, want)
+	}
+	for c, classTerms := range terms {
+		for _, term := range classTerms {
+			if term == "shoes" {
+				t.Errorf("class %d term %q is in every class", c, term)
+			}
+		}
+	}
+
+	// A single item keeps its own terms
+	single := DistinctiveTerms([][]Result{{{Name: "Espresso machine"}}}, 3)
+	if len(single[0]) != 2 {
+		t.Errorf("DistinctiveTerms(single item) = %v, want its 2 terms", single)
+	}
+}
@@ -0,0 +1,548 @@
+package ize
+
+import (
+	"container/heap"
+	"encoding/binary"
+	"fmt"
+	"hash/fnv"
+	"math"
+
+	"ize/internal/logger"
+)
+
+// LSHConfig controls the accuracy/speed tradeoff of MinHash locality-sensitive hashing.
+// Signatures have Bands*Rows hash values. Items become candidate neighbors when all
+// Rows values of at least one band agree, so more bands raise recall (more pairs found,
+// slower) and more rows per band raise the similarity needed to become a candidate
+// (fewer pairs, faster). The similarity at which a pair has a 50% chance of being
+// found is roughly (1/Bands)^(1/Rows).
+type LSHConfig struct {
+	Bands int // Number of bands the signature is split into
+	Rows  int // Number of hash values per band
+}
+
+// Default LSH parameters: 20 bands of 4 rows finds pairs with Jaccard similarity
+// above ~0.5 with high probability while keeping signatures small (80 hashes)
+const (
+	defaultLSHBands = 20
+	defaultLSHRows  = 4
+)
+
+// DefaultLSHConfig returns the LSH parameters used when none are configured
+func DefaultLSHConfig() LSHConfig {
+	return LSHConfig{Bands: defaultLSHBands, Rows: defaultLSHRows}
+}
+
+// withDefaults fills in zero-valued fields with defaults
+func (c LSHConfig) withDefaults() LSHConfig {
+	if c.Bands <= 0 {
+		c.Bands = defaultLSHBands
+	}
+	if c.Rows <= 0 {
+		c.Rows = defaultLSHRows
+	}
+	return c
+}
+
+// numHashes returns the MinHash signature length
+func (c LSHConfig) numHashes() int {
+	return c.Bands * c.Rows
+}
+
+// maxGraphDistance is the distance assumed between items that are not neighbors in the graph
+const maxGraphDistance = 1.0
+
+// minHashSignature is a fixed-length MinHash sketch of a facet set
+type minHashSignature []uint64
+
+// splitmix64 is a fast, well-distributed 64-bit mixing function
+func splitmix64(x uint64) uint64 {
+	x += 0x9e3779b97f4a7c15
+	x = (x ^ (x >> 30)) * 0xbf58476d1ce4e5b9
+	x = (x ^ (x >> 27)) * 0x94d049bb133111eb
+	return x ^ (x >> 31)
+}
+
+// minHashSeeds derives deterministic per-hash seeds so signatures are reproducible
+func minHashSeeds(numHashes int) []uint64 {
+	seeds := make([]uint64, numHashes)
+	state := uint64(0x1ce4e5b9)
+	for i := range seeds {
+		state = splitmix64(state)
+		seeds[i] = state
+	}
+	return seeds
+}
+
+// hashToken hashes a "facetName:facetValue" token to 64 bits
+func hashToken(token string) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(token))
+	return h.Sum64()
+}
+
+// computeMinHash builds the MinHash signature for a facet set
+// Returns nil for an empty set, which has no meaningful signature
+func computeMinHash(fs FacetSet, seeds []uint64) minHashSignature {
+	if len(fs) == 0 {
+		return nil
+	}
+
+	sig := make(minHashSignature, len(seeds))
+	for i := range sig {
+		sig[i] = math.MaxUint64
+	}
+
+	for token := range fs {
+		base := hashToken(token)
+		for i, seed := range seeds {
+			if v := splitmix64(base ^ seed); v < sig[i] {
+				sig[i] = v
+			}
+		}
+	}
+
+	return sig
+}
+
+// estimateJaccard estimates Jaccard similarity as the fraction of agreeing signature slots
+func estimateJaccard(a, b minHashSignature) float64 {
+	if len(a) == 0 || len(a) != len(b) {
+		return 0
+	}
+	agree := 0
+	for i := range a {
+		if a[i] == b[i] {
+			agree++
+		}
+	}
+	return float64(agree) / float64(len(a))
+}
+
+// bandKey hashes one band of a signature into a bucket key
+func bandKey(sig minHashSignature, band, rows int) uint64 {
+	h := fnv.New64a()
+	var buf [8]byte
+	binary.LittleEndian.PutUint64(buf[:], uint64(band))
+	h.Write(buf[:])
+	for _, v := range sig[band*rows : (band+1)*rows] {
+		binary.LittleEndian.PutUint64(buf[:], v)
+		h.Write(buf[:])
+	}
+	return h.Sum64()
+}
+
+// neighborGraph is a sparse, symmetric distance graph over items
+// Pairs without an edge are treated as being maxGraphDistance apart
+type neighborGraph struct {
+	n     int
+	edges []map[int]float64 // edges[i][j] = distance between items i and j
+}
+
+// newNeighborGraph creates an empty graph over n items
+func newNeighborGraph(n int) *neighborGraph {
+	edges := make([]map[int]float64, n)
+	for i := range edges {
+		edges[i] = make(map[int]float64)
+	}
+	return &neighborGraph{n: n, edges: edges}
+}
+
+// addEdge records a symmetric edge between items i and j
+func (g *neighborGraph) addEdge(i, j int, dist float64) {
+	g.edges[i][j] = dist
+	g.edges[j][i] = dist
+}
+
+// distance returns the distance between items i and j
+func (g *neighborGraph) distance(i, j int) float64 {
+	if i == j {
+		return 0
+	}
+	if d, ok := g.edges[i][j]; ok {
+		return d
+	}
+	return maxGraphDistance
+}
+
+// edgeCount returns the number of undirected edges in the graph
+func (g *neighborGraph) edgeCount() int {
+	total := 0
+	for _, e := range g.edges {
+		total += len(e)
+	}
+	return total / 2
+}
+
+// buildNeighborGraph finds candidate neighbors with MinHash LSH and stores their exact
+// Jaccard distance. Only pairs that collide in at least one band are compared, so the
+// cost is roughly linear in the number of items rather than quadratic.
+func buildNeighborGraph(facetSets []FacetSet, cfg LSHConfig) *neighborGraph {
+	return buildNeighborGraphWith(facetSets, cfg, func(i, j int) float64 {
+		return jaccardDistance(facetSets[i], facetSets[j])
+	})
+}
+
+// buildNeighborGraphWith is buildNeighborGraph with a custom distance for candidate pairs
+func buildNeighborGraphWith(facetSets []FacetSet, cfg LSHConfig, dist pairDistance) *neighborGraph {
+	return buildCandidateGraph(len(facetSets), dist, minHashIndex(facetSets, cfg))
+}
+
+// bandIndex holds each item's bucket key in every LSH band. Items that share a key in
+// any band are candidate neighbors; a nil entry means the item has no signature.
+type bandIndex [][]uint64
+
+// minHashIndex buckets token sets (facet values, text terms) by banded MinHash
+// signatures, so sets with high Jaccard similarity are likely to share a bucket
+func minHashIndex(sets []FacetSet, cfg LSHConfig) bandIndex {
+	cfg = cfg.withDefaults()
+	seeds := minHashSeeds(cfg.numHashes())
+
+	index := make(bandIndex, len(sets))
+	for i, fs := range sets {
+		sig := computeMinHash(fs, seeds)
+		if sig == nil {
+			continue // Empty sets have no neighbors
+		}
+		index[i] = make([]uint64, cfg.Bands)
+		for band := range index[i] {
+			index[i][band] = bandKey(sig, band, cfg.Rows)
+		}
+	}
+	return index
+}
+
+// hyperplaneRowsFactor scales the rows per band for embeddings: a random hyperplane
+// separates even unrelated vectors only half the time, so more rows are needed than
+// for MinHash to keep unrelated pairs from becoming candidates
+const hyperplaneRowsFactor = 2
+
+// hyperplaneIndex buckets dense vectors by which side of random hyperplanes they fall
+// on (SimHash), so vectors with high cosine similarity are likely to share a bucket.
+// Items without a vector have no signature.
+func hyperplaneIndex(vectors [][]float64, cfg LSHConfig) bandIndex {
+	cfg = cfg.withDefaults()
+	rows := cfg.Rows * hyperplaneRowsFactor
+
+	dims := 0
+	for _, vec := range vectors {
+		if len(vec) > dims {
+			dims = len(vec)
+		}
+	}
+	planes := randomHyperplanes(cfg.Bands*rows, dims)
+
+	index := make(bandIndex, len(vectors))
+	sig := make(minHashSignature, len(planes))
+	for i, vec := range vectors {
+		if len(vec) == 0 {
+			continue
+		}
+		for p, plane := range planes {
+			dot := 0.0
+			for d, x := range vec {
+				dot += x * plane[d]
+			}
+			sig[p] = 0
+			if dot >= 0 {
+				sig[p] = 1
+			}
+		}
+		index[i] = make([]uint64, cfg.Bands)
+		for band := range index[i] {
+			index[i][band] = bandKey(sig, band, rows)
+		}
+	}
+	return index
+}
+
+// randomHyperplanes returns count deterministic hyperplane normals of dims dimensions.
+// Components are Gaussian (Box-Muller) so the normals' directions are uniform.
+func randomHyperplanes(count, dims int) [][]float64 {
+	state := uint64(0x94d049bb)
+	uniform := func() float64 {
+		state = splitmix64(state)
+		return (float64(state>>11) + 0.5) / (1 << 53) // In (0, 1)
+	}
+
+	planes := make([][]float64, count)
+	for p := range planes {
+		planes[p] = make([]float64, dims)
+		for d := range planes[p] {
+			planes[p][d] = math.Sqrt(-2*math.Log(uniform())) * math.Cos(2*math.Pi*uniform())
+		}
+	}
+	return planes
+}
+
+// buildCandidateGraph compares the pairs of items that share a bucket in any band of
+// any of the indexes, and keeps an edge for those closer than maxGraphDistance. Each
+// index proposes pairs that are similar in one respect (facets, text, embeddings), so
+// a blended distance is computed for pairs that any of its parts considers close.
+func buildCandidateGraph(n int, dist pairDistance, indexes ...bandIndex) *neighborGraph {
+	graph := newNeighborGraph(n)
+
+	for _, index := range indexes {
+		bands := 0
+		for _, keys := range index {
+			if len(keys) > bands {
+				bands = len(keys)
+			}
+		}
+
+		for band := 0; band < bands; band++ {
+			buckets := make(map[uint64][]int)
+			for i, keys := range index {
+				if keys == nil {
+					continue
+				}
+				buckets[keys[band]] = append(buckets[keys[band]], i)
+			}
+
+			for _, members := range buckets {
+				for a := 0; a < len(members); a++ {
+					for b := a + 1; b < len(members); b++ {
+						i, j := members[a], members[b]
+						if _, seen := graph.edges[i][j]; seen {
+							continue
+						}
+						if d := dist(i, j); d < maxGraphDistance {
+							graph.addEdge(i, j, d)
+						}
+					}
+				}
+			}
+		}
+	}
+
+	return graph
+}
+
+// linkStat accumulates the known pairwise distances between two clusters
+type linkStat struct {
+	sum   float64 // Sum of known distances
+	count int     // Number of known pairs
+}
+
+// averageDistance returns the average linkage distance between clusters of the given
+// sizes, counting unknown pairs as maxGraphDistance
+func (l linkStat) averageDistance(sizeA, sizeB int) float64 {
+	pairs := sizeA * sizeB
+	if pairs == 0 {
+		return math.Inf(1)
+	}
+	unknown := pairs - l.count
+	return (l.sum + float64(unknown)*maxGraphDistance) / float64(pairs)
+}
+
+// mergeCandidate is a heap entry proposing to merge two active clusters
+type mergeCandidate struct {
+	dist float64
+	a, b int // Cluster IDs with a < b
+}
+
+// mergeHeap is a min-heap of merge candidates ordered by distance, then IDs
+type mergeHeap []mergeCandidate
+
+func (h mergeHeap) Len() int { return len(h) }
+func (h mergeHeap) Less(i, j int) bool {
+	if h[i].dist != h[j].dist {
+		return h[i].dist < h[j].dist
+	}
+	if h[i].a != h[j].a {
+		return h[i].a < h[j].a
+	}
+	return h[i].b < h[j].b
+}
+func (h mergeHeap) Swap(i, j int) { h[i], h[j] = h[j], h[i] }
+func (h *mergeHeap) Push(x any)   { *h = append(*h, x.(mergeCandidate)) }
+func (h *mergeHeap) Pop() any {
+	old := *h
+	item := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return item
+}
+
+// newMergeCandidate orders the cluster IDs so equal pairs compare equal
+func newMergeCandidate(dist float64, a, b int) mergeCandidate {
+	if a > b {
+		a, b = b, a
+	}
+	return mergeCandidate{dist: dist, a: a, b: b}
+}
+
+// agglomerativeClusterGraph performs average-linkage agglomerative clustering on a
+// sparse neighbor graph. It produces the same dendrogram shape as agglomerativeCluster
+// on the equivalent dense matrix, but only tracks cluster pairs joined by at least one
+// edge. Clusters with no edges between them are joined last at maxGraphDistance.
+func agglomerativeClusterGraph(g *neighborGraph) *clusterNode {
+	if g == nil || g.n == 0 {
+		return nil
+	}
+
+	nodes := make(map[int]*clusterNode, g.n)
+	links := make(map[int]map[int]linkStat, g.n)
+	h := &mergeHeap{}
+
+	for i := 0; i < g.n; i++ {
+		nodes[i] = &clusterNode{id: i, members: []int{i}}
+		links[i] = make(map[int]linkStat)
+	}
+	for i, edges := range g.edges {
+		for j, dist := range edges {
+			links[i][j] = linkStat{sum: dist, count: 1}
+			if i < j {
+				heap.Push(h, newMergeCandidate(dist, i, j))
+			}
+		}
+	}
+
+	nextID := g.n
+	for h.Len() > 0 && len(nodes) > 1 {
+		cand := heap.Pop(h).(mergeCandidate)
+		left, okA := nodes[cand.a]
+		right, okB := nodes[cand.b]
+		if !okA || !okB {
+			continue // Stale entry: one side was already merged
+		}
+		if cand.dist >= maxGraphDistance {
+			break // Remaining pairs are unrelated; join them below
+		}
+
+		merged := mergeClusterNodes(nextID, left, right, cand.dist)
+		nextID++
+
+		// Combine link statistics from both sides
+		mergedLinks := make(map[int]linkStat)
+		for _, side := range []int{cand.a, cand.b} {
+			for other, stat := range links[side] {
+				if other == cand.a || other == cand.b {
+					continue
+				}
+				acc := mergedLinks[other]
+				acc.sum += stat.sum
+				acc.count += stat.count
+				mergedLinks[other] = acc
+				delete(links[other], side)
+			}
+			delete(links, side)
+			delete(nodes, side)
+		}
+
+		nodes[merged.id] = merged
+		links[merged.id] = mergedLinks
+		for other, stat := range mergedLinks {
+			links[other][merged.id] = stat
+			dist := stat.averageDistance(len(merged.members), len(nodes[other].members))
+			heap.Push(h, newMergeCandidate(dist, merged.id, other))
+		}
+	}
+
+	// Join any disconnected components at maximum distance, in ID order for determinism
+	var root *clusterNode
+	for id := 0; id < nextID; id++ {
+		node, ok := nodes[id]
+		if !ok {
+			continue
+		}
+		if root == nil {
+			root = node
+			continue
+		}
+		root = mergeClusterNodes(nextID, root, node, maxGraphDistance)
+		nextID++
+	}
+
+	return root
+}
+
+// mergeClusterNodes creates the parent node of two dendrogram nodes
+func mergeClusterNodes(id int, left, right *clusterNode, height float64) *clusterNode {
+	members := make([]int, 0, len(left.members)+len(right.members))
+	members = append(members, left.members...)
+	members = append(members, right.members...)
+	return &clusterNode{
+		id:      id,
+		left:    left,
+		right:   right,
+		height:  height,
+		members: members,
+	}
+}
+
+// silhouetteScoreGraph calculates the silhouette score using graph distances
+// Each point only visits its own edges, so the cost is O(edges + n*k)
+func silhouetteScoreGraph(g *neighborGraph, assignments []int, k int) float64 {
+	n := len(assignments)
+	if n < 2 || k < 2 {
+		return 0
+	}
+
+	clusters := groupByCluster(assignments, k)
+	totalSilhouette := 0.0
+	validPoints := 0
+
+	stats := make([]linkStat, k)
+	for i := 0; i < n; i++ {
+		own := assignments[i]
+		if own < 0 || own >= k || len(clusters[own]) <= 1 {
+			continue
+		}
+
+		for c := range stats {
+			stats[c] = linkStat{}
+		}
+		for j, dist := range g.edges[i] {
+			c := assignments[j]
+			if c < 0 || c >= k {
+				continue
+			}
+			stats[c].sum += dist
+			stats[c].count++
+		}
+
+		// a(i): average distance to the other members of its own cluster
+		a := stats[own].averageDistance(1, len(clusters[own])-1)
+
+		// b(i): minimum average distance to another cluster
+		b := math.Inf(1)
+		for c := 0; c < k; c++ {
+			if c == own || len(clusters[c]) == 0 {
+				continue
+			}
+			if d := stats[c].averageDistance(1, len(clusters[c])); d < b {
+				b = d
+			}
+		}
+		if b == math.Inf(1) {
+			continue
+		}
+
+		maxAB := math.Max(a, b)
+		if maxAB == 0 {
+			continue
+		}
+		totalSilhouette += (b - a) / maxAB
+		validPoints++
+	}
+
+	if validPoints == 0 {
+		return 0
+	}
+	return totalSilhouette / float64(validPoints)
+}
+
+// selectOptimalKGraph is the neighbor-graph counterpart of selectOptimalK
+func selectOptimalKGraph(g *neighborGraph, log *logger.Logger) (int, []int, map[int]float64) {
+	root := agglomerativeClusterGraph(g)
+	return selectOptimalKFromDendrogram(root, g.n, func(assignments []int, k int) float64 {
+		return silhouetteScoreGraph(g, assignments, k)
+	}, log)
+}
+
+// describeLSH returns a short description of the LSH parameters for logging
+func describeLSH(cfg LSHConfig) string {
+	cfg = cfg.withDefaults()
+	threshold := math.Pow(1/float64(cfg.Bands), 1/float64(cfg.Rows))
+	return fmt.Sprintf("bands=%d rows=%d threshold~%.2f", cfg.Bands, cfg.Rows, threshold)
+}
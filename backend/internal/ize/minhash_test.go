@@ -0,0 +1,350 @@
+package ize
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"strconv"
+	"testing"
+
+	"ize/internal/algolia"
+	"ize/internal/logger"
+)
+
+// randomFacetSets generates n facet sets drawn from a few overlapping "profiles" so
+// that the collection contains both near-duplicate and unrelated pairs
+func randomFacetSets(n int, seed int64) []FacetSet {
+	rng := rand.New(rand.NewSource(seed))
+	sets := make([]FacetSet, n)
+	for i := range sets {
+		profile := rng.Intn(5)
+		fs := FacetSet{
+			fmt.Sprintf("category:C%d", profile): true,
+			fmt.Sprintf("brand:B%d", profile):    true,
+		}
+		for f := 0; f < 4; f++ {
+			fs[fmt.Sprintf("attr%d:V%d", f, rng.Intn(3)+profile*3)] = true
+		}
+		sets[i] = fs
+	}
+	return sets
+}
+
+func TestEstimateJaccard_ErrorBounded(t *testing.T) {
+	seeds := minHashSeeds(256)
+	sets := randomFacetSets(40, 1)
+
+	// Standard error of the estimate is sqrt(J(1-J)/k) <= 0.5/sqrt(256) ~ 0.031
+	const maxError = 0.12
+	worst := 0.0
+	for i := 0; i < len(sets); i++ {
+		for j := i + 1; j < len(sets); j++ {
+			exact := 1 - jaccardDistance(sets[i], sets[j])
+			est := estimateJaccard(computeMinHash(sets[i], seeds), computeMinHash(sets[j], seeds))
+			if diff := math.Abs(exact - est); diff > worst {
+				worst = diff
+			}
+		}
+	}
+
+	if worst > maxError {
+		t.Errorf("estimateJaccard() worst error = %.3f, want <= %.3f", worst, maxError)
+	}
+}
+
+func TestComputeMinHash_EmptySet(t *testing.T) {
+	if sig := computeMinHash(FacetSet{}, minHashSeeds(8)); sig != nil {
+		t.Errorf("computeMinHash() on empty set = %v, want nil", sig)
+	}
+}
+
+func TestBuildNeighborGraph_RecallOfSimilarPairs(t *testing.T) {
+	sets := randomFacetSets(200, 2)
+	graph := buildNeighborGraph(sets, DefaultLSHConfig())
+
+	// Pairs well above the LSH threshold (~0.47) should almost always be found
+	similar, found := 0, 0
+	for i := 0; i < len(sets); i++ {
+		for j := i + 1; j < len(sets); j++ {
+			if 1-jaccardDistance(sets[i], sets[j]) < 0.7 {
+				continue
+			}
+			similar++
+			if _, ok := graph.edges[i][j]; ok {
+				found++
+			}
+		}
+	}
+
+	if similar == 0 {
+		t.Fatal("test data has no similar pairs")
+	}
+	recall := float64(found) / float64(similar)
+	if recall < 0.98 {
+		t.Errorf("buildNeighborGraph() recall for pairs with J>=0.7 = %.3f (%d/%d), want >= 0.98", recall, found, similar)
+	}
+
+	// The graph must be much sparser than the dense matrix
+	allPairs := len(sets) * (len(sets) - 1) / 2
+	if graph.edgeCount() >= allPairs/2 {
+		t.Errorf("buildNeighborGraph() edges = %d, want well below %d pairs", graph.edgeCount(), allPairs)
+	}
+}
+
+func TestBuildNeighborGraph_EdgesHaveExactDistances(t *testing.T) {
+	sets := randomFacetSets(60, 3)
+	graph := buildNeighborGraph(sets, DefaultLSHConfig())
+
+	for i, edges := range graph.edges {
+		for j, dist := range edges {
+			if want := jaccardDistance(sets[i], sets[j]); dist != want {
+				t.Errorf("edge (%d,%d) distance = %f, want %f", i, j, dist, want)
+			}
+			if graph.distance(j, i) != dist {
+				t.Errorf("edge (%d,%d) is not symmetric", i, j)
+			}
+		}
+	}
+}
+
+func TestBuildNeighborGraph_MoreBandsFindMorePairs(t *testing.T) {
+	sets := randomFacetSets(150, 4)
+	fast := buildNeighborGraph(sets, LSHConfig{Bands: 4, Rows: 6})
+	accurate := buildNeighborGraph(sets, LSHConfig{Bands: 40, Rows: 2})
+
+	if fast.edgeCount() > accurate.edgeCount() {
+		t.Errorf("edges with fewer bands = %d, want <= edges with more bands = %d", fast.edgeCount(), accurate.edgeCount())
+	}
+}
+
+// completeGraph builds a neighbor graph containing every pair of a dense matrix
+func completeGraph(distMatrix [][]float64) *neighborGraph {
+	g := newNeighborGraph(len(distMatrix))
+	for i := range distMatrix {
+		for j := i + 1; j < len(distMatrix); j++ {
+			g.addEdge(i, j, distMatrix[i][j])
+		}
+	}
+	return g
+}
+
+func TestSilhouetteScoreGraph_MatchesDense(t *testing.T) {
+	sets := randomFacetSets(30, 5)
+	distMatrix := buildDistanceMatrix(sets)
+	graph := completeGraph(distMatrix)
+
+	assignments := make([]int, len(sets))
+	for i := range assignments {
+		assignments[i] = i % 3
+	}
+
+	dense := silhouetteScore(distMatrix, assignments, 3)
+	sparse := silhouetteScoreGraph(graph, assignments, 3)
+	if math.Abs(dense-sparse) > 1e-9 {
+		t.Errorf("silhouetteScoreGraph() = %f, want %f (dense)", sparse, dense)
+	}
+}
+
+func TestAgglomerativeClusterGraph_MatchesDense(t *testing.T) {
+	distMatrix := [][]float64{
+		{0.0, 0.1, 0.9, 0.9, 0.8},
+		{0.1, 0.0, 0.9, 0.9, 0.8},
+		{0.9, 0.9, 0.0, 0.2, 0.7},
+		{0.9, 0.9, 0.2, 0.0, 0.6},
+		{0.8, 0.8, 0.7, 0.6, 0.0},
+	}
+
+	dense := cutDendrogram(agglomerativeCluster(distMatrix), 2)
+	sparse := cutDendrogram(agglomerativeClusterGraph(completeGraph(distMatrix)), 2)
+
+	if !samePartition(clustersToAssignments(dense, 5), clustersToAssignments(sparse, 5)) {
+		t.Errorf("agglomerativeClusterGraph() k=2 cut = %v, want %v", sparse, dense)
+	}
+}
+
+func TestAgglomerativeClusterGraph_DisconnectedComponents(t *testing.T) {
+	g := newNeighborGraph(5)
+	g.addEdge(0, 1, 0.1)
+	g.addEdge(2, 3, 0.2)
+	// Item 4 has no neighbors
+
+	root := agglomerativeClusterGraph(g)
+	if root == nil || len(root.members) != 5 {
+		t.Fatalf("agglomerativeClusterGraph() root should contain all 5 items")
+	}
+
+	clusters := cutDendrogram(root, 3)
+	if len(clusters) != 3 {
+		t.Fatalf("cutDendrogram(k=3) = %d clusters, want 3", len(clusters))
+	}
+	assignments := clustersToAssignments(clusters, 5)
+	if assignments[0] != assignments[1] || assignments[2] != assignments[3] || assignments[0] == assignments[2] {
+		t.Errorf("cutDendrogram(k=3) assignments = %v, want {0,1} and {2,3} grouped", assignments)
+	}
+}
+
+// samePartition reports whether two assignment arrays describe the same grouping
+func samePartition(a, b []int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		for j := range a {
+			if (a[i] == a[j]) != (b[i] == b[j]) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func TestProcessClusterWithOptions_Approximate(t *testing.T) {
+	hits := make([]algolia.Hit, 0, 60)
+	for i := 0; i < 60; i++ {
+		group := i % 3
+		hits = append(hits, algolia.Hit{
+			ObjectID: fmt.Sprintf("%d", i),
+			Name:     fmt.Sprintf("Item %d", i),
+			Facets: map[string]interface{}{
+				"category": fmt.Sprintf("C%d", group),
+				"brand":    fmt.Sprintf("B%d", group),
+				"color":    fmt.Sprintf("Color%d", i%2),
+			},
+		})
+	}
+	algoliaResults := &algolia.SearchResult{Hits: hits}
+
+	exact, err := ProcessCluster("test", algoliaResults, logger.Default())
+	if err != nil {
+		t.Fatalf("ProcessCluster() error = %v", err)
+	}
+	approx, err := ProcessClusterWithOptions("test", algoliaResults, ClusterOptions{ApproximateThreshold: 10}, logger.Default())
+	if err != nil {
+		t.Fatalf("ProcessClusterWithOptions() error = %v", err)
+	}
+
+	if approx.ClusterCount != exact.ClusterCount {
+		t.Errorf("approximate cluster count = %d, want %d (exact)", approx.ClusterCount, exact.ClusterCount)
+	}
+	for i, group := range approx.Groups {
+		if group.Rule == nil || len(group.Rule.Clauses) == 0 {
+			t.Errorf("approximate group %d has no rule", i)
+		}
+	}
+}
+
+// textOnlyHits returns hits in two product types that only their names tell apart
+func textOnlyHits(facets bool) []algolia.Hit {
+	hits := make([]algolia.Hit, 0, 20)
+	for i := 0; i < 20; i++ {
+		name := fmt.Sprintf("Wireless noise cancelling headphones %d", i)
+		if i%2 == 1 {
+			name = fmt.Sprintf("Waterproof leather hiking boots %d", i)
+		}
+		hit := algolia.Hit{ObjectID: fmt.Sprintf("%d", i), Name: name}
+		if facets {
+			hit.Facets = map[string]interface{}{"sku": fmt.Sprintf("S%d", i)}
+		}
+		hits = append(hits, hit)
+	}
+	return hits
+}
+
+func TestClusterBySimilarity_TextCandidates(t *testing.T) {
+	allItems, facetSets := extractItemsAndFacets(&algolia.SearchResult{Hits: textOnlyHits(true)})
+	opts := ClusterOptions{ApproximateThreshold: 10, TextWeight: 1}
+
+	k, assignments, _ := clusterBySimilarity(allItems, facetSets, opts, logger.Default())
+	if k != 2 {
+		t.Fatalf("clusterBySimilarity() k = %d, want 2 (one per product type)", k)
+	}
+	for i := range assignments {
+		if assignments[i] != assignments[i%2] {
+			t.Errorf("item %d assigned to %d, want %d with its product type", i, assignments[i], assignments[i%2])
+		}
+	}
+}
+
+func TestProcessClusterWithOptions_TextWithoutFacets(t *testing.T) {
+	algoliaResults := &algolia.SearchResult{Hits: textOnlyHits(false)}
+
+	result, err := ProcessClusterWithOptions("test", algoliaResults, ClusterOptions{TextWeight: 1}, logger.Default())
+	if err != nil {
+		t.Fatalf("ProcessClusterWithOptions() error = %v", err)
+	}
+	if len(result.Groups) != 2 || len(result.RepeatedItems) != 0 {
+		t.Fatalf("ProcessClusterWithOptions() = %d groups, %d repeated items, want 2 disjoint text-based groups", len(result.Groups), len(result.RepeatedItems))
+	}
+	// Without facets no rule can be fitted; each group keeps its text cluster's items
+	for g, group := range result.Groups {
+		if group.Rule != nil || len(group.Items) != 10 {
+			t.Errorf("group %d = %d items, rule %v, want 10 items and no rule", g, len(group.Items), group.Rule)
+		}
+		parity, _ := strconv.Atoi(group.Items[0].ID)
+		for _, item := range group.Items {
+			if id, _ := strconv.Atoi(item.ID); id%2 != parity%2 {
+				t.Errorf("group %d mixes items %s and %s of different product types", g, group.Items[0].ID, item.ID)
+			}
+		}
+	}
+
+	facetOnly, _ := ProcessClusterWithOptions("test", algoliaResults, ClusterOptions{}, logger.Default())
+	if len(facetOnly.Groups) != 0 {
+		t.Errorf("facet-only clustering without facets = %d groups, want 0", len(facetOnly.Groups))
+	}
+}
+
+func TestHyperplaneIndex_SimilarVectorsCollide(t *testing.T) {
+	vectors := [][]float64{
+		{1, 0, 0, 0},
+		{0.99, 0.14, 0, 0}, // cos ~0.99 with the first
+		{0, 0, 1, 0},       // orthogonal to both
+		nil,                // no embedding
+	}
+	index := hyperplaneIndex(vectors, DefaultLSHConfig())
+
+	shared := func(i, j int) bool {
+		for band := range index[i] {
+			if index[i][band] == index[j][band] {
+				return true
+			}
+		}
+		return false
+	}
+	if !shared(0, 1) {
+		t.Error("hyperplaneIndex() put near-identical vectors in no common bucket")
+	}
+	if index[3] != nil {
+		t.Errorf("hyperplaneIndex() keys for a missing vector = %v, want nil", index[3])
+	}
+	if len(index[2]) != defaultLSHBands {
+		t.Errorf("hyperplaneIndex() bands = %d, want %d", len(index[2]), defaultLSHBands)
+	}
+}
+
+func TestClusterBySimilarity_EmbeddingCandidates(t *testing.T) {
+	hits := make([]algolia.Hit, 20)
+	embeddings := make(map[string][]float64, len(hits))
+	for i := range hits {
+		id := fmt.Sprintf("%d", i)
+		hits[i] = algolia.Hit{ObjectID: id, Facets: map[string]interface{}{"sku": "S" + id}}
+		noise := float64(i) / 200
+		if i%2 == 0 {
+			embeddings[id] = []float64{1, noise, 0}
+		} else {
+			embeddings[id] = []float64{0, noise, 1}
+		}
+	}
+	allItems, facetSets := extractItemsAndFacets(&algolia.SearchResult{Hits: hits})
+	opts := ClusterOptions{ApproximateThreshold: 10, EmbeddingWeight: 1, Embeddings: embeddings}
+
+	k, assignments, _ := clusterBySimilarity(allItems, facetSets, opts, logger.Default())
+	if k != 2 {
+		t.Fatalf("clusterBySimilarity() k = %d, want 2 (one per embedding direction)", k)
+	}
+	for i := range assignments {
+		if assignments[i] != assignments[i%2] {
+			t.Errorf("item %d assigned to %d, want %d with its direction", i, assignments[i], assignments[i%2])
+		}
+	}
+}
@@ -11,6 +11,7 @@ import (
 type RipperGroup struct {
 	FacetName  string
 	FacetValue string
+	Name       string // Curated label (empty for algorithmic groups)
 	Items      []Result
 	// TotalCount is the total number of items in the current result set
 	// that have this facet value (including items that were assigned to
@@ -0,0 +1,386 @@
+package ize
+
+import (
+	"fmt"
+	"math/bits"
+	"sort"
+	"time"
+
+	"ize/internal/algolia"
+	"ize/internal/logger"
+)
+
+// exactDeadlineCheckInterval is how many search nodes are explored between clock checks
+const exactDeadlineCheckInterval = 256
+
+// bitset is a fixed-size set of item indices
+type bitset []uint64
+
+func newBitset(n int) bitset {
+	return make(bitset, (n+63)/64)
+}
+
+func (b bitset) set(i int) {
+	b[i/64] |= 1 << (uint(i) % 64)
+}
+
+// and returns the intersection of two bitsets of the same size
+func (b bitset) and(other bitset) bitset {
+	out := make(bitset, len(b))
+	for i := range b {
+		out[i] = b[i] & other[i]
+	}
+	return out
+}
+
+// count returns the number of set bits
+func (b bitset) count() int {
+	n := 0
+	for _, w := range b {
+		n += bits.OnesCount64(w)
+	}
+	return n
+}
+
+// andCount returns the size of the intersection without allocating
+func (b bitset) andCount(other bitset) int {
+	n := 0
+	for i := range b {
+		n += bits.OnesCount64(b[i] & other[i])
+	}
+	return n
+}
+
+// indexBitset builds a bitset from item indices
+func indexBitset(n int, indices []int) bitset {
+	b := newBitset(n)
+	for _, idx := range indices {
+		b.set(idx)
+	}
+	return b
+}
+
+// exactClause is a candidate clause with the items it matches
+type exactClause struct {
+	clause  Clause
+	matches bitset
+}
+
+// exactSearch holds branch-and-bound state for one cluster
+type exactSearch struct {
+	scorer      *ruleScorer
+	pool        []exactClause
+	positives   bitset
+	siblings    bitset
+	deadline    time.Time
+	nodes       int
+	timedOut    bool
+	best        ruleCandidate
+	diagnostics *diagnosticsRecorder
+}
+
+// fitDecisionListExact finds the best-scoring conjunction of up to MaxClausesInRule
+// clauses by branch-and-bound. If the search exceeds opts.ExactBudget the rule comes
+// from the non-exact fitter and the diagnostics say so.
+func fitDecisionListExact(positiveIndices, siblingIndices []int, allFacetSets []FacetSet, opts RuleFitOptions, log *logger.Logger) (*DecisionList, *RuleQuality, *RuleDiagnostics) {
+	if len(positiveIndices) == 0 || len(allFacetSets) == 0 {
+		return &DecisionList{}, &RuleQuality{}, nil
+	}
+
+	fallback := opts
+	fallback.Exact = false
+	if !opts.usesSearch() {
+		// Recall-first fitting has no score to bound; optimize F-beta instead
+		opts.Objective = RuleObjectiveFBeta
+	}
+
+	start := time.Now()
+	rule, diagnostics, ok := exactSearchRule(positiveIndices, siblingIndices, allFacetSets, opts)
+	if !ok {
+		log.Debug("fitDecisionList: exact search exceeded budget, falling back",
+			"budget_ms", opts.ExactBudget.Milliseconds(),
+			"nodes", diagnostics.NodesExplored,
+		)
+
+		fallbackRule, quality, fallbackDiagnostics := fitDecisionListWithOptions(positiveIndices, siblingIndices, allFacetSets, fallback, log)
+		if fallbackDiagnostics == nil {
+			scorer := newRuleScorer(positiveIndices, siblingIndices, allFacetSets, opts)
+			fallbackDiagnostics = &RuleDiagnostics{
+				Objective: opts.Objective,
+				Score:     scorer.evaluate(fallbackRule.Clauses).score,
+			}
+		}
+		fallbackDiagnostics.ExactTimedOut = true
+		fallbackDiagnostics.NodesExplored = diagnostics.NodesExplored
+		return fallbackRule, quality, fallbackDiagnostics
+	}
+
+	quality := computeRuleQuality(*rule, positiveIndices, allFacetSets)
+
+	log.Debug("fitDecisionList: exact search completed",
+		"objective", string(opts.Objective),
+		"rule", rule.String(),
+		"score", fmt.Sprintf("%.3f", diagnostics.Score),
+		"nodes", diagnostics.NodesExplored,
+		"duration_ms", time.Since(start).Milliseconds(),
+	)
+
+	return rule, quality, diagnostics
+}
+
+// exactSearchRule runs branch-and-bound over the candidate clauses. It returns false if
+// the time budget ran out before the search space was exhausted.
+func exactSearchRule(positiveIndices, siblingIndices []int, allFacetSets []FacetSet, opts RuleFitOptions) (*DecisionList, *RuleDiagnostics, bool) {
+	n := len(allFacetSets)
+	scorer := newRuleScorer(positiveIndices, siblingIndices, allFacetSets, opts)
+
+	search := &exactSearch{
+		scorer:      scorer,
+		pool:        buildExactClauses(scorer.positiveSet, allFacetSets, len(positiveIndices)),
+		positives:   indexBitset(n, positiveIndices),
+		siblings:    indexBitset(n, siblingIndices),
+		deadline:    time.Now().Add(opts.ExactBudget),
+		best:        scorer.evaluate(nil),
+		diagnostics: newDiagnosticsRecorder(),
+	}
+
+	all := newBitset(n)
+	for i := 0; i < n; i++ {
+		all.set(i)
+	}
+	search.search(0, nil, make(map[string]bool), all)
+
+	diagnostics := search.diagnostics.build(opts.Objective, search.best)
+	diagnostics.NodesExplored = search.nodes
+	if search.timedOut {
+		return nil, diagnostics, false
+	}
+
+	diagnostics.Exact = true
+	rule := search.best.rule()
+	return &rule, diagnostics, true
+}
+
+// search extends the current conjunction with each remaining clause on an unused facet,
+// pruning branches whose upper bound cannot beat the best rule found so far
+func (e *exactSearch) search(start int, clauses []Clause, usedFacets map[string]bool, matched bitset) {
+	for i := start; i < len(e.pool); i++ {
+		if e.timedOut {
+			return
+		}
+
+		candidateClause := e.pool[i]
+		facetName := candidateClause.clause.FacetName
+		if usedFacets[facetName] {
+			continue
+		}
+
+		e.nodes++
+		if e.nodes%exactDeadlineCheckInterval == 0 && time.Now().After(e.deadline) {
+			e.timedOut = true
+			return
+		}
+
+		next := matched.and(candidateClause.matches)
+		truePositives := next.andCount(e.positives)
+		if truePositives == 0 {
+			continue
+		}
+		// Adding clauses only removes matches, so no refinement can beat the bound
+		if e.scorer.upperBound(truePositives) <= e.best.score {
+			continue
+		}
+
+		candidate := e.scorer.score(truePositives, next.count(), next.andCount(e.siblings))
+		candidate.clauses = append(append([]Clause{}, clauses...), candidateClause.clause)
+		if candidate.score > e.best.score {
+			e.diagnostics.reject(e.best, rejectOutscored)
+			e.best = candidate
+		}
+
+		if len(candidate.clauses) < MaxClausesInRule {
+			usedFacets[facetName] = true
+			e.search(i+1, candidate.clauses, usedFacets, next)
+			delete(usedFacets, facetName)
+		}
+	}
+}
+
+// buildExactClauses enumerates the clauses exact search combines: for each facet, the
+// over-represented values as one clause (as the greedy fitter builds them), each value
+// present on a positive, the negation of each value present on a negative, and the
+// negation of the most common values found only on negatives
+func buildExactClauses(positiveSet map[int]bool, allFacetSets []FacetSet, numPositives int) []exactClause {
+	facetStats := collectFacetStats(positiveSet, allFacetSets)
+
+	facetNames := make([]string, 0, len(facetStats))
+	for facetName := range facetStats {
+		facetNames = append(facetNames, facetName)
+	}
+	sort.Strings(facetNames)
+
+	var clauses []Clause
+	for _, facetName := range facetNames {
+		values := make([]string, 0, len(facetStats[facetName]))
+		for value := range facetStats[facetName] {
+			values = append(values, value)
+		}
+		sort.Strings(values)
+
+		liftValues := selectValuesWithLift(facetStats[facetName], numPositives, len(allFacetSets))
+		if len(liftValues) > 1 {
+			sort.Strings(liftValues)
+			clauses = append(clauses, Clause{FacetName: facetName, Values: liftValues})
+		}
+		for _, value := range values {
+			if facetStats[facetName][value].positiveCount > 0 {
+				clauses = append(clauses, Clause{FacetName: facetName, Values: []string{value}})
+			}
+		}
+		var negativeOnly []string
+		for _, value := range values {
+			stats := facetStats[facetName][value]
+			if stats.totalCount > stats.positiveCount {
+				clauses = append(clauses, Clause{FacetName: facetName, Values: []string{value}, Negated: true})
+			}
+			if stats.positiveCount == 0 {
+				negativeOnly = append(negativeOnly, value)
+			}
+		}
+		if len(negativeOnly) > 1 {
+			// Exclude the most common values no positive has, as findBestNegatedClause does
+			sort.SliceStable(negativeOnly, func(a, b int) bool {
+				return facetStats[facetName][negativeOnly[a]].totalCount > facetStats[facetName][negativeOnly[b]].totalCount
+			})
+			if len(negativeOnly) > MaxNegatedValues {
+				negativeOnly = negativeOnly[:MaxNegatedValues]
+			}
+			clauses = append(clauses, Clause{FacetName: facetName, Values: negativeOnly, Negated: true})
+		}
+	}
+
+	pool := make([]exactClause, len(clauses))
+	for i, clause := range clauses {
+		matches := newBitset(len(allFacetSets))
+		for idx, fs := range allFacetSets {
+			if clause.matches(fs) {
+				matches.set(idx)
+			}
+		}
+		pool[i] = exactClause{clause: clause, matches: matches}
+	}
+	return pool
+}
+
+// RuleFitResult summarizes one fitted rule for comparison
+type RuleFitResult struct {
+	Rule      string
+	Score     float64
+	Precision float64
+	Recall    float64
+	Duration  time.Duration
+}
+
+// RuleComparison compares the configured rule fitter with exact search on one cluster
+type RuleComparison struct {
+	Cluster       int           // Index of the similarity cluster
+	Size          int           // Number of items in the cluster
+	Objective     RuleObjective // Objective both rules are scored with
+	Fitted        RuleFitResult // Rule from the configured (non-exact) fitter
+	Exact         RuleFitResult // Optimal rule from exact search
+	ExactTimedOut bool          // Exact search exceeded its budget; Exact is not proven optimal
+}
+
+// Gap returns how much higher the exact rule scores than the fitted rule
+func (c RuleComparison) Gap() float64 {
+	return c.Exact.Score - c.Fitted.Score
+}
+
+// CompareRuleFits clusters results as ProcessClusterWithOptions does, then fits each
+// cluster's rule with both the configured fitter and exact search, scoring both with the
+// same objective. It is meant for offline evaluation of how far fitting is from optimal.
+func CompareRuleFits(query string, algoliaResults *algolia.SearchResult, opts ClusterOptions, log *logger.Logger) ([]RuleComparison, error) {
+	if log == nil {
+		log = logger.Default()
+	}
+	if algoliaResults == nil || len(algoliaResults.Hits) == 0 {
+		return []RuleComparison{}, nil
+	}
+
+	allItems, facetSets := extractItemsAndFacets(algoliaResults)
+	if result := handleEdgeCases(allItems, facetSets, opts, log); result != nil {
+		return []RuleComparison{}, nil
+	}
+
+	optimalK, assignments, _ := clusterBySimilarity(allItems, facetSets, opts, log)
+	groups, _ := buildClusterGroups(allItems, facetSets, assignments, optimalK, log)
+
+	itemIndex := make(map[string]int, len(allItems))
+	for i, item := range allItems {
+		itemIndex[item.ID] = i
+	}
+	memberIndices := make([][]int, len(groups))
+	for i, group := range groups {
+		for _, item := range group.Items {
+			memberIndices[i] = append(memberIndices[i], itemIndex[item.ID])
+		}
+	}
+
+	fitted := opts.RuleFit.withDefaults()
+	fitted.Exact = false
+	exact := fitted
+	exact.Exact = true
+	if !exact.usesSearch() {
+		exact.Objective = RuleObjectiveFBeta
+	}
+
+	comparisons := make([]RuleComparison, len(groups))
+	for i := range groups {
+		var siblingIndices []int
+		for j, indices := range memberIndices {
+			if j != i {
+				siblingIndices = append(siblingIndices, indices...)
+			}
+		}
+		scorer := newRuleScorer(memberIndices[i], siblingIndices, facetSets, exact)
+
+		start := time.Now()
+		fittedRule, fittedQuality, _ := fitDecisionListWithOptions(memberIndices[i], siblingIndices, facetSets, fitted, log)
+		fittedDuration := time.Since(start)
+
+		start = time.Now()
+		exactRule, exactQuality, exactDiagnostics := fitDecisionListWithOptions(memberIndices[i], siblingIndices, facetSets, exact, log)
+		exactDuration := time.Since(start)
+
+		comparisons[i] = RuleComparison{
+			Cluster:   i,
+			Size:      len(memberIndices[i]),
+			Objective: exact.Objective,
+			Fitted: RuleFitResult{
+				Rule:      fittedRule.String(),
+				Score:     scorer.evaluate(fittedRule.Clauses).score,
+				Precision: fittedQuality.Precision,
+				Recall:    fittedQuality.Recall,
+				Duration:  fittedDuration,
+			},
+			Exact: RuleFitResult{
+				Rule:      exactRule.String(),
+				Score:     scorer.evaluate(exactRule.Clauses).score,
+				Precision: exactQuality.Precision,
+				Recall:    exactQuality.Recall,
+				Duration:  exactDuration,
+			},
+			ExactTimedOut: exactDiagnostics != nil && exactDiagnostics.ExactTimedOut,
+		}
+
+		log.Debug("CompareRuleFits: compared cluster",
+			"query", query,
+			"cluster", i,
+			"fitted_rule", comparisons[i].Fitted.Rule,
+			"exact_rule", comparisons[i].Exact.Rule,
+			"gap", fmt.Sprintf("%.3f", comparisons[i].Gap()),
+		)
+	}
+
+	return comparisons, nil
+}
@@ -0,0 +1,169 @@
+package ize
+
+import (
+	"fmt"
+	"ize/internal/algolia"
+	"ize/internal/logger"
+	"math"
+	"math/rand"
+	"testing"
+	"time"
+)
+
+func TestBitset(t *testing.T) {
+	a := indexBitset(130, []int{0, 5, 64, 129})
+	b := indexBitset(130, []int{5, 64, 100})
+
+	if a.count() != 4 {
+		t.Errorf("count() = %d, want 4", a.count())
+	}
+	if a.andCount(b) != 2 {
+		t.Errorf("andCount() = %d, want 2", a.andCount(b))
+	}
+	if c := a.and(b); c.count() != 2 {
+		t.Errorf("and().count() = %d, want 2", c.count())
+	}
+}
+
+// noisyFacetSets generates items with random facet values and a positive set that
+// no single clause describes exactly
+func noisyFacetSets(n, facets, values int, seed int64) ([]FacetSet, []int) {
+	rng := rand.New(rand.NewSource(seed))
+	facetSets := make([]FacetSet, n)
+	var positiveIndices []int
+	for i := range facetSets {
+		fs := FacetSet{}
+		for f := 0; f < facets; f++ {
+			fs[fmt.Sprintf("f%d:v%d", f, rng.Intn(values))] = true
+		}
+		facetSets[i] = fs
+		if (fs["f0:v0"] || fs["f0:v1"]) && (!fs["f1:v2"] || rng.Intn(4) == 0) {
+			positiveIndices = append(positiveIndices, i)
+		}
+	}
+	return facetSets, positiveIndices
+}
+
+// bruteForceBest scores every conjunction of pool clauses on distinct facets
+func bruteForceBest(scorer *ruleScorer, pool []exactClause) float64 {
+	best := scorer.evaluate(nil).score
+	var recurse func(start int, clauses []Clause, used map[string]bool)
+	recurse = func(start int, clauses []Clause, used map[string]bool) {
+		for i := start; i < len(pool); i++ {
+			facetName := pool[i].clause.FacetName
+			if used[facetName] {
+				continue
+			}
+			next := append(append([]Clause{}, clauses...), pool[i].clause)
+			if score := scorer.evaluate(next).score; score > best {
+				best = score
+			}
+			if len(next) < MaxClausesInRule {
+				used[facetName] = true
+				recurse(i+1, next, used)
+				delete(used, facetName)
+			}
+		}
+	}
+	recurse(0, nil, make(map[string]bool))
+	return best
+}
+
+func TestExactSearchRule_MatchesBruteForce(t *testing.T) {
+	for seed := int64(1); seed <= 10; seed++ {
+		facetSets, positiveIndices := noisyFacetSets(30, 4, 3, seed)
+		if len(positiveIndices) == 0 {
+			continue
+		}
+
+		for _, objective := range []RuleObjective{RuleObjectiveFBeta, RuleObjectiveLaplace} {
+			opts := RuleFitOptions{Objective: objective, ExactBudget: time.Minute}.withDefaults()
+			scorer := newRuleScorer(positiveIndices, nil, facetSets, opts)
+			pool := buildExactClauses(scorer.positiveSet, facetSets, len(positiveIndices))
+
+			rule, diagnostics, ok := exactSearchRule(positiveIndices, nil, facetSets, opts)
+			if !ok {
+				t.Fatalf("seed %d %s: exact search timed out", seed, objective)
+			}
+			if !diagnostics.Exact {
+				t.Errorf("seed %d %s: diagnostics.Exact = false", seed, objective)
+			}
+
+			expected := bruteForceBest(scorer, pool)
+			if got := scorer.evaluate(rule.Clauses).score; math.Abs(got-expected) > 1e-9 {
+				t.Errorf("seed %d %s: exact score = %f, brute force = %f (rule %q)", seed, objective, got, expected, rule.String())
+			}
+		}
+	}
+}
+
+func TestFitDecisionListExact_AtLeastAsGoodAsGreedy(t *testing.T) {
+	for seed := int64(1); seed <= 10; seed++ {
+		facetSets, positiveIndices := noisyFacetSets(40, 5, 4, seed)
+		if len(positiveIndices) == 0 {
+			continue
+		}
+
+		opts := RuleFitOptions{Objective: RuleObjectiveFBeta, ExactBudget: time.Minute}
+		_, _, greedy := fitDecisionListWithOptions(positiveIndices, nil, facetSets, opts, logger.Default())
+		opts.Exact = true
+		_, _, exact := fitDecisionListWithOptions(positiveIndices, nil, facetSets, opts, logger.Default())
+
+		if exact.Score < greedy.Score-1e-9 {
+			t.Errorf("seed %d: exact score %f < greedy score %f", seed, exact.Score, greedy.Score)
+		}
+	}
+}
+
+func TestFitDecisionListExact_FallsBackWhenOverBudget(t *testing.T) {
+	facetSets, positiveIndices := noisyFacetSets(80, 8, 10, 1)
+	opts := RuleFitOptions{Exact: true, ExactBudget: time.Nanosecond}
+
+	rule, quality, diagnostics := fitDecisionListWithOptions(positiveIndices, nil, facetSets, opts, logger.Default())
+	if rule == nil || quality == nil {
+		t.Fatal("fitDecisionListWithOptions() returned nil rule or quality")
+	}
+	if diagnostics == nil || !diagnostics.ExactTimedOut {
+		t.Fatalf("fitDecisionListWithOptions() diagnostics = %+v, want ExactTimedOut", diagnostics)
+	}
+	if diagnostics.Exact {
+		t.Error("fitDecisionListWithOptions() diagnostics.Exact = true for a timed-out search")
+	}
+
+	// The fallback is the default greedy fitter (value order within clauses may differ)
+	_, expected := fitDecisionList(positiveIndices, facetSets, logger.Default())
+	if *quality != *expected {
+		t.Errorf("fitDecisionListWithOptions() fallback quality = %+v, want greedy quality %+v", *quality, *expected)
+	}
+}
+
+func TestCompareRuleFits(t *testing.T) {
+	algoliaResults := &algolia.SearchResult{
+		Hits: []algolia.Hit{
+			{ObjectID: "1", Name: "iPhone", Facets: map[string]interface{}{"category": "Electronics", "brand": "Apple", "type": "Phone"}},
+			{ObjectID: "2", Name: "iPad", Facets: map[string]interface{}{"category": "Electronics", "brand": "Apple", "type": "Tablet"}},
+			{ObjectID: "3", Name: "MacBook", Facets: map[string]interface{}{"category": "Electronics", "brand": "Apple", "type": "Laptop"}},
+			{ObjectID: "4", Name: "Galaxy", Facets: map[string]interface{}{"category": "Electronics", "brand": "Samsung", "type": "Phone"}},
+			{ObjectID: "5", Name: "T-Shirt", Facets: map[string]interface{}{"category": "Clothing", "brand": "Nike", "type": "Top"}},
+			{ObjectID: "6", Name: "Jeans", Facets: map[string]interface{}{"category": "Clothing", "brand": "Levi", "type": "Bottom"}},
+			{ObjectID: "7", Name: "Hoodie", Facets: map[string]interface{}{"category": "Clothing", "brand": "Nike", "type": "Top"}},
+			{ObjectID: "8", Name: "Shorts", Facets: map[string]interface{}{"category": "Clothing", "brand": "Adidas", "type": "Bottom"}},
+		},
+	}
+
+	comparisons, err := CompareRuleFits("test", algoliaResults, ClusterOptions{}, logger.Default())
+	if err != nil {
+		t.Fatalf("CompareRuleFits() error = %v", err)
+	}
+	if len(comparisons) == 0 {
+		t.Fatal("CompareRuleFits() returned no comparisons")
+	}
+	for _, c := range comparisons {
+		if c.Objective != RuleObjectiveFBeta {
+			t.Errorf("CompareRuleFits() objective = %q, want %q", c.Objective, RuleObjectiveFBeta)
+		}
+		if c.Gap() < -1e-9 {
+			t.Errorf("CompareRuleFits() cluster %d gap = %f, want >= 0", c.Cluster, c.Gap())
+		}
+	}
+}
@@ -0,0 +1,504 @@
+package ize
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"ize/internal/logger"
+)
+
+// RuleObjective selects what decision list fitting optimizes
+type RuleObjective string
+
+const (
+	// RuleObjectiveRecall is the default greedy fitter: maximize recall first, then
+	// add clauses that raise precision without losing much recall
+	RuleObjectiveRecall RuleObjective = "recall"
+
+	// RuleObjectiveFBeta maximizes F-beta of precision and recall
+	RuleObjectiveFBeta RuleObjective = "fbeta"
+
+	// RuleObjectiveLaplace maximizes F-beta using Laplace-corrected precision
+	// (tp+1)/(matches+2), which distrusts rules supported by only a few items
+	RuleObjectiveLaplace RuleObjective = "laplace"
+
+	// RuleObjectiveExclusive maximizes F-beta minus a penalty for the fraction of
+	// matches that belong to sibling clusters, so rules don't swallow each other
+	RuleObjectiveExclusive RuleObjective = "exclusive"
+)
+
+// Rule search constants
+const (
+	// defaultRuleBeta weighs precision and recall equally (F1)
+	defaultRuleBeta = 1.0
+
+	// defaultExclusivityPenalty is the weight of the sibling-overlap penalty
+	defaultExclusivityPenalty = 1.0
+
+	// maxRejectedCandidates is the number of rejected candidates kept in diagnostics
+	maxRejectedCandidates = 10
+
+	// defaultExactBudget is how long exact search may run per cluster before falling back
+	defaultExactBudget = 250 * time.Millisecond
+)
+
+// Rejection reasons reported in RuleDiagnostics
+const (
+	rejectNoImprovement  = "does not improve objective"
+	rejectPrunedFromBeam = "pruned from beam"
+	rejectOutscored      = "outscored by selected rule"
+)
+
+// RuleFitOptions configures decision list fitting. The zero value uses the default
+// greedy recall-first fitter.
+type RuleFitOptions struct {
+	// Objective selects the scoring function (default RuleObjectiveRecall)
+	Objective RuleObjective
+	// Beta weighs recall beta times as much as precision; < 1 favors precision (default 1)
+	Beta float64
+	// BeamWidth is the number of partial rules kept at each step; 1 (default) is greedy
+	BeamWidth int
+	// ExclusivityPenalty weighs the sibling-overlap penalty for RuleObjectiveExclusive (default 1)
+	ExclusivityPenalty float64
+	// Exact finds the optimal rule by branch-and-bound instead of greedy or beam search.
+	// The default recall objective is replaced by F-beta, which exact search can bound.
+	Exact bool
+	// ExactBudget limits exact search per cluster; when exceeded the rule comes from the
+	// non-exact fitter (default 250ms)
+	ExactBudget time.Duration
+}
+
+// withDefaults fills in unset fields
+func (o RuleFitOptions) withDefaults() RuleFitOptions {
+	if o.Objective == "" {
+		o.Objective = RuleObjectiveRecall
+	}
+	if o.Beta <= 0 {
+		o.Beta = defaultRuleBeta
+	}
+	if o.BeamWidth < 1 {
+		o.BeamWidth = 1
+	}
+	if o.ExclusivityPenalty <= 0 {
+		o.ExclusivityPenalty = defaultExclusivityPenalty
+	}
+	if o.ExactBudget <= 0 {
+		o.ExactBudget = defaultExactBudget
+	}
+	return o
+}
+
+// isKnown reports whether the objective is one of the RuleObjective constants
+func (o RuleFitOptions) isKnown() bool {
+	return o.Objective == RuleObjectiveRecall || o.usesSearch()
+}
+
+// usesSearch reports whether rules are fitted by objective search rather than the
+// default greedy fitter
+func (o RuleFitOptions) usesSearch() bool {
+	switch o.Objective {
+	case RuleObjectiveFBeta, RuleObjectiveLaplace, RuleObjectiveExclusive:
+		return true
+	}
+	return false
+}
+
+// RuleDiagnostics explains how a cluster's rule was chosen
+type RuleDiagnostics struct {
+	Objective     RuleObjective       // Objective the rule was fitted for
+	Score         float64             // Objective score of the selected rule
+	Rejected      []RejectedCandidate // Best-scoring candidates that were not selected
+	Exact         bool                // The rule is proven optimal over the candidate clauses
+	ExactTimedOut bool                // Exact search exceeded its budget; the rule is from the fallback fitter
+	NodesExplored int                 // Partial rules evaluated by exact search
+}
+
+// RejectedCandidate is a candidate rule that the search considered and discarded
+type RejectedCandidate struct {
+	Rule      string  // Human-readable rule
+	Score     float64 // Objective score
+	Precision float64
+	Recall    float64
+	Reason    string // Why the candidate was rejected
+}
+
+// ruleCandidate is a (partial) rule under evaluation
+type ruleCandidate struct {
+	clauses   []Clause
+	score     float64
+	precision float64
+	recall    float64
+}
+
+func (c ruleCandidate) rule() DecisionList {
+	return DecisionList{Clauses: c.clauses}
+}
+
+// negatedCount returns the number of negated clauses in the candidate
+func (c ruleCandidate) negatedCount() int {
+	count := 0
+	for _, clause := range c.clauses {
+		if clause.Negated {
+			count++
+		}
+	}
+	return count
+}
+
+// key returns a canonical string for the candidate, independent of clause order
+func (c ruleCandidate) key() string {
+	parts := make([]string, len(c.clauses))
+	for i, clause := range c.clauses {
+		parts[i] = DecisionList{Clauses: []Clause{clause}}.String()
+	}
+	sort.Strings(parts)
+	return joinStrings(parts, " AND ")
+}
+
+// ruleScorer evaluates rules against one cluster's positives and its siblings
+type ruleScorer struct {
+	opts         RuleFitOptions
+	positiveSet  map[int]bool
+	siblingSet   map[int]bool
+	facetSets    []FacetSet
+	numPositives int
+}
+
+// newRuleScorer creates a scorer for one cluster; siblings that are also positives are ignored
+func newRuleScorer(positiveIndices, siblingIndices []int, allFacetSets []FacetSet, opts RuleFitOptions) *ruleScorer {
+	scorer := &ruleScorer{
+		opts:         opts,
+		positiveSet:  make(map[int]bool, len(positiveIndices)),
+		siblingSet:   make(map[int]bool, len(siblingIndices)),
+		facetSets:    allFacetSets,
+		numPositives: len(positiveIndices),
+	}
+	for _, idx := range positiveIndices {
+		scorer.positiveSet[idx] = true
+	}
+	for _, idx := range siblingIndices {
+		if !scorer.positiveSet[idx] {
+			scorer.siblingSet[idx] = true
+		}
+	}
+	return scorer
+}
+
+// evaluate scores a set of clauses under the configured objective
+func (s *ruleScorer) evaluate(clauses []Clause) ruleCandidate {
+	rule := DecisionList{Clauses: clauses}
+	matches, truePositives, siblingMatches := 0, 0, 0
+	for idx, fs := range s.facetSets {
+		if !rule.Matches(fs) {
+			continue
+		}
+		matches++
+		if s.positiveSet[idx] {
+			truePositives++
+		} else if s.siblingSet[idx] {
+			siblingMatches++
+		}
+	}
+
+	candidate := s.score(truePositives, matches, siblingMatches)
+	candidate.clauses = clauses
+	return candidate
+}
+
+// score computes precision, recall and the objective from match counts
+func (s *ruleScorer) score(truePositives, matches, siblingMatches int) ruleCandidate {
+	var precision, recall float64
+	if matches > 0 {
+		precision = float64(truePositives) / float64(matches)
+	}
+	if s.numPositives > 0 {
+		recall = float64(truePositives) / float64(s.numPositives)
+	}
+
+	var score float64
+	switch s.opts.Objective {
+	case RuleObjectiveLaplace:
+		laplace := float64(truePositives+1) / float64(matches+2)
+		score = fBeta(laplace, recall, s.opts.Beta)
+	case RuleObjectiveExclusive:
+		score = fBeta(precision, recall, s.opts.Beta)
+		if matches > 0 {
+			score -= s.opts.ExclusivityPenalty * float64(siblingMatches) / float64(matches)
+		}
+	default:
+		score = fBeta(precision, recall, s.opts.Beta)
+	}
+
+	return ruleCandidate{
+		score:     score,
+		precision: precision,
+		recall:    recall,
+	}
+}
+
+// upperBound is the best score any refinement of a rule with truePositives matched
+// positives could reach: adding clauses can only drop matches, so at best precision
+// becomes perfect while recall stays the same. Sibling penalties are at best zero.
+func (s *ruleScorer) upperBound(truePositives int) float64 {
+	if s.numPositives == 0 {
+		return 0
+	}
+	recall := float64(truePositives) / float64(s.numPositives)
+	precision := 1.0
+	if s.opts.Objective == RuleObjectiveLaplace {
+		precision = float64(truePositives+1) / float64(truePositives+2)
+	}
+	return fBeta(precision, recall, s.opts.Beta)
+}
+
+// fBeta is the weighted harmonic mean of precision and recall
+func fBeta(precision, recall, beta float64) float64 {
+	b2 := beta * beta
+	denom := b2*precision + recall
+	if denom == 0 {
+		return 0
+	}
+	return (1 + b2) * precision * recall / denom
+}
+
+// diagnosticsRecorder collects rejected candidates, keeping one entry per rule
+type diagnosticsRecorder struct {
+	rejected map[string]RejectedCandidate
+}
+
+func newDiagnosticsRecorder() *diagnosticsRecorder {
+	return &diagnosticsRecorder{rejected: make(map[string]RejectedCandidate)}
+}
+
+// reject records a candidate, keeping the first reason seen for each rule
+func (d *diagnosticsRecorder) reject(c ruleCandidate, reason string) {
+	key := c.key()
+	if _, ok := d.rejected[key]; ok {
+		return
+	}
+	d.rejected[key] = RejectedCandidate{
+		Rule:      c.rule().String(),
+		Score:     c.score,
+		Precision: c.precision,
+		Recall:    c.recall,
+		Reason:    reason,
+	}
+}
+
+// build returns diagnostics for the selected rule with the best-scoring rejections
+func (d *diagnosticsRecorder) build(objective RuleObjective, selected ruleCandidate) *RuleDiagnostics {
+	delete(d.rejected, selected.key())
+
+	rejected := make([]RejectedCandidate, 0, len(d.rejected))
+	for _, r := range d.rejected {
+		rejected = append(rejected, r)
+	}
+	sort.Slice(rejected, func(a, b int) bool {
+		if rejected[a].Score != rejected[b].Score {
+			return rejected[a].Score > rejected[b].Score
+		}
+		return rejected[a].Rule < rejected[b].Rule
+	})
+	if len(rejected) > maxRejectedCandidates {
+		rejected = rejected[:maxRejectedCandidates]
+	}
+
+	return &RuleDiagnostics{
+		Objective: objective,
+		Score:     selected.score,
+		Rejected:  rejected,
+	}
+}
+
+// fitDecisionListWithOptions fits a rule for a cluster. With the default options it is
+// fitDecisionList; with a search objective it runs beam search over clause combinations
+// and also returns diagnostics. siblingIndices are the items of the other clusters.
+func fitDecisionListWithOptions(positiveIndices, siblingIndices []int, allFacetSets []FacetSet, opts RuleFitOptions, log *logger.Logger) (*DecisionList, *RuleQuality, *RuleDiagnostics) {
+	opts = opts.withDefaults()
+	if opts.Exact {
+		return fitDecisionListExact(positiveIndices, siblingIndices, allFacetSets, opts, log)
+	}
+	if !opts.usesSearch() {
+		rule, quality := fitDecisionList(positiveIndices, allFacetSets, log)
+		return rule, quality, nil
+	}
+
+	if len(positiveIndices) == 0 || len(allFacetSets) == 0 {
+		return &DecisionList{}, &RuleQuality{}, nil
+	}
+
+	rule, diagnostics := beamSearchRule(positiveIndices, siblingIndices, allFacetSets, opts, log)
+	quality := computeRuleQuality(*rule, positiveIndices, allFacetSets)
+
+	log.Debug("fitDecisionList: beam search completed",
+		"objective", string(opts.Objective),
+		"beam_width", opts.BeamWidth,
+		"rule", rule.String(),
+		"score", fmt.Sprintf("%.3f", diagnostics.Score),
+		"precision", fmt.Sprintf("%.3f", quality.Precision),
+		"recall", fmt.Sprintf("%.3f", quality.Recall),
+		"rejected", len(diagnostics.Rejected),
+	)
+
+	return rule, quality, diagnostics
+}
+
+// beamSearchRule searches clause combinations up to MaxClausesInRule, keeping the
+// BeamWidth best partial rules at each depth, and returns the best rule seen
+func beamSearchRule(positiveIndices, siblingIndices []int, allFacetSets []FacetSet, opts RuleFitOptions, log *logger.Logger) (*DecisionList, *RuleDiagnostics) {
+	scorer := newRuleScorer(positiveIndices, siblingIndices, allFacetSets, opts)
+	facetStats := collectFacetStats(scorer.positiveSet, allFacetSets)
+	diagnostics := newDiagnosticsRecorder()
+
+	best := scorer.evaluate(nil)
+	beam := []ruleCandidate{best}
+
+	for depth := 0; depth < MaxClausesInRule; depth++ {
+		seen := make(map[string]bool)
+		var expansions []ruleCandidate
+
+		for _, state := range beam {
+			for _, clause := range candidateClauses(state.clauses, facetStats, scorer) {
+				clauses := append(append([]Clause{}, state.clauses...), clause)
+				candidate := scorer.evaluate(clauses)
+				if candidate.score <= state.score {
+					diagnostics.reject(candidate, rejectNoImprovement)
+					continue
+				}
+
+				// The same rule can be reached from several partial rules
+				key := candidate.key()
+				if seen[key] {
+					continue
+				}
+				seen[key] = true
+				expansions = append(expansions, candidate)
+			}
+		}
+
+		if len(expansions) == 0 {
+			break
+		}
+
+		sort.Slice(expansions, func(a, b int) bool {
+			if expansions[a].score != expansions[b].score {
+				return expansions[a].score > expansions[b].score
+			}
+			// On ties prefer positive clauses, which read more naturally
+			if na, nb := expansions[a].negatedCount(), expansions[b].negatedCount(); na != nb {
+				return na < nb
+			}
+			return expansions[a].key() < expansions[b].key()
+		})
+		if len(expansions) > opts.BeamWidth {
+			for _, pruned := range expansions[opts.BeamWidth:] {
+				diagnostics.reject(pruned, rejectPrunedFromBeam)
+			}
+			expansions = expansions[:opts.BeamWidth]
+		}
+		beam = expansions
+
+		for _, candidate := range beam {
+			if candidate.score > best.score {
+				diagnostics.reject(best, rejectOutscored)
+				best = candidate
+			} else {
+				diagnostics.reject(candidate, rejectOutscored)
+			}
+		}
+
+		log.Debug("fitDecisionList: beam search step",
+			"depth", depth+1,
+			"beam_size", len(beam),
+			"best_rule", best.rule().String(),
+			"best_score", fmt.Sprintf("%.3f", best.score),
+		)
+	}
+
+	rule := best.rule()
+	return &rule, diagnostics.build(opts.Objective, best)
+}
+
+// candidateClauses returns the clauses that could extend a partial rule: for each facet
+// not yet used, a positive clause of its over-represented values and a negated clause of
+// the values that only appear on negatives the rule currently matches
+func candidateClauses(current []Clause, facetStats facetValueStats, scorer *ruleScorer) []Clause {
+	usedFacets := make(map[string]bool, len(current))
+	for _, clause := range current {
+		usedFacets[clause.FacetName] = true
+	}
+
+	facetNames := make([]string, 0, len(facetStats))
+	for facetName := range facetStats {
+		if !usedFacets[facetName] {
+			facetNames = append(facetNames, facetName)
+		}
+	}
+	sort.Strings(facetNames)
+
+	totalItems := len(scorer.facetSets)
+	var candidates []Clause
+	for _, facetName := range facetNames {
+		values := selectValuesWithLift(facetStats[facetName], scorer.numPositives, totalItems)
+		if len(values) == 0 {
+			continue
+		}
+		sort.Strings(values)
+		candidates = append(candidates, Clause{FacetName: facetName, Values: values})
+	}
+
+	for _, facetName := range facetNames {
+		if negated, ok := negatedClauseForFacet(current, facetName, scorer.positiveSet, scorer.facetSets); ok {
+			candidates = append(candidates, negated)
+		}
+	}
+
+	return candidates
+}
+
+// negatedClauseForFacet builds a negated clause excluding up to MaxNegatedValues values of
+// a facet that appear on negatives matched by the current rule but on none of its positives
+func negatedClauseForFacet(current []Clause, facetName string, positiveSet map[int]bool, allFacetSets []FacetSet) (Clause, bool) {
+	rule := DecisionList{Clauses: current}
+	positiveValues := make(map[string]bool)
+	negativeCounts := make(map[string]int)
+	for idx, fs := range allFacetSets {
+		if !rule.Matches(fs) {
+			continue
+		}
+		for facetKey := range fs {
+			name, value := parseFacetKey(facetKey)
+			if name != facetName {
+				continue
+			}
+			if positiveSet[idx] {
+				positiveValues[value] = true
+			} else {
+				negativeCounts[value]++
+			}
+		}
+	}
+
+	var values []string
+	for value := range negativeCounts {
+		if !positiveValues[value] {
+			values = append(values, value)
+		}
+	}
+	if len(values) == 0 {
+		return Clause{}, false
+	}
+
+	sort.Slice(values, func(a, b int) bool {
+		if negativeCounts[values[a]] != negativeCounts[values[b]] {
+			return negativeCounts[values[a]] > negativeCounts[values[b]]
+		}
+		return values[a] < values[b]
+	})
+	if len(values) > MaxNegatedValues {
+		values = values[:MaxNegatedValues]
+	}
+
+	return Clause{FacetName: facetName, Values: values, Negated: true}, true
+}
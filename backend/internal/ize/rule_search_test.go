@@ -0,0 +1,196 @@
+package ize
+
+import (
+	"fmt"
+	"ize/internal/logger"
+	"math"
+	"math/rand"
+	"testing"
+)
+
+func TestFBeta(t *testing.T) {
+	tests := []struct {
+		name      string
+		precision float64
+		recall    float64
+		beta      float64
+		expected  float64
+	}{
+		{"F1", 0.5, 1.0, 1, 2.0 / 3.0},
+		{"F0.5 favors precision", 1.0, 0.5, 0.5, 1.25 * 0.5 / (0.25 + 0.5)},
+		{"F2 favors recall", 1.0, 0.5, 2, 5 * 0.5 / (4 + 0.5)},
+		{"zero", 0, 0, 1, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if result := fBeta(tt.precision, tt.recall, tt.beta); math.Abs(result-tt.expected) > 1e-9 {
+				t.Errorf("fBeta(%v, %v, %v) = %f, want %f", tt.precision, tt.recall, tt.beta, result, tt.expected)
+			}
+		})
+	}
+}
+
+// shoesFacetSets has a cluster of 4 shoes (indices 0-3): three Nike and one red shoe
+// without a brand. Index 4 is another red shoe outside the cluster; the rest are hats.
+func shoesFacetSets() []FacetSet {
+	facetSets := []FacetSet{
+		{"category:Shoes": true, "brand:Nike": true},
+		{"category:Shoes": true, "brand:Nike": true},
+		{"category:Shoes": true, "brand:Nike": true},
+		{"category:Shoes": true, "color:Red": true},
+		{"category:Shoes": true, "color:Red": true},
+	}
+	for i := 0; i < 5; i++ {
+		facetSets = append(facetSets, FacetSet{"category:Hats": true, "color:Blue": true})
+	}
+	return facetSets
+}
+
+func TestFitDecisionListWithOptions_DefaultIsGreedy(t *testing.T) {
+	facetSets := shoesFacetSets()
+	positiveIndices := []int{0, 1, 2, 3}
+
+	rule, quality, diagnostics := fitDecisionListWithOptions(positiveIndices, nil, facetSets, RuleFitOptions{}, logger.Default())
+	expectedRule, expectedQuality := fitDecisionList(positiveIndices, facetSets, logger.Default())
+
+	if rule.String() != expectedRule.String() {
+		t.Errorf("fitDecisionListWithOptions() rule = %q, want %q", rule.String(), expectedRule.String())
+	}
+	if *quality != *expectedQuality {
+		t.Errorf("fitDecisionListWithOptions() quality = %+v, want %+v", *quality, *expectedQuality)
+	}
+	if diagnostics != nil {
+		t.Errorf("fitDecisionListWithOptions() diagnostics = %+v, want nil for default fitter", diagnostics)
+	}
+}
+
+func TestFitDecisionListWithOptions_FBeta(t *testing.T) {
+	facetSets := shoesFacetSets()
+	positiveIndices := []int{0, 1, 2, 3}
+
+	// F1: category:Shoes (P=0.8, R=1) beats brand:Nike (P=1, R=0.75)
+	rule, _, diagnostics := fitDecisionListWithOptions(positiveIndices, nil, facetSets, RuleFitOptions{Objective: RuleObjectiveFBeta}, logger.Default())
+	if rule.String() != "category:Shoes" {
+		t.Errorf("fitDecisionListWithOptions(F1) rule = %q, want %q", rule.String(), "category:Shoes")
+	}
+	if diagnostics == nil {
+		t.Fatal("fitDecisionListWithOptions(F1) diagnostics = nil")
+	}
+	if math.Abs(diagnostics.Score-fBeta(0.8, 1, 1)) > 1e-9 {
+		t.Errorf("fitDecisionListWithOptions(F1) score = %f, want %f", diagnostics.Score, fBeta(0.8, 1, 1))
+	}
+
+	// The runner-up should be reported with a reason
+	found := false
+	for _, r := range diagnostics.Rejected {
+		if r.Rule == "brand:Nike" {
+			found = true
+			if r.Reason == "" {
+				t.Errorf("rejected candidate %q has no reason", r.Rule)
+			}
+			if r.Precision != 1 || r.Recall != 0.75 {
+				t.Errorf("rejected candidate %q precision/recall = %f/%f, want 1/0.75", r.Rule, r.Precision, r.Recall)
+			}
+		}
+		if r.Rule == rule.String() {
+			t.Errorf("selected rule %q listed as rejected", r.Rule)
+		}
+	}
+	if !found {
+		t.Errorf("fitDecisionListWithOptions(F1) rejected = %+v, want brand:Nike", diagnostics.Rejected)
+	}
+
+	// F0.5 favors precision: brand:Nike wins
+	rule, _, _ = fitDecisionListWithOptions(positiveIndices, nil, facetSets, RuleFitOptions{Objective: RuleObjectiveFBeta, Beta: 0.5}, logger.Default())
+	if rule.String() != "brand:Nike" {
+		t.Errorf("fitDecisionListWithOptions(F0.5) rule = %q, want %q", rule.String(), "brand:Nike")
+	}
+}
+
+func TestFitDecisionListWithOptions_ExclusivityPenalty(t *testing.T) {
+	facetSets := shoesFacetSets()
+	positiveIndices := []int{0, 1, 2, 3}
+	opts := RuleFitOptions{Objective: RuleObjectiveExclusive}
+
+	// Without siblings the objective is plain F1
+	rule, _, _ := fitDecisionListWithOptions(positiveIndices, nil, facetSets, opts, logger.Default())
+	if rule.String() != "category:Shoes" {
+		t.Errorf("fitDecisionListWithOptions(no siblings) rule = %q, want %q", rule.String(), "category:Shoes")
+	}
+
+	// When the other shoe belongs to a sibling cluster, swallowing it is penalized
+	rule, quality, _ := fitDecisionListWithOptions(positiveIndices, []int{4}, facetSets, opts, logger.Default())
+	if rule.String() != "brand:Nike" {
+		t.Errorf("fitDecisionListWithOptions(sibling) rule = %q, want %q", rule.String(), "brand:Nike")
+	}
+	if quality.Precision != 1 {
+		t.Errorf("fitDecisionListWithOptions(sibling) precision = %f, want 1", quality.Precision)
+	}
+}
+
+func TestRuleScorer_Laplace(t *testing.T) {
+	facetSets := shoesFacetSets()
+	scorer := &ruleScorer{
+		opts:         RuleFitOptions{Objective: RuleObjectiveLaplace}.withDefaults(),
+		positiveSet:  map[int]bool{0: true, 1: true, 2: true, 3: true},
+		facetSets:    facetSets,
+		numPositives: 4,
+	}
+
+	// brand:Nike: 3 matches, all positive; Laplace precision (3+1)/(3+2) = 0.8
+	candidate := scorer.evaluate([]Clause{{FacetName: "brand", Values: []string{"Nike"}}})
+	if candidate.precision != 1 || candidate.recall != 0.75 {
+		t.Errorf("evaluate() precision/recall = %f/%f, want 1/0.75", candidate.precision, candidate.recall)
+	}
+	if expected := fBeta(0.8, 0.75, 1); math.Abs(candidate.score-expected) > 1e-9 {
+		t.Errorf("evaluate() laplace score = %f, want %f", candidate.score, expected)
+	}
+}
+
+func TestBeamSearchRule_WiderBeamNeverWorse(t *testing.T) {
+	for seed := int64(1); seed <= 20; seed++ {
+		rng := rand.New(rand.NewSource(seed))
+		facetSets := make([]FacetSet, 40)
+		var positiveIndices []int
+		for i := range facetSets {
+			fs := FacetSet{}
+			for f := 0; f < 4; f++ {
+				fs[fmt.Sprintf("f%d:v%d", f, rng.Intn(4))] = true
+			}
+			facetSets[i] = fs
+			if fs["f0:v0"] || (fs["f1:v1"] && rng.Intn(2) == 0) {
+				positiveIndices = append(positiveIndices, i)
+			}
+		}
+		if len(positiveIndices) == 0 {
+			continue
+		}
+
+		_, _, greedy := fitDecisionListWithOptions(positiveIndices, nil, facetSets, RuleFitOptions{Objective: RuleObjectiveFBeta}, logger.Default())
+		_, _, beam := fitDecisionListWithOptions(positiveIndices, nil, facetSets, RuleFitOptions{Objective: RuleObjectiveFBeta, BeamWidth: 5}, logger.Default())
+		if beam.Score < greedy.Score-1e-9 {
+			t.Errorf("seed %d: beam score %f < greedy score %f", seed, beam.Score, greedy.Score)
+		}
+		if len(beam.Rejected) > maxRejectedCandidates {
+			t.Errorf("seed %d: %d rejected candidates, want <= %d", seed, len(beam.Rejected), maxRejectedCandidates)
+		}
+	}
+}
+
+func TestFitAndReassign_UnknownObjectiveUsesDefault(t *testing.T) {
+	facetSets := shoesFacetSets()
+	allItems := make([]Result, len(facetSets))
+	for i := range allItems {
+		allItems[i] = Result{ID: fmt.Sprintf("%d", i)}
+	}
+	groups := []ClusterGroup{{Name: "Shoes", Items: allItems[:4]}}
+
+	result := fitAndReassign(groups, allItems, facetSets, ClusterOptions{RuleFit: RuleFitOptions{Objective: "bogus"}}, logger.Default())
+	if len(result) != 1 || result[0].Rule == nil {
+		t.Fatalf("fitAndReassign() = %+v, want one group with a rule", result)
+	}
+	if result[0].RuleDiagnostics != nil {
+		t.Errorf("fitAndReassign() diagnostics = %+v, want nil for default fitter", result[0].RuleDiagnostics)
+	}
+}
@@ -34,9 +34,151 @@ func jaccardDistance(a, b FacetSet) float64 {
 	return 1.0 - similarity
 }
 
+// DistanceMetric selects how pairwise item distances are computed for clustering
+type DistanceMetric string
+
+const (
+	// DistanceJaccard treats every facet value as equally informative
+	DistanceJaccard DistanceMetric = "jaccard"
+	// DistanceWeightedJaccard weights facet values by their inverse document frequency
+	// within the result set and by per-facet weights, so rare, distinctive values
+	// (brand:Sony) count for more than near-universal ones (in_stock:true)
+	DistanceWeightedJaccard DistanceMetric = "weighted_jaccard"
+)
+
+// pairDistance returns the distance between the items at indices i and j
+type pairDistance func(i, j int) float64
+
+// newPairDistance builds the distance function selected by the cluster options.
+// When opts.TextWeight is positive, the facet distance is blended with the cosine
+// distance between TF-IDF vectors of item names and descriptions. When
+// opts.EmbeddingWeight is positive, that result is in turn blended with the cosine
+// distance between the items' embeddings.
+func newPairDistance(items []Result, facetSets []FacetSet, opts ClusterOptions) pairDistance {
+	dist := newFacetDistance(facetSets, opts)
+
+	if opts.TextWeight > 0 {
+		vectors := buildTextVectors(items)
+		textDist := func(i, j int) float64 {
+			return cosineDistance(vectors[i], vectors[j])
+		}
+		dist = hybridDistance(dist, textDist, opts.TextWeight)
+	}
+
+	if embeddings := itemEmbeddings(items, opts); embeddings != nil {
+		embeddingDist := func(i, j int) float64 {
+			return denseCosineDistance(embeddings[i], embeddings[j])
+		}
+		dist = hybridDistance(dist, embeddingDist, opts.EmbeddingWeight)
+	}
+
+	return dist
+}
+
+// itemEmbeddings returns each item's embedding (nil where it has none), or nil when
+// embedding similarity is not blended in
+func itemEmbeddings(items []Result, opts ClusterOptions) [][]float64 {
+	if opts.EmbeddingWeight <= 0 || len(opts.Embeddings) == 0 {
+		return nil
+	}
+	embeddings := make([][]float64, len(items))
+	for i, item := range items {
+		embeddings[i] = opts.Embeddings[item.ID]
+	}
+	return embeddings
+}
+
+// newFacetDistance builds the facet-set distance selected by opts.Distance
+func newFacetDistance(facetSets []FacetSet, opts ClusterOptions) pairDistance {
+	switch opts.Distance {
+	case DistanceWeightedJaccard:
+		weights := computeTokenWeights(facetSets, opts.FacetWeights)
+		return func(i, j int) float64 {
+			return weightedJaccardDistance(facetSets[i], facetSets[j], weights)
+		}
+	default:
+		return func(i, j int) float64 {
+			return jaccardDistance(facetSets[i], facetSets[j])
+		}
+	}
+}
+
+// computeTokenWeights assigns each "facetName:facetValue" token the weight
+// idf(token) * facetWeight(facetName), where idf = ln(1 + N/df) over the result set.
+// The smoothing keeps a token present on every item at a small positive weight (ln 2),
+// so items sharing only such tokens still count as similar. Facets missing from
+// facetWeights (or with a non-positive weight) have weight 1.
+func computeTokenWeights(facetSets []FacetSet, facetWeights map[string]float64) map[string]float64 {
+	docFreq := make(map[string]int)
+	for _, fs := range facetSets {
+		for token := range fs {
+			docFreq[token]++
+		}
+	}
+
+	n := float64(len(facetSets))
+	weights := make(map[string]float64, len(docFreq))
+	for token, df := range docFreq {
+		facetName, _ := parseFacetKey(token)
+		facetWeight := 1.0
+		if w, ok := facetWeights[facetName]; ok && w > 0 {
+			facetWeight = w
+		}
+		weights[token] = math.Log(1+n/float64(df)) * facetWeight
+	}
+	return weights
+}
+
+// weightedJaccardDistance calculates 1 - weighted Jaccard similarity, where the
+// similarity is the weight of shared tokens over the weight of all tokens.
+// When the union carries no weight, identical non-empty sets are at distance 0 and
+// any others at 1.0 (no information)
+func weightedJaccardDistance(a, b FacetSet, weights map[string]float64) float64 {
+	intersection := 0.0
+	union := 0.0
+
+	for key := range a {
+		w := weights[key]
+		union += w
+		if b[key] {
+			intersection += w
+		}
+	}
+	for key := range b {
+		if !a[key] {
+			union += weights[key]
+		}
+	}
+
+	if union <= 0 {
+		if len(a) > 0 && len(a) == len(b) && intersectionSize(a, b) == len(a) {
+			return 0.0
+		}
+		return 1.0
+	}
+	return 1.0 - intersection/union
+}
+
+// intersectionSize counts the tokens in both a and b
+func intersectionSize(a, b FacetSet) int {
+	n := 0
+	for key := range a {
+		if b[key] {
+			n++
+		}
+	}
+	return n
+}
+
 // buildDistanceMatrix creates a symmetric distance matrix using Jaccard distance
 func buildDistanceMatrix(facetSets []FacetSet) [][]float64 {
-	n := len(facetSets)
+	return buildDistanceMatrixWith(len(facetSets), func(i, j int) float64 {
+		return jaccardDistance(facetSets[i], facetSets[j])
+	})
+}
+
+// buildDistanceMatrixWith creates a symmetric n x n distance matrix from a pair distance function
+func buildDistanceMatrixWith(n int, dist pairDistance) [][]float64 {
 	matrix := make([][]float64, n)
 
 	for i := 0; i < n; i++ {
@@ -47,9 +189,9 @@ func buildDistanceMatrix(facetSets []FacetSet) [][]float64 {
 	// Fill upper triangle and mirror to lower
 	for i := 0; i < n; i++ {
 		for j := i + 1; j < n; j++ {
-			dist := jaccardDistance(facetSets[i], facetSets[j])
-			matrix[i][j] = dist
-			matrix[j][i] = dist
+			d := dist(i, j)
+			matrix[i][j] = d
+			matrix[j][i] = d
 		}
 	}
 
@@ -170,12 +312,19 @@ func computeNearestClusterDistance(i, ownCluster int, clusters [][]int, distMatr
 // selectOptimalK finds the optimal number of clusters using silhouette score
 // Returns the optimal k, cluster assignments, and all silhouette scores tried
 func selectOptimalK(distMatrix [][]float64, facetSets []FacetSet, log *logger.Logger) (int, []int, map[int]float64) {
-	n := len(distMatrix)
-	silhouetteScores := make(map[int]float64)
-
 	// Build dendrogram once
 	root := agglomerativeCluster(distMatrix)
 
+	return selectOptimalKFromDendrogram(root, len(distMatrix), func(assignments []int, k int) float64 {
+		return silhouetteScore(distMatrix, assignments, k)
+	}, log)
+}
+
+// selectOptimalKFromDendrogram cuts the dendrogram at each candidate k and keeps the
+// cut with the best score, so dense and sparse distance representations share k selection
+func selectOptimalKFromDendrogram(root *clusterNode, n int, scoreFn func(assignments []int, k int) float64, log *logger.Logger) (int, []int, map[int]float64) {
+	silhouetteScores := make(map[int]float64)
+
 	// Maximum k is min(6, n-1)
 	maxK := 6
 	if n-1 < maxK {
@@ -196,7 +345,7 @@ func selectOptimalK(distMatrix [][]float64, facetSets []FacetSet, log *logger.Lo
 		}
 
 		assignments := clustersToAssignments(clusters, n)
-		score := silhouetteScore(distMatrix, assignments, k)
+		score := scoreFn(assignments, k)
 		silhouetteScores[k] = score
 
 		log.Debug("ProcessCluster: evaluated k",
@@ -0,0 +1,243 @@
+package ize
+
+import (
+	"math"
+	"strings"
+	"unicode"
+)
+
+// textVector is a sparse, L2-normalized TF-IDF vector keyed by stemmed term
+type textVector map[string]float64
+
+// nameTermBoost is how many times each name term is counted relative to description
+// terms, since product names are short and usually say what the item is
+const nameTermBoost = 2
+
+// stopwords are common English words that carry no information for grouping products
+var stopwords = map[string]bool{
+	"a": true, "about": true, "above": true, "after": true, "all": true, "also": true,
+	"an": true, "and": true, "any": true, "are": true, "as": true, "at": true,
+	"be": true, "been": true, "being": true, "both": true, "but": true, "by": true,
+	"can": true, "could": true, "did": true, "do": true, "does": true, "each": true,
+	"for": true, "from": true, "further": true, "had": true, "has": true, "have": true,
+	"having": true, "he": true, "her": true, "here": true, "his": true, "how": true,
+	"i": true, "if": true, "in": true, "into": true, "is": true, "it": true, "its": true,
+	"just": true, "may": true, "more": true, "most": true, "much": true, "my": true,
+	"no": true, "nor": true, "not": true, "now": true, "of": true, "off": true, "on": true,
+	"once": true, "only": true, "or": true, "other": true, "our": true, "out": true,
+	"over": true, "own": true, "per": true, "same": true, "she": true, "should": true,
+	"so": true, "some": true, "such": true, "than": true, "that": true, "the": true,
+	"their": true, "them": true, "then": true, "there": true, "these": true, "they": true,
+	"this": true, "those": true, "through": true, "to": true, "too": true, "under": true,
+	"until": true, "up": true, "us": true, "very": true, "was": true, "we": true,
+	"were": true, "what": true, "when": true, "where": true, "which": true, "while": true,
+	"who": true, "why": true, "will": true, "with": true, "would": true, "you": true,
+	"your": true,
+}
+
+// stemSuffixes are stripped (or rewritten) in order; the first matching rule wins.
+// This is a deliberately small suffix stripper rather than a full Porter stemmer:
+// it only needs to map common inflections of the same word to one term.
+var stemSuffixes = []struct {
+	suffix      string
+	replacement string
+}{
+	{"ational", "ate"},
+	{"ization", "ize"},
+	{"fulness", "ful"},
+	{"ousness", "ous"},
+	{"iveness", "ive"},
+	{"ements", "ement"},
+	{"ments", "ment"},
+	{"ness", ""},
+	{"ings", ""},
+	{"ing", ""},
+	{"edly", ""},
+	{"ed", ""},
+	{"ies", "y"},
+	{"sses", "ss"},
+	{"ly", ""},
+	{"s", ""},
+}
+
+// minStemLength is the shortest stem a suffix rule may leave behind
+const minStemLength = 3
+
+// stemWord reduces a lowercase word to its stem
+func stemWord(word string) string {
+	for _, rule := range stemSuffixes {
+		if !strings.HasSuffix(word, rule.suffix) {
+			continue
+		}
+		stem := word[:len(word)-len(rule.suffix)]
+		if len(stem) < minStemLength {
+			continue
+		}
+		// Keep words like "glass" or "status" intact
+		if rule.suffix == "s" && (strings.HasSuffix(stem, "s") || strings.HasSuffix(stem, "u") || strings.HasSuffix(stem, "i")) {
+			return word
+		}
+		return stem + rule.replacement
+	}
+	return word
+}
+
+// tokenizeText lowercases text, splits it on non-alphanumeric characters, drops
+// stopwords and very short tokens, and stems what remains
+func tokenizeText(text string) []string {
+	words := contentWords(text)
+	terms := make([]string, len(words))
+	for i, w := range words {
+		terms[i] = stemWord(w)
+	}
+	return terms
+}
+
+// contentWords lowercases text, splits it on non-alphanumeric characters, and drops
+// stopwords and very short tokens, without stemming
+func contentWords(text string) []string {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+
+	kept := make([]string, 0, len(words))
+	for _, w := range words {
+		if len(w) < 2 || stopwords[w] {
+			continue
+		}
+		kept = append(kept, w)
+	}
+	return kept
+}
+
+// surfaceForms maps each stem to its most frequent unstemmed word in texts, so that
+// terms can be shown to users as real words ("cancelling" rather than "cancell")
+func surfaceForms(texts []string) map[string]string {
+	counts := make(map[string]map[string]int)
+	for _, text := range texts {
+		for _, w := range contentWords(text) {
+			stem := stemWord(w)
+			if counts[stem] == nil {
+				counts[stem] = make(map[string]int)
+			}
+			counts[stem][w]++
+		}
+	}
+
+	forms := make(map[string]string, len(counts))
+	for stem, words := range counts {
+		best, bestCount := "", 0
+		for w, c := range words {
+			if c > bestCount || (c == bestCount && w < best) {
+				best, bestCount = w, c
+			}
+		}
+		forms[stem] = best
+	}
+	return forms
+}
+
+// itemTermCounts counts stemmed terms in an item's name and description
+func itemTermCounts(item Result) map[string]int {
+	counts := make(map[string]int)
+	for _, term := range tokenizeText(item.Name) {
+		counts[term] += nameTermBoost
+	}
+	for _, term := range tokenizeText(item.Description) {
+		counts[term]++
+	}
+	return counts
+}
+
+// buildTextVectors computes TF-IDF vectors for items from their names and descriptions.
+// Term frequency is log-scaled (1 + ln tf) and idf = ln(N / df) within the result set,
+// so terms on every item are ignored. Items with no informative terms get an empty vector.
+func buildTextVectors(items []Result) []textVector {
+	termCounts := make([]map[string]int, len(items))
+	docFreq := make(map[string]int)
+	for i, item := range items {
+		termCounts[i] = itemTermCounts(item)
+		for term := range termCounts[i] {
+			docFreq[term]++
+		}
+	}
+
+	n := float64(len(items))
+	vectors := make([]textVector, len(items))
+	for i, counts := range termCounts {
+		vec := make(textVector, len(counts))
+		norm := 0.0
+		for term, tf := range counts {
+			idf := math.Log(n / float64(docFreq[term]))
+			if idf <= 0 {
+				continue
+			}
+			w := (1 + math.Log(float64(tf))) * idf
+			vec[term] = w
+			norm += w * w
+		}
+		if norm > 0 {
+			norm = math.Sqrt(norm)
+			for term := range vec {
+				vec[term] /= norm
+			}
+		}
+		vectors[i] = vec
+	}
+
+	return vectors
+}
+
+// textTermSets returns the set of informative terms of each item's text vector, for
+// finding items with similar text by MinHash
+func textTermSets(vectors []textVector) []FacetSet {
+	sets := make([]FacetSet, len(vectors))
+	for i, vec := range vectors {
+		sets[i] = make(FacetSet, len(vec))
+		for term := range vec {
+			sets[i][term] = true
+		}
+	}
+	return sets
+}
+
+// cosineDistance calculates 1 - cosine similarity between two normalized vectors
+// Returns 1.0 if either vector is empty (no shared information)
+func cosineDistance(a, b textVector) float64 {
+	if len(a) == 0 || len(b) == 0 {
+		return 1.0
+	}
+	if len(b) < len(a) {
+		a, b = b, a
+	}
+	dot := 0.0
+	for term, w := range a {
+		dot += w * b[term]
+	}
+	// Guard against rounding pushing identical vectors slightly past 1
+	return math.Max(0, 1.0-dot)
+}
+
+// denseCosineDistance calculates 1 - cosine similarity between two unit-length dense vectors
+// Returns 1.0 if either vector is missing or their dimensions differ
+func denseCosineDistance(a, b []float64) float64 {
+	if len(a) == 0 || len(a) != len(b) {
+		return 1.0
+	}
+	dot := 0.0
+	for i := range a {
+		dot += a[i] * b[i]
+	}
+	// Cosine distance of normalized vectors lies in [0, 2]; clamp to the [0, 1] range
+	// used by the other distances so opposite vectors read as "unrelated"
+	return math.Max(0, math.Min(1, 1.0-dot))
+}
+
+// hybridDistance blends a base distance (e.g. facets) with another distance (e.g. text)
+// weight is clamped to [0, 1]; 0 is base-only and 1 is other-only
+func hybridDistance(base, other pairDistance, weight float64) pairDistance {
+	weight = math.Max(0, math.Min(1, weight))
+	return func(i, j int) float64 {
+		return (1-weight)*base(i, j) + weight*other(i, j)
+	}
+}
@@ -0,0 +1,145 @@
+package ize
+
+import (
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestStemWord(t *testing.T) {
+	tests := []struct {
+		word     string
+		expected string
+	}{
+		{"headphones", "headphone"},
+		{"headphone", "headphone"},
+		{"batteries", "battery"},
+		{"cancelling", "cancell"},
+		{"cancelled", "cancell"},
+		{"glass", "glass"},
+		{"status", "status"},
+		{"wireless", "wireless"},
+		{"bus", "bus"},
+		{"tv", "tv"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.word, func(t *testing.T) {
+			if result := stemWord(tt.word); result != tt.expected {
+				t.Errorf("stemWord(%q) = %q, want %q", tt.word, result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestTokenizeText(t *testing.T) {
+	result := tokenizeText("The Wireless Headphones, with Noise-Cancelling and 30h batteries!")
+	expected := []string{"wireless", "headphone", "noise", "cancell", "30h", "battery"}
+
+	if !reflect.DeepEqual(result, expected) {
+		t.Errorf("tokenizeText() = %v, want %v", result, expected)
+	}
+}
+
+func TestBuildTextVectors(t *testing.T) {
+	items := []Result{
+		{ID: "1", Name: "Wireless Headphones", Description: "Great sound"},
+		{ID: "2", Name: "Wired Headphones", Description: "Great sound"},
+		{ID: "3", Name: "Leather Boots", Description: "Great fit"},
+		{ID: "4", Name: "", Description: ""},
+	}
+
+	vectors := buildTextVectors(items)
+
+	// Vectors with terms should be unit length
+	for i := 0; i < 3; i++ {
+		norm := 0.0
+		for _, w := range vectors[i] {
+			norm += w * w
+		}
+		if math.Abs(norm-1) > 1e-9 {
+			t.Errorf("buildTextVectors() vector %d norm^2 = %f, want 1", i, norm)
+		}
+	}
+
+	// "great" appears in 3 of 4 documents: still informative, but less than "headphone"
+	if vectors[0]["great"] >= vectors[0]["headphone"] {
+		t.Errorf("buildTextVectors() great = %f should be < headphone = %f", vectors[0]["great"], vectors[0]["headphone"])
+	}
+
+	if len(vectors[3]) != 0 {
+		t.Errorf("buildTextVectors() empty item vector = %v, want empty", vectors[3])
+	}
+
+	// Headphones should be closer to each other than to boots
+	if cosineDistance(vectors[0], vectors[1]) >= cosineDistance(vectors[0], vectors[2]) {
+		t.Errorf("cosineDistance() headphones should be closer to each other than to boots")
+	}
+	if cosineDistance(vectors[0], vectors[3]) != 1.0 {
+		t.Errorf("cosineDistance() with empty vector = %f, want 1", cosineDistance(vectors[0], vectors[3]))
+	}
+	if d := cosineDistance(vectors[0], vectors[0]); d > 1e-9 {
+		t.Errorf("cosineDistance() to self = %f, want 0", d)
+	}
+}
+
+func TestNewPairDistance_HybridText(t *testing.T) {
+	// Items share no facets (sparse), but names reveal two product types
+	items := []Result{
+		{ID: "1", Name: "Noise cancelling headphones"},
+		{ID: "2", Name: "Over-ear headphones noise cancelling"},
+		{ID: "3", Name: "Leather hiking boots"},
+		{ID: "4", Name: "Waterproof hiking boots"},
+	}
+	facetSets := []FacetSet{
+		{"brand:Sony": true},
+		{},
+		{"brand:Merrell": true},
+		{},
+	}
+
+	facetOnly := newPairDistance(items, facetSets, ClusterOptions{})
+	if facetOnly(0, 1) != 1.0 {
+		t.Errorf("facet-only distance(0,1) = %f, want 1", facetOnly(0, 1))
+	}
+
+	hybrid := newPairDistance(items, facetSets, ClusterOptions{TextWeight: 0.5})
+	if hybrid(0, 1) >= hybrid(0, 2) {
+		t.Errorf("hybrid distance(0,1) = %f should be < distance(0,2) = %f", hybrid(0, 1), hybrid(0, 2))
+	}
+	if hybrid(2, 3) >= hybrid(1, 3) {
+		t.Errorf("hybrid distance(2,3) = %f should be < distance(1,3) = %f", hybrid(2, 3), hybrid(1, 3))
+	}
+
+	// Weight is clamped to [0, 1]
+	textOnly := newPairDistance(items, facetSets, ClusterOptions{TextWeight: 5})
+	vectors := buildTextVectors(items)
+	if math.Abs(textOnly(0, 2)-cosineDistance(vectors[0], vectors[2])) > 1e-9 {
+		t.Errorf("text-only distance(0,2) = %f, want cosine distance %f", textOnly(0, 2), cosineDistance(vectors[0], vectors[2]))
+	}
+}
+
+func TestNewPairDistance_Embeddings(t *testing.T) {
+	items := []Result{{ID: "a"}, {ID: "b"}, {ID: "c"}, {ID: "d"}}
+	facetSets := []FacetSet{{}, {}, {}, {}}
+	opts := ClusterOptions{
+		EmbeddingWeight: 1,
+		Embeddings: map[string][]float64{
+			"a": {1, 0},
+			"b": {0.8, 0.6},
+			"c": {0, 1},
+			// "d" has no embedding
+		},
+	}
+
+	dist := newPairDistance(items, facetSets, opts)
+	if math.Abs(dist(0, 1)-0.2) > 1e-9 {
+		t.Errorf("embedding distance(a,b) = %f, want 0.2", dist(0, 1))
+	}
+	if dist(0, 2) != 1.0 {
+		t.Errorf("embedding distance(a,c) = %f, want 1 (orthogonal)", dist(0, 2))
+	}
+	if dist(0, 3) != 1.0 {
+		t.Errorf("embedding distance(a,d) = %f, want 1 (missing vector)", dist(0, 3))
+	}
+}
@@ -0,0 +1,360 @@
+package ize
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+	"strings"
+
+	"ize/internal/algolia"
+	"ize/internal/logger"
+)
+
+// Topic modeling constants
+const (
+	// maxTopics is the maximum number of topics extracted from a result set
+	maxTopics = 5
+
+	// topicLabelTerms is the number of top terms used as a topic's label
+	topicLabelTerms = 3
+
+	// topTermsPerTopic is the number of top terms returned for each topic
+	topTermsPerTopic = 8
+
+	// nmfIterations is the number of multiplicative update rounds
+	nmfIterations = 200
+
+	// nmfEpsilon avoids division by zero in the multiplicative updates
+	nmfEpsilon = 1e-9
+
+	// minTopicWeight is the smallest document-topic weight that counts as an assignment;
+	// items whose strongest topic is weaker than this go to "Other"
+	minTopicWeight = 1e-6
+)
+
+// ProcessTopics groups items by the dominant topic of their descriptions.
+// It factorizes the TF-IDF document-term matrix with non-negative matrix factorization
+// (NMF), assigns each item to its highest-weighted topic, and labels each topic with
+// its top terms. The output has the same shape as ProcessCluster.
+func ProcessTopics(query string, algoliaResults *algolia.SearchResult, log *logger.Logger) (*ClusterResult, error) {
+	if log == nil {
+		log = logger.Default()
+	}
+
+	log.Debug("ProcessTopics started",
+		"query", query,
+		"hits_count", hitsCount(algoliaResults),
+	)
+
+	if algoliaResults == nil || len(algoliaResults.Hits) == 0 {
+		log.Debug("ProcessTopics: empty results, returning empty groups")
+		return &ClusterResult{
+			Groups:       []ClusterGroup{},
+			OtherGroup:   []Result{},
+			ClusterCount: 0,
+		}, nil
+	}
+
+	allItems, facetSets := extractItemsAndFacets(algoliaResults)
+	texts := topicTexts(allItems)
+
+	docs, vocab := buildTopicMatrix(texts)
+	k := topicCount(len(allItems), len(vocab))
+	if k < 2 {
+		log.Debug("ProcessTopics: not enough items or shared terms for topics",
+			"items", len(allItems),
+			"vocabulary", len(vocab),
+		)
+		return &ClusterResult{
+			Groups:       []ClusterGroup{},
+			OtherGroup:   allItems,
+			ClusterCount: 0,
+		}, nil
+	}
+
+	docTopics, topicTerms := factorizeNMF(docs, len(vocab), k, nmfIterations)
+	log.Debug("ProcessTopics: factorized document-term matrix",
+		"items", len(docs),
+		"vocabulary", len(vocab),
+		"topics", k,
+	)
+
+	// Assign each item to its dominant topic
+	assignments := make([]int, len(allItems))
+	for i, weights := range docTopics {
+		assignments[i] = dominantTopic(weights)
+	}
+
+	clusterItems, otherIndices := partitionByCluster(assignments, k, log)
+	forms := surfaceForms(texts)
+
+	groups := make([]ClusterGroup, 0, k)
+	for topic, indices := range clusterItems {
+		if len(indices) == 0 {
+			continue
+		}
+
+		terms := topTopicTerms(topicTerms[topic], vocab, forms, topTermsPerTopic)
+		topFacets := computeTopFacetsForIndices(facetSets, indices)
+		items := collectItems(allItems, indices)
+
+		labelTerms := terms
+		if len(labelTerms) > topicLabelTerms {
+			labelTerms = labelTerms[:topicLabelTerms]
+		}
+		name := strings.Join(labelTerms, ", ")
+		if name == "" {
+			name = fmt.Sprintf("Topic %d", topic+1)
+		}
+
+		log.Debug("ProcessTopics: built topic",
+			"topic", topic,
+			"size", len(items),
+			"terms", terms,
+		)
+
+		groups = append(groups, ClusterGroup{
+			Name:      name,
+			Items:     items,
+			TopFacets: topFacets,
+			TopTerms:  terms,
+			Stats: ClusterStats{
+				Size:      len(items),
+				TopFacets: topFacets,
+			},
+		})
+	}
+
+	otherItems := collectItems(allItems, otherIndices)
+
+	log.Info("ProcessTopics: completed",
+		"topics", len(groups),
+		"other_count", len(otherItems),
+	)
+
+	return &ClusterResult{
+		Groups:       groups,
+		OtherGroup:   otherItems,
+		ClusterCount: len(groups),
+	}, nil
+}
+
+// topicTexts returns the text modeled for each item: its description, or its name
+// when the description has no content words
+func topicTexts(items []Result) []string {
+	texts := make([]string, len(items))
+	for i, item := range items {
+		if len(contentWords(item.Description)) > 0 {
+			texts[i] = item.Description
+		} else {
+			texts[i] = item.Name
+		}
+	}
+	return texts
+}
+
+// buildTopicMatrix builds sparse TF-IDF rows over a vocabulary of stems that appear in at
+// least two documents but not in all of them (terms that cannot separate topics are dropped)
+// Returns one map (term index -> weight) per document and the vocabulary.
+func buildTopicMatrix(texts []string) ([]map[int]float64, []string) {
+	termCounts := make([]map[string]int, len(texts))
+	docFreq := make(map[string]int)
+	for i, text := range texts {
+		termCounts[i] = make(map[string]int)
+		for _, term := range tokenizeText(text) {
+			termCounts[i][term]++
+		}
+		for term := range termCounts[i] {
+			docFreq[term]++
+		}
+	}
+
+	n := len(texts)
+	var vocab []string
+	for term, df := range docFreq {
+		if df >= 2 && df < n {
+			vocab = append(vocab, term)
+		}
+	}
+	sort.Strings(vocab) // Deterministic column order
+	termIndex := make(map[string]int, len(vocab))
+	for j, term := range vocab {
+		termIndex[term] = j
+	}
+
+	docs := make([]map[int]float64, n)
+	for i, counts := range termCounts {
+		row := make(map[int]float64)
+		for term, tf := range counts {
+			j, ok := termIndex[term]
+			if !ok {
+				continue
+			}
+			idf := math.Log(float64(n) / float64(docFreq[term]))
+			row[j] = (1 + math.Log(float64(tf))) * idf
+		}
+		docs[i] = row
+	}
+
+	return docs, vocab
+}
+
+// topicCount chooses how many topics to extract: at most maxTopics, and no more than
+// the vocabulary or the number of minimum-sized groups the items could form
+func topicCount(items, vocabulary int) int {
+	k := maxTopics
+	if limit := items / minClusterSize; limit < k {
+		k = limit
+	}
+	if vocabulary < k {
+		k = vocabulary
+	}
+	return k
+}
+
+// factorizeNMF approximates the non-negative document-term matrix V (n x m) as W*H with
+// W (n x k) document-topic weights and H (k x m) topic-term weights, using Lee & Seung
+// multiplicative updates minimizing squared error. Initialization is seeded so results
+// are reproducible.
+func factorizeNMF(docs []map[int]float64, m, k, iterations int) ([][]float64, [][]float64) {
+	n := len(docs)
+	rng := rand.New(rand.NewSource(1))
+
+	W := make([][]float64, n)
+	for i := range W {
+		W[i] = make([]float64, k)
+		for t := range W[i] {
+			W[i][t] = rng.Float64() + 0.01
+		}
+	}
+	H := make([][]float64, k)
+	for t := range H {
+		H[t] = make([]float64, m)
+		for j := range H[t] {
+			H[t][j] = rng.Float64() + 0.01
+		}
+	}
+
+	for iter := 0; iter < iterations; iter++ {
+		updateTopicTerms(docs, W, H)
+		updateDocTopics(docs, W, H)
+	}
+
+	return W, H
+}
+
+// updateTopicTerms applies H <- H * (W^T V) / (W^T W H)
+func updateTopicTerms(docs []map[int]float64, W, H [][]float64) {
+	k := len(H)
+	m := len(H[0])
+
+	// W^T V (k x m), using the sparsity of V
+	wtv := make([][]float64, k)
+	for t := range wtv {
+		wtv[t] = make([]float64, m)
+	}
+	for i, row := range docs {
+		for j, v := range row {
+			for t := 0; t < k; t++ {
+				wtv[t][j] += W[i][t] * v
+			}
+		}
+	}
+
+	// W^T W (k x k)
+	wtw := make([][]float64, k)
+	for a := 0; a < k; a++ {
+		wtw[a] = make([]float64, k)
+		for b := 0; b < k; b++ {
+			for i := range W {
+				wtw[a][b] += W[i][a] * W[i][b]
+			}
+		}
+	}
+
+	for t := 0; t < k; t++ {
+		for j := 0; j < m; j++ {
+			denom := 0.0
+			for b := 0; b < k; b++ {
+				denom += wtw[t][b] * H[b][j]
+			}
+			H[t][j] *= wtv[t][j] / (denom + nmfEpsilon)
+		}
+	}
+}
+
+// updateDocTopics applies W <- W * (V H^T) / (W H H^T)
+func updateDocTopics(docs []map[int]float64, W, H [][]float64) {
+	k := len(H)
+
+	// H H^T (k x k)
+	hht := make([][]float64, k)
+	for a := 0; a < k; a++ {
+		hht[a] = make([]float64, k)
+		for b := 0; b < k; b++ {
+			for j := range H[a] {
+				hht[a][b] += H[a][j] * H[b][j]
+			}
+		}
+	}
+
+	for i, row := range docs {
+		// V H^T for this document, using the sparsity of V
+		vht := make([]float64, k)
+		for j, v := range row {
+			for t := 0; t < k; t++ {
+				vht[t] += v * H[t][j]
+			}
+		}
+		for t := 0; t < k; t++ {
+			denom := 0.0
+			for b := 0; b < k; b++ {
+				denom += W[i][b] * hht[b][t]
+			}
+			W[i][t] *= vht[t] / (denom + nmfEpsilon)
+		}
+	}
+}
+
+// dominantTopic returns the index of the largest topic weight, or -1 if all weights
+// are negligible (the document shares no vocabulary with any topic)
+func dominantTopic(weights []float64) int {
+	best := -1
+	bestWeight := minTopicWeight
+	for t, w := range weights {
+		if w > bestWeight {
+			best = t
+			bestWeight = w
+		}
+	}
+	return best
+}
+
+// topTopicTerms returns up to n of a topic's highest-weighted terms as display words
+func topTopicTerms(termWeights []float64, vocab []string, forms map[string]string, n int) []string {
+	indices := make([]int, 0, len(termWeights))
+	for j, w := range termWeights {
+		if w > minTopicWeight {
+			indices = append(indices, j)
+		}
+	}
+	sort.Slice(indices, func(a, b int) bool {
+		if termWeights[indices[a]] != termWeights[indices[b]] {
+			return termWeights[indices[a]] > termWeights[indices[b]]
+		}
+		return vocab[indices[a]] < vocab[indices[b]]
+	})
+	if len(indices) > n {
+		indices = indices[:n]
+	}
+
+	terms := make([]string, len(indices))
+	for i, j := range indices {
+		terms[i] = vocab[j]
+		if form, ok := forms[vocab[j]]; ok {
+			terms[i] = form
+		}
+	}
+	return terms
+}
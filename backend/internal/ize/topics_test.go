@@ -0,0 +1,120 @@
+package ize
+
+import (
+	"ize/internal/algolia"
+	"ize/internal/logger"
+	"strings"
+	"testing"
+)
+
+func TestProcessTopics_EmptyResults(t *testing.T) {
+	result, err := ProcessTopics("test", nil, logger.Default())
+	if err != nil {
+		t.Fatalf("ProcessTopics() error = %v", err)
+	}
+	if len(result.Groups) != 0 || len(result.OtherGroup) != 0 || result.ClusterCount != 0 {
+		t.Errorf("ProcessTopics() = %+v, want empty result", result)
+	}
+}
+
+func TestProcessTopics_TooFewItems(t *testing.T) {
+	algoliaResults := &algolia.SearchResult{
+		Hits: []algolia.Hit{
+			{ObjectID: "1", Name: "Item 1", Description: "Wireless headphones"},
+			{ObjectID: "2", Name: "Item 2", Description: "Wireless speaker"},
+		},
+	}
+
+	result, err := ProcessTopics("test", algoliaResults, logger.Default())
+	if err != nil {
+		t.Fatalf("ProcessTopics() error = %v", err)
+	}
+	if len(result.Groups) != 0 {
+		t.Errorf("ProcessTopics() groups count = %d, want 0", len(result.Groups))
+	}
+	if len(result.OtherGroup) != 2 {
+		t.Errorf("ProcessTopics() other group count = %d, want 2", len(result.OtherGroup))
+	}
+}
+
+func TestProcessTopics_TwoThemes(t *testing.T) {
+	algoliaResults := &algolia.SearchResult{
+		Hits: []algolia.Hit{
+			{ObjectID: "1", Name: "A", Description: "Noise cancelling headphones with long battery life"},
+			{ObjectID: "2", Name: "B", Description: "Wireless headphones, noise cancelling, great battery"},
+			{ObjectID: "3", Name: "C", Description: "Battery powered headphones with active noise cancelling"},
+			{ObjectID: "4", Name: "D", Description: "Compact noise cancelling headphones"},
+			{ObjectID: "5", Name: "E", Description: "Waterproof leather hiking boots with ankle support"},
+			{ObjectID: "6", Name: "F", Description: "Leather boots for hiking, waterproof"},
+			{ObjectID: "7", Name: "G", Description: "Hiking boots with waterproof leather upper"},
+			{ObjectID: "8", Name: "H", Description: "Lightweight waterproof hiking boots"},
+		},
+	}
+
+	result, err := ProcessTopics("test", algoliaResults, logger.Default())
+	if err != nil {
+		t.Fatalf("ProcessTopics() error = %v", err)
+	}
+
+	// Every headphone item must share a topic only with headphone items, and likewise for boots
+	topicOf := make(map[string]int)
+	for g, group := range result.Groups {
+		if len(group.TopTerms) == 0 {
+			t.Errorf("ProcessTopics() group %q has no top terms", group.Name)
+		}
+		if group.Stats.Size != len(group.Items) {
+			t.Errorf("ProcessTopics() group %q stats size = %d, want %d", group.Name, group.Stats.Size, len(group.Items))
+		}
+		for _, item := range group.Items {
+			topicOf[item.ID] = g
+		}
+	}
+	for _, pair := range [][2]string{{"1", "5"}, {"2", "6"}, {"3", "7"}, {"4", "8"}} {
+		a, aok := topicOf[pair[0]]
+		b, bok := topicOf[pair[1]]
+		if aok && bok && a == b {
+			t.Errorf("ProcessTopics() items %s and %s share topic %q", pair[0], pair[1], result.Groups[a].Name)
+		}
+	}
+
+	// Labels should come from the themes' vocabulary, shown as surface forms
+	var names []string
+	for _, group := range result.Groups {
+		names = append(names, group.Name)
+	}
+	joined := strings.Join(names, " | ")
+	if !strings.Contains(joined, "headphones") && !strings.Contains(joined, "noise") && !strings.Contains(joined, "cancelling") {
+		t.Errorf("ProcessTopics() names = %q, want a headphone-related label", joined)
+	}
+	if !strings.Contains(joined, "boots") && !strings.Contains(joined, "hiking") && !strings.Contains(joined, "waterproof") {
+		t.Errorf("ProcessTopics() names = %q, want a boot-related label", joined)
+	}
+
+	// All items are accounted for
+	total := len(result.OtherGroup)
+	for _, group := range result.Groups {
+		total += len(group.Items)
+	}
+	if total != 8 {
+		t.Errorf("ProcessTopics() total items = %d, want 8", total)
+	}
+}
+
+func TestSurfaceForms(t *testing.T) {
+	forms := surfaceForms([]string{"Noise cancelling", "cancelling noise", "cancelled order"})
+	if forms["cancell"] != "cancelling" {
+		t.Errorf("surfaceForms()[cancell] = %q, want %q", forms["cancell"], "cancelling")
+	}
+	if forms["noise"] != "noise" {
+		t.Errorf("surfaceForms()[noise] = %q, want %q", forms["noise"], "noise")
+	}
+}
+
+func TestDominantTopic(t *testing.T) {
+	if got := dominantTopic([]float64{0.1, 0.7, 0.2}); got != 1 {
+		t.Errorf("dominantTopic() = %d, want 1", got)
+	}
+	if got := dominantTopic([]float64{0, 0}); got != -1 {
+		t.Errorf("dominantTopic() = %d, want -1 for zero weights", got)
+	}
+}
@@ -0,0 +1,85 @@
+package labeler
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+const (
+	anthropicBaseURL    = "https://api.anthropic.com/v1"
+	anthropicAPIVersion = "2023-06-01"
+	anthropicModel      = "claude-3-haiku-20240307"
+)
+
+// anthropicProvider calls the Anthropic Messages API
+type anthropicProvider struct {
+	apiKey string
+}
+
+// messageRequest represents the Anthropic API request format
+type messageRequest struct {
+	Model       string    `json:"model"`
+	MaxTokens   int       `json:"max_tokens"`
+	Temperature *float64  `json:"temperature,omitempty"`
+	Messages    []message `json:"messages"`
+}
+
+// messageResponse represents the Anthropic API response format
+type messageResponse struct {
+	Content []contentBlock  `json:"content"`
+	Usage   *anthropicUsage `json:"usage,omitempty"`
+	Error   *apiError       `json:"error,omitempty"`
+}
+
+// anthropicUsage is the token count of a Messages API response
+type anthropicUsage struct {
+	InputTokens  int64 `json:"input_tokens"`
+	OutputTokens int64 `json:"output_tokens"`
+}
+
+// contentBlock represents a content block in the response
+type contentBlock struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+func (anthropicProvider) name() string           { return ProviderAnthropic }
+func (anthropicProvider) defaultBaseURL() string { return anthropicBaseURL }
+func (anthropicProvider) defaultModel() string   { return anthropicModel }
+
+func (p anthropicProvider) complete(ctx context.Context, httpClient *http.Client, baseURL string, req completionRequest) (completion, int, error) {
+	reqBody := messageRequest{
+		Model:       req.Model,
+		MaxTokens:   req.MaxTokens,
+		Temperature: req.Temperature,
+		Messages: []message{
+			{Role: "user", Content: req.Prompt},
+		},
+	}
+	headers := map[string]string{
+		"x-api-key":         p.apiKey,
+		"anthropic-version": anthropicAPIVersion,
+	}
+
+	var msgResp messageResponse
+	status, err := postJSON(ctx, httpClient, baseURL+"/messages", headers, reqBody, &msgResp)
+	if err != nil {
+		return completion{}, status, err
+	}
+
+	if msgResp.Error != nil {
+		return completion{}, status, fmt.Errorf("API error: %s - %s", msgResp.Error.Type, msgResp.Error.Message)
+	}
+
+	if len(msgResp.Content) == 0 || msgResp.Content[0].Type != "text" {
+		return completion{}, status, fmt.Errorf("unexpected response format")
+	}
+
+	result := completion{Text: strings.TrimSpace(msgResp.Content[0].Text)}
+	if msgResp.Usage != nil {
+		result.Usage = tokenUsage{InputTokens: msgResp.Usage.InputTokens, OutputTokens: msgResp.Usage.OutputTokens}
+	}
+	return result, status, nil
+}
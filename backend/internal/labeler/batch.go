@@ -0,0 +1,133 @@
+package labeler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Batch response limits
+const (
+	maxBatchLabelWords    = 5  // Longest label accepted (the prompt asks for 1-3 words)
+	batchTokensPerCluster = 60 // Room for a description and JSON syntax, on top of maxTokens
+	batchTokensBase       = 20 // Room for the enclosing JSON object
+)
+
+// batchResponse is the JSON object the model is asked to return for a batch
+type batchResponse struct {
+	Clusters []batchLabel `json:"clusters"`
+}
+
+// batchLabel is one cluster's entry in a batchResponse; Index is 1-based
+type batchLabel struct {
+	Index       int    `json:"index"`
+	Label       string `json:"label"`
+	Description string `json:"description"`
+}
+
+// generateBatch labels all clusters in a single request. Clusters are served from the
+// cache only when all of them are cached, since a batch's labels depend on each other.
+// Concurrent requests for the same clusters share one API call.
+func (c *Client) generateBatch(ctx context.Context, statsSlice []ClusterStats) ([]Label, error) {
+	log := c.logger.WithContext(ctx)
+
+	data := batchPromptData(statsSlice)
+	prompt, err := render(c.prompts.batch, data)
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make([]string, len(statsSlice))
+	labels := make([]Label, len(statsSlice))
+	cached := 0
+	for i := range statsSlice {
+		keys[i] = c.batchCacheKey(prompt, i)
+		if label, ok := c.getCached(keys[i]); ok {
+			labels[i] = label
+			cached++
+		}
+	}
+	if cached == len(statsSlice) {
+		log.Debug("batch cluster labels cache hit", "cluster_count", len(statsSlice))
+		return labels, nil
+	}
+
+	start := time.Now()
+	labels, shared, err := c.flight.do(ctx, "batch|"+c.cacheKey(prompt), func(ctx context.Context) ([]Label, error) {
+		text, err := c.complete(ctx, data.Query, completionRequest{
+			Model:       c.model,
+			MaxTokens:   batchTokensBase + len(statsSlice)*(c.maxTokens+batchTokensPerCluster),
+			Temperature: c.temperature,
+			Prompt:      prompt,
+			JSON:        true,
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		labels, err := parseBatchLabels(text, len(statsSlice))
+		if err != nil {
+			return nil, err
+		}
+		for i, label := range labels {
+			c.setCache(keys[i], label)
+		}
+		return labels, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	log.Info("generated cluster labels in one batch",
+		"cluster_count", len(statsSlice),
+		"shared", shared,
+		"duration_ms", time.Since(start).Milliseconds(),
+	)
+	return labels, nil
+}
+
+// parseBatchLabels extracts the JSON object from a batch response and validates it:
+// exactly one entry per cluster, each with a 1-5 word label distinct from the others
+// and a description. Returns the labels in cluster order.
+func parseBatchLabels(text string, n int) ([]Label, error) {
+	start, end := strings.Index(text, "{"), strings.LastIndex(text, "}")
+	if start < 0 || end < start {
+		return nil, fmt.Errorf("batch response has no JSON object")
+	}
+
+	var resp batchResponse
+	if err := json.Unmarshal([]byte(text[start:end+1]), &resp); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal batch response: %w", err)
+	}
+	if len(resp.Clusters) != n {
+		return nil, fmt.Errorf("batch response has %d clusters, want %d", len(resp.Clusters), n)
+	}
+
+	labels := make([]Label, n)
+	seen := make(map[int]bool, n)
+	names := make(map[string]int, n)
+	for _, entry := range resp.Clusters {
+		if entry.Index < 1 || entry.Index > n || seen[entry.Index] {
+			return nil, fmt.Errorf("batch response has invalid or repeated cluster index %d", entry.Index)
+		}
+		seen[entry.Index] = true
+
+		name := strings.TrimSpace(entry.Label)
+		description := strings.TrimSpace(entry.Description)
+		if words := len(strings.Fields(name)); words == 0 || words > maxBatchLabelWords {
+			return nil, fmt.Errorf("batch response label %q for cluster %d is not 1-%d words", name, entry.Index, maxBatchLabelWords)
+		}
+		if description == "" {
+			return nil, fmt.Errorf("batch response has no description for cluster %d", entry.Index)
+		}
+		if other, ok := names[strings.ToLower(name)]; ok {
+			return nil, fmt.Errorf("batch response repeats label %q for clusters %d and %d", name, other, entry.Index)
+		}
+		names[strings.ToLower(name)] = entry.Index
+
+		labels[entry.Index-1] = Label{Name: name, Description: description}
+	}
+	return labels, nil
+}
@@ -0,0 +1,195 @@
+package labeler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"ize/internal/config"
+)
+
+func TestParseBatchLabels(t *testing.T) {
+	valid := "Here you go:\n```json\n" + `{"clusters": [
+		{"index": 2, "label": " Samsung Phones ", "description": "Galaxy phones."},
+		{"index": 1, "label": "Apple Phones", "description": "iPhones from Apple."}
+	]}` + "\n```"
+	labels, err := parseBatchLabels(valid, 2)
+	if err != nil {
+		t.Fatalf("parseBatchLabels() error = %v", err)
+	}
+	want := []Label{
+		{Name: "Apple Phones", Description: "iPhones from Apple."},
+		{Name: "Samsung Phones", Description: "Galaxy phones."},
+	}
+	if !reflect.DeepEqual(labels, want) {
+		t.Errorf("parseBatchLabels() = %+v, want %+v", labels, want)
+	}
+
+	invalid := map[string]string{
+		"not json":           "Apple Phones, Samsung Phones",
+		"malformed":          `{"clusters": [{"index": 1,}]}`,
+		"too few clusters":   `{"clusters": [{"index": 1, "label": "Apple", "description": "Apple."}]}`,
+		"index out of range": `{"clusters": [{"index": 1, "label": "Apple", "description": "Apple."}, {"index": 3, "label": "Samsung", "description": "Samsung."}]}`,
+		"repeated index":     `{"clusters": [{"index": 1, "label": "Apple", "description": "Apple."}, {"index": 1, "label": "Samsung", "description": "Samsung."}]}`,
+		"empty label":        `{"clusters": [{"index": 1, "label": " ", "description": "Apple."}, {"index": 2, "label": "Samsung", "description": "Samsung."}]}`,
+		"long label":         `{"clusters": [{"index": 1, "label": "Phones made by Apple in California", "description": "Apple."}, {"index": 2, "label": "Samsung", "description": "Samsung."}]}`,
+		"no description":     `{"clusters": [{"index": 1, "label": "Apple", "description": ""}, {"index": 2, "label": "Samsung", "description": "Samsung."}]}`,
+		"duplicate labels":   `{"clusters": [{"index": 1, "label": "Phones", "description": "Apple."}, {"index": 2, "label": "phones", "description": "Samsung."}]}`,
+	}
+	for name, text := range invalid {
+		t.Run(name, func(t *testing.T) {
+			if _, err := parseBatchLabels(text, 2); err == nil {
+				t.Errorf("parseBatchLabels(%s) error = nil, want error", text)
+			}
+		})
+	}
+}
+
+func TestGenerateClusterLabels_Batch(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		var req chatCompletionRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Fatalf("Failed to decode request: %v", err)
+		}
+		if req.ResponseFormat == nil || req.ResponseFormat.Type != "json_object" {
+			t.Errorf("response_format = %+v, want json_object", req.ResponseFormat)
+		}
+		if req.MaxTokens <= defaultMaxTokens {
+			t.Errorf("max_tokens = %d, want room for every cluster", req.MaxTokens)
+		}
+		prompt := req.Messages[0].Content
+		if !strings.Contains(prompt, "Cluster 1 (10 items)") || !strings.Contains(prompt, "- brand:Samsung (90%)") {
+			t.Errorf("prompt does not describe every cluster:\n%s", prompt)
+		}
+
+		content := `{"clusters": [{"index": 1, "label": "Apple Phones", "description": "iPhones from Apple."}, {"index": 2, "label": "Samsung Phones", "description": "Galaxy phones."}]}`
+		json.NewEncoder(w).Encode(chatCompletionResponse{Choices: []chatChoice{{Message: message{Role: "assistant", Content: content}}}})
+	}))
+	defer server.Close()
+
+	client := newTestClient(t, config.LabelingConfig{Provider: ProviderOpenAI, BaseURL: server.URL, Batch: true})
+	statsSlice := []ClusterStats{
+		{Size: 10, TopFacets: []FacetInfo{{Name: "brand", Value: "Apple", Percentage: 100}}},
+		{Size: 8, TopFacets: []FacetInfo{{Name: "brand", Value: "Samsung", Percentage: 90}}},
+	}
+
+	labels, err := client.GenerateClusterLabels(context.Background(), statsSlice)
+	if err != nil {
+		t.Fatalf("GenerateClusterLabels() error = %v", err)
+	}
+	if len(labels) != 2 || labels[0].Name != "Apple Phones" || labels[1].Description != "Galaxy phones." {
+		t.Errorf("GenerateClusterLabels() = %+v, want the batch labels", labels)
+	}
+
+	// Names come from the cached batch
+	names, err := client.GenerateClusterNames(context.Background(), statsSlice)
+	if err != nil || !reflect.DeepEqual(names, []string{"Apple Phones", "Samsung Phones"}) {
+		t.Errorf("GenerateClusterNames() = %v, %v, want the batch names", names, err)
+	}
+	if calls.Load() != 1 {
+		t.Errorf("API calls = %d, want 1", calls.Load())
+	}
+}
+
+func TestGenerateClusterLabels_BatchFallback(t *testing.T) {
+	var batchCalls, singleCalls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req ollamaChatRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Fatalf("Failed to decode request: %v", err)
+		}
+		content := "Phones"
+		if req.Format == "json" {
+			// Both clusters get the same label, which fails validation
+			batchCalls.Add(1)
+			content = `{"clusters": [{"index": 1, "label": "Phones", "description": "Phones."}, {"index": 2, "label": "Phones", "description": "Phones."}]}`
+		} else {
+			singleCalls.Add(1)
+			if strings.Contains(req.Messages[0].Content, "Samsung") {
+				content = "Galaxy"
+			}
+		}
+		json.NewEncoder(w).Encode(ollamaChatResponse{Message: message{Role: "assistant", Content: content}})
+	}))
+	defer server.Close()
+
+	client := newTestClient(t, config.LabelingConfig{Provider: ProviderOllama, BaseURL: server.URL, Batch: true})
+	statsSlice := []ClusterStats{
+		{Size: 10, TopFacets: []FacetInfo{{Name: "brand", Value: "Apple", Percentage: 100}}},
+		{Size: 8, TopFacets: []FacetInfo{{Name: "brand", Value: "Samsung", Percentage: 90}}},
+	}
+	labels, err := client.GenerateClusterLabels(context.Background(), statsSlice)
+	if err != nil {
+		t.Fatalf("GenerateClusterLabels() error = %v", err)
+	}
+	want := []Label{{Name: "Phones"}, {Name: "Galaxy"}}
+	if !reflect.DeepEqual(labels, want) {
+		t.Errorf("GenerateClusterLabels() = %+v, want per-cluster labels %+v", labels, want)
+	}
+	if batchCalls.Load() != 1 || singleCalls.Load() != 2 {
+		t.Errorf("API calls = %d batch, %d single, want 1 and 2", batchCalls.Load(), singleCalls.Load())
+	}
+
+	// The cached per-cluster names have no descriptions, so the batch is tried again
+	if _, err := client.GenerateClusterLabels(context.Background(), statsSlice); err != nil {
+		t.Fatalf("GenerateClusterLabels() error = %v", err)
+	}
+	if batchCalls.Load() != 2 || singleCalls.Load() != 2 {
+		t.Errorf("API calls = %d batch, %d single, want 2 and 2", batchCalls.Load(), singleCalls.Load())
+	}
+}
+
+func TestStreamClusterLabels(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req ollamaChatRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Fatalf("Failed to decode request: %v", err)
+		}
+		prompt := req.Messages[0].Content
+		content := "Apple"
+		switch {
+		case req.Format == "json":
+			content = `{"clusters": [{"index": 1, "label": "Apple Phones", "description": "iPhones."}, {"index": 2, "label": "Nike Shoes", "description": "Sneakers."}]}`
+		case strings.Contains(prompt, "Nike"):
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		json.NewEncoder(w).Encode(ollamaChatResponse{Message: message{Role: "assistant", Content: content}})
+	}))
+	defer server.Close()
+
+	statsSlice := []ClusterStats{
+		{Size: 10, TopFacets: []FacetInfo{{Name: "brand", Value: "Apple", Percentage: 100}}},
+		{Size: 8, TopFacets: []FacetInfo{{Name: "brand", Value: "Nike", Percentage: 90}}},
+	}
+	stream := func(client *Client) map[int]Label {
+		t.Helper()
+		emitted := make(map[int]Label)
+		if err := client.StreamClusterLabels(context.Background(), statsSlice, func(i int, label Label) {
+			emitted[i] = label
+		}); err != nil {
+			t.Fatalf("StreamClusterLabels() error = %v", err)
+		}
+		return emitted
+	}
+
+	// Per-cluster requests emit each label; the failed cluster is not emitted
+	single := stream(newTestClient(t, config.LabelingConfig{Provider: ProviderOllama, BaseURL: server.URL}))
+	if want := map[int]Label{0: {Name: "Apple"}}; !reflect.DeepEqual(single, want) {
+		t.Errorf("per-cluster StreamClusterLabels() emitted %+v, want %+v", single, want)
+	}
+
+	// A batch emits every label with its description
+	batch := stream(newTestClient(t, config.LabelingConfig{Provider: ProviderOllama, BaseURL: server.URL, Batch: true}))
+	want := map[int]Label{0: {Name: "Apple Phones", Description: "iPhones."}, 1: {Name: "Nike Shoes", Description: "Sneakers."}}
+	if !reflect.DeepEqual(batch, want) {
+		t.Errorf("batch StreamClusterLabels() emitted %+v, want %+v", batch, want)
+	}
+}
@@ -0,0 +1,217 @@
+package labeler
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"ize/internal/config"
+	"ize/internal/logger"
+)
+
+// Cache backends accepted in config.LabelCacheConfig.Backend
+const (
+	CacheMemory = "memory" // Bounded LRU in memory, lost on restart
+	CacheDisk   = "disk"   // LRU persisted to a file owned by this instance
+	CacheShared = "shared" // LRU in a file shared by several instances
+)
+
+const defaultCacheCapacity = 10000
+
+// Cache stores cluster labels by cacheKey, a hash of the prompt that produced them. Implementations are safe for concurrent use.
+type Cache interface {
+	// Get returns the label for key if it is cached and has not expired
+	Get(key string) (Label, bool)
+	// Set stores the label for key, evicting the least recently used entry when full
+	Set(key string, label Label)
+	// Lookup returns the entry for key without counting a hit or miss
+	Lookup(key string) (CacheEntry, bool)
+	// Entries returns up to limit unexpired entries, most recently used first (limit <= 0 = all)
+	Entries(limit int) []CacheEntry
+	// Delete removes the entry for key, reporting whether it existed
+	Delete(key string) bool
+	// Purge removes every entry and returns how many there were
+	Purge() int
+	Stats() CacheStats
+}
+
+// Flusher is implemented by caches that write changes in the background
+type Flusher interface {
+	// Flush writes the changes not written yet
+	Flush()
+}
+
+// CacheEntry is a cached label with its lifetime
+type CacheEntry struct {
+	Key         string    `json:"key"`
+	Name        string    `json:"name"`
+	Description string    `json:"description,omitempty"`
+	CreatedAt   time.Time `json:"createdAt"`
+	ExpiresAt   time.Time `json:"expiresAt"`
+}
+
+// CacheStats reports a cache's size and counters since startup
+type CacheStats struct {
+	Backend     string `json:"backend"`
+	Entries     int    `json:"entries"`
+	Capacity    int    `json:"capacity"`
+	TTLSeconds  int64  `json:"ttlSeconds"`
+	Hits        int64  `json:"hits"`
+	Misses      int64  `json:"misses"`
+	Evictions   int64  `json:"evictions"`   // Entries removed to stay within capacity
+	Expirations int64  `json:"expirations"` // Entries removed after their TTL
+}
+
+// NewCache creates the configured label cache; nil configures the default memory cache
+func NewCache(cfg *config.LabelCacheConfig, log *logger.Logger) (Cache, error) {
+	var c config.LabelCacheConfig
+	if cfg != nil {
+		c = *cfg
+	}
+	ttl := cacheTTL(cfg)
+
+	switch c.Backend {
+	case "", CacheMemory:
+		log.Info("label cache initialized", "backend", CacheMemory, "capacity", capacityOrDefault(c.Capacity), "ttl", ttl.String())
+		return NewLRUCache(c.Capacity, ttl), nil
+	case CacheDisk, CacheShared:
+		if c.Path == "" {
+			return nil, fmt.Errorf("label cache backend %q requires a path", c.Backend)
+		}
+		return OpenFileCache(c.Path, c.Backend == CacheShared, c.Capacity, ttl, log)
+	default:
+		return nil, fmt.Errorf("unknown label cache backend %q", c.Backend)
+	}
+}
+
+// cacheTTL is the configured lifetime of cached entries, 1 hour by default
+func cacheTTL(cfg *config.LabelCacheConfig) time.Duration {
+	if cfg != nil && cfg.TTLMinutes > 0 {
+		return time.Duration(cfg.TTLMinutes) * time.Minute
+	}
+	return defaultCacheTTL
+}
+
+func capacityOrDefault(capacity int) int {
+	if capacity <= 0 {
+		return defaultCacheCapacity
+	}
+	return capacity
+}
+
+// LRUCache is a bounded in-memory label cache with a TTL, evicting the least recently
+// used entry when full
+type LRUCache struct {
+	mu sync.Mutex
+	lru[Label]
+}
+
+// NewLRUCache creates an LRU cache; capacity <= 0 uses the default of 10000 entries
+func NewLRUCache(capacity int, ttl time.Duration) *LRUCache {
+	return &LRUCache{lru: newLRU[Label](capacity, ttl)}
+}
+
+// Get implements Cache
+func (c *LRUCache) Get(key string) (Label, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.get(key)
+}
+
+// Set implements Cache
+func (c *LRUCache) Set(key string, label Label) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.set(key, label)
+}
+
+// Lookup implements Cache
+func (c *LRUCache) Lookup(key string) (CacheEntry, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.lookup(key)
+	if !ok {
+		return CacheEntry{}, false
+	}
+	return toCacheEntry(entry), true
+}
+
+// Entries implements Cache
+func (c *LRUCache) Entries(limit int) []CacheEntry {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.entriesLocked(limit)
+}
+
+// Delete implements Cache
+func (c *LRUCache) Delete(key string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.delete(key)
+}
+
+// Purge implements Cache
+func (c *LRUCache) Purge() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	n := len(c.items)
+	c.purge()
+	return n
+}
+
+// Stats implements Cache
+func (c *LRUCache) Stats() CacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	stats := c.stats()
+	stats.Backend = CacheMemory
+	return stats
+}
+
+// putLocked stores entry as the most recently used, evicting the least recently used
+// entries beyond capacity
+func (c *LRUCache) putLocked(entry CacheEntry) {
+	c.put(fromCacheEntry(entry))
+}
+
+// entriesLocked returns up to limit unexpired entries, most recently used first
+func (c *LRUCache) entriesLocked(limit int) []CacheEntry {
+	stored := c.entries(limit)
+	entries := make([]CacheEntry, len(stored))
+	for i, entry := range stored {
+		entries[i] = toCacheEntry(entry)
+	}
+	return entries
+}
+
+// replaceLocked replaces the contents with entries (most recently used first), dropping
+// expired ones and those beyond capacity without counting them as evictions
+func (c *LRUCache) replaceLocked(entries []CacheEntry) {
+	stored := make([]lruEntry[Label], len(entries))
+	for i, entry := range entries {
+		stored[i] = fromCacheEntry(entry)
+	}
+	c.replace(stored)
+}
+
+func toCacheEntry(entry lruEntry[Label]) CacheEntry {
+	return CacheEntry{
+		Key:         entry.key,
+		Name:        entry.value.Name,
+		Description: entry.value.Description,
+		CreatedAt:   entry.createdAt,
+		ExpiresAt:   entry.expiresAt,
+	}
+}
+
+func fromCacheEntry(entry CacheEntry) lruEntry[Label] {
+	return lruEntry[Label]{
+		key:       entry.Key,
+		value:     Label{Name: entry.Name, Description: entry.Description},
+		createdAt: entry.CreatedAt,
+		expiresAt: entry.ExpiresAt,
+	}
+}
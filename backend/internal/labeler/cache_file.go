@@ -0,0 +1,318 @@
+package labeler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"ize/internal/logger"
+)
+
+// cacheFileFormatVersion is written to the cache file so the format can evolve
+const cacheFileFormatVersion = 1
+
+// Lock file timing for shared caches
+const (
+	defaultFlushDelay = 1 * time.Second // How long new labels wait to be written with others
+
+	lockRetryDelay = 10 * time.Millisecond
+	lockTimeout    = 2 * time.Second
+	lockStaleAfter = 10 * time.Second // A lock this old was left behind by a crashed instance
+)
+
+// cacheFile is the on-disk layout
+type cacheFile struct {
+	Format  int          `json:"format"`
+	Entries []CacheEntry `json:"entries"` // Most recently used first
+}
+
+// FileCache is an LRU label cache persisted to a JSON file, which is rewritten (to a
+// temporary file renamed into place) after changes. New labels are written together a
+// second after the first of them, so a response naming many clusters writes the file
+// once; deletes and purges are written right away.
+//
+// A disk cache owns its file and reads it only when opened. A shared cache lets several
+// instances use one file: it reloads the file whenever another instance has changed it,
+// and holds a lock file (path + ".lock") while updating it so no instance's change is
+// lost. The cache is best-effort: a file that cannot be read or written is logged and
+// the labels are kept in memory.
+type FileCache struct {
+	mu     sync.Mutex // Serializes file access; taken before lru.mu
+	lru    *LRUCache
+	path   string
+	shared bool
+	logger *logger.Logger
+
+	loadedModTime time.Time // Modification time and size of the file when last read or written
+	loadedSize    int64
+
+	flushDelay time.Duration
+	pending    []cacheChange // Changes not yet written, replayed over the file's entries when it is reloaded
+	flushTimer *time.Timer   // Set while a write is scheduled
+}
+
+// cacheChange is a change to the cache that has not been written to the file yet
+type cacheChange struct {
+	entry  CacheEntry // Entry to store
+	delete string     // Key to remove instead
+	purge  bool       // Remove every entry instead
+}
+
+// OpenFileCache loads the cache at path, creating it on the first write if it does not
+// exist. capacity <= 0 uses the default of 10000 entries.
+func OpenFileCache(path string, shared bool, capacity int, ttl time.Duration, log *logger.Logger) (*FileCache, error) {
+	if log == nil {
+		log = logger.Default()
+	}
+	if path == "" {
+		return nil, fmt.Errorf("label cache path is required")
+	}
+
+	c := &FileCache{
+		lru:        NewLRUCache(capacity, ttl),
+		path:       path,
+		shared:     shared,
+		logger:     log,
+		flushDelay: defaultFlushDelay,
+	}
+	c.refreshLocked()
+
+	log.Info("label cache initialized",
+		"backend", c.backend(),
+		"path", path,
+		"entries", c.lru.Stats().Entries,
+		"capacity", c.lru.capacity,
+		"ttl", ttl.String(),
+	)
+	return c, nil
+}
+
+// Get implements Cache
+func (c *FileCache) Get(key string) (Label, bool) {
+	c.refresh()
+	return c.lru.Get(key)
+}
+
+// Set implements Cache. The label is written to the file with the others set within
+// the flush delay.
+func (c *FileCache) Set(key string, label Label) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.lru.Set(key, label)
+	if entry, ok := c.lru.Lookup(key); ok {
+		c.pending = append(c.pending, cacheChange{entry: entry})
+	}
+	if c.flushTimer == nil {
+		c.flushTimer = time.AfterFunc(c.flushDelay, c.Flush)
+	}
+}
+
+// Lookup implements Cache
+func (c *FileCache) Lookup(key string) (CacheEntry, bool) {
+	c.refresh()
+	return c.lru.Lookup(key)
+}
+
+// Entries implements Cache
+func (c *FileCache) Entries(limit int) []CacheEntry {
+	c.refresh()
+	return c.lru.Entries(limit)
+}
+
+// Delete implements Cache
+func (c *FileCache) Delete(key string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.shared {
+		// Delete what other instances see, not only the entries read last
+		c.refreshLocked()
+	}
+	deleted := c.lru.Delete(key)
+	c.pending = append(c.pending, cacheChange{delete: key})
+	c.flushLocked()
+	return deleted
+}
+
+// Purge implements Cache
+func (c *FileCache) Purge() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.shared {
+		c.refreshLocked()
+	}
+	purged := c.lru.Purge()
+	c.pending = append(c.pending, cacheChange{purge: true})
+	c.flushLocked()
+	return purged
+}
+
+// Flush writes the changes that are waiting for the flush delay
+func (c *FileCache) Flush() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.flushLocked()
+}
+
+// Stats implements Cache
+func (c *FileCache) Stats() CacheStats {
+	c.refresh()
+	stats := c.lru.Stats()
+	stats.Backend = c.backend()
+	return stats
+}
+
+func (c *FileCache) backend() string {
+	if c.shared {
+		return CacheShared
+	}
+	return CacheDisk
+}
+
+// refresh reloads a shared cache's file if another instance changed it
+func (c *FileCache) refresh() {
+	if !c.shared {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.refreshLocked()
+}
+
+// flushLocked writes the pending changes. A shared cache takes the lock file and
+// reloads the file first, so changes made by other instances are kept.
+func (c *FileCache) flushLocked() {
+	if c.flushTimer != nil {
+		c.flushTimer.Stop()
+		c.flushTimer = nil
+	}
+	if len(c.pending) == 0 {
+		return
+	}
+	count := len(c.pending)
+
+	if c.shared {
+		unlock, err := lockFile(c.path + ".lock")
+		if err != nil {
+			c.pending = nil
+			c.logger.Warn("failed to lock shared label cache, changes kept in memory only", "path", c.path, "changes", count, "error", err)
+			return
+		}
+		defer unlock()
+		c.refreshLocked()
+	}
+
+	c.pending = nil
+	if err := c.saveLocked(); err != nil {
+		c.logger.Warn("failed to write label cache", "path", c.path, "changes", count, "error", err)
+	}
+}
+
+// refreshLocked replaces the in-memory entries with the file's if it changed since it
+// was last read or written, then applies the changes not written yet
+func (c *FileCache) refreshLocked() {
+	info, err := os.Stat(c.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return
+	}
+	if err != nil {
+		c.logger.Warn("failed to read label cache", "path", c.path, "error", err)
+		return
+	}
+	if info.ModTime().Equal(c.loadedModTime) && info.Size() == c.loadedSize {
+		return
+	}
+
+	data, err := os.ReadFile(c.path)
+	if err != nil {
+		c.logger.Warn("failed to read label cache", "path", c.path, "error", err)
+		return
+	}
+	c.loadedModTime, c.loadedSize = info.ModTime(), info.Size()
+
+	var file cacheFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		c.logger.Warn("failed to parse label cache, ignoring its entries", "path", c.path, "error", err)
+		return
+	}
+	if file.Format > cacheFileFormatVersion {
+		c.logger.Warn("label cache format is newer than supported, ignoring its entries",
+			"path", c.path,
+			"format", file.Format,
+			"supported", cacheFileFormatVersion,
+		)
+		return
+	}
+
+	c.lru.mu.Lock()
+	c.lru.replaceLocked(file.Entries)
+	for _, change := range c.pending {
+		switch {
+		case change.purge:
+			c.lru.purge()
+		case change.delete != "":
+			c.lru.delete(change.delete)
+		default:
+			c.lru.putLocked(change.entry)
+		}
+	}
+	c.lru.mu.Unlock()
+}
+
+func (c *FileCache) saveLocked() error {
+	data, err := json.Marshal(cacheFile{Format: cacheFileFormatVersion, Entries: c.lru.Entries(0)})
+	if err != nil {
+		return fmt.Errorf("failed to encode label cache: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(c.path), filepath.Base(c.path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to write label cache: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write label cache: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write label cache: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), c.path); err != nil {
+		return fmt.Errorf("failed to write label cache: %w", err)
+	}
+
+	if info, err := os.Stat(c.path); err == nil {
+		c.loadedModTime, c.loadedSize = info.ModTime(), info.Size()
+	}
+	return nil
+}
+
+// lockFile creates path exclusively, waiting for another holder to remove it, and
+// returns the function that releases the lock
+func lockFile(path string) (func(), error) {
+	deadline := time.Now().Add(lockTimeout)
+	for {
+		f, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o644)
+		if err == nil {
+			f.Close()
+			return func() { os.Remove(path) }, nil
+		}
+		if !errors.Is(err, os.ErrExist) {
+			return nil, err
+		}
+		if info, err := os.Stat(path); err == nil && time.Since(info.ModTime()) > lockStaleAfter {
+			os.Remove(path)
+			continue
+		}
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("timed out waiting for lock %s", path)
+		}
+		time.Sleep(lockRetryDelay)
+	}
+}
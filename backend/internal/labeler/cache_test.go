@@ -0,0 +1,239 @@
+package labeler
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"ize/internal/config"
+	"ize/internal/logger"
+)
+
+func TestNewCache(t *testing.T) {
+	c, err := NewCache(nil, logger.Default())
+	if err != nil {
+		t.Fatalf("NewCache(nil) error = %v", err)
+	}
+	if stats := c.Stats(); stats.Backend != CacheMemory || stats.Capacity != defaultCacheCapacity || stats.TTLSeconds != 3600 {
+		t.Errorf("NewCache(nil) stats = %+v, want the default memory cache", stats)
+	}
+	if _, err := NewCache(&config.LabelCacheConfig{Backend: CacheDisk}, logger.Default()); err == nil {
+		t.Error("NewCache(disk without path) error = nil, want error")
+	}
+	if _, err := NewCache(&config.LabelCacheConfig{Backend: "redis"}, logger.Default()); err == nil {
+		t.Error("NewCache(unknown backend) error = nil, want error")
+	}
+	c, err = NewCache(&config.LabelCacheConfig{Backend: CacheShared, Path: filepath.Join(t.TempDir(), "labels.json"), Capacity: 5, TTLMinutes: 10}, logger.Default())
+	if err != nil {
+		t.Fatalf("NewCache(shared) error = %v", err)
+	}
+	if stats := c.Stats(); stats.Backend != CacheShared || stats.Capacity != 5 || stats.TTLSeconds != 600 {
+		t.Errorf("NewCache(shared) stats = %+v, want capacity 5 and a 10 minute TTL", stats)
+	}
+}
+
+func TestLRUCache(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	c := NewLRUCache(2, time.Hour)
+	c.now = func() time.Time { return now }
+
+	c.Set("a", Label{Name: "Apple"})
+	c.Set("b", Label{Name: "Samsung"})
+	if label, ok := c.Get("a"); !ok || label.Name != "Apple" {
+		t.Fatalf("Get(a) = %+v, %v, want Apple", label, ok)
+	}
+
+	// "b" is now the least recently used and is evicted
+	c.Set("c", Label{Name: "Sony", Description: "Sony phones."})
+	if _, ok := c.Get("b"); ok {
+		t.Error("Get(b) found an evicted entry")
+	}
+	entries := c.Entries(0)
+	if len(entries) != 2 || entries[0].Key != "c" || entries[1].Key != "a" || entries[0].Description != "Sony phones." {
+		t.Errorf("Entries() = %+v, want c then a", entries)
+	}
+	if entry, ok := c.Lookup("a"); !ok || !entry.ExpiresAt.Equal(now.Add(time.Hour)) {
+		t.Errorf("Lookup(a) = %+v, %v, want an entry expiring in an hour", entry, ok)
+	}
+
+	now = now.Add(2 * time.Hour)
+	if _, ok := c.Get("a"); ok {
+		t.Error("Get(a) found an expired entry")
+	}
+	if entries := c.Entries(0); len(entries) != 0 {
+		t.Errorf("Entries() = %+v, want none unexpired", entries)
+	}
+
+	stats := c.Stats()
+	want := CacheStats{Backend: CacheMemory, Entries: 1, Capacity: 2, TTLSeconds: 3600, Hits: 1, Misses: 2, Evictions: 1, Expirations: 1}
+	if stats != want {
+		t.Errorf("Stats() = %+v, want %+v", stats, want)
+	}
+
+	if !c.Delete("c") || c.Delete("c") {
+		t.Error("Delete(c) should succeed once")
+	}
+	c.Set("d", Label{Name: "LG"})
+	if purged := c.Purge(); purged != 1 {
+		t.Errorf("Purge() = %d, want 1", purged)
+	}
+}
+
+func TestFileCache_Disk(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "labels.json")
+
+	c, err := OpenFileCache(path, false, 0, time.Hour, logger.Default())
+	if err != nil {
+		t.Fatalf("OpenFileCache() error = %v", err)
+	}
+	c.Set("a", Label{Name: "Apple", Description: "iPhones."})
+	c.Set("b", Label{Name: "Samsung"})
+	c.Flush()
+
+	// A restart keeps the labels
+	reopened, err := OpenFileCache(path, false, 0, time.Hour, logger.Default())
+	if err != nil {
+		t.Fatalf("OpenFileCache(existing) error = %v", err)
+	}
+	if label, ok := reopened.Get("a"); !ok || label.Description != "iPhones." {
+		t.Errorf("reopened Get(a) = %+v, %v, want the stored label", label, ok)
+	}
+	if entries := reopened.Entries(0); len(entries) != 2 || entries[0].Key != "a" {
+		t.Errorf("reopened Entries() = %+v, want a (just used) then b", entries)
+	}
+
+	// A smaller capacity keeps the most recently used entries
+	small, _ := OpenFileCache(path, false, 1, time.Hour, logger.Default())
+	if entries := small.Entries(0); len(entries) != 1 || entries[0].Key != "b" {
+		t.Errorf("capacity 1 Entries() = %+v, want b (most recently used when written)", entries)
+	}
+
+	if purged := reopened.Purge(); purged != 2 {
+		t.Errorf("Purge() = %d, want 2", purged)
+	}
+	if again, _ := OpenFileCache(path, false, 0, time.Hour, logger.Default()); again.Stats().Entries != 0 {
+		t.Error("purged entries came back after reopening")
+	}
+
+	// A corrupt file is ignored, not fatal
+	if err := os.WriteFile(path, []byte("{not json"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if corrupt, err := OpenFileCache(path, false, 0, time.Hour, logger.Default()); err != nil || corrupt.Stats().Entries != 0 {
+		t.Errorf("OpenFileCache(corrupt) = %v, want an empty cache", err)
+	}
+}
+
+func TestFileCache_Shared(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "labels.json")
+	first, _ := OpenFileCache(path, true, 0, time.Hour, logger.Default())
+	second, _ := OpenFileCache(path, true, 0, time.Hour, logger.Default())
+
+	first.Set("a", Label{Name: "Apple"})
+	first.Flush()
+	if label, ok := second.Get("a"); !ok || label.Name != "Apple" {
+		t.Errorf("second Get(a) = %+v, %v, want the label written by the first instance", label, ok)
+	}
+
+	// Writes from both instances are merged
+	second.Set("b", Label{Name: "Samsung"})
+	first.Set("c", Label{Name: "Sony"})
+	second.Flush()
+	first.Flush()
+	for _, key := range []string{"a", "b", "c"} {
+		if _, ok := second.Lookup(key); !ok {
+			t.Errorf("second Lookup(%s) not found", key)
+		}
+	}
+
+	if !first.Delete("b") {
+		t.Error("first Delete(b) = false, want true")
+	}
+	if _, ok := second.Get("b"); ok {
+		t.Error("second Get(b) found an entry deleted by the first instance")
+	}
+
+	// No lock or temporary files are left behind
+	entries, _ := os.ReadDir(filepath.Dir(path))
+	if len(entries) != 1 {
+		t.Errorf("cache directory has %d entries, want only the cache file", len(entries))
+	}
+}
+
+func TestFileCache_FlushDelay(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "labels.json")
+	c, _ := OpenFileCache(path, true, 0, time.Hour, logger.Default())
+	c.flushDelay = 20 * time.Millisecond
+
+	// Labels set together are written together, after the delay
+	c.Set("a", Label{Name: "Apple"})
+	c.Set("b", Label{Name: "Samsung"})
+	if label, ok := c.Get("a"); !ok || label.Name != "Apple" {
+		t.Errorf("Get(a) = %+v, %v, want the label before it is written", label, ok)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Fatalf("cache file written before the flush delay: %v", err)
+	}
+	deadline := time.Now().Add(time.Second)
+	for {
+		if reopened, _ := OpenFileCache(path, true, 0, time.Hour, logger.Default()); reopened.Stats().Entries == 2 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("labels were not written after the flush delay")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	// A label waiting to be written survives another instance's write
+	other, _ := OpenFileCache(path, true, 0, time.Hour, logger.Default())
+	c.Set("c", Label{Name: "Sony"})
+	if !other.Delete("a") {
+		t.Error("other Delete(a) = false, want true")
+	}
+	if _, ok := c.Get("a"); ok {
+		t.Error("Get(a) found an entry deleted by the other instance")
+	}
+	c.Flush()
+	if reopened, _ := OpenFileCache(path, true, 0, time.Hour, logger.Default()); reopened.Stats().Entries != 2 {
+		t.Errorf("file has %d entries, want b and c", reopened.Stats().Entries)
+	}
+}
+
+func TestLockFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "labels.json.lock")
+
+	unlock, err := lockFile(path)
+	if err != nil {
+		t.Fatalf("lockFile() error = %v", err)
+	}
+	released := make(chan struct{})
+	go func(unlock func()) {
+		time.Sleep(5 * lockRetryDelay)
+		close(released)
+		unlock()
+	}(unlock)
+	unlockSecond, err := lockFile(path)
+	if err != nil {
+		t.Fatalf("lockFile(held) error = %v", err)
+	}
+	select {
+	case <-released:
+	default:
+		t.Error("lockFile() acquired a lock that was still held")
+	}
+	unlockSecond()
+
+	// A lock left behind by a crashed instance is taken over
+	if err := os.WriteFile(path, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	stale := time.Now().Add(-2 * lockStaleAfter)
+	os.Chtimes(path, stale, stale)
+	unlock, err = lockFile(path)
+	if err != nil {
+		t.Fatalf("lockFile(stale) error = %v", err)
+	}
+	unlock()
+}
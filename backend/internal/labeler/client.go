@@ -0,0 +1,463 @@
+package labeler
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"ize/internal/config"
+	"ize/internal/logger"
+)
+
+const (
+	defaultMaxTokens  = 20
+	defaultCacheTTL   = 1 * time.Hour
+	defaultRetryDelay = 500 * time.Millisecond
+	maxRetries        = 3
+)
+
+// Client names clusters with an LLM. The provider adapter speaks the vendor's API;
+// the client adds the prompt, caching, retries, rate limiting and parallel naming.
+type Client struct {
+	provider    provider
+	baseURL     string
+	model       string
+	maxTokens   int
+	temperature *float64
+	batch       bool
+	httpClient  *http.Client
+	logger      *logger.Logger
+	retryDelay  time.Duration
+	cache       Cache
+	prompts     *prompts
+	usage       *UsageTracker
+	limiter     *limiter
+	flight      flightGroup[Label] // Shares in-flight calls for the same clusters
+	suggestions *suggestionCache
+	suggesting  flightGroup[string] // Shares in-flight calls for the same suggestions
+}
+
+// NewClient creates an LLM labeling client for the anthropic, openai or ollama provider.
+// Unset model, max tokens and base URL take the provider's defaults.
+func NewClient(cfg *config.LabelingConfig, log *logger.Logger) (*Client, error) {
+	if cfg == nil {
+		return nil, fmt.Errorf("labeling configuration is required")
+	}
+
+	var p provider
+	switch cfg.Provider {
+	case ProviderAnthropic:
+		if cfg.APIKey == "" {
+			return nil, fmt.Errorf("anthropic API key is required")
+		}
+		p = anthropicProvider{apiKey: cfg.APIKey}
+	case ProviderOpenAI:
+		p = openAIProvider{apiKey: cfg.APIKey}
+	case ProviderOllama:
+		p = ollamaProvider{}
+	default:
+		return nil, fmt.Errorf("unknown labeling provider %q", cfg.Provider)
+	}
+
+	baseURL := cfg.BaseURL
+	if baseURL == "" {
+		baseURL = p.defaultBaseURL()
+	}
+	if !strings.HasPrefix(baseURL, "http://") && !strings.HasPrefix(baseURL, "https://") {
+		return nil, fmt.Errorf("labeling base URL must be http(s): %q", baseURL)
+	}
+
+	model := cfg.Model
+	if model == "" {
+		model = p.defaultModel()
+	}
+	maxTokens := cfg.MaxTokens
+	if maxTokens <= 0 {
+		maxTokens = defaultMaxTokens
+	}
+	prompts, err := loadPrompts(cfg)
+	if err != nil {
+		return nil, err
+	}
+	cache, err := NewCache(cfg.Cache, log)
+	if err != nil {
+		return nil, err
+	}
+	usage := NewUsageTracker(cfg.Pricing, cfg.DailyBudgetUSD)
+	if !usage.Priced(model) {
+		log.Info("no price for labeling model, its cost is counted as zero", "model", model)
+	}
+
+	log.Info("labeling client initialized",
+		"provider", cfg.Provider,
+		"model", model,
+		"base_url", baseURL,
+		"batch", cfg.Batch,
+		"prompt_version", prompts.version,
+		"daily_budget_usd", cfg.DailyBudgetUSD,
+		"max_concurrency", cfg.MaxConcurrency,
+		"requests_per_minute", cfg.RequestsPerMinute,
+	)
+
+	return &Client{
+		provider:    p,
+		baseURL:     strings.TrimRight(baseURL, "/"),
+		model:       model,
+		maxTokens:   maxTokens,
+		temperature: cfg.Temperature,
+		batch:       cfg.Batch,
+		httpClient: &http.Client{
+			Timeout: 30 * time.Second,
+		},
+		logger:      log,
+		retryDelay:  defaultRetryDelay,
+		cache:       cache,
+		prompts:     prompts,
+		usage:       usage,
+		limiter:     newLimiter(cfg.MaxConcurrency, cfg.RequestsPerMinute),
+		suggestions: newSuggestionCache(maxCachedSuggestions, cacheTTL(cfg.Cache)),
+	}, nil
+}
+
+// cacheKey generates a deterministic cache key from a rendered prompt. Keying on the
+// prompt rather than the cluster statistics means a label is reused only for a request
+// the model would see the same way, whatever the template reads (query, siblings,
+// sample names). The provider, model and prompt version are part of the key so changing
+// any of them renames clusters.
+func (c *Client) cacheKey(prompt string) string {
+	h := sha256.Sum256([]byte(strings.Join([]string{c.provider.name(), c.model, "prompt:" + c.prompts.version, prompt}, "\x00")))
+	return hex.EncodeToString(h[:16]) // Use first 16 bytes (32 hex chars)
+}
+
+// batchCacheKey is the cache key of cluster i's label from a batch prompt. Batch labels
+// have descriptions, so they are kept apart from per-cluster labels: a batch that fell
+// back to per-cluster requests is tried again rather than served from them.
+func (c *Client) batchCacheKey(prompt string, i int) string {
+	return c.cacheKey(fmt.Sprintf("batch:%d\x00%s", i+1, prompt))
+}
+
+// Cache returns the client's label cache
+func (c *Client) Cache() Cache {
+	return c.cache
+}
+
+// Usage returns the client's token usage and cost accounting
+func (c *Client) Usage() *UsageTracker {
+	return c.usage
+}
+
+// getCached returns a cached label if it exists and hasn't expired
+func (c *Client) getCached(key string) (Label, bool) {
+	return c.cache.Get(key)
+}
+
+// setCache stores a label in the cache
+func (c *Client) setCache(key string, label Label) {
+	c.cache.Set(key, label)
+}
+
+// isRetryableStatus returns true if the HTTP status code indicates a transient error
+func isRetryableStatus(status int) bool {
+	return status == 429 || // Rate limited
+		status == 500 || // Internal server error
+		status == 502 || // Bad gateway
+		status == 503 || // Service unavailable
+		status == 529 // Overloaded
+}
+
+// GenerateClusterName generates a pithy 1-3 word label for a cluster
+// Includes retry logic for transient errors with exponential backoff
+// Results are cached (by default in memory for 1 hour)
+// Returns ErrBudgetExceeded once the daily budget is spent and the name is not cached
+func (c *Client) GenerateClusterName(ctx context.Context, stats ClusterStats) (string, error) {
+	name, err := c.generateName(ctx, []ClusterStats{stats}, 0)
+	if errors.Is(err, ErrBudgetExceeded) {
+		c.usage.reject()
+	}
+	return name, err
+}
+
+// generateName labels cluster i of statsSlice with the per-cluster prompt, which can
+// describe the other clusters as its siblings
+func (c *Client) generateName(ctx context.Context, statsSlice []ClusterStats, i int) (string, error) {
+	log := c.logger.WithContext(ctx)
+	stats := statsSlice[i]
+
+	prompt, err := render(c.prompts.cluster, clusterPromptData(statsSlice, i))
+	if err != nil {
+		return "", err
+	}
+
+	// Check cache first
+	key := c.cacheKey(prompt)
+	if cached, ok := c.getCached(key); ok {
+		log.Debug("cluster name cache hit",
+			"cluster_size", stats.Size,
+			"name", cached.Name,
+		)
+		return cached.Name, nil
+	}
+
+	// Concurrent requests for the same cluster share one API call
+	labels, shared, err := c.flight.do(ctx, key, func(ctx context.Context) ([]Label, error) {
+		log.Debug("generating cluster name (cache miss)",
+			"cluster_size", stats.Size,
+			"top_facets_count", len(stats.TopFacets),
+		)
+
+		label, err := c.complete(ctx, stats.Query, completionRequest{
+			Model:       c.model,
+			MaxTokens:   c.maxTokens,
+			Temperature: c.temperature,
+			Prompt:      prompt,
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		// Cache the successful result
+		c.setCache(key, Label{Name: label})
+		return []Label{{Name: label}}, nil
+	})
+	if err != nil {
+		return "", err
+	}
+
+	log.Debug("generated cluster name",
+		"label", labels[0].Name,
+		"shared", shared,
+	)
+	return labels[0].Name, nil
+}
+
+// complete sends a completion request, retrying transient errors with exponential
+// backoff, and returns the non-empty response text. It returns ErrBudgetExceeded
+// without calling the API once the daily budget is spent; a call that starts within
+// the budget is completed even if it exceeds it. Each attempt waits for the limiter;
+// a Retry-After header pauses all of the client's requests. The tokens of every
+// response are recorded against the model and query.
+func (c *Client) complete(ctx context.Context, query string, req completionRequest) (string, error) {
+	log := c.logger.WithContext(ctx)
+
+	if err := c.usage.checkBudget(); err != nil {
+		return "", err
+	}
+
+	var lastErr error
+	for attempt := 0; attempt <= maxRetries; attempt++ {
+		if attempt > 0 {
+			// Exponential backoff: 500ms, 1s, 2s
+			delay := c.retryDelay * time.Duration(1<<(attempt-1))
+			log.Debug("retrying labeling API call",
+				"attempt", attempt+1,
+				"delay_ms", delay.Milliseconds(),
+			)
+			select {
+			case <-ctx.Done():
+				return "", ctx.Err()
+			case <-time.After(delay):
+			}
+		}
+
+		release, err := c.limiter.acquire(ctx)
+		if err != nil {
+			return "", err
+		}
+		resp, statusCode, err := c.provider.complete(ctx, c.httpClient, c.baseURL, req)
+		release()
+		var apiErr *statusError
+		if errors.As(err, &apiErr) && apiErr.RetryAfter > 0 {
+			log.Warn("labeling API asked to retry later, pausing requests",
+				"status", apiErr.Status,
+				"retry_after_ms", apiErr.RetryAfter.Milliseconds(),
+			)
+			c.limiter.pause(apiErr.RetryAfter)
+		}
+		if err == nil {
+			cost := c.usage.record(ctx, req.Model, query, resp.Usage)
+			log.Debug("labeling API usage",
+				"model", req.Model,
+				"input_tokens", resp.Usage.InputTokens,
+				"output_tokens", resp.Usage.OutputTokens,
+				"cost_usd", cost,
+			)
+			if resp.Text == "" {
+				err = fmt.Errorf("API returned an empty response")
+			}
+		}
+		if err == nil {
+			return resp.Text, nil
+		}
+
+		lastErr = err
+
+		// Only retry on transient errors
+		if statusCode > 0 && !isRetryableStatus(statusCode) {
+			log.Error("labeling API returned non-retryable error",
+				"status", statusCode,
+				"error", err,
+			)
+			return "", err
+		}
+
+		if attempt < maxRetries {
+			log.Warn("labeling API call failed, will retry",
+				"attempt", attempt+1,
+				"max_retries", maxRetries,
+				"error", err,
+			)
+		}
+	}
+
+	return "", fmt.Errorf("failed after %d retries: %w", maxRetries+1, lastErr)
+}
+
+// GenerateClusterNames generates names for multiple clusters: in one batch request in
+// batch mode, otherwise in parallel per-cluster requests. Once the daily budget is spent,
+// clusters whose names are not cached are left unnamed and ErrBudgetExceeded is
+// returned with the others' names.
+func (c *Client) GenerateClusterNames(ctx context.Context, statsSlice []ClusterStats) ([]string, error) {
+	labels, err := c.GenerateClusterLabels(ctx, statsSlice)
+	if err != nil && !errors.Is(err, ErrBudgetExceeded) {
+		return nil, err
+	}
+	names := make([]string, len(labels))
+	for i, label := range labels {
+		names[i] = label.Name
+	}
+	return names, err
+}
+
+// GenerateClusterLabels generates labels for multiple clusters. In batch mode all
+// clusters are sent in one request so the model can keep their names distinct, and each
+// gets a description; if that request fails or its output does not validate, the
+// clusters are named by per-cluster requests instead. Outside batch mode labels have
+// no descriptions. Cached labels are served even over the daily budget; clusters that
+// would need an API call then get no label, and ErrBudgetExceeded is returned with the
+// other clusters' labels.
+func (c *Client) GenerateClusterLabels(ctx context.Context, statsSlice []ClusterStats) ([]Label, error) {
+	return c.generateLabels(ctx, statsSlice, nil)
+}
+
+// StreamClusterLabels labels clusters like GenerateClusterLabels, calling emit with
+// each cluster's index and label as soon as it is known: all at once for a batch, one
+// by one for per-cluster requests. Clusters that fail or are refused over the budget
+// are not emitted. emit is called from the calling goroutine.
+func (c *Client) StreamClusterLabels(ctx context.Context, statsSlice []ClusterStats, emit func(i int, label Label)) error {
+	_, err := c.generateLabels(ctx, statsSlice, emit)
+	return err
+}
+
+// generateLabels implements GenerateClusterLabels, also passing each label to emit
+// (if not nil) as it arrives
+func (c *Client) generateLabels(ctx context.Context, statsSlice []ClusterStats, emit func(i int, label Label)) ([]Label, error) {
+	log := c.logger.WithContext(ctx)
+
+	if !c.batch || len(statsSlice) == 0 {
+		names, err := c.generateNamesParallel(ctx, statsSlice, emit)
+		return namesToLabels(names), err
+	}
+
+	labels, err := c.generateBatch(ctx, statsSlice)
+	if err == nil {
+		if emit != nil {
+			for i, label := range labels {
+				emit(i, label)
+			}
+		}
+		return labels, nil
+	}
+	if errors.Is(err, ErrBudgetExceeded) {
+		// Per-cluster labels that are already cached cost nothing
+		log.Debug("daily labeling budget exceeded, serving cached per-cluster labels", "cluster_count", len(statsSlice))
+	} else {
+		log.Warn("batch cluster labeling failed, falling back to per-cluster requests",
+			"cluster_count", len(statsSlice),
+			"error", err,
+		)
+	}
+	names, err := c.generateNamesParallel(ctx, statsSlice, emit)
+	return namesToLabels(names), err
+}
+
+// generateNamesParallel generates names for multiple clusters in parallel, using
+// "Cluster N" for those that fail and passing the others to emit (if not nil) as they
+// arrive. Clusters refused over the daily budget get an empty name, and
+// ErrBudgetExceeded is returned with the names; the refusal counts once against the
+// budget however many clusters it affected.
+func (c *Client) generateNamesParallel(ctx context.Context, statsSlice []ClusterStats, emit func(i int, label Label)) ([]string, error) {
+	log := c.logger.WithContext(ctx)
+
+	if len(statsSlice) == 0 {
+		return []string{}, nil
+	}
+
+	start := time.Now()
+	results := make([]string, len(statsSlice))
+
+	// Result struct for channel communication
+	type result struct {
+		index int
+		name  string
+		err   error
+	}
+
+	resultCh := make(chan result, len(statsSlice))
+
+	// Launch parallel goroutines for each cluster
+	for i := range statsSlice {
+		go func(idx int) {
+			name, err := c.generateName(ctx, statsSlice, idx)
+			resultCh <- result{index: idx, name: name, err: err}
+		}(i)
+	}
+
+	// Collect results
+	var errorCount, overBudget int
+	for range statsSlice {
+		r := <-resultCh
+		switch {
+		case errors.Is(r.err, ErrBudgetExceeded):
+			overBudget++
+		case r.err != nil:
+			log.Warn("failed to generate cluster name, using fallback",
+				"cluster_index", r.index,
+				"error", r.err,
+			)
+			results[r.index] = fmt.Sprintf("Cluster %d", r.index+1)
+			errorCount++
+		default:
+			results[r.index] = r.name
+			if emit != nil {
+				emit(r.index, Label{Name: r.name})
+			}
+		}
+	}
+
+	log.Info("generated cluster names in parallel",
+		"cluster_count", len(statsSlice),
+		"errors", errorCount,
+		"over_budget", overBudget,
+		"duration_ms", time.Since(start).Milliseconds(),
+	)
+
+	if overBudget > 0 {
+		c.usage.reject()
+		return results, ErrBudgetExceeded
+	}
+	return results, nil
+}
+
+// namesToLabels wraps names in labels without descriptions
+func namesToLabels(names []string) []Label {
+	labels := make([]Label, len(names))
+	for i, name := range names {
+		labels[i] = Label{Name: name}
+	}
+	return labels
+}
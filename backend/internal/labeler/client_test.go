@@ -0,0 +1,445 @@
+package labeler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"ize/internal/config"
+	"ize/internal/logger"
+)
+
+// newTestClient creates a client for a stub server with no retry delay
+func newTestClient(t *testing.T, cfg config.LabelingConfig) *Client {
+	t.Helper()
+	client, err := NewClient(&cfg, logger.Default())
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+	client.retryDelay = time.Millisecond
+	return client
+}
+
+func TestNewClient_MissingAPIKey(t *testing.T) {
+	_, err := NewClient(&config.LabelingConfig{Provider: ProviderAnthropic}, logger.Default())
+	if err == nil {
+		t.Error("NewClient() with empty API key should return error")
+	}
+}
+
+func TestNewClient_ValidAPIKey(t *testing.T) {
+	client, err := NewClient(&config.LabelingConfig{Provider: ProviderAnthropic, APIKey: "test-api-key"}, logger.Default())
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+	if client == nil {
+		t.Fatal("NewClient() returned nil client")
+	}
+	if client.model != anthropicModel || client.maxTokens != defaultMaxTokens || client.baseURL != anthropicBaseURL {
+		t.Errorf("NewClient() defaults = %s, %d, %s, want %s, %d, %s", client.model, client.maxTokens, client.baseURL, anthropicModel, defaultMaxTokens, anthropicBaseURL)
+	}
+}
+
+func TestNew(t *testing.T) {
+	if _, err := New(nil, logger.Default()); err == nil {
+		t.Error("New(nil) should return error")
+	}
+	if _, err := New(&config.LabelingConfig{Provider: "unknown"}, logger.Default()); err == nil {
+		t.Error("New() with unknown provider should return error")
+	}
+	if _, err := New(&config.LabelingConfig{Provider: ProviderOllama, BaseURL: "localhost:11434"}, logger.Default()); err == nil {
+		t.Error("New() with a non-http base URL should return error")
+	}
+	l, err := New(&config.LabelingConfig{Provider: ProviderLocal}, logger.Default())
+	if err != nil {
+		t.Fatalf("New(local) error = %v", err)
+	}
+	if _, ok := l.(*Local); !ok {
+		t.Errorf("New(local) = %T, want *Local", l)
+	}
+	l, err = New(&config.LabelingConfig{Provider: ProviderOpenAI}, logger.Default())
+	if err != nil {
+		t.Fatalf("New(openai) error = %v", err)
+	}
+	if _, ok := l.(*Client); !ok {
+		t.Errorf("New(openai) = %T, want *Client", l)
+	}
+}
+
+func TestGenerateClusterName_Success(t *testing.T) {
+	// Create a mock server
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Verify request
+		if r.Method != "POST" {
+			t.Errorf("Expected POST method, got %s", r.Method)
+		}
+		if r.URL.Path != "/v1/messages" {
+			t.Errorf("Path = %s, want /v1/messages", r.URL.Path)
+		}
+		if r.Header.Get("x-api-key") != "test-api-key" {
+			t.Errorf("Missing or wrong API key header")
+		}
+		if r.Header.Get("anthropic-version") == "" {
+			t.Errorf("Missing anthropic-version header")
+		}
+		var req messageRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Fatalf("Failed to decode request: %v", err)
+		}
+		if req.Model != "claude-test" || req.MaxTokens != 12 || req.Temperature == nil || *req.Temperature != 0.2 {
+			t.Errorf("request = model %s, max_tokens %d, temperature %v, want claude-test, 12, 0.2", req.Model, req.MaxTokens, req.Temperature)
+		}
+		if len(req.Messages) != 1 || !strings.Contains(req.Messages[0].Content, "brand:Apple (80%)") {
+			t.Errorf("request messages = %+v, want the prompt with the facets", req.Messages)
+		}
+
+		// Return mock response
+		resp := messageResponse{
+			Content: []contentBlock{
+				{Type: "text", Text: " Apple Phones\n"},
+			},
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(resp)
+	}))
+	defer server.Close()
+
+	temperature := 0.2
+	client := newTestClient(t, config.LabelingConfig{
+		Provider:    ProviderAnthropic,
+		APIKey:      "test-api-key",
+		BaseURL:     server.URL + "/v1/",
+		Model:       "claude-test",
+		MaxTokens:   12,
+		Temperature: &temperature,
+	})
+
+	stats := ClusterStats{
+		Size: 10,
+		TopFacets: []FacetInfo{
+			{Name: "brand", Value: "Apple", Percentage: 80},
+			{Name: "category", Value: "Phone", Percentage: 100},
+		},
+	}
+
+	name, err := client.GenerateClusterName(context.Background(), stats)
+	if err != nil {
+		t.Fatalf("GenerateClusterName() error = %v", err)
+	}
+	if name != "Apple Phones" {
+		t.Errorf("GenerateClusterName() = %q, want %q", name, "Apple Phones")
+	}
+}
+
+func TestGenerateClusterName_OpenAI(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/chat/completions" {
+			t.Errorf("Path = %s, want /v1/chat/completions", r.URL.Path)
+		}
+		if got := r.Header.Get("Authorization"); got != "Bearer test-api-key" {
+			t.Errorf("Authorization = %q, want %q", got, "Bearer test-api-key")
+		}
+		var raw map[string]interface{}
+		if err := json.NewDecoder(r.Body).Decode(&raw); err != nil {
+			t.Fatalf("Failed to decode request: %v", err)
+		}
+		if raw["model"] != openAIModel || raw["max_tokens"] != float64(defaultMaxTokens) {
+			t.Errorf("request = %v, want the default model and max tokens", raw)
+		}
+		if _, ok := raw["temperature"]; ok {
+			t.Error("request has a temperature, want it omitted when unset")
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"choices":[{"index":0,"message":{"role":"assistant","content":"Running Shoes"}}]}`))
+	}))
+	defer server.Close()
+
+	client := newTestClient(t, config.LabelingConfig{Provider: ProviderOpenAI, APIKey: "test-api-key", BaseURL: server.URL + "/v1"})
+
+	name, err := client.GenerateClusterName(context.Background(), ClusterStats{Size: 3})
+	if err != nil {
+		t.Fatalf("GenerateClusterName() error = %v", err)
+	}
+	if name != "Running Shoes" {
+		t.Errorf("GenerateClusterName() = %q, want %q", name, "Running Shoes")
+	}
+}
+
+func TestGenerateClusterName_Ollama(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/chat" {
+			t.Errorf("Path = %s, want /api/chat", r.URL.Path)
+		}
+		if got := r.Header.Get("Authorization"); got != "" {
+			t.Errorf("Authorization = %q, want none", got)
+		}
+		var req ollamaChatRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Fatalf("Failed to decode request: %v", err)
+		}
+		if req.Model != "qwen2.5" || req.Stream || req.Options.NumPredict != 8 || req.Options.Temperature == nil || *req.Options.Temperature != 0 {
+			t.Errorf("request = %+v, want model qwen2.5, no streaming, num_predict 8, temperature 0", req)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"model":"qwen2.5","message":{"role":"assistant","content":"Trail Gear"},"done":true}`))
+	}))
+	defer server.Close()
+
+	temperature := 0.0
+	client := newTestClient(t, config.LabelingConfig{Provider: ProviderOllama, BaseURL: server.URL, Model: "qwen2.5", MaxTokens: 8, Temperature: &temperature})
+
+	name, err := client.GenerateClusterName(context.Background(), ClusterStats{Size: 3})
+	if err != nil {
+		t.Fatalf("GenerateClusterName() error = %v", err)
+	}
+	if name != "Trail Gear" {
+		t.Errorf("GenerateClusterName() = %q, want %q", name, "Trail Gear")
+	}
+}
+
+func TestGenerateClusterName_Retries(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte(`{"choices":[{"message":{"role":"assistant","content":"Sandals"}}]}`))
+	}))
+	defer server.Close()
+
+	client := newTestClient(t, config.LabelingConfig{Provider: ProviderOpenAI, BaseURL: server.URL})
+
+	name, err := client.GenerateClusterName(context.Background(), ClusterStats{Size: 2})
+	if err != nil || name != "Sandals" {
+		t.Fatalf("GenerateClusterName() = %q, %v, want Sandals after retries", name, err)
+	}
+	if calls.Load() != 3 {
+		t.Errorf("API calls = %d, want 3", calls.Load())
+	}
+}
+
+func TestGenerateClusterName_NoRetryOnClientError(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"error":{"type":"invalid_request_error","message":"bad model"}}`))
+	}))
+	defer server.Close()
+
+	client := newTestClient(t, config.LabelingConfig{Provider: ProviderAnthropic, APIKey: "test-api-key", BaseURL: server.URL})
+
+	if _, err := client.GenerateClusterName(context.Background(), ClusterStats{Size: 2}); err == nil {
+		t.Error("GenerateClusterName() error = nil, want error")
+	}
+	if calls.Load() != 1 {
+		t.Errorf("API calls = %d, want 1 (no retry on 400)", calls.Load())
+	}
+}
+
+func TestGenerateClusterNames_FallbackOnError(t *testing.T) {
+	// Create a mock server that returns an error
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("Internal Server Error"))
+	}))
+	defer server.Close()
+
+	client := newTestClient(t, config.LabelingConfig{Provider: ProviderOllama, BaseURL: server.URL})
+
+	// The GenerateClusterNames method should use fallback names on error
+	statsSlice := []ClusterStats{
+		{Size: 5, TopFacets: []FacetInfo{{Name: "category", Value: "A", Percentage: 100}}},
+		{Size: 3, TopFacets: []FacetInfo{{Name: "category", Value: "B", Percentage: 100}}},
+	}
+
+	names, err := client.GenerateClusterNames(context.Background(), statsSlice)
+	if err != nil {
+		t.Fatalf("GenerateClusterNames() error = %v", err)
+	}
+	for i, name := range names {
+		expectedName := "Cluster " + string(rune('1'+i))
+		if name != expectedName {
+			t.Errorf("GenerateClusterNames()[%d] = %q, want %q", i, name, expectedName)
+		}
+	}
+}
+
+func TestClusterStats_Structure(t *testing.T) {
+	stats := ClusterStats{
+		Size: 15,
+		TopFacets: []FacetInfo{
+			{Name: "brand", Value: "Nike", Percentage: 60.5},
+			{Name: "category", Value: "Shoes", Percentage: 80.0},
+			{Name: "color", Value: "Black", Percentage: 40.0},
+		},
+	}
+
+	if stats.Size != 15 {
+		t.Errorf("Size = %d, want 15", stats.Size)
+	}
+
+	if len(stats.TopFacets) != 3 {
+		t.Errorf("TopFacets length = %d, want 3", len(stats.TopFacets))
+	}
+
+	// Verify facet info structure
+	brandFacet := stats.TopFacets[0]
+	if brandFacet.Name != "brand" || brandFacet.Value != "Nike" || brandFacet.Percentage != 60.5 {
+		t.Errorf("First facet = %+v, want brand:Nike:60.5", brandFacet)
+	}
+}
+
+func TestClientInterface(t *testing.T) {
+	// Verify that Client implements Labeler
+	var _ Labeler = (*Client)(nil)
+	var _ Streamer = (*Client)(nil)
+}
+
+func TestGenerateClusterName_BuildsCorrectPrompt(t *testing.T) {
+	// Test that the prompt building logic works correctly
+	stats := ClusterStats{
+		Size: 10,
+		TopFacets: []FacetInfo{
+			{Name: "brand", Value: "Apple", Percentage: 80},
+			{Name: "category", Value: "Phone", Percentage: 100},
+		},
+	}
+
+	// Verify the structure is correct for prompt building
+	if stats.Size <= 0 {
+		t.Error("Size should be positive")
+	}
+
+	for _, f := range stats.TopFacets {
+		if f.Name == "" {
+			t.Error("Facet name should not be empty")
+		}
+		if f.Value == "" {
+			t.Error("Facet value should not be empty")
+		}
+		if f.Percentage < 0 || f.Percentage > 100 {
+			t.Errorf("Facet percentage %f should be between 0 and 100", f.Percentage)
+		}
+	}
+}
+
+// MockLabeler implements Labeler for testing
+type MockLabeler struct {
+	Names []string
+	Err   error
+}
+
+func (m *MockLabeler) GenerateClusterName(ctx context.Context, stats ClusterStats) (string, error) {
+	if m.Err != nil {
+		return "", m.Err
+	}
+	if len(m.Names) > 0 {
+		return m.Names[0], nil
+	}
+	return "Mock Cluster", nil
+}
+
+func (m *MockLabeler) GenerateClusterNames(ctx context.Context, statsSlice []ClusterStats) ([]string, error) {
+	if m.Err != nil {
+		// Return fallback names
+		names := make([]string, len(statsSlice))
+		for i := range statsSlice {
+			names[i] = "Cluster " + string(rune('1'+i))
+		}
+		return names, nil
+	}
+	if len(m.Names) >= len(statsSlice) {
+		return m.Names[:len(statsSlice)], nil
+	}
+	return m.Names, nil
+}
+
+func (m *MockLabeler) GenerateClusterLabels(ctx context.Context, statsSlice []ClusterStats) ([]Label, error) {
+	names, err := m.GenerateClusterNames(ctx, statsSlice)
+	return namesToLabels(names), err
+}
+
+func TestMockLabeler(t *testing.T) {
+	// Verify mock implements interface
+	var _ Labeler = (*MockLabeler)(nil)
+
+	mock := &MockLabeler{
+		Names: []string{"Electronics", "Clothing"},
+	}
+
+	name, err := mock.GenerateClusterName(context.Background(), ClusterStats{})
+	if err != nil {
+		t.Fatalf("GenerateClusterName() error = %v", err)
+	}
+	if name != "Electronics" {
+		t.Errorf("GenerateClusterName() = %s, want Electronics", name)
+	}
+
+	names, err := mock.GenerateClusterNames(context.Background(), []ClusterStats{{}, {}})
+	if err != nil {
+		t.Fatalf("GenerateClusterNames() error = %v", err)
+	}
+	if len(names) != 2 {
+		t.Errorf("GenerateClusterNames() length = %d, want 2", len(names))
+	}
+}
+
+func TestCacheKey_Deterministic(t *testing.T) {
+	client := newTestClient(t, config.LabelingConfig{Provider: ProviderAnthropic, APIKey: "test-key"})
+
+	prompt1 := "- brand:Apple (80%)\n- category:Phone (100%)\n- 10 items total"
+	prompt2 := "- brand:Samsung (80%)\n- category:Phone (100%)\n- 10 items total"
+
+	key1 := client.cacheKey(prompt1)
+
+	// The same prompt should produce the same key
+	if key1 != client.cacheKey(prompt1) {
+		t.Errorf("cacheKey() should be deterministic: %s != %s", key1, client.cacheKey(prompt1))
+	}
+
+	// Different prompts should produce different keys
+	if key1 == client.cacheKey(prompt2) {
+		t.Errorf("cacheKey() should differ for different prompts: %s", key1)
+	}
+
+	// A batch entry should not share a key with a per-cluster label
+	if key1 == client.batchCacheKey(prompt1, 0) || client.batchCacheKey(prompt1, 0) == client.batchCacheKey(prompt1, 1) {
+		t.Errorf("batchCacheKey() should differ from cacheKey() and between clusters")
+	}
+
+	// A different model should produce a different key
+	other := newTestClient(t, config.LabelingConfig{Provider: ProviderAnthropic, APIKey: "test-key", Model: "claude-other"})
+	if key1 == other.cacheKey(prompt1) {
+		t.Errorf("cacheKey() should differ for different models: %s", key1)
+	}
+}
+
+func TestCache_SetAndGet(t *testing.T) {
+	client := newTestClient(t, config.LabelingConfig{Provider: ProviderAnthropic, APIKey: "test-key"})
+
+	key := client.cacheKey("- brand:Nike (100%)\n- 5 items total")
+
+	// Initially should not be cached
+	if _, ok := client.getCached(key); ok {
+		t.Error("getCached() should return false for uncached key")
+	}
+
+	// Set the cache
+	client.setCache(key, Label{Name: "Sports Gear"})
+
+	// Now should be cached
+	if label, ok := client.getCached(key); !ok {
+		t.Error("getCached() should return true after setCache()")
+	} else if label.Name != "Sports Gear" {
+		t.Errorf("getCached() = %s, want Sports Gear", label.Name)
+	}
+}
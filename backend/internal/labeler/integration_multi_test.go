@@ -1,7 +1,7 @@
 //go:build integration
 // +build integration
 
-package anthropic
+package labeler
 
 import (
 	"context"
@@ -14,7 +14,7 @@ import (
 )
 
 // TestGenerateClusterNames_Integration tests generating names for multiple clusters
-// Run with: go test -tags=integration -v ./internal/anthropic/... -run MultiCluster
+// Run with: go test -tags=integration -v ./internal/labeler/... -run MultiCluster
 func TestGenerateClusterNames_MultiCluster_Integration(t *testing.T) {
 	// Load config to get API key
 	if err := os.Chdir("../.."); err != nil {
@@ -26,11 +26,12 @@ func TestGenerateClusterNames_MultiCluster_Integration(t *testing.T) {
 		t.Skipf("Skipping integration test: cannot load config: %v", err)
 	}
 
-	if cfg.AnthropicAPIKey == "" {
-		t.Skip("Skipping integration test: ANTHROPIC_API_KEY not configured")
+	labeling := cfg.GetLabeling()
+	if labeling.Provider == ProviderLocal {
+		t.Skip("Skipping integration test: no labeling provider or ANTHROPIC_API_KEY configured")
 	}
 
-	client, err := NewClient(cfg.AnthropicAPIKey, logger.Default())
+	client, err := NewClient(&labeling, logger.Default())
 	if err != nil {
 		t.Fatalf("NewClient() error = %v", err)
 	}
@@ -1,7 +1,7 @@
 //go:build integration
 // +build integration
 
-package anthropic
+package labeler
 
 import (
 	"context"
@@ -13,8 +13,8 @@ import (
 	"ize/internal/logger"
 )
 
-// TestGenerateClusterName_Integration tests the real Anthropic API
-// Run with: go test -tags=integration -v ./internal/anthropic/...
+// TestGenerateClusterName_Integration tests the configured labeling provider's real API
+// Run with: go test -tags=integration -v ./internal/labeler/...
 func TestGenerateClusterName_Integration(t *testing.T) {
 	// Load config to get API key
 	// Change to backend directory for config loading
@@ -27,11 +27,12 @@ func TestGenerateClusterName_Integration(t *testing.T) {
 		t.Skipf("Skipping integration test: cannot load config: %v", err)
 	}
 
-	if cfg.AnthropicAPIKey == "" {
-		t.Skip("Skipping integration test: ANTHROPIC_API_KEY not configured")
+	labeling := cfg.GetLabeling()
+	if labeling.Provider == ProviderLocal {
+		t.Skip("Skipping integration test: no labeling provider or ANTHROPIC_API_KEY configured")
 	}
 
-	client, err := NewClient(cfg.AnthropicAPIKey, logger.Default())
+	client, err := NewClient(&labeling, logger.Default())
 	if err != nil {
 		t.Fatalf("NewClient() error = %v", err)
 	}
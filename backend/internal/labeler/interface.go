@@ -0,0 +1,65 @@
+package labeler
+
+import "context"
+
+// Labeler names clusters. Implementations call an LLM provider or work locally; this
+// allows providers to be swapped by configuration and mocked in tests.
+type Labeler interface {
+	GenerateClusterName(ctx context.Context, stats ClusterStats) (string, error)
+	GenerateClusterNames(ctx context.Context, statsSlice []ClusterStats) ([]string, error)
+	// GenerateClusterLabels labels clusters together; descriptions may be empty. With
+	// ErrBudgetExceeded it may return the labels it could serve, empty for the others.
+	GenerateClusterLabels(ctx context.Context, statsSlice []ClusterStats) ([]Label, error)
+}
+
+// Cached is implemented by labelers that cache their labels
+type Cached interface {
+	Cache() Cache
+}
+
+// Metered is implemented by labelers that account for their API usage
+type Metered interface {
+	Usage() *UsageTracker
+}
+
+// Streamer is implemented by labelers that can hand over labels as they arrive
+type Streamer interface {
+	// StreamClusterLabels labels clusters like GenerateClusterLabels, calling emit
+	// with each labeled cluster's index and label as soon as it is known
+	StreamClusterLabels(ctx context.Context, statsSlice []ClusterStats, emit func(i int, label Label)) error
+}
+
+// Suggester is implemented by labelers that can propose refined queries
+type Suggester interface {
+	// SuggestQueries proposes up to 5 refinements of query from the facet distribution
+	// of its results
+	SuggestQueries(ctx context.Context, query string, facets []FacetInfo) ([]string, error)
+}
+
+// Label is a cluster's name and a one-sentence description of it
+type Label struct {
+	Name        string
+	Description string
+}
+
+// ClusterStats holds statistics about a cluster for labeling
+type ClusterStats struct {
+	Size      int
+	TopFacets []FacetInfo
+	Items     []ItemInfo // The cluster's items, for labelers that use item text
+	Query     string     // The search query, for prompt templates
+}
+
+// FacetInfo holds facet information for the prompt
+type FacetInfo struct {
+	Name        string
+	DisplayName string // Configured display name (empty = Name)
+	Value       string
+	Percentage  float64
+}
+
+// ItemInfo holds the text of a cluster item
+type ItemInfo struct {
+	Name        string
+	Description string
+}
@@ -0,0 +1,29 @@
+package labeler
+
+import (
+	"fmt"
+
+	"ize/internal/config"
+	"ize/internal/logger"
+)
+
+// Provider names accepted in config.LabelingConfig.Provider
+const (
+	ProviderAnthropic = "anthropic"
+	ProviderOpenAI    = "openai"
+	ProviderOllama    = "ollama"
+	ProviderLocal     = "local"
+)
+
+// New creates the configured labeler: an LLM client for the anthropic, openai and
+// ollama providers, or the local labeler
+func New(cfg *config.LabelingConfig, log *logger.Logger) (Labeler, error) {
+	if cfg == nil {
+		return nil, fmt.Errorf("labeling configuration is required")
+	}
+	if cfg.Provider == ProviderLocal {
+		log.Info("local labeler initialized")
+		return NewLocal(log), nil
+	}
+	return NewClient(cfg, log)
+}
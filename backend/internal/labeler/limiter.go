@@ -0,0 +1,187 @@
+package labeler
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	defaultMaxConcurrency = 4
+	maxRetryAfter         = 30 * time.Second // Longest pause honored from a Retry-After header
+)
+
+// limiter paces the client's API requests: at most maxConcurrent in flight, at most
+// requestsPerMinute started per minute (a token bucket), and none while the API has
+// asked callers to back off. Safe for concurrent use.
+type limiter struct {
+	slots chan struct{} // Semaphore of in-flight requests (nil = no limit)
+
+	mu          sync.Mutex
+	interval    time.Duration // Time to earn one token (0 = no rate limit)
+	burst       float64       // Most tokens that can be saved up
+	tokens      float64
+	last        time.Time // When tokens was last updated
+	pausedUntil time.Time // Set from Retry-After headers
+	now         func() time.Time
+}
+
+// newLimiter creates a limiter; maxConcurrent 0 uses the default of 4 and a negative
+// value removes the cap, and requestsPerMinute <= 0 removes the rate limit
+func newLimiter(maxConcurrent, requestsPerMinute int) *limiter {
+	l := &limiter{now: time.Now}
+	if maxConcurrent == 0 {
+		maxConcurrent = defaultMaxConcurrency
+	}
+	if maxConcurrent > 0 {
+		l.slots = make(chan struct{}, maxConcurrent)
+	}
+	if requestsPerMinute > 0 {
+		l.interval = time.Minute / time.Duration(requestsPerMinute)
+		// Let the requests of one response start together, within the per-minute limit
+		l.burst = float64(requestsPerMinute)
+		if maxConcurrent > 0 && float64(maxConcurrent) < l.burst {
+			l.burst = float64(maxConcurrent)
+		}
+		l.tokens = l.burst
+		l.last = l.now()
+	}
+	return l
+}
+
+// acquire waits for a free slot, the end of any pause and a token, and returns the
+// function that frees the slot
+func (l *limiter) acquire(ctx context.Context) (func(), error) {
+	release := func() {}
+	if l.slots != nil {
+		select {
+		case l.slots <- struct{}{}:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		release = func() { <-l.slots }
+	}
+
+	for {
+		wait := l.reserve()
+		if wait <= 0 {
+			return release, nil
+		}
+		select {
+		case <-time.After(wait):
+		case <-ctx.Done():
+			release()
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// reserve takes a token and returns 0, or returns how long to wait before trying again
+func (l *limiter) reserve() time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	if now.Before(l.pausedUntil) {
+		return l.pausedUntil.Sub(now)
+	}
+	if l.interval <= 0 {
+		return 0
+	}
+	l.tokens = math.Min(l.burst, l.tokens+float64(now.Sub(l.last))/float64(l.interval))
+	l.last = now
+	if l.tokens >= 1 {
+		l.tokens--
+		return 0
+	}
+	return time.Duration((1 - l.tokens) * float64(l.interval))
+}
+
+// pause holds back every request for d (at most 30s), as asked by a Retry-After header
+func (l *limiter) pause(d time.Duration) {
+	if d > maxRetryAfter {
+		d = maxRetryAfter
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if until := l.now().Add(d); until.After(l.pausedUntil) {
+		l.pausedUntil = until
+	}
+}
+
+// parseRetryAfter reads a Retry-After header given in seconds or as an HTTP date,
+// returning 0 if it is absent or invalid
+func parseRetryAfter(value string, now time.Time) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0
+		}
+		return time.Duration(seconds) * time.Second
+	}
+	if t, err := http.ParseTime(value); err == nil && t.After(now) {
+		return t.Sub(now)
+	}
+	return 0
+}
+
+// flightGroup lets concurrent callers with the same key share one labeling call that
+// returns a slice of E (labels, or query suggestions)
+type flightGroup[E any] struct {
+	mu    sync.Mutex
+	calls map[string]*flightCall[E]
+}
+
+// flightCall is an in-flight or completed call
+type flightCall[E any] struct {
+	done   chan struct{}
+	values []E
+	err    error
+	usage  *RequestUsage // The call's API usage, added to each caller's request
+}
+
+// do runs fn unless a call with the same key is in flight, in which case it joins that
+// call (and returns shared = true). The call runs with ctx's values but not its
+// cancellation, so it completes, and caches its labels, even if the caller that started
+// it gives up; each caller stops waiting when its own ctx is done. Callers sharing a
+// result get their own copy of the values, and each has the call's usage added to its
+// RequestUsage.
+func (g *flightGroup[E]) do(ctx context.Context, key string, fn func(ctx context.Context) ([]E, error)) (values []E, shared bool, err error) {
+	g.mu.Lock()
+	if g.calls == nil {
+		g.calls = make(map[string]*flightCall[E])
+	}
+	call, shared := g.calls[key]
+	if !shared {
+		call = &flightCall[E]{done: make(chan struct{})}
+		g.calls[key] = call
+		go g.run(ctx, key, call, fn)
+	}
+	g.mu.Unlock()
+
+	select {
+	case <-call.done:
+	case <-ctx.Done():
+		return nil, shared, ctx.Err()
+	}
+	if ru := requestUsageFrom(ctx); ru != nil {
+		ru.addTotals(call.usage.Totals())
+	}
+	return append([]E(nil), call.values...), shared, call.err
+}
+
+// run runs the call's fn, recording its usage on its own, and releases the waiters
+func (g *flightGroup[E]) run(ctx context.Context, key string, call *flightCall[E], fn func(ctx context.Context) ([]E, error)) {
+	ctx, call.usage = WithRequestUsage(context.WithoutCancel(ctx))
+	call.values, call.err = fn(ctx)
+
+	g.mu.Lock()
+	delete(g.calls, key)
+	g.mu.Unlock()
+	close(call.done)
+}
@@ -0,0 +1,252 @@
+package labeler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"ize/internal/config"
+)
+
+func TestLimiter_RateLimit(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	l := newLimiter(1, 2)
+	l.now = func() time.Time { return now }
+	l.last = now
+
+	if wait := l.reserve(); wait != 0 {
+		t.Errorf("first reserve() = %v, want 0", wait)
+	}
+	if wait := l.reserve(); wait != 30*time.Second {
+		t.Errorf("second reserve() = %v, want 30s at 2 requests per minute", wait)
+	}
+	now = now.Add(30 * time.Second)
+	if wait := l.reserve(); wait != 0 {
+		t.Errorf("reserve() after 30s = %v, want 0", wait)
+	}
+
+	l.pause(5 * time.Second)
+	if wait := l.reserve(); wait != 5*time.Second {
+		t.Errorf("reserve() while paused = %v, want 5s", wait)
+	}
+	l.pause(time.Hour)
+	if wait := l.reserve(); wait != maxRetryAfter {
+		t.Errorf("reserve() after a long Retry-After = %v, want %v", wait, maxRetryAfter)
+	}
+
+	unlimited := newLimiter(-1, 0)
+	if unlimited.slots != nil || unlimited.reserve() != 0 {
+		t.Error("newLimiter(-1, 0) should not limit requests")
+	}
+	if defaults := newLimiter(0, 0); cap(defaults.slots) != defaultMaxConcurrency {
+		t.Errorf("newLimiter(0, 0) allows %d requests in flight, want %d", cap(defaults.slots), defaultMaxConcurrency)
+	}
+}
+
+func TestLimiter_AcquireCanceled(t *testing.T) {
+	l := newLimiter(1, 0)
+	release, err := l.acquire(context.Background())
+	if err != nil {
+		t.Fatalf("acquire() error = %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := l.acquire(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("acquire() with no free slot error = %v, want the context's", err)
+	}
+	release()
+	if release, err := l.acquire(context.Background()); err != nil {
+		t.Errorf("acquire() after release error = %v", err)
+	} else {
+		release()
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		value string
+		want  time.Duration
+	}{
+		{"", 0},
+		{"3", 3 * time.Second},
+		{"-1", 0},
+		{"soon", 0},
+		{now.Add(90 * time.Second).Format(http.TimeFormat), 90 * time.Second},
+		{now.Add(-time.Minute).Format(http.TimeFormat), 0},
+	}
+	for _, tt := range tests {
+		if got := parseRetryAfter(tt.value, now); got != tt.want {
+			t.Errorf("parseRetryAfter(%q) = %v, want %v", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestClient_MaxConcurrency(t *testing.T) {
+	var inFlight, peak, calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&inFlight, 1)
+		defer atomic.AddInt32(&inFlight, -1)
+		for {
+			p := atomic.LoadInt32(&peak)
+			if n <= p || atomic.CompareAndSwapInt32(&peak, p, n) {
+				break
+			}
+		}
+		atomic.AddInt32(&calls, 1)
+		time.Sleep(20 * time.Millisecond)
+		json.NewEncoder(w).Encode(ollamaChatResponse{Message: message{Role: "assistant", Content: "Label"}})
+	}))
+	defer server.Close()
+
+	client := newTestClient(t, config.LabelingConfig{Provider: ProviderOllama, BaseURL: server.URL, MaxConcurrency: 2})
+	statsSlice := make([]ClusterStats, 6)
+	for i := range statsSlice {
+		statsSlice[i] = ClusterStats{Size: i + 1}
+	}
+	if _, err := client.GenerateClusterNames(context.Background(), statsSlice); err != nil {
+		t.Fatalf("GenerateClusterNames() error = %v", err)
+	}
+	if calls != 6 || peak > 2 {
+		t.Errorf("made %d calls with up to %d in flight, want 6 with at most 2", calls, peak)
+	}
+}
+
+func TestClient_HonorsRetryAfter(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			w.Header().Set("Retry-After", "1")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		json.NewEncoder(w).Encode(ollamaChatResponse{Message: message{Role: "assistant", Content: "Label"}})
+	}))
+	defer server.Close()
+
+	client := newTestClient(t, config.LabelingConfig{Provider: ProviderOllama, BaseURL: server.URL})
+	start := time.Now()
+	if _, err := client.GenerateClusterName(context.Background(), ClusterStats{Size: 1}); err != nil {
+		t.Fatalf("GenerateClusterName() error = %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < time.Second {
+		t.Errorf("retried after %v, want the 1s Retry-After honored", elapsed)
+	}
+}
+
+func TestClient_SharesInFlightCalls(t *testing.T) {
+	var calls int32
+	started := make(chan struct{})
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			close(started)
+		}
+		<-release
+		json.NewEncoder(w).Encode(ollamaChatResponse{Message: message{Role: "assistant", Content: "Apple Phones"}})
+	}))
+	defer server.Close()
+
+	client := newTestClient(t, config.LabelingConfig{Provider: ProviderOllama, BaseURL: server.URL})
+	stats := ClusterStats{Size: 5, TopFacets: []FacetInfo{{Name: "brand", Value: "Apple", Percentage: 100}}}
+
+	var wg sync.WaitGroup
+	names := make([]string, 5)
+	errs := make([]error, 5)
+	usages := make([]*RequestUsage, 5)
+	for i := range names {
+		var ctx context.Context
+		ctx, usages[i] = WithRequestUsage(context.Background())
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			names[i], errs[i] = client.GenerateClusterName(ctx, stats)
+		}(i)
+	}
+	<-started
+	time.Sleep(50 * time.Millisecond) // Let the other callers join the in-flight call
+	close(release)
+	wg.Wait()
+
+	for i := range names {
+		if errs[i] != nil || names[i] != "Apple Phones" {
+			t.Errorf("caller %d got %q, %v, want Apple Phones", i, names[i], errs[i])
+		}
+		// Every caller's request accounts for the call it shared
+		if requests := usages[i].Totals().Requests; requests != 1 {
+			t.Errorf("caller %d usage has %d API requests, want 1", i, requests)
+		}
+	}
+	if calls != 1 {
+		t.Errorf("API called %d times, want 1", calls)
+	}
+}
+
+func TestFlightGroup(t *testing.T) {
+	var g flightGroup[Label]
+	labels, shared, err := g.do(context.Background(), "k", func(ctx context.Context) ([]Label, error) { return []Label{{Name: "A"}}, nil })
+	if err != nil || shared || len(labels) != 1 {
+		t.Errorf("do() = %v, %v, %v, want one unshared label", labels, shared, err)
+	}
+
+	// A finished call is not reused
+	_, _, err = g.do(context.Background(), "k", func(ctx context.Context) ([]Label, error) { return nil, fmt.Errorf("boom") })
+	if err == nil {
+		t.Error("do() after the first call finished returned its result")
+	}
+}
+
+func TestFlightGroup_Cancel(t *testing.T) {
+	var g flightGroup[Label]
+	release := make(chan struct{})
+	finished := make(chan error, 1)
+	fn := func(ctx context.Context) ([]Label, error) {
+		<-release
+		finished <- ctx.Err()
+		return []Label{{Name: "A"}}, nil
+	}
+
+	// The caller that started the call gives up; the call goes on
+	leaderCtx, cancelLeader := context.WithCancel(context.Background())
+	leaderDone := make(chan error, 1)
+	go func() {
+		_, _, err := g.do(leaderCtx, "k", fn)
+		leaderDone <- err
+	}()
+	time.Sleep(10 * time.Millisecond)
+	cancelLeader()
+	if err := <-leaderDone; !errors.Is(err, context.Canceled) {
+		t.Errorf("canceled leader do() error = %v, want context.Canceled", err)
+	}
+
+	// A waiter stops waiting when its own context is done
+	waiterCtx, cancelWaiter := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancelWaiter()
+	if _, shared, err := g.do(waiterCtx, "k", fn); !shared || !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("timed out waiter do() = %v, %v, want a shared call and context.DeadlineExceeded", shared, err)
+	}
+
+	// Another waiter still gets the result, from a call that was never canceled
+	ctx, usage := WithRequestUsage(context.Background())
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		close(release)
+	}()
+	labels, shared, err := g.do(ctx, "k", fn)
+	if err != nil || !shared || len(labels) != 1 {
+		t.Errorf("waiter do() = %v, %v, %v, want the shared label", labels, shared, err)
+	}
+	if err := <-finished; err != nil {
+		t.Errorf("call ran with a canceled context: %v", err)
+	}
+	if usage.Totals().Requests != 0 {
+		t.Errorf("waiter usage = %+v, want none for a call without API requests", usage.Totals())
+	}
+}
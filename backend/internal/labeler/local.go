@@ -0,0 +1,143 @@
+// Package labeler names clusters.
+package labeler
+
+import (
+	"context"
+	"strings"
+	"unicode"
+
+	"ize/internal/ize"
+	"ize/internal/logger"
+)
+
+// Label composition limits
+const (
+	maxLabelTerms = 2  // Distinctive terms added after the facet value
+	minFacetShare = 50 // Percentage of a cluster a facet value must cover to name it
+)
+
+// Local names clusters without an LLM, from their most distinctive facet value and the
+// terms that set their item names and descriptions apart (c-TF-IDF across the clusters
+// labeled together). It needs no API key or network access.
+type Local struct {
+	logger *logger.Logger
+}
+
+var _ Labeler = (*Local)(nil)
+
+// NewLocal creates a local labeler
+func NewLocal(log *logger.Logger) *Local {
+	if log == nil {
+		log = logger.Default()
+	}
+	return &Local{logger: log}
+}
+
+// GenerateClusterName labels a single cluster; with no siblings to contrast with, its
+// terms are simply its most frequent ones
+func (l *Local) GenerateClusterName(ctx context.Context, stats ClusterStats) (string, error) {
+	names, err := l.GenerateClusterNames(ctx, []ClusterStats{stats})
+	if err != nil {
+		return "", err
+	}
+	return names[0], nil
+}
+
+// GenerateClusterNames labels clusters together, so each label says what distinguishes
+// its cluster from the others. A cluster with no distinctive facet value or term gets
+// an empty name, leaving the caller's fallback in place.
+func (l *Local) GenerateClusterNames(ctx context.Context, statsSlice []ClusterStats) ([]string, error) {
+	log := l.logger.WithContext(ctx)
+
+	classes := make([][]ize.Result, len(statsSlice))
+	for i, stats := range statsSlice {
+		classes[i] = make([]ize.Result, len(stats.Items))
+		for j, item := range stats.Items {
+			classes[i][j] = ize.Result{Name: item.Name, Description: item.Description}
+		}
+	}
+	terms := ize.DistinctiveTerms(classes, maxLabelTerms+2)
+
+	names := make([]string, len(statsSlice))
+	for i := range statsSlice {
+		names[i] = composeLabel(distinctiveFacet(statsSlice, i), terms[i])
+	}
+
+	log.Debug("local cluster labels generated", "count", len(names), "names", names)
+	return names, nil
+}
+
+// GenerateClusterLabels labels clusters like GenerateClusterNames, without descriptions
+func (l *Local) GenerateClusterLabels(ctx context.Context, statsSlice []ClusterStats) ([]Label, error) {
+	names, err := l.GenerateClusterNames(ctx, statsSlice)
+	if err != nil {
+		return nil, err
+	}
+	return namesToLabels(names), nil
+}
+
+// distinctiveFacet returns the value of the facet that best sets cluster i apart: among
+// values covering at least minFacetShare of the cluster, the one whose share most
+// exceeds its largest share in any other cluster. Empty if none stands out.
+func distinctiveFacet(statsSlice []ClusterStats, i int) string {
+	best, bestLift := "", 0.0
+	for _, f := range statsSlice[i].TopFacets {
+		if f.Percentage < minFacetShare {
+			continue
+		}
+		others := 0.0
+		for j, stats := range statsSlice {
+			if j == i {
+				continue
+			}
+			for _, g := range stats.TopFacets {
+				if g.Name == f.Name && g.Value == f.Value && g.Percentage > others {
+					others = g.Percentage
+				}
+			}
+		}
+		if lift := f.Percentage - others; lift > bestLift {
+			best, bestLift = f.Value, lift
+		}
+	}
+	return best
+}
+
+// composeLabel joins a facet value with up to maxLabelTerms title-cased terms that the
+// facet value does not already contain, e.g. "Nike Trail Running"
+func composeLabel(facetValue string, terms []string) string {
+	var words []string
+	used := make(map[string]bool)
+	if facetValue != "" {
+		words = append(words, facetValue)
+		for _, w := range strings.FieldsFunc(strings.ToLower(facetValue), func(r rune) bool {
+			return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+		}) {
+			used[w] = true
+		}
+	}
+
+	added := 0
+	for _, term := range terms {
+		if added == maxLabelTerms {
+			break
+		}
+		if used[term] {
+			continue
+		}
+		used[term] = true
+		words = append(words, titleCase(term))
+		added++
+	}
+	return strings.Join(words, " ")
+}
+
+// titleCase upper-cases the first letter of a word
+func titleCase(word string) string {
+	runes := []rune(word)
+	if len(runes) == 0 {
+		return word
+	}
+	runes[0] = unicode.ToUpper(runes[0])
+	return string(runes)
+}
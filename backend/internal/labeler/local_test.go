@@ -0,0 +1,77 @@
+package labeler
+
+import (
+	"context"
+	"testing"
+
+	"ize/internal/logger"
+)
+
+func TestLocal_GenerateClusterNames(t *testing.T) {
+	statsSlice := []ClusterStats{
+		{
+			Size: 2,
+			TopFacets: []FacetInfo{
+				{Name: "brand", Value: "Salomon", Percentage: 100},
+				{Name: "category", Value: "Shoes", Percentage: 100},
+			},
+			Items: []ItemInfo{
+				{Name: "Salomon trail running shoes", Description: "Grippy trail shoes"},
+				{Name: "Salomon trail shoes", Description: "Waterproof trail runner"},
+			},
+		},
+		{
+			Size: 2,
+			TopFacets: []FacetInfo{
+				{Name: "category", Value: "Shoes", Percentage: 100},
+				{Name: "material", Value: "Leather", Percentage: 50},
+			},
+			Items: []ItemInfo{
+				{Name: "Oxford dress shoes", Description: "Polished leather"},
+				{Name: "Derby dress shoes", Description: "Soft suede"},
+			},
+		},
+		{
+			Size:  1,
+			Items: []ItemInfo{{Name: "Shoe shop gift card"}},
+		},
+	}
+
+	names, err := NewLocal(logger.Default()).GenerateClusterNames(context.Background(), statsSlice)
+	if err != nil {
+		t.Fatalf("GenerateClusterNames() error = %v", err)
+	}
+	want := []string{
+		"Salomon Trail", // Brand stands out; "shoes" is in every cluster
+		"Leather Dress", // Only facet value above the share threshold that other clusters lack
+		"Card Gift",     // No facets: terms only
+	}
+	for i := range want {
+		if names[i] != want[i] {
+			t.Errorf("names[%d] = %q, want %q", i, names[i], want[i])
+		}
+	}
+
+	name, err := NewLocal(nil).GenerateClusterName(context.Background(), ClusterStats{Size: 0})
+	if err != nil || name != "" {
+		t.Errorf("GenerateClusterName(empty) = %q, %v, want empty name", name, err)
+	}
+}
+
+func TestComposeLabel(t *testing.T) {
+	tests := []struct {
+		facet    string
+		terms    []string
+		expected string
+	}{
+		{"Nike", []string{"running", "trail", "road"}, "Nike Running Trail"},
+		{"Running Shoes", []string{"running", "trail"}, "Running Shoes Trail"},
+		{"", []string{"espresso"}, "Espresso"},
+		{"", nil, ""},
+	}
+	for _, tt := range tests {
+		if got := composeLabel(tt.facet, tt.terms); got != tt.expected {
+			t.Errorf("composeLabel(%q, %v) = %q, want %q", tt.facet, tt.terms, got, tt.expected)
+		}
+	}
+}
@@ -0,0 +1,162 @@
+package labeler
+
+import (
+	"container/list"
+	"time"
+)
+
+// lru is a bounded map with a TTL, evicting the least recently used entry when full,
+// that counts hits, misses, evictions and expirations. It is the store of LRUCache and
+// of the suggestion cache; callers hold their own lock.
+type lru[V any] struct {
+	capacity int
+	ttl      time.Duration
+	order    *list.List // *lruEntry[V] values, most recently used at the front
+	items    map[string]*list.Element
+	now      func() time.Time
+
+	hits, misses, evictions, expirations int64
+}
+
+// lruEntry is a stored value with its lifetime
+type lruEntry[V any] struct {
+	key       string
+	value     V
+	createdAt time.Time
+	expiresAt time.Time
+}
+
+// newLRU creates an LRU store; capacity <= 0 uses the default of 10000 entries
+func newLRU[V any](capacity int, ttl time.Duration) lru[V] {
+	return lru[V]{
+		capacity: capacityOrDefault(capacity),
+		ttl:      ttl,
+		order:    list.New(),
+		items:    make(map[string]*list.Element),
+		now:      time.Now,
+	}
+}
+
+// get returns the value for key if it is stored and has not expired, counting a hit or
+// miss and removing an expired entry
+func (c *lru[V]) get(key string) (V, bool) {
+	var zero V
+	elem, ok := c.items[key]
+	if !ok {
+		c.misses++
+		return zero, false
+	}
+	entry := elem.Value.(*lruEntry[V])
+	if c.now().After(entry.expiresAt) {
+		c.remove(elem)
+		c.expirations++
+		c.misses++
+		return zero, false
+	}
+	c.order.MoveToFront(elem)
+	c.hits++
+	return entry.value, true
+}
+
+// lookup returns the unexpired entry for key without counting a hit or miss
+func (c *lru[V]) lookup(key string) (lruEntry[V], bool) {
+	elem, ok := c.items[key]
+	if !ok {
+		return lruEntry[V]{}, false
+	}
+	entry := elem.Value.(*lruEntry[V])
+	if c.now().After(entry.expiresAt) {
+		return lruEntry[V]{}, false
+	}
+	return *entry, true
+}
+
+// set stores value for key, expiring after the TTL
+func (c *lru[V]) set(key string, value V) {
+	now := c.now()
+	c.put(lruEntry[V]{key: key, value: value, createdAt: now, expiresAt: now.Add(c.ttl)})
+}
+
+// put stores entry as the most recently used, evicting the least recently used entries
+// beyond capacity
+func (c *lru[V]) put(entry lruEntry[V]) {
+	if elem, ok := c.items[entry.key]; ok {
+		*elem.Value.(*lruEntry[V]) = entry
+		c.order.MoveToFront(elem)
+		return
+	}
+	c.items[entry.key] = c.order.PushFront(&entry)
+	for len(c.items) > c.capacity {
+		c.remove(c.order.Back())
+		c.evictions++
+	}
+}
+
+func (c *lru[V]) delete(key string) bool {
+	elem, ok := c.items[key]
+	if ok {
+		c.remove(elem)
+	}
+	return ok
+}
+
+func (c *lru[V]) purge() {
+	c.order.Init()
+	c.items = make(map[string]*list.Element)
+}
+
+func (c *lru[V]) remove(elem *list.Element) {
+	c.order.Remove(elem)
+	delete(c.items, elem.Value.(*lruEntry[V]).key)
+}
+
+// entries returns up to limit unexpired entries, most recently used first
+func (c *lru[V]) entries(limit int) []lruEntry[V] {
+	now := c.now()
+	entries := make([]lruEntry[V], 0, len(c.items))
+	for elem := c.order.Front(); elem != nil; elem = elem.Next() {
+		if limit > 0 && len(entries) == limit {
+			break
+		}
+		entry := elem.Value.(*lruEntry[V])
+		if now.After(entry.expiresAt) {
+			continue
+		}
+		entries = append(entries, *entry)
+	}
+	return entries
+}
+
+// replace replaces the contents with entries (most recently used first), dropping
+// expired ones and those beyond capacity without counting them as evictions
+func (c *lru[V]) replace(entries []lruEntry[V]) {
+	c.order.Init()
+	c.items = make(map[string]*list.Element, len(entries))
+	now := c.now()
+	for _, entry := range entries {
+		if len(c.items) == c.capacity {
+			break
+		}
+		if entry.key == "" || now.After(entry.expiresAt) {
+			continue
+		}
+		if _, ok := c.items[entry.key]; ok {
+			continue
+		}
+		entry := entry
+		c.items[entry.key] = c.order.PushBack(&entry)
+	}
+}
+
+// stats returns the size and counters, for the caller to fill in the backend
+func (c *lru[V]) stats() CacheStats {
+	return CacheStats{
+		Entries:     len(c.items),
+		Capacity:    c.capacity,
+		TTLSeconds:  int64(c.ttl / time.Second),
+		Hits:        c.hits,
+		Misses:      c.misses,
+		Evictions:   c.evictions,
+		Expirations: c.expirations,
+	}
+}
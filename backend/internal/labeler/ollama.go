@@ -0,0 +1,74 @@
+package labeler
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+const (
+	ollamaBaseURL = "http://localhost:11434"
+	ollamaModel   = "llama3.2"
+)
+
+// ollamaProvider calls a local Ollama server's /api/chat endpoint without streaming
+type ollamaProvider struct{}
+
+// ollamaChatRequest represents the /api/chat request format
+type ollamaChatRequest struct {
+	Model    string        `json:"model"`
+	Messages []message     `json:"messages"`
+	Stream   bool          `json:"stream"`
+	Format   string        `json:"format,omitempty"` // "json" for JSON output
+	Options  ollamaOptions `json:"options"`
+}
+
+// ollamaOptions holds the model parameters of a request
+type ollamaOptions struct {
+	NumPredict  int      `json:"num_predict"`
+	Temperature *float64 `json:"temperature,omitempty"`
+}
+
+// ollamaChatResponse represents the /api/chat response format
+type ollamaChatResponse struct {
+	Message         message `json:"message"`
+	PromptEvalCount int64   `json:"prompt_eval_count,omitempty"` // Prompt tokens
+	EvalCount       int64   `json:"eval_count,omitempty"`        // Generated tokens
+	Error           string  `json:"error,omitempty"`
+}
+
+func (ollamaProvider) name() string           { return ProviderOllama }
+func (ollamaProvider) defaultBaseURL() string { return ollamaBaseURL }
+func (ollamaProvider) defaultModel() string   { return ollamaModel }
+
+func (ollamaProvider) complete(ctx context.Context, httpClient *http.Client, baseURL string, req completionRequest) (completion, int, error) {
+	reqBody := ollamaChatRequest{
+		Model: req.Model,
+		Messages: []message{
+			{Role: "user", Content: req.Prompt},
+		},
+		Options: ollamaOptions{
+			NumPredict:  req.MaxTokens,
+			Temperature: req.Temperature,
+		},
+	}
+	if req.JSON {
+		reqBody.Format = "json"
+	}
+
+	var chatResp ollamaChatResponse
+	status, err := postJSON(ctx, httpClient, baseURL+"/api/chat", nil, reqBody, &chatResp)
+	if err != nil {
+		return completion{}, status, err
+	}
+
+	if chatResp.Error != "" {
+		return completion{}, status, fmt.Errorf("API error: %s", chatResp.Error)
+	}
+
+	return completion{
+		Text:  strings.TrimSpace(chatResp.Message.Content),
+		Usage: tokenUsage{InputTokens: chatResp.PromptEvalCount, OutputTokens: chatResp.EvalCount},
+	}, status, nil
+}
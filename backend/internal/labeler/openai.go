@@ -0,0 +1,93 @@
+package labeler
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+const (
+	openAIBaseURL = "https://api.openai.com/v1"
+	openAIModel   = "gpt-4o-mini"
+)
+
+// openAIProvider calls an OpenAI-compatible /chat/completions endpoint (OpenAI, vLLM,
+// LM Studio, llama.cpp server and others)
+type openAIProvider struct {
+	apiKey string // May be empty for local servers that don't require authentication
+}
+
+// chatCompletionRequest represents the /chat/completions request format
+type chatCompletionRequest struct {
+	Model          string          `json:"model"`
+	MaxTokens      int             `json:"max_tokens"`
+	Temperature    *float64        `json:"temperature,omitempty"`
+	Messages       []message       `json:"messages"`
+	ResponseFormat *responseFormat `json:"response_format,omitempty"`
+}
+
+// responseFormat constrains the completion's output format
+type responseFormat struct {
+	Type string `json:"type"` // "json_object" for JSON output
+}
+
+// chatCompletionResponse represents the /chat/completions response format
+type chatCompletionResponse struct {
+	Choices []chatChoice `json:"choices"`
+	Usage   *openAIUsage `json:"usage,omitempty"`
+	Error   *apiError    `json:"error,omitempty"`
+}
+
+// openAIUsage is the token count of a /chat/completions response
+type openAIUsage struct {
+	PromptTokens     int64 `json:"prompt_tokens"`
+	CompletionTokens int64 `json:"completion_tokens"`
+}
+
+// chatChoice is one completion choice in the response
+type chatChoice struct {
+	Message message `json:"message"`
+}
+
+func (openAIProvider) name() string           { return ProviderOpenAI }
+func (openAIProvider) defaultBaseURL() string { return openAIBaseURL }
+func (openAIProvider) defaultModel() string   { return openAIModel }
+
+func (p openAIProvider) complete(ctx context.Context, httpClient *http.Client, baseURL string, req completionRequest) (completion, int, error) {
+	reqBody := chatCompletionRequest{
+		Model:       req.Model,
+		MaxTokens:   req.MaxTokens,
+		Temperature: req.Temperature,
+		Messages: []message{
+			{Role: "user", Content: req.Prompt},
+		},
+	}
+	if req.JSON {
+		reqBody.ResponseFormat = &responseFormat{Type: "json_object"}
+	}
+	headers := map[string]string{}
+	if p.apiKey != "" {
+		headers["Authorization"] = "Bearer " + p.apiKey
+	}
+
+	var chatResp chatCompletionResponse
+	status, err := postJSON(ctx, httpClient, baseURL+"/chat/completions", headers, reqBody, &chatResp)
+	if err != nil {
+		return completion{}, status, err
+	}
+
+	if chatResp.Error != nil {
+		return completion{}, status, fmt.Errorf("API error: %s - %s", chatResp.Error.Type, chatResp.Error.Message)
+	}
+
+	if len(chatResp.Choices) == 0 {
+		return completion{}, status, fmt.Errorf("unexpected response format")
+	}
+
+	result := completion{Text: strings.TrimSpace(chatResp.Choices[0].Message.Content)}
+	if chatResp.Usage != nil {
+		result.Usage = tokenUsage{InputTokens: chatResp.Usage.PromptTokens, OutputTokens: chatResp.Usage.CompletionTokens}
+	}
+	return result, status, nil
+}
@@ -0,0 +1,174 @@
+package labeler
+
+import (
+	"crypto/sha256"
+	"embed"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"strings"
+	"text/template"
+
+	"ize/internal/config"
+)
+
+// maxSampleNames is the number of item names given to prompt templates per cluster
+const maxSampleNames = 5
+
+// defaultPrompts holds the templates used when none are configured
+//
+//go:embed prompts/*.tmpl
+var defaultPrompts embed.FS
+
+// PromptData is the data available to prompt templates
+type PromptData struct {
+	Query    string          // The search query the clusters came from
+	Cluster  PromptCluster   // The cluster to label (per-cluster prompt)
+	Siblings []PromptCluster // The clusters labeled alongside it (per-cluster prompt)
+	Clusters []PromptCluster // Every cluster to label (batch prompt)
+	Facets   []PromptFacet   // Facet distribution of the query's results (suggestion prompt)
+}
+
+// PromptCluster summarizes a cluster for prompt templates
+type PromptCluster struct {
+	Index       int           // 1-based position among the clusters labeled together
+	Size        int           // Number of items
+	Facets      []PromptFacet // Most common facet values
+	SampleNames []string      // Up to 5 item names
+}
+
+// PromptFacet is a facet value with its share of a cluster
+type PromptFacet struct {
+	Name        string  // Facet field, e.g., "attributes.Brand"
+	DisplayName string  // Configured display name, or the field
+	Value       string  // Facet value
+	Percentage  float64 // Share of the cluster's items
+}
+
+// prompts are the parsed labeling templates
+type prompts struct {
+	cluster *template.Template
+	batch   *template.Template
+	suggest *template.Template
+	version string // Part of the cache key, so labels from other templates are not reused
+}
+
+// promptFuncs are the functions available to prompt templates
+var promptFuncs = template.FuncMap{
+	"percent": func(p float64) string { return fmt.Sprintf("%.0f", p) },
+	"join":    strings.Join,
+}
+
+// loadPrompts parses the configured prompt template files, using the built-in templates
+// for those not configured. The version is the configured prompt_version, or a hash of
+// the templates' text.
+func loadPrompts(cfg *config.LabelingConfig) (*prompts, error) {
+	clusterText, err := readPrompt(cfg.PromptTemplate, "prompts/cluster.tmpl")
+	if err != nil {
+		return nil, err
+	}
+	batchText, err := readPrompt(cfg.BatchPromptTemplate, "prompts/batch.tmpl")
+	if err != nil {
+		return nil, err
+	}
+	suggestText, err := readPrompt(cfg.SuggestPromptTemplate, "prompts/suggest.tmpl")
+	if err != nil {
+		return nil, err
+	}
+
+	p := &prompts{version: cfg.PromptVersion}
+	if p.cluster, err = template.New("cluster").Funcs(promptFuncs).Parse(clusterText); err != nil {
+		return nil, fmt.Errorf("failed to parse prompt template: %w", err)
+	}
+	if p.batch, err = template.New("batch").Funcs(promptFuncs).Parse(batchText); err != nil {
+		return nil, fmt.Errorf("failed to parse batch prompt template: %w", err)
+	}
+	if p.suggest, err = template.New("suggest").Funcs(promptFuncs).Parse(suggestText); err != nil {
+		return nil, fmt.Errorf("failed to parse suggestion prompt template: %w", err)
+	}
+	if p.version == "" {
+		h := sha256.Sum256([]byte(clusterText + "\x00" + batchText + "\x00" + suggestText))
+		p.version = hex.EncodeToString(h[:8])
+	}
+	return p, nil
+}
+
+// readPrompt reads a template file, or the built-in template when path is empty
+func readPrompt(path, builtin string) (string, error) {
+	if path == "" {
+		data, err := defaultPrompts.ReadFile(builtin)
+		return string(data), err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read prompt template: %w", err)
+	}
+	return string(data), nil
+}
+
+// render executes a template, trimming surrounding whitespace
+func render(tmpl *template.Template, data PromptData) (string, error) {
+	var b strings.Builder
+	if err := tmpl.Execute(&b, data); err != nil {
+		return "", fmt.Errorf("failed to render %s prompt: %w", tmpl.Name(), err)
+	}
+	return strings.TrimSpace(b.String()), nil
+}
+
+// clusterPromptData is the data for labeling cluster i of statsSlice on its own
+func clusterPromptData(statsSlice []ClusterStats, i int) PromptData {
+	data := PromptData{Query: statsSlice[i].Query, Cluster: promptCluster(statsSlice[i], i)}
+	for j, stats := range statsSlice {
+		if j != i {
+			data.Siblings = append(data.Siblings, promptCluster(stats, j))
+		}
+	}
+	return data
+}
+
+// batchPromptData is the data for labeling all of statsSlice in one request
+func batchPromptData(statsSlice []ClusterStats) PromptData {
+	var data PromptData
+	for i, stats := range statsSlice {
+		if data.Query == "" {
+			data.Query = stats.Query
+		}
+		data.Clusters = append(data.Clusters, promptCluster(stats, i))
+	}
+	return data
+}
+
+// suggestPromptData is the data for suggesting refinements of query
+func suggestPromptData(query string, facets []FacetInfo) PromptData {
+	return PromptData{Query: query, Facets: promptFacets(facets)}
+}
+
+func promptCluster(stats ClusterStats, i int) PromptCluster {
+	cluster := PromptCluster{Index: i + 1, Size: stats.Size, Facets: promptFacets(stats.TopFacets)}
+	for _, item := range stats.Items {
+		if len(cluster.SampleNames) == maxSampleNames {
+			break
+		}
+		if item.Name != "" {
+			cluster.SampleNames = append(cluster.SampleNames, item.Name)
+		}
+	}
+	return cluster
+}
+
+func promptFacets(facets []FacetInfo) []PromptFacet {
+	var result []PromptFacet
+	for _, f := range facets {
+		displayName := f.DisplayName
+		if displayName == "" {
+			displayName = f.Name
+		}
+		result = append(result, PromptFacet{
+			Name:        f.Name,
+			DisplayName: displayName,
+			Value:       f.Value,
+			Percentage:  f.Percentage,
+		})
+	}
+	return result
+}
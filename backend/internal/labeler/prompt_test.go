@@ -0,0 +1,180 @@
+package labeler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"ize/internal/config"
+	"ize/internal/logger"
+)
+
+func TestLoadPrompts_Default(t *testing.T) {
+	p, err := loadPrompts(&config.LabelingConfig{})
+	if err != nil {
+		t.Fatalf("loadPrompts() error = %v", err)
+	}
+
+	stats := ClusterStats{
+		Size: 10,
+		TopFacets: []FacetInfo{
+			{Name: "brand", Value: "Apple", Percentage: 80},
+			{Name: "category", Value: "Phone", Percentage: 100},
+		},
+	}
+	prompt, err := render(p.cluster, clusterPromptData([]ClusterStats{stats}, 0))
+	if err != nil {
+		t.Fatalf("render() error = %v", err)
+	}
+	want := `Given these facet characteristics of a product cluster:
+- brand:Apple (80%)
+- category:Phone (100%)
+- 10 items total
+
+Generate a pithy 1-3 word label for this cluster that captures what makes these items similar.
+Respond with ONLY the label, nothing else. No quotes, no punctuation, just the label words.`
+	if prompt != want {
+		t.Errorf("default prompt =\n%s\nwant\n%s", prompt, want)
+	}
+
+	batch, err := render(p.batch, batchPromptData([]ClusterStats{stats, {Size: 4, TopFacets: []FacetInfo{{Name: "brand", Value: "Samsung", Percentage: 75}}}}))
+	if err != nil {
+		t.Fatalf("render(batch) error = %v", err)
+	}
+	wantBatch := `Here are 2 product clusters from the same search results:
+
+Cluster 1 (10 items):
+- brand:Apple (80%)
+- category:Phone (100%)
+
+Cluster 2 (4 items):
+- brand:Samsung (75%)
+
+Give each cluster a pithy 1-3 word label that captures what makes its items similar and sets it apart from the other clusters. No two clusters may share a label. Also write a one-sentence description of each cluster.
+Respond with ONLY a JSON object of this form, with one entry per cluster:
+{"clusters": [{"index": 1, "label": "...", "description": "..."}]}`
+	if batch != wantBatch {
+		t.Errorf("default batch prompt =\n%s\nwant\n%s", batch, wantBatch)
+	}
+}
+
+func TestLoadPrompts_Errors(t *testing.T) {
+	dir := t.TempDir()
+	invalid := filepath.Join(dir, "invalid.tmpl")
+	if err := os.WriteFile(invalid, []byte("{{range .Cluster.Facets}}"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := loadPrompts(&config.LabelingConfig{PromptTemplate: filepath.Join(dir, "missing.tmpl")}); err == nil {
+		t.Error("loadPrompts(missing file) error = nil, want error")
+	}
+	if _, err := loadPrompts(&config.LabelingConfig{BatchPromptTemplate: invalid}); err == nil {
+		t.Error("loadPrompts(invalid template) error = nil, want error")
+	}
+	if _, err := NewClient(&config.LabelingConfig{Provider: ProviderOllama, PromptTemplate: invalid}, logger.Default()); err == nil {
+		t.Error("NewClient(invalid template) error = nil, want error")
+	}
+}
+
+func TestPromptVersion_CacheKey(t *testing.T) {
+	dir := t.TempDir()
+	custom := filepath.Join(dir, "custom.tmpl")
+	if err := os.WriteFile(custom, []byte("Name the {{.Cluster.Size}} items."), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	prompt := "Name the 3 items."
+	builtin := newTestClient(t, config.LabelingConfig{Provider: ProviderOllama})
+	changed := newTestClient(t, config.LabelingConfig{Provider: ProviderOllama, PromptTemplate: custom})
+	if builtin.cacheKey(prompt) == changed.cacheKey(prompt) {
+		t.Error("cacheKey() is the same for different templates")
+	}
+
+	// An explicit version replaces the hash of the templates, and changing it renames
+	// clusters even when the prompts stay the same
+	v1 := newTestClient(t, config.LabelingConfig{Provider: ProviderOllama, PromptVersion: "v1"})
+	v1Custom := newTestClient(t, config.LabelingConfig{Provider: ProviderOllama, PromptTemplate: custom, PromptVersion: "v1"})
+	v2 := newTestClient(t, config.LabelingConfig{Provider: ProviderOllama, PromptVersion: "v2"})
+	if v1.prompts.version != "v1" || v1.cacheKey(prompt) != v1Custom.cacheKey(prompt) {
+		t.Errorf("cacheKey() differs for the same prompt_version %q", v1.prompts.version)
+	}
+	if v1.cacheKey(prompt) == v2.cacheKey(prompt) {
+		t.Error("cacheKey() is the same for different prompt versions")
+	}
+}
+
+func TestGenerateClusterNames_CustomTemplate(t *testing.T) {
+	dir := t.TempDir()
+	custom := filepath.Join(dir, "cluster.tmpl")
+	template := `Query: {{.Query}}
+Cluster {{.Cluster.Index}}:{{range .Cluster.Facets}} {{.DisplayName}}={{.Value}}{{end}}
+Examples: {{join .Cluster.SampleNames ", "}}
+{{range .Siblings}}Sibling {{.Index}}:{{range .Facets}} {{.DisplayName}}={{.Value}}{{end}}
+{{end}}`
+	if err := os.WriteFile(custom, []byte(template), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	prompts := make(chan string, 4)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req ollamaChatRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Fatalf("Failed to decode request: %v", err)
+		}
+		prompts <- req.Messages[0].Content
+		json.NewEncoder(w).Encode(ollamaChatResponse{Message: message{Role: "assistant", Content: "Label"}})
+	}))
+	defer server.Close()
+
+	client := newTestClient(t, config.LabelingConfig{Provider: ProviderOllama, BaseURL: server.URL, PromptTemplate: custom})
+	statsSlice := []ClusterStats{
+		{
+			Size:      2,
+			TopFacets: []FacetInfo{{Name: "attributes.Brand", DisplayName: "Brand", Value: "Apple", Percentage: 100}},
+			Items:     []ItemInfo{{Name: "iPhone 15"}, {Name: ""}, {Name: "iPhone 14"}},
+			Query:     "phones",
+		},
+		{
+			Size:      1,
+			TopFacets: []FacetInfo{{Name: "attributes.Brand", Value: "Samsung", Percentage: 100}},
+			Items:     []ItemInfo{{Name: "Galaxy S24"}},
+			Query:     "phones",
+		},
+	}
+	if _, err := client.GenerateClusterNames(context.Background(), statsSlice); err != nil {
+		t.Fatalf("GenerateClusterNames() error = %v", err)
+	}
+
+	got := map[string]bool{<-prompts: true, <-prompts: true}
+	want := []string{
+		"Query: phones\nCluster 1: Brand=Apple\nExamples: iPhone 15, iPhone 14\nSibling 2: attributes.Brand=Samsung",
+		"Query: phones\nCluster 2: attributes.Brand=Samsung\nExamples: Galaxy S24\nSibling 1: Brand=Apple",
+	}
+	for _, prompt := range want {
+		if !got[prompt] {
+			t.Errorf("prompts %v do not include\n%s", got, prompt)
+		}
+	}
+
+	// The labels are cached for these prompts, not for the cluster statistics: the same
+	// clusters under another query are labeled again
+	if _, err := client.GenerateClusterNames(context.Background(), statsSlice); err != nil {
+		t.Fatalf("GenerateClusterNames() error = %v", err)
+	}
+	if len(prompts) != 0 {
+		t.Errorf("GenerateClusterNames() made %d API calls for cached prompts, want 0", len(prompts))
+	}
+	for i := range statsSlice {
+		statsSlice[i].Query = "tablets"
+	}
+	if _, err := client.GenerateClusterNames(context.Background(), statsSlice); err != nil {
+		t.Fatalf("GenerateClusterNames() error = %v", err)
+	}
+	if len(prompts) != 2 {
+		t.Errorf("GenerateClusterNames() made %d API calls for another query, want 2", len(prompts))
+	}
+}
@@ -0,0 +1,102 @@
+package labeler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// completionRequest is a provider-neutral single-turn completion
+type completionRequest struct {
+	Model       string
+	MaxTokens   int
+	Temperature *float64 // nil leaves the provider's default
+	Prompt      string
+	JSON        bool // Ask for a JSON object where the API supports it
+}
+
+// completion is a provider's response: the text and the tokens it reported
+type completion struct {
+	Text  string
+	Usage tokenUsage
+}
+
+// provider adapts a vendor's completion API
+type provider interface {
+	name() string
+	defaultBaseURL() string
+	defaultModel() string
+	// complete makes a single API request and returns the completion, the HTTP status
+	// code (0 if no response was received) and an error
+	complete(ctx context.Context, httpClient *http.Client, baseURL string, req completionRequest) (completion, int, error)
+}
+
+// apiError represents an API error in the Anthropic and OpenAI response formats
+type apiError struct {
+	Type    string `json:"type"`
+	Message string `json:"message"`
+}
+
+// statusError is an API response with a status other than 200
+type statusError struct {
+	Status     int
+	Body       string
+	RetryAfter time.Duration // From the Retry-After header (0 if absent)
+}
+
+func (e *statusError) Error() string {
+	return fmt.Sprintf("API returned status %d: %s", e.Status, e.Body)
+}
+
+// message represents a single message in the conversation
+type message struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+// postJSON posts reqBody as JSON with the given headers and decodes a 200 response into
+// respBody. It returns the HTTP status code (0 if no response was received); other
+// statuses are returned as a *statusError.
+func postJSON(ctx context.Context, httpClient *http.Client, url string, headers map[string]string, reqBody, respBody interface{}) (int, error) {
+	jsonBody, err := json.Marshal(reqBody)
+	if err != nil {
+		return 0, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(jsonBody))
+	if err != nil {
+		return 0, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("API call failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return resp.StatusCode, fmt.Errorf("failed to read response: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return resp.StatusCode, &statusError{
+			Status:     resp.StatusCode,
+			Body:       string(body),
+			RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After"), time.Now()),
+		}
+	}
+
+	if err := json.Unmarshal(body, respBody); err != nil {
+		return resp.StatusCode, fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+	return resp.StatusCode, nil
+}
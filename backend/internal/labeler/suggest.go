@@ -0,0 +1,163 @@
+package labeler
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Query suggestion limits
+const (
+	maxSuggestions        = 5    // Suggestions kept from a response (the prompt asks for 3-5)
+	maxSuggestionWords    = 10   // Longest suggestion accepted
+	suggestTokensPerQuery = 20   // Room for one suggestion and JSON syntax
+	maxCachedSuggestions  = 1000 // Queries whose suggestions are kept
+)
+
+// suggestResponse is the JSON object the model is asked to return for suggestions
+type suggestResponse struct {
+	Queries []string `json:"queries"`
+}
+
+// suggestKey is the cache key of the suggestions for a query and facet distribution.
+// Percentages are rounded so small changes in the results reuse the suggestions.
+func (c *Client) suggestKey(query string, facets []FacetInfo) string {
+	parts := []string{"suggest", c.provider.name(), c.model, "prompt:" + c.prompts.version, strings.ToLower(strings.TrimSpace(query))}
+
+	facetStrings := make([]string, 0, len(facets))
+	for _, f := range facets {
+		facetStrings = append(facetStrings, fmt.Sprintf("%s:%s:%.0f", f.Name, f.Value, f.Percentage))
+	}
+	sort.Strings(facetStrings)
+	parts = append(parts, facetStrings...)
+
+	h := sha256.Sum256([]byte(strings.Join(parts, "|")))
+	return hex.EncodeToString(h[:16])
+}
+
+// SuggestQueries asks the model for 3-5 refinements of query, given the facet
+// distribution of its results, and returns up to 5 distinct ones. Suggestions are
+// cached in memory apart from the labels, for the label cache's TTL. Returns
+// ErrBudgetExceeded once the daily budget is spent and the suggestions are not cached.
+func (c *Client) SuggestQueries(ctx context.Context, query string, facets []FacetInfo) ([]string, error) {
+	log := c.logger.WithContext(ctx)
+
+	key := c.suggestKey(query, facets)
+	if cached, ok := c.suggestions.get(key); ok {
+		log.Debug("query suggestions cache hit", "query", query)
+		return cached, nil
+	}
+
+	start := time.Now()
+	suggestions, shared, err := c.suggesting.do(ctx, key, func(ctx context.Context) ([]string, error) {
+		prompt, err := render(c.prompts.suggest, suggestPromptData(query, facets))
+		if err != nil {
+			return nil, err
+		}
+
+		text, err := c.complete(ctx, query, completionRequest{
+			Model:       c.model,
+			MaxTokens:   batchTokensBase + maxSuggestions*suggestTokensPerQuery,
+			Temperature: c.temperature,
+			Prompt:      prompt,
+			JSON:        true,
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		suggestions, err := parseSuggestions(text, query)
+		if err != nil {
+			return nil, err
+		}
+		c.suggestions.set(key, suggestions)
+		return suggestions, nil
+	})
+	if errors.Is(err, ErrBudgetExceeded) {
+		c.usage.reject()
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	log.Info("generated query suggestions",
+		"query", query,
+		"suggestion_count", len(suggestions),
+		"shared", shared,
+		"duration_ms", time.Since(start).Milliseconds(),
+	)
+	return suggestions, nil
+}
+
+// parseSuggestions extracts the JSON object from a suggestion response and returns up
+// to 5 distinct suggestions of 1-10 words, dropping any that repeat the query. It is an
+// error if none are left.
+func parseSuggestions(text, query string) ([]string, error) {
+	start, end := strings.Index(text, "{"), strings.LastIndex(text, "}")
+	if start < 0 || end < start {
+		return nil, fmt.Errorf("suggestion response has no JSON object")
+	}
+
+	var resp suggestResponse
+	if err := json.Unmarshal([]byte(text[start:end+1]), &resp); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal suggestion response: %w", err)
+	}
+
+	seen := map[string]bool{strings.ToLower(strings.Join(strings.Fields(query), " ")): true}
+	var suggestions []string
+	for _, suggestion := range resp.Queries {
+		words := strings.Fields(suggestion)
+		normalized := strings.ToLower(strings.Join(words, " "))
+		if len(words) == 0 || len(words) > maxSuggestionWords || seen[normalized] {
+			continue
+		}
+		seen[normalized] = true
+		suggestions = append(suggestions, strings.Join(words, " "))
+		if len(suggestions) == maxSuggestions {
+			break
+		}
+	}
+	if len(suggestions) == 0 {
+		return nil, fmt.Errorf("suggestion response has no usable queries")
+	}
+	return suggestions, nil
+}
+
+// suggestionCache is a bounded in-memory LRU of query suggestions with a TTL. It is
+// kept apart from the label cache so suggestions neither show up among nor evict the
+// labels. Safe for concurrent use.
+type suggestionCache struct {
+	mu sync.Mutex
+	lru[[]string]
+}
+
+func newSuggestionCache(capacity int, ttl time.Duration) *suggestionCache {
+	return &suggestionCache{lru: newLRU[[]string](capacity, ttl)}
+}
+
+// get returns a copy of the suggestions for key if they are cached and have not expired
+func (c *suggestionCache) get(key string) ([]string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	suggestions, ok := c.lru.get(key)
+	if !ok {
+		return nil, false
+	}
+	return append([]string(nil), suggestions...), true
+}
+
+// set stores a copy of the suggestions for key, evicting the least recently used
+// entries beyond capacity
+func (c *suggestionCache) set(key string, suggestions []string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.lru.set(key, append([]string(nil), suggestions...))
+}
@@ -0,0 +1,136 @@
+package labeler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"ize/internal/config"
+)
+
+func TestParseSuggestions(t *testing.T) {
+	tests := []struct {
+		name    string
+		text    string
+		want    []string
+		wantErr bool
+	}{
+		{
+			name: "plain JSON",
+			text: `{"queries": ["wireless headphones", "noise-cancelling headphones", "over-ear headphones"]}`,
+			want: []string{"wireless headphones", "noise-cancelling headphones", "over-ear headphones"},
+		},
+		{
+			name: "surrounding text and duplicates",
+			text: "Here you go:\n```json\n{\"queries\": [\"  Wireless   Headphones \", \"wireless headphones\", \"HEADPHONES\", \"\"]}\n```",
+			want: []string{"Wireless Headphones"},
+		},
+		{
+			name: "long and extra suggestions dropped",
+			text: `{"queries": ["a", "b", "one two three four five six seven eight nine ten eleven", "c", "d", "e", "f"]}`,
+			want: []string{"a", "b", "c", "d", "e"},
+		},
+		{name: "only the query", text: `{"queries": ["headphones"]}`, wantErr: true},
+		{name: "no JSON", text: "wireless headphones", wantErr: true},
+		{name: "invalid JSON", text: `{"queries": [}`, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseSuggestions(tt.text, "headphones")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseSuggestions() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseSuggestions() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSuggestQueries(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		var req chatCompletionRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Fatalf("Failed to decode request: %v", err)
+		}
+		if req.ResponseFormat == nil || req.ResponseFormat.Type != "json_object" {
+			t.Errorf("response_format = %+v, want json_object", req.ResponseFormat)
+		}
+		prompt := req.Messages[0].Content
+		if !strings.Contains(prompt, `"headphones"`) || !strings.Contains(prompt, "- brand:") {
+			t.Errorf("prompt does not describe the query and its facets:\n%s", prompt)
+		}
+
+		content := `{"queries": ["wireless noise-cancelling headphones", "sony headphones", "headphones"]}`
+		json.NewEncoder(w).Encode(chatCompletionResponse{Choices: []chatChoice{{Message: message{Role: "assistant", Content: content}}}})
+	}))
+	defer server.Close()
+
+	client := newTestClient(t, config.LabelingConfig{Provider: ProviderOpenAI, BaseURL: server.URL})
+	facets := []FacetInfo{{Name: "brand", Value: "Sony", Percentage: 40}}
+	want := []string{"wireless noise-cancelling headphones", "sony headphones"}
+
+	suggestions, err := client.SuggestQueries(context.Background(), "headphones", facets)
+	if err != nil {
+		t.Fatalf("SuggestQueries() error = %v", err)
+	}
+	if !reflect.DeepEqual(suggestions, want) {
+		t.Errorf("SuggestQueries() = %q, want %q", suggestions, want)
+	}
+
+	// The same query and facets are answered from the cache
+	suggestions, err = client.SuggestQueries(context.Background(), " Headphones", facets)
+	if err != nil || !reflect.DeepEqual(suggestions, want) {
+		t.Errorf("cached SuggestQueries() = %q, %v, want %q", suggestions, err, want)
+	}
+	if calls.Load() != 1 {
+		t.Errorf("API calls = %d, want 1", calls.Load())
+	}
+	if entries := client.Cache().Stats().Entries; entries != 0 {
+		t.Errorf("label cache has %d entries, want suggestions kept apart", entries)
+	}
+
+	// Different facets ask again
+	if _, err := client.SuggestQueries(context.Background(), "headphones", []FacetInfo{{Name: "brand", Value: "Bose", Percentage: 60}}); err != nil {
+		t.Fatalf("SuggestQueries() error = %v", err)
+	}
+	if calls.Load() != 2 {
+		t.Errorf("API calls = %d, want 2", calls.Load())
+	}
+}
+
+func TestSuggestionCache(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	c := newSuggestionCache(2, time.Hour)
+	c.now = func() time.Time { return now }
+
+	suggestions := []string{"wireless headphones", "sony headphones"}
+	c.set("a", suggestions)
+	suggestions[0] = "changed"
+	c.set("b", []string{"running shoes"})
+	if got, ok := c.get("a"); !ok || got[0] != "wireless headphones" {
+		t.Fatalf("get(a) = %q, %v, want the stored suggestions", got, ok)
+	}
+
+	// "b" is now the least recently used and is evicted
+	c.set("c", []string{"red shirts"})
+	if _, ok := c.get("b"); ok {
+		t.Error("get(b) found an evicted entry")
+	}
+
+	now = now.Add(2 * time.Hour)
+	if _, ok := c.get("a"); ok {
+		t.Error("get(a) found an expired entry")
+	}
+	if stats := c.stats(); stats.Hits != 1 || stats.Misses != 2 || stats.Evictions != 1 || stats.Expirations != 1 {
+		t.Errorf("stats() = %+v, want 1 hit, 2 misses, 1 eviction and 1 expiration", stats)
+	}
+}
@@ -0,0 +1,267 @@
+package labeler
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"ize/internal/config"
+)
+
+// maxTrackedQueries bounds the per-query totals; later queries count only in the
+// overall and per-model totals
+const maxTrackedQueries = 1000
+
+// ErrBudgetExceeded is returned instead of calling the API once the daily budget is spent
+var ErrBudgetExceeded = errors.New("daily labeling budget exceeded")
+
+// builtinPricing holds list prices (USD per million tokens) of the default models and
+// their common alternatives. Configured prices take precedence.
+var builtinPricing = map[string]config.ModelPricing{
+	"claude-3-haiku-20240307":    {InputPerMTok: 0.25, OutputPerMTok: 1.25},
+	"claude-3-5-haiku-20241022":  {InputPerMTok: 0.80, OutputPerMTok: 4.00},
+	"claude-3-5-sonnet-20241022": {InputPerMTok: 3.00, OutputPerMTok: 15.00},
+	"gpt-4o-mini":                {InputPerMTok: 0.15, OutputPerMTok: 0.60},
+	"gpt-4o":                     {InputPerMTok: 2.50, OutputPerMTok: 10.00},
+}
+
+// tokenUsage is the token count a provider reported for one completion
+type tokenUsage struct {
+	InputTokens  int64
+	OutputTokens int64
+}
+
+// UsageTotals accumulates API calls, tokens and their estimated cost
+type UsageTotals struct {
+	Requests     int64   `json:"requests"`
+	InputTokens  int64   `json:"inputTokens"`
+	OutputTokens int64   `json:"outputTokens"`
+	CostUSD      float64 `json:"costUsd"`
+}
+
+func (t *UsageTotals) add(u tokenUsage, cost float64) {
+	t.Requests++
+	t.InputTokens += u.InputTokens
+	t.OutputTokens += u.OutputTokens
+	t.CostUSD += cost
+}
+
+// QueryUsage is the usage attributed to one search query
+type QueryUsage struct {
+	Query string `json:"query"`
+	UsageTotals
+}
+
+// DailyUsage is the spend of the current UTC day against the budget
+type DailyUsage struct {
+	Date      string  `json:"date"`
+	CostUSD   float64 `json:"costUsd"`
+	BudgetUSD float64 `json:"budgetUsd,omitempty"` // 0 = no limit
+	Exceeded  bool    `json:"exceeded"`
+	Rejected  int64   `json:"rejected"` // Labeling requests refused over budget today
+}
+
+// UsageReport is a snapshot of a UsageTracker
+type UsageReport struct {
+	Since   time.Time              `json:"since"`
+	Totals  UsageTotals            `json:"totals"`
+	Today   DailyUsage             `json:"today"`
+	Models  map[string]UsageTotals `json:"models"`
+	Queries []QueryUsage           `json:"queries"` // Most expensive first
+}
+
+// UsageTracker records the tokens and estimated cost of labeling API calls since
+// startup, in total, per model and per query, and enforces an optional daily budget.
+// Costs are estimates from the configured or built-in prices; models without a price
+// (such as local Ollama models) cost nothing. Safe for concurrent use.
+type UsageTracker struct {
+	mu      sync.Mutex
+	pricing map[string]config.ModelPricing
+	budget  float64
+	now     func() time.Time
+
+	since   time.Time
+	totals  UsageTotals
+	models  map[string]*UsageTotals
+	queries map[string]*UsageTotals
+
+	day      string // UTC date of daySpent
+	daySpent float64
+	rejected int64
+}
+
+// NewUsageTracker creates a tracker with the built-in prices overridden by pricing and
+// a daily budget in US dollars (0 = no limit)
+func NewUsageTracker(pricing map[string]config.ModelPricing, dailyBudgetUSD float64) *UsageTracker {
+	prices := make(map[string]config.ModelPricing, len(builtinPricing)+len(pricing))
+	for model, price := range builtinPricing {
+		prices[model] = price
+	}
+	for model, price := range pricing {
+		prices[model] = price
+	}
+	t := &UsageTracker{
+		pricing: prices,
+		budget:  dailyBudgetUSD,
+		now:     time.Now,
+		models:  make(map[string]*UsageTotals),
+		queries: make(map[string]*UsageTotals),
+	}
+	t.since = t.now()
+	return t
+}
+
+// Priced reports whether the tracker knows the price of model
+func (t *UsageTracker) Priced(model string) bool {
+	_, ok := t.pricing[model]
+	return ok
+}
+
+// checkBudget returns ErrBudgetExceeded once today's estimated spend has reached the
+// daily budget, without counting a rejection
+func (t *UsageTracker) checkBudget() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.budget <= 0 {
+		return nil
+	}
+	t.rollDayLocked()
+	if t.daySpent >= t.budget {
+		return ErrBudgetExceeded
+	}
+	return nil
+}
+
+// reject counts a labeling request refused over the budget
+func (t *UsageTracker) reject() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.rollDayLocked()
+	t.rejected++
+}
+
+// record adds one API call's usage to the totals, and to the request's usage if ctx
+// carries one (see WithRequestUsage). It returns the call's estimated cost.
+func (t *UsageTracker) record(ctx context.Context, model, query string, u tokenUsage) float64 {
+	price := t.pricing[model]
+	cost := (float64(u.InputTokens)*price.InputPerMTok + float64(u.OutputTokens)*price.OutputPerMTok) / 1e6
+
+	t.mu.Lock()
+	t.totals.add(u, cost)
+	if t.models[model] == nil {
+		t.models[model] = &UsageTotals{}
+	}
+	t.models[model].add(u, cost)
+	query = strings.TrimSpace(query)
+	if totals, ok := t.queries[query]; ok {
+		totals.add(u, cost)
+	} else if len(t.queries) < maxTrackedQueries {
+		t.queries[query] = &UsageTotals{}
+		t.queries[query].add(u, cost)
+	}
+	t.rollDayLocked()
+	t.daySpent += cost
+	t.mu.Unlock()
+
+	if ru := requestUsageFrom(ctx); ru != nil {
+		ru.add(u, cost)
+	}
+	return cost
+}
+
+// rollDayLocked resets the daily spend when the UTC date changes
+func (t *UsageTracker) rollDayLocked() {
+	day := t.now().UTC().Format("2006-01-02")
+	if day != t.day {
+		t.day, t.daySpent, t.rejected = day, 0, 0
+	}
+}
+
+// Report returns the totals with up to limit queries, most expensive first
+// (limit <= 0 = all)
+func (t *UsageTracker) Report(limit int) UsageReport {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.rollDayLocked()
+	report := UsageReport{
+		Since:  t.since,
+		Totals: t.totals,
+		Today: DailyUsage{
+			Date:      t.day,
+			CostUSD:   t.daySpent,
+			BudgetUSD: t.budget,
+			Exceeded:  t.budget > 0 && t.daySpent >= t.budget,
+			Rejected:  t.rejected,
+		},
+		Models:  make(map[string]UsageTotals, len(t.models)),
+		Queries: make([]QueryUsage, 0, len(t.queries)),
+	}
+	for model, totals := range t.models {
+		report.Models[model] = *totals
+	}
+	for query, totals := range t.queries {
+		report.Queries = append(report.Queries, QueryUsage{Query: query, UsageTotals: *totals})
+	}
+	sort.Slice(report.Queries, func(i, j int) bool {
+		a, b := report.Queries[i], report.Queries[j]
+		if a.CostUSD != b.CostUSD {
+			return a.CostUSD > b.CostUSD
+		}
+		if a.InputTokens+a.OutputTokens != b.InputTokens+b.OutputTokens {
+			return a.InputTokens+a.OutputTokens > b.InputTokens+b.OutputTokens
+		}
+		return a.Query < b.Query
+	})
+	if limit > 0 && len(report.Queries) > limit {
+		report.Queries = report.Queries[:limit]
+	}
+	return report
+}
+
+// RequestUsage accumulates the labeling usage of one HTTP request
+type RequestUsage struct {
+	mu     sync.Mutex
+	totals UsageTotals
+}
+
+func (r *RequestUsage) add(u tokenUsage, cost float64) {
+	r.mu.Lock()
+	r.totals.add(u, cost)
+	r.mu.Unlock()
+}
+
+// addTotals adds the usage of a call shared with other requests
+func (r *RequestUsage) addTotals(t UsageTotals) {
+	r.mu.Lock()
+	r.totals.Requests += t.Requests
+	r.totals.InputTokens += t.InputTokens
+	r.totals.OutputTokens += t.OutputTokens
+	r.totals.CostUSD += t.CostUSD
+	r.mu.Unlock()
+}
+
+// Totals returns the usage recorded so far
+func (r *RequestUsage) Totals() UsageTotals {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.totals
+}
+
+type requestUsageKey struct{}
+
+// WithRequestUsage returns a context whose labeling API calls are also recorded in the
+// returned RequestUsage
+func WithRequestUsage(ctx context.Context) (context.Context, *RequestUsage) {
+	ru := &RequestUsage{}
+	return context.WithValue(ctx, requestUsageKey{}, ru), ru
+}
+
+func requestUsageFrom(ctx context.Context) *RequestUsage {
+	ru, _ := ctx.Value(requestUsageKey{}).(*RequestUsage)
+	return ru
+}
@@ -0,0 +1,193 @@
+package labeler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"ize/internal/config"
+)
+
+func TestUsageTracker(t *testing.T) {
+	now := time.Date(2024, 1, 1, 23, 0, 0, 0, time.UTC)
+	tracker := NewUsageTracker(map[string]config.ModelPricing{
+		"custom":      {InputPerMTok: 1, OutputPerMTok: 2},
+		"gpt-4o-mini": {InputPerMTok: 10, OutputPerMTok: 20}, // Overrides the built-in price
+	}, 0.01)
+	tracker.now = func() time.Time { return now }
+
+	ctx, request := WithRequestUsage(context.Background())
+	if cost := tracker.record(ctx, "custom", "phones", tokenUsage{InputTokens: 1000, OutputTokens: 500}); math.Abs(cost-0.002) > 1e-12 {
+		t.Errorf("record(custom) cost = %v, want 0.002", cost)
+	}
+	tracker.record(ctx, "gpt-4o-mini", "laptops", tokenUsage{InputTokens: 100, OutputTokens: 100})
+	tracker.record(context.Background(), "llama3.2", "phones", tokenUsage{InputTokens: 50, OutputTokens: 5})
+
+	if !tracker.Priced("claude-3-haiku-20240307") || tracker.Priced("llama3.2") {
+		t.Error("Priced() should know the built-in models only")
+	}
+	if totals := request.Totals(); totals.Requests != 2 || totals.InputTokens != 1100 || math.Abs(totals.CostUSD-0.005) > 1e-12 {
+		t.Errorf("request Totals() = %+v, want the two calls made with its context", totals)
+	}
+
+	report := tracker.Report(0)
+	if report.Totals.Requests != 3 || report.Totals.InputTokens != 1150 || report.Totals.OutputTokens != 605 {
+		t.Errorf("Report() totals = %+v", report.Totals)
+	}
+	if llama := report.Models["llama3.2"]; llama.Requests != 1 || llama.CostUSD != 0 {
+		t.Errorf("Report() llama3.2 = %+v, want one free call", llama)
+	}
+	if len(report.Queries) != 2 || report.Queries[0].Query != "laptops" || report.Queries[1].Requests != 2 {
+		t.Errorf("Report() queries = %+v, want laptops (most expensive) then phones (2 calls)", report.Queries)
+	}
+	if limited := tracker.Report(1); len(limited.Queries) != 1 {
+		t.Errorf("Report(1) has %d queries, want 1", len(limited.Queries))
+	}
+
+	// $0.005 of the $0.01 budget is spent
+	if err := tracker.checkBudget(); err != nil {
+		t.Fatalf("checkBudget() under budget error = %v", err)
+	}
+	tracker.record(ctx, "custom", "phones", tokenUsage{OutputTokens: 2500})
+	if err := tracker.checkBudget(); !errors.Is(err, ErrBudgetExceeded) {
+		t.Errorf("checkBudget() over budget error = %v, want ErrBudgetExceeded", err)
+	}
+	tracker.reject()
+	if today := tracker.Report(0).Today; today.Date != "2024-01-01" || !today.Exceeded || today.Rejected != 1 {
+		t.Errorf("Report() today = %+v, want the exceeded budget with one rejection", today)
+	}
+
+	// The budget starts over the next UTC day
+	now = now.Add(2 * time.Hour)
+	if err := tracker.checkBudget(); err != nil {
+		t.Errorf("checkBudget() the next day error = %v", err)
+	}
+	if today := tracker.Report(0).Today; today.Date != "2024-01-02" || today.CostUSD != 0 || today.Exceeded {
+		t.Errorf("Report() the next day = %+v, want nothing spent", today)
+	}
+}
+
+func TestUsageTracker_QueryLimit(t *testing.T) {
+	tracker := NewUsageTracker(nil, 0)
+	for i := 0; i < maxTrackedQueries+10; i++ {
+		tracker.record(context.Background(), "m", fmt.Sprintf("q%d", i), tokenUsage{InputTokens: 1})
+	}
+	tracker.record(context.Background(), "m", "q0", tokenUsage{InputTokens: 1})
+
+	report := tracker.Report(0)
+	if len(report.Queries) != maxTrackedQueries || report.Totals.Requests != maxTrackedQueries+11 {
+		t.Errorf("Report() has %d queries and %d requests, want %d and %d", len(report.Queries), report.Totals.Requests, maxTrackedQueries, maxTrackedQueries+11)
+	}
+	if err := tracker.checkBudget(); err != nil {
+		t.Errorf("checkBudget() without a budget error = %v", err)
+	}
+}
+
+func TestClient_RecordsUsage(t *testing.T) {
+	tests := []struct {
+		provider string
+		response interface{}
+	}{
+		{ProviderAnthropic, map[string]interface{}{
+			"content": []map[string]string{{"type": "text", "text": "Label"}},
+			"usage":   map[string]int{"input_tokens": 120, "output_tokens": 4},
+		}},
+		{ProviderOpenAI, map[string]interface{}{
+			"choices": []map[string]interface{}{{"message": map[string]string{"role": "assistant", "content": "Label"}}},
+			"usage":   map[string]int{"prompt_tokens": 120, "completion_tokens": 4},
+		}},
+		{ProviderOllama, map[string]interface{}{
+			"message":           map[string]string{"role": "assistant", "content": "Label"},
+			"prompt_eval_count": 120,
+			"eval_count":        4,
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.provider, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				json.NewEncoder(w).Encode(tt.response)
+			}))
+			defer server.Close()
+
+			client := newTestClient(t, config.LabelingConfig{
+				Provider: tt.provider,
+				APIKey:   "test-key",
+				BaseURL:  server.URL,
+				Model:    "test-model",
+				Pricing:  map[string]config.ModelPricing{"test-model": {InputPerMTok: 1000, OutputPerMTok: 5000}},
+			})
+			ctx, request := WithRequestUsage(context.Background())
+			if _, err := client.GenerateClusterName(ctx, ClusterStats{Size: 3, Query: "shoes"}); err != nil {
+				t.Fatalf("GenerateClusterName() error = %v", err)
+			}
+
+			report := client.Usage().Report(0)
+			want := UsageTotals{Requests: 1, InputTokens: 120, OutputTokens: 4, CostUSD: 0.14}
+			got := report.Models["test-model"]
+			if got.Requests != want.Requests || got.InputTokens != want.InputTokens || got.OutputTokens != want.OutputTokens || math.Abs(got.CostUSD-want.CostUSD) > 1e-9 {
+				t.Errorf("model usage = %+v, want %+v", got, want)
+			}
+			if len(report.Queries) != 1 || report.Queries[0].Query != "shoes" {
+				t.Errorf("query usage = %+v, want shoes", report.Queries)
+			}
+			if request.Totals().Requests != 1 {
+				t.Errorf("request usage = %+v, want one call", request.Totals())
+			}
+		})
+	}
+}
+
+func TestClient_DailyBudget(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		json.NewEncoder(w).Encode(ollamaChatResponse{
+			Message:         message{Role: "assistant", Content: "Label"},
+			PromptEvalCount: 1000,
+		})
+	}))
+	defer server.Close()
+
+	client := newTestClient(t, config.LabelingConfig{
+		Provider:       ProviderOllama,
+		BaseURL:        server.URL,
+		Pricing:        map[string]config.ModelPricing{ollamaModel: {InputPerMTok: 1000}},
+		DailyBudgetUSD: 1,
+	})
+
+	// The first call spends the whole budget; it still completes
+	if _, err := client.GenerateClusterLabels(context.Background(), []ClusterStats{{Size: 1}}); err != nil {
+		t.Fatalf("GenerateClusterLabels() within budget error = %v", err)
+	}
+	if _, err := client.GenerateClusterLabels(context.Background(), []ClusterStats{{Size: 3}}); !errors.Is(err, ErrBudgetExceeded) {
+		t.Errorf("GenerateClusterLabels() over budget error = %v, want ErrBudgetExceeded", err)
+	}
+	if _, err := client.GenerateClusterName(context.Background(), ClusterStats{Size: 3}); !errors.Is(err, ErrBudgetExceeded) {
+		t.Errorf("GenerateClusterName() over budget error = %v, want ErrBudgetExceeded", err)
+	}
+
+	// Cached labels are still served, alongside the clusters that were refused
+	if name, err := client.GenerateClusterName(context.Background(), ClusterStats{Size: 1}); err != nil || name != "Label" {
+		t.Errorf("GenerateClusterName(cached) over budget = %q, %v, want the cached name", name, err)
+	}
+	labels, err := client.GenerateClusterLabels(context.Background(), []ClusterStats{{Size: 1}, {Size: 4}, {Size: 5}})
+	if !errors.Is(err, ErrBudgetExceeded) || !reflect.DeepEqual(labels, []Label{{Name: "Label"}, {}, {}}) {
+		t.Errorf("GenerateClusterLabels(partly cached) = %+v, %v, want the cached label and ErrBudgetExceeded", labels, err)
+	}
+	if calls := atomic.LoadInt32(&calls); calls != 1 {
+		t.Errorf("API called %d times, want 1", calls)
+	}
+	// Each refused request counts once
+	if rejected := client.Usage().Report(0).Today.Rejected; rejected != 3 {
+		t.Errorf("rejected = %d, want 3", rejected)
+	}
+}
@@ -1,4 +1,4 @@
-import type { SearchRequest, SearchResponse, RipperResponse, ClusterResponse } from '../types'
+import type { SearchRequest, SearchResponse, RipperResponse, ClusterRequest, ClusterResponse, ClusterItemsRequest, ClusterItemsResponse, ScatterGatherRequest, ScatterGatherResponse, CurateRequest } from '../types'
 
 // Use relative URL to leverage Vite proxy in development
 // In production, set VITE_API_URL environment variable if backend is on different domain
@@ -86,8 +86,15 @@ export async function searchRipper(query: string, facetFilters: string[][] = [])
   return response.json() as Promise<RipperResponse>
 }
 
-export async function searchCluster(query: string, facetFilters: string[][] = []): Promise<ClusterResponse> {
-  const requestBody: SearchRequest = { query }
+// Optional /api/cluster request fields
+export type ClusterSearchOptions = Omit<ClusterRequest, 'query' | 'facetFilters'>
+
+export async function searchCluster(
+  query: string,
+  facetFilters: string[][] = [],
+  options: ClusterSearchOptions = {},
+): Promise<ClusterResponse> {
+  const requestBody: ClusterRequest = { query, ...options }
   if (facetFilters.length > 0) requestBody.facetFilters = facetFilters
 
   const response = await fetch(`${API_BASE_URL}/api/cluster`, {
@@ -105,3 +112,68 @@ export async function searchCluster(query: string, facetFilters: string[][] = []
 
   return response.json() as Promise<ClusterResponse>
 }
+
+// Fetch one page of all items matching a cluster's rule ("load more")
+export async function fetchClusterItems(
+  query: string,
+  rule: string[][],
+  facetFilters: string[][] = [],
+  page = 0,
+  hitsPerPage?: number,
+): Promise<ClusterItemsResponse> {
+  const requestBody: ClusterItemsRequest = { query, rule, page }
+  if (facetFilters.length > 0) requestBody.facetFilters = facetFilters
+  if (hitsPerPage) requestBody.hitsPerPage = hitsPerPage
+
+  const response = await fetch(`${API_BASE_URL}/api/cluster/items`, {
+    method: 'POST',
+    headers: {
+      'Content-Type': 'application/json',
+    },
+    body: JSON.stringify(requestBody),
+  })
+
+  if (!response.ok) {
+    const errorText = await response.text()
+    throw new Error(`Cluster items fetch failed: ${response.status} ${errorText}`)
+  }
+
+  return response.json() as Promise<ClusterItemsResponse>
+}
+
+// Run one step of a Scatter/Gather session (scatter, gather, back or forward)
+export async function scatterGather(request: ScatterGatherRequest): Promise<ScatterGatherResponse> {
+  const response = await fetch(`${API_BASE_URL}/api/scatter-gather`, {
+    method: 'POST',
+    headers: {
+      'Content-Type': 'application/json',
+    },
+    body: JSON.stringify(request),
+  })
+
+  if (!response.ok) {
+    const errorText = await response.text()
+    throw new Error(`Scatter/Gather ${request.action} failed: ${response.status} ${errorText}`)
+  }
+
+  return response.json() as Promise<ScatterGatherResponse>
+}
+
+// Merge, split or rename clusters of a query; later cluster requests for the same query
+// and filters return the curated grouping
+export async function curateCluster(request: CurateRequest): Promise<ClusterResponse> {
+  const response = await fetch(`${API_BASE_URL}/api/curate`, {
+    method: 'POST',
+    headers: {
+      'Content-Type': 'application/json',
+    },
+    body: JSON.stringify(request),
+  })
+
+  if (!response.ok) {
+    const errorText = await response.text()
+    throw new Error(`Curate ${request.action} failed: ${response.status} ${errorText}`)
+  }
+
+  return response.json() as Promise<ClusterResponse>
+}
@@ -5,6 +5,29 @@ export interface SearchRequest {
   facetFilters?: string[][]
 }
 
+export type AssignmentMode = 'overlapping' | 'first_match' | 'best_match'
+
+export interface ClusterRequest extends SearchRequest {
+  assignment?: AssignmentMode
+  explain?: boolean
+  exactCounts?: boolean // Count each cluster rule in Algolia for exact totalCount
+  previousToken?: string // resultToken of the response being refined, to keep cluster identities
+}
+
+export interface ClusterItemsRequest extends SearchRequest {
+  rule: string[][] // Cluster rule, ANDed with facetFilters
+  page?: number // Zero-based
+  hitsPerPage?: number // Defaults to 20, at most 1000
+}
+
+export interface ClusterItemsResponse {
+  items: SearchResult[]
+  nbHits: number // True cluster size
+  page: number
+  nbPages: number
+  hitsPerPage: number
+}
+
 export interface FacetMeta {
   field: string
   displayName: string
@@ -39,6 +62,7 @@ export interface Facet {
 export interface RipperGroup {
   facetName: string
   facetValue: string
+  name?: string // Curated label
   items: SearchResult[]
   count: number // Accurate count from Algolia facets
 }
@@ -47,6 +71,7 @@ export interface RipperResponse {
   groups: RipperGroup[]
   otherGroup: SearchResult[]
   facetMeta?: FacetMeta[]
+  curated?: boolean // Groups come from a curated grouping
 }
 
 export interface FacetCount {
@@ -63,10 +88,17 @@ export interface RuleQuality {
 }
 
 export interface ClusterGroup {
+  id?: string // Identity kept across refinements
+  color: number // Palette index kept with the identity
+  stable?: boolean // Identity inherited from the previous result
+  pinned?: boolean // Name pinned by a curator
   name: string
+  description?: string // One-sentence description (batch labeling only)
   items: SearchResult[]
-  percentage: number // Approximate percentage (~X%)
+  percentage: number // Approximate percentage (~X%), exact with totalCount
+  totalCount?: number // Exact cluster size (exactCounts only)
   topFacets: FacetCount[]
+  topTerms?: string[] // Topic terms (topic groupings only)
   rule?: string[][] // Algolia filter format for "load more"
   ruleDescription?: string // Human-readable rule
   ruleQuality?: RuleQuality // Rule quality metrics
@@ -77,4 +109,51 @@ export interface ClusterResponse {
   otherGroup: SearchResult[]
   clusterCount: number
   totalHits: number
+  repeatedItems?: Record<string, number> // Item ID -> number of groups, for items in more than one
+  explanations?: Record<string, ItemExplanation> // Item ID -> membership explanation (when requested)
+  resultToken?: string // Pass as previousToken when refining this result
+  curated?: boolean // Groups come from a curated grouping
+}
+
+export interface ClusterMembership {
+  group: number // Index into groups
+  name: string
+  satisfiedClauses: string[]
+  failedClauses: string[]
+  distance: number // Average distance to the group's other items
+}
+
+export interface ItemExplanation {
+  memberships: ClusterMembership[] // Empty for "Other" items
+  silhouette: number // -1..1
+  nearestAlternative?: ClusterMembership
+}
+
+export type ScatterGatherAction = 'scatter' | 'gather' | 'back' | 'forward'
+
+export interface ScatterGatherRequest {
+  action: ScatterGatherAction
+  sessionId?: string // Required except for 'scatter'
+  query?: string // 'scatter' only
+  facetFilters?: string[][] // 'scatter' only
+  clusterIds?: string[] // 'gather': clusters of the current step ('other' for the Other group)
+}
+
+export interface ScatterGatherResponse extends ClusterResponse {
+  sessionId: string
+  step: number // Zero-based position in the session history
+  steps: number
+  gathered?: string[] // Cluster IDs of the previous step gathered into this one
+  canBack: boolean
+  canForward: boolean
+}
+
+export type CurateAction = 'merge' | 'split' | 'rename'
+
+export interface CurateRequest extends SearchRequest {
+  action: CurateAction
+  clusterIds?: string[] // 'merge': the two clusters to merge
+  clusterId?: string // 'split' and 'rename': the cluster to edit
+  parts?: number // 'split': number of parts (default 2, at most 5)
+  name?: string // 'rename': the name to pin
 }